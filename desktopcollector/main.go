@@ -8,6 +8,8 @@ package main
 import (
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"go.opentelemetry.io/collector/component"
@@ -52,20 +54,59 @@ func runInteractive(params otelcol.CollectorSettings) error {
 }
 
 func newCommand(set otelcol.CollectorSettings) *cobra.Command {
-	var httpPortFlag, grpcPortFlag, browserPortFlag int
-	var hostFlag, dbFlag string
+	var httpPortFlag, grpcPortFlag, browserPortFlag, maxSpansFlag, ingestRateFlag, dbThreadsFlag, defaultPageSizeFlag, maxPageSizeFlag int
+	var hostFlag, browserSocketFlag, dbFlag, dbMemoryLimitFlag, retentionFlag, spillAfterFlag, grpcAddrFlag, forwardToFlag, groupByFlag, authTokenFlag, tlsCertFlag, tlsKeyFlag, logLevelFlag, logFormatFlag string
+	var authUIFlag, tlsSelfSignedFlag, metricsFlag, readOnlyFlag bool
+	var maxBodyBytesFlag int64
+	var corsOriginFlags, redactAttrFlags, redactPatternFlags, anonymizeAttrFlags, listenerFlags []string
+	var retentionIntervalFlag, shutdownTimeoutFlag, idleTimeoutFlag time.Duration
 
 	rootCmd := &cobra.Command{
 		Use:          set.BuildInfo.Command,
 		Version:      set.BuildInfo.Version,
 		SilenceUsage: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
+			browserEndpoint := hostFlag + `:` + strconv.Itoa(browserPortFlag)
+			if browserSocketFlag != "" {
+				browserEndpoint = "unix://" + browserSocketFlag
+			}
 			set.ConfigProviderSettings.ResolverSettings.URIs = []string{
 				`yaml:receivers::otlp::protocols::http::endpoint: ` + hostFlag + `:` + strconv.Itoa(httpPortFlag),
 				`yaml:receivers::otlp::protocols::grpc::endpoint: ` + hostFlag + `:` + strconv.Itoa(grpcPortFlag),
 				`yaml:exporters::desktop:`,
-				`yaml:exporters::desktop::endpoint: ` + hostFlag + `:` + strconv.Itoa(browserPortFlag),
+				`yaml:exporters::desktop::endpoint: ` + browserEndpoint,
 				`yaml:exporters::desktop::db: ` + dbFlag,
+				`yaml:exporters::desktop::read_only: ` + strconv.FormatBool(readOnlyFlag),
+				`yaml:exporters::desktop::db_memory_limit: "` + dbMemoryLimitFlag + `"`,
+				`yaml:exporters::desktop::db_threads: ` + strconv.Itoa(dbThreadsFlag),
+				`yaml:exporters::desktop::grpc_endpoint: ` + grpcAddrFlag,
+				`yaml:exporters::desktop::listeners: ` + yamlList(listenerFlags),
+				`yaml:exporters::desktop::forward_to: ` + forwardToFlag,
+				// Quoted so a trace count stays a string rather than being read as a number
+				`yaml:exporters::desktop::retention: "` + retentionFlag + `"`,
+				`yaml:exporters::desktop::retention_interval: ` + retentionIntervalFlag.String(),
+				`yaml:exporters::desktop::spill_after: "` + spillAfterFlag + `"`,
+				`yaml:exporters::desktop::max_spans: ` + strconv.Itoa(maxSpansFlag),
+				`yaml:exporters::desktop::ingest_rate: ` + strconv.Itoa(ingestRateFlag),
+				`yaml:exporters::desktop::default_page_size: ` + strconv.Itoa(defaultPageSizeFlag),
+				`yaml:exporters::desktop::max_page_size: ` + strconv.Itoa(maxPageSizeFlag),
+				`yaml:exporters::desktop::redact_attributes: ` + yamlList(redactAttrFlags),
+				`yaml:exporters::desktop::redact_patterns: ` + yamlList(redactPatternFlags),
+				`yaml:exporters::desktop::anonymize_attributes: ` + yamlList(anonymizeAttrFlags),
+				`yaml:exporters::desktop::group_by: ` + groupByFlag,
+				`yaml:exporters::desktop::max_body_bytes: ` + strconv.FormatInt(maxBodyBytesFlag, 10),
+				`yaml:exporters::desktop::shutdown_timeout: ` + shutdownTimeoutFlag.String(),
+				`yaml:exporters::desktop::idle_timeout: ` + idleTimeoutFlag.String(),
+				// Quoted so a token of digits stays a string
+				`yaml:exporters::desktop::auth_token: "` + authTokenFlag + `"`,
+				`yaml:exporters::desktop::auth_ui: ` + strconv.FormatBool(authUIFlag),
+				`yaml:exporters::desktop::tls_cert: ` + tlsCertFlag,
+				`yaml:exporters::desktop::tls_key: ` + tlsKeyFlag,
+				`yaml:exporters::desktop::tls_self_signed: ` + strconv.FormatBool(tlsSelfSignedFlag),
+				`yaml:exporters::desktop::cors_origins: ` + yamlList(corsOriginFlags),
+				`yaml:exporters::desktop::metrics: ` + strconv.FormatBool(metricsFlag),
+				`yaml:exporters::desktop::log_level: ` + logLevelFlag,
+				`yaml:exporters::desktop::log_format: ` + logFormatFlag,
 				`yaml:service::pipelines::traces::receivers: [otlp]`,
 				`yaml:service::pipelines::traces::exporters: [desktop]`,
 				`yaml:service::pipelines::metrics::receivers: [otlp]`,
@@ -86,7 +127,46 @@ func newCommand(set otelcol.CollectorSettings) *cobra.Command {
 	rootCmd.Flags().IntVar(&grpcPortFlag, "grpc", 4317, "The port number on which we listen for OTLP grpc payloads")
 	rootCmd.Flags().IntVar(&browserPortFlag, "browser", 8000, "The port number where we expose our data")
 	rootCmd.Flags().StringVar(&hostFlag, "host", "localhost", "The host where we expose our all endpoints (OTLP receivers and browser)")
+	rootCmd.Flags().StringVar(&browserSocketFlag, "browser-socket", "", "The path of a unix socket to expose our data on in place of --host and --browser. TLS flags don't apply to it.")
 	rootCmd.Flags().StringVar(&dbFlag, "db", "", "The path of your database file. Omitting this flag opens DuckDB in in-memory mode, with no data persisted to disk.")
+	rootCmd.Flags().BoolVar(&readOnlyFlag, "read-only", false, "Open the --db file read-only, e.g. to look through traces captured elsewhere. The file must exist, and nothing can be added to it or removed from it.")
+	rootCmd.Flags().StringVar(&dbMemoryLimitFlag, "db-memory-limit", "", "The most memory DuckDB may use (e.g. 2GB). Omitting this flag lets it use up to 80% of the system's memory.")
+	rootCmd.Flags().IntVar(&dbThreadsFlag, "db-threads", 0, "The most threads DuckDB runs queries on. Omitting this flag runs a thread per core.")
+	rootCmd.Flags().StringVar(&grpcAddrFlag, "grpc-addr", "", "The host and port where the viewer itself receives OTLP grpc payloads, bypassing the collector pipeline (e.g. localhost:4319). Omitting this flag disables it.")
+	rootCmd.Flags().StringArrayVar(&listenerFlags, "listener", nil, "Another host and port where the viewer receives OTLP grpc payloads, followed by resource attributes to set on whatever it receives that doesn't set them already (e.g. localhost:4320,env=staging). Repeat the flag to listen on several.")
+	rootCmd.Flags().StringVar(&forwardToFlag, "forward-to", "", "The host and port of an OTLP grpc endpoint (e.g. collector:4317) to forward every span received to once it is stored. Spans are dropped rather than held up if it falls behind or is down.")
+	rootCmd.Flags().StringVar(&retentionFlag, "retention", "", "How many traces to keep: either a duration (e.g. 30m) after which traces are evicted, or a maximum number of traces (e.g. 10000). Omitting this flag keeps every trace.")
+	rootCmd.Flags().DurationVar(&retentionIntervalFlag, "retention-interval", time.Minute, "How often the retention and spill policies are enforced")
+	rootCmd.Flags().StringVar(&spillAfterFlag, "spill-after", "", "Keep recent traces in memory and move older ones to the --db file: either a duration (e.g. 30m) after which traces are moved, or a number of traces (e.g. 1000) to keep in memory. Requires --db.")
+	rootCmd.Flags().IntVar(&maxSpansFlag, "max-spans", 0, "The most spans to keep. Once it is reached, the least recently active traces are evicted whole to make room for new spans. Omitting this flag keeps every span.")
+	rootCmd.Flags().IntVar(&ingestRateFlag, "ingest-rate", 0, "The most spans per second to accept from your services, in bursts of up to a second's worth. Spans past it are dropped. Omitting this flag accepts every span.")
+	rootCmd.Flags().IntVar(&defaultPageSizeFlag, "default-page-size", 0, "The number of traces the traces list and search return when no limit is asked for. Omitting this flag returns every trace.")
+	rootCmd.Flags().IntVar(&maxPageSizeFlag, "max-page-size", 0, "The most traces the traces list and search return, whatever limit is asked for. Larger limits are clamped to it. Omitting this flag allows any limit.")
+	rootCmd.Flags().StringVar(&groupByFlag, "group-by", "", "A resource attribute (e.g. tenant.id) to sort traces into groups by, for the traces list to filter on. Traces without it are in the default group.")
+	rootCmd.Flags().StringArrayVar(&redactAttrFlags, "redact-attr", nil, "An attribute key (e.g. user.email) whose values are replaced with *** before spans are stored. Repeat the flag to redact several.")
+	rootCmd.Flags().StringArrayVar(&redactPatternFlags, "redact-pattern", nil, "A regular expression (e.g. \\d{13,16}) whose matches in string attribute values are replaced with *** before spans are stored. Repeat the flag to redact several.")
+	rootCmd.Flags().StringArrayVar(&anonymizeAttrFlags, "anonymize-attr", nil, "An attribute key (e.g. http.url) whose values are hashed, along with service and span names, in exports asked for with ?anonymize=true. Repeat the flag to anonymize several.")
+	rootCmd.Flags().Int64Var(&maxBodyBytesFlag, "max-body-bytes", 64<<20, "The largest OTLP/HTTP, Zipkin or import request body to accept, before and after decompressing it. Larger ones are refused with 413.")
+	rootCmd.Flags().StringVar(&authTokenFlag, "auth-token", "", "A token every /api request must send as \"Authorization: Bearer <token>\". Omitting this flag leaves the API open.")
+	rootCmd.Flags().BoolVar(&authUIFlag, "auth-ui", false, "Require the auth token for the UI as well as the API")
+	rootCmd.Flags().StringVar(&tlsCertFlag, "tls-cert", "", "The path of a PEM certificate to serve the browser over HTTPS with. Requires --tls-key.")
+	rootCmd.Flags().StringVar(&tlsKeyFlag, "tls-key", "", "The path of the PEM private key of --tls-cert")
+	rootCmd.Flags().BoolVar(&tlsSelfSignedFlag, "tls-self-signed", false, "Serve the browser over HTTPS with a self-signed certificate generated on start")
+	rootCmd.Flags().StringArrayVar(&corsOriginFlags, "cors-origin", nil, "An origin (e.g. http://localhost:3000), or * for any, whose pages may call the API. Repeat the flag to allow several. Omitting this flag sends no CORS headers.")
+	rootCmd.Flags().BoolVar(&metricsFlag, "metrics", false, "Serve metrics about the viewer itself on /metrics, in the Prometheus format")
+	rootCmd.Flags().StringVar(&logLevelFlag, "log-level", "info", "The least severe level requests are logged at: debug, info, warn, or error. At debug the spans each payload adds are logged too.")
+	rootCmd.Flags().StringVar(&logFormatFlag, "log-format", "text", "How requests are logged: text or json")
+	rootCmd.Flags().DurationVar(&shutdownTimeoutFlag, "shutdown-timeout", 5*time.Second, "How long in-flight requests are given to finish when the viewer is stopped")
+	rootCmd.Flags().DurationVar(&idleTimeoutFlag, "idle-timeout", 0, "Stop the viewer once it has gone this long (e.g. 2h) without an API request or any telemetry to store. Omitting this flag keeps it running.")
 	return rootCmd
 }
 
+// yamlList formats values as a yaml flow sequence, quoting each so colons and asterisks are kept as they are
+func yamlList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
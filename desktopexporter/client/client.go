@@ -0,0 +1,239 @@
+// Package client calls the otel-desktop-viewer API from Go, returning the same types the viewer
+// serves its JSON from.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// The viewer's types are aliased here, as their own package is internal to the viewer
+type (
+	TraceSummaries = telemetry.TraceSummaries
+	TraceSummary   = telemetry.TraceSummary
+	TraceData      = telemetry.TraceData
+	SpanData       = telemetry.SpanData
+)
+
+// APIError is returned for any response outside the 2xx range
+type APIError struct {
+	StatusCode int
+	// Message is the body of the response, which the viewer fills in for bad requests
+	Message string
+}
+
+func (err *APIError) Error() string {
+	if err.Message == "" {
+		return fmt.Sprintf("viewer responded with %d %s", err.StatusCode, http.StatusText(err.StatusCode))
+	}
+	return fmt.Sprintf("viewer responded with %d %s: %s", err.StatusCode, http.StatusText(err.StatusCode), err.Message)
+}
+
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	authToken  string
+}
+
+// Option configures optional Client behaviour
+type Option func(*Client)
+
+// WithHTTPClient sends requests with httpClient in place of http.DefaultClient
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithAuthToken sends the token the viewer was started with --auth-token on every request
+func WithAuthToken(token string) Option {
+	return func(c *Client) {
+		c.authToken = token
+	}
+}
+
+// NewClient calls the viewer served at baseURL, such as http://localhost:8000
+func NewClient(baseURL string, opts ...Option) (*Client, error) {
+	parsed, err := url.Parse(baseURL)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: must be a scheme and host, such as http://localhost:8000", baseURL)
+	}
+	parsed.Path = strings.TrimSuffix(parsed.Path, "/")
+
+	c := &Client{
+		baseURL:    parsed,
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// TraceQuery narrows down and pages through the traces ListTraces and Search return.
+// The zero value returns every trace, most recently active first.
+type TraceQuery struct {
+	Limit  int
+	Offset int
+
+	// Sort is start, duration, or spanCount, and empty for the default ordering
+	Sort      string
+	Ascending bool
+
+	Services    []string
+	MinDuration time.Duration
+	MaxDuration time.Duration
+	Start       time.Time
+	End         time.Time
+
+	// Status is Error or Ok, and empty for traces with or without errors
+	Status string
+	// Attributes are each key=value or just key, as the viewer's attr parameter takes them
+	Attributes []string
+	EventNames []string
+
+	// Operation keeps traces with a span of this name, matched as OperationMatch says:
+	// exact, prefix, or glob, and exactly when it is empty
+	Operation      string
+	OperationMatch string
+}
+
+func (query TraceQuery) values() url.Values {
+	values := url.Values{}
+	if query.Limit > 0 {
+		values.Set("limit", strconv.Itoa(query.Limit))
+	}
+	if query.Offset > 0 {
+		values.Set("offset", strconv.Itoa(query.Offset))
+	}
+	if query.Sort != "" {
+		values.Set("sort", query.Sort)
+	}
+	if query.Ascending {
+		values.Set("order", "asc")
+	}
+	for _, service := range query.Services {
+		values.Add("service", service)
+	}
+	if query.MinDuration > 0 {
+		values.Set("minDuration", query.MinDuration.String())
+	}
+	if query.MaxDuration > 0 {
+		values.Set("maxDuration", query.MaxDuration.String())
+	}
+	if !query.Start.IsZero() {
+		values.Set("start", query.Start.Format(time.RFC3339Nano))
+	}
+	if !query.End.IsZero() {
+		values.Set("end", query.End.Format(time.RFC3339Nano))
+	}
+	if query.Status != "" {
+		values.Set("status", query.Status)
+	}
+	for _, attr := range query.Attributes {
+		values.Add("attr", attr)
+	}
+	for _, event := range query.EventNames {
+		values.Add("event", event)
+	}
+	if query.Operation != "" {
+		values.Set("operation", query.Operation)
+	}
+	if query.OperationMatch != "" {
+		values.Set("operationMatch", query.OperationMatch)
+	}
+	return values
+}
+
+// ListTraces returns the summaries of the traces matching the query
+func (c *Client) ListTraces(ctx context.Context, query TraceQuery) (TraceSummaries, error) {
+	summaries := TraceSummaries{}
+	err := c.get(ctx, "/api/traces", query.values(), &summaries)
+	return summaries, err
+}
+
+// Search returns the summaries of the traces matching the query that have a span whose name,
+// status message, or any attribute value contains the text (case-insensitive)
+func (c *Client) Search(ctx context.Context, text string, query TraceQuery) (TraceSummaries, error) {
+	values := query.values()
+	values.Set("q", text)
+
+	summaries := TraceSummaries{}
+	err := c.get(ctx, "/api/search", values, &summaries)
+	return summaries, err
+}
+
+// FindTraces returns the summaries of the traces whose root span has the attribute key set to
+// value, such as a request ID a service stamps on each request it handles
+func (c *Client) FindTraces(ctx context.Context, key string, value string) (TraceSummaries, error) {
+	values := url.Values{}
+	values.Set("key", key)
+	values.Set("value", value)
+
+	summaries := TraceSummaries{}
+	err := c.get(ctx, "/api/traces/by-attr", values, &summaries)
+	return summaries, err
+}
+
+// GetTrace returns every span of a trace. The viewer responds to trace IDs it doesn't have
+// with a bad request, so they come back as an APIError with a 400 status code.
+func (c *Client) GetTrace(ctx context.Context, traceID string) (TraceData, error) {
+	trace := TraceData{}
+	err := c.get(ctx, "/api/traces/"+url.PathEscape(traceID), nil, &trace)
+	return trace, err
+}
+
+// ClearData clears every trace, log, and metric, and returns the number of traces cleared
+func (c *Client) ClearData(ctx context.Context) (int, error) {
+	cleared := telemetry.ClearedTraces{}
+	err := c.get(ctx, "/api/clearData", nil, &cleared)
+	return cleared.ClearedTraces, err
+}
+
+// LoadSampleData adds the viewer's sample traces
+func (c *Client) LoadSampleData(ctx context.Context) error {
+	return c.get(ctx, "/api/sampleData", nil, nil)
+}
+
+// get sends a GET request for path and decodes the JSON response into result, unless it is nil
+func (c *Client) get(ctx context.Context, path string, values url.Values, result any) error {
+	requestURL := *c.baseURL
+	requestURL.Path += path
+	requestURL.RawQuery = values.Encode()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL.String(), nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %s", err.Error())
+	}
+	if c.authToken != "" {
+		request.Header.Set("Authorization", "Bearer "+c.authToken)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("could not send request: %s", err.Error())
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		return &APIError{StatusCode: response.StatusCode, Message: strings.TrimSpace(string(body))}
+	}
+
+	if result == nil {
+		return nil
+	}
+	if err = json.NewDecoder(response.Body).Decode(result); err != nil {
+		return fmt.Errorf("could not decode response: %s", err.Error())
+	}
+	return nil
+}
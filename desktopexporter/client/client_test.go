@@ -0,0 +1,110 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/client"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/server"
+	"github.com/stretchr/testify/assert"
+)
+
+func setup(opts ...server.Option) (*httptest.Server, func()) {
+	viewer := server.NewServer("localhost:8000", "", opts...)
+	testServer := httptest.NewServer(viewer.Handler(false))
+
+	return testServer, func() {
+		testServer.Close()
+		viewer.Store.Close()
+	}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	testServer, teardown := setup(server.WithAuthToken("s3cret", false))
+	defer teardown()
+
+	c, err := client.NewClient(testServer.URL+"/", client.WithHTTPClient(testServer.Client()), client.WithAuthToken("s3cret"))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	err = c.LoadSampleData(ctx)
+	assert.NoError(t, err)
+
+	t.Run("List Traces", func(t *testing.T) {
+		summaries, err := c.ListTraces(ctx, client.TraceQuery{})
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, summaries.TotalCount)
+			assert.Len(t, summaries.TraceSummaries, 2)
+		}
+
+		summaries, err = c.ListTraces(ctx, client.TraceQuery{Services: []string{"sample.currencyservice"}, Limit: 1})
+		if assert.NoError(t, err) && assert.Len(t, summaries.TraceSummaries, 1) {
+			assert.Equal(t, "7979cec4d1c04222fa9a3c7c97c0a99c", summaries.TraceSummaries[0].TraceID)
+			assert.Nil(t, summaries.NextOffset)
+		}
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		summaries, err := c.Search(ctx, "currency", client.TraceQuery{})
+		if assert.NoError(t, err) && assert.Len(t, summaries.TraceSummaries, 1) {
+			assert.Equal(t, "7979cec4d1c04222fa9a3c7c97c0a99c", summaries.TraceSummaries[0].TraceID)
+		}
+	})
+
+	t.Run("Find Traces", func(t *testing.T) {
+		summaries, err := c.FindTraces(ctx, "rpc.system", "grpc")
+		if assert.NoError(t, err) && assert.Len(t, summaries.TraceSummaries, 1) {
+			assert.Equal(t, "7979cec4d1c04222fa9a3c7c97c0a99c", summaries.TraceSummaries[0].TraceID)
+		}
+	})
+
+	t.Run("Get Trace", func(t *testing.T) {
+		trace, err := c.GetTrace(ctx, "42957c7c2fca940a0d32a0cdd38c06a4")
+		if assert.NoError(t, err) {
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", trace.TraceID)
+			assert.Len(t, trace.Spans, 3)
+		}
+	})
+
+	t.Run("Bad Request", func(t *testing.T) {
+		_, err := c.ListTraces(ctx, client.TraceQuery{Sort: "pumpkin"})
+		apiErr := &client.APIError{}
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+			assert.Contains(t, apiErr.Message, "pumpkin")
+		}
+	})
+
+	t.Run("Clear Data", func(t *testing.T) {
+		cleared, err := c.ClearData(ctx)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 2, cleared)
+		}
+
+		summaries, err := c.ListTraces(ctx, client.TraceQuery{})
+		if assert.NoError(t, err) {
+			assert.Empty(t, summaries.TraceSummaries)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		anonymous, err := client.NewClient(testServer.URL, client.WithHTTPClient(testServer.Client()))
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		_, err = anonymous.ListTraces(ctx, client.TraceQuery{})
+		apiErr := &client.APIError{}
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+		}
+	})
+
+	_, err = client.NewClient("localhost:8000")
+	assert.Error(t, err)
+}
@@ -2,15 +2,130 @@ package desktopexporter
 
 import (
 	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/server"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
 )
 
 // Config represents the exporter config settings (provided to the collector via command line on launch)
 type Config struct {
-	// Endpoint defines the host and port where we serve our frontend app
+	// Endpoint defines the host and port where we serve our frontend app, or a unix socket to serve it
+	// on instead, such as unix:///tmp/otel-desktop-viewer.sock. TLS doesn't apply to unix sockets.
 	Endpoint string `mapstructure:"endpoint"`
 
-	// Endpoint defines the path of your database file. Setting an enpty string opens DuckDB in in-memory mode
+	// DbPath defines the path of your database file. Setting an empty string opens DuckDB in in-memory mode.
+	// Otherwise the file is created if it doesn't exist, and its traces are kept across restarts.
 	DbPath string `mapstructure:"db"`
+
+	// ReadOnly opens the database file without ever writing to it. The file has to exist, and routes
+	// that would add or remove data are refused with 405 Method Not Allowed.
+	ReadOnly bool `mapstructure:"read_only"`
+
+	// DbMemoryLimit caps the memory DuckDB may use, such as 2GB. Setting an empty string leaves
+	// DuckDB's default of 80% of the system's memory.
+	DbMemoryLimit string `mapstructure:"db_memory_limit"`
+
+	// DbThreads caps the threads DuckDB runs queries on. Setting zero leaves DuckDB's default of
+	// a thread per core.
+	DbThreads int `mapstructure:"db_threads"`
+
+	// GrpcEndpoint defines the host and port where we receive OTLP grpc payloads directly,
+	// alongside those handed to us by the collector. Setting an empty string disables it.
+	GrpcEndpoint string `mapstructure:"grpc_endpoint"`
+
+	// Listeners lists more endpoints to receive OTLP grpc payloads on, each of the form
+	// endpoint,key=value,... such as localhost:4320,env=staging. The resource attributes of a
+	// listener are set on every resource it receives that doesn't set them already.
+	Listeners []string `mapstructure:"listeners"`
+
+	// ForwardTo defines the host and port of an OTLP grpc endpoint that every span received is
+	// exported to as well, after being stored. Setting an empty string disables forwarding.
+	ForwardTo string `mapstructure:"forward_to"`
+
+	// Retention bounds how many traces are kept: either a duration such as 30m, after which traces are evicted,
+	// or a maximum number of traces such as 10000. Setting an empty string keeps every trace.
+	Retention string `mapstructure:"retention"`
+
+	// RetentionInterval defines how often the retention and spill policies are enforced
+	RetentionInterval time.Duration `mapstructure:"retention_interval"`
+
+	// SpillAfter keeps recent traces in memory and moves older ones to the database file: either a duration
+	// such as 30m, or a number of traces such as 1000 to keep in memory. Setting an empty string keeps every
+	// trace in the database file, as usual. It requires DbPath to be set.
+	SpillAfter string `mapstructure:"spill_after"`
+
+	// MaxSpans caps the number of spans kept, evicting the least recently active traces as new spans
+	// arrive. Setting zero keeps every span.
+	MaxSpans int `mapstructure:"max_spans"`
+
+	// IngestRate caps the spans per second accepted from instrumented services, dropping the rest,
+	// with bursts of up to a second's worth allowed. Setting zero accepts every span.
+	IngestRate int `mapstructure:"ingest_rate"`
+
+	// DefaultPageSize is the number of traces the traces list and search return when the client
+	// doesn't give a limit, and MaxPageSize the most they return whatever limit it gives, larger
+	// limits being clamped to it. Setting zero returns every trace.
+	DefaultPageSize int `mapstructure:"default_page_size"`
+	MaxPageSize     int `mapstructure:"max_page_size"`
+
+	// RedactAttributes lists attribute keys whose values are replaced with *** before spans are
+	// stored, and RedactPatterns regular expressions whose matches in string attribute values are.
+	// Both apply to span, resource, scope, event and link attributes, nested values included.
+	RedactAttributes []string `mapstructure:"redact_attributes"`
+	RedactPatterns   []string `mapstructure:"redact_patterns"`
+
+	// AnonymizeAttributes lists attribute keys whose values are hashed, along with service and
+	// span names, in exports asked for with ?anonymize=true
+	AnonymizeAttributes []string `mapstructure:"anonymize_attributes"`
+
+	// GroupBy names a resource attribute, such as tenant.id, whose values sort traces into groups
+	// the traces list can be filtered by. Traces without it are in the default group. Setting an
+	// empty string leaves every trace in the default group.
+	GroupBy string `mapstructure:"group_by"`
+
+	// MaxBodyBytes caps the size of OTLP/HTTP, Zipkin and import request bodies, before and after
+	// decompressing them. Larger ones are refused with 413 Request Entity Too Large.
+	MaxBodyBytes int64 `mapstructure:"max_body_bytes"`
+
+	// ShutdownTimeout defines how long in-flight requests are given to finish when the viewer is stopped
+	ShutdownTimeout time.Duration `mapstructure:"shutdown_timeout"`
+
+	// IdleTimeout stops the viewer once it has gone this long without an API request or any
+	// telemetry to store. Zero keeps it running.
+	IdleTimeout time.Duration `mapstructure:"idle_timeout"`
+
+	// AuthToken, when set, is required as a bearer token on every /api route. Setting an empty string disables auth.
+	AuthToken string `mapstructure:"auth_token"`
+
+	// AuthUI requires the auth token for the UI as well as the API
+	AuthUI bool `mapstructure:"auth_ui"`
+
+	// TLSCert and TLSKey define the PEM files of the certificate and private key to serve HTTPS with.
+	// Setting both to empty strings serves plain HTTP.
+	TLSCert string `mapstructure:"tls_cert"`
+	TLSKey  string `mapstructure:"tls_key"`
+
+	// TLSSelfSigned serves HTTPS with a certificate generated on start, in place of TLSCert and TLSKey
+	TLSSelfSigned bool `mapstructure:"tls_self_signed"`
+
+	// CORSOrigins lists the origins, such as http://localhost:3000, whose pages may call the API.
+	// An origin of "*" allows any page, and an empty list sends no CORS headers.
+	CORSOrigins []string `mapstructure:"cors_origins"`
+
+	// Metrics serves metrics about the viewer itself on /metrics, in the Prometheus format
+	Metrics bool `mapstructure:"metrics"`
+
+	// LogLevel is the least severe level requests are logged at: debug, info, warn, or error.
+	// At debug the spans each payload adds are logged too.
+	LogLevel string `mapstructure:"log_level"`
+
+	// LogFormat is either text or json
+	LogFormat string `mapstructure:"log_format"`
 }
 
 // Validate checks if the exporter configuration is valid
@@ -19,5 +134,144 @@ func (cfg *Config) Validate() error {
 		return fmt.Errorf("port 8888 is not supported as it is used internally")
 	}
 
+	if cfg.Endpoint == "unix://" {
+		return fmt.Errorf("endpoint must name a unix socket path after unix://")
+	}
+
+	if err := store.ValidateResourceLimits(cfg.DbMemoryLimit, cfg.DbThreads); err != nil {
+		return err
+	}
+
+	if cfg.GrpcEndpoint != "" && cfg.GrpcEndpoint == cfg.Endpoint {
+		return fmt.Errorf("grpc_endpoint must differ from endpoint")
+	}
+
+	endpoints := map[string]bool{cfg.Endpoint: true, cfg.GrpcEndpoint: true}
+	for _, spec := range cfg.Listeners {
+		listener, err := server.ParseListener(spec)
+		if err != nil {
+			return err
+		}
+		if endpoints[listener.Endpoint] {
+			return fmt.Errorf("listener %q must differ from endpoint, grpc_endpoint, and every other listener", spec)
+		}
+		if listener.Endpoint == cfg.ForwardTo {
+			return fmt.Errorf("listener %q must differ from forward_to, or every span would be forwarded back to the viewer", spec)
+		}
+		endpoints[listener.Endpoint] = true
+	}
+
+	if cfg.ForwardTo != "" && cfg.ForwardTo == cfg.GrpcEndpoint {
+		return fmt.Errorf("forward_to must differ from grpc_endpoint, or every span would be forwarded back to the viewer")
+	}
+
+	if cfg.ForwardTo != "" && cfg.ReadOnly {
+		return fmt.Errorf("read_only can't be combined with forward_to, as no spans are received")
+	}
+
+	if strings.HasPrefix(cfg.GroupBy, "$") {
+		return fmt.Errorf("invalid group_by %q: must be an attribute key, not a path", cfg.GroupBy)
+	}
+
+	if _, err := store.ParseRetentionPolicy(cfg.Retention); err != nil {
+		return err
+	}
+
+	if cfg.Retention != "" && cfg.RetentionInterval <= 0 {
+		return fmt.Errorf("retention_interval must be positive when retention is set")
+	}
+
+	if _, err := store.ParseRetentionPolicy(cfg.SpillAfter); err != nil {
+		return fmt.Errorf("invalid spill_after %q: must be a positive duration (e.g. 30m) or trace count (e.g. 1000)", cfg.SpillAfter)
+	}
+
+	if cfg.SpillAfter != "" && cfg.DbPath == "" {
+		return fmt.Errorf("spill_after requires db to be set")
+	}
+
+	if cfg.SpillAfter != "" && cfg.RetentionInterval <= 0 {
+		return fmt.Errorf("retention_interval must be positive when spill_after is set")
+	}
+
+	if cfg.MaxSpans < 0 {
+		return fmt.Errorf("max_spans must not be negative")
+	}
+
+	if cfg.ReadOnly && cfg.DbPath == "" {
+		return fmt.Errorf("read_only requires db to be set")
+	}
+
+	if cfg.ReadOnly && (cfg.Retention != "" || cfg.SpillAfter != "" || cfg.MaxSpans > 0) {
+		return fmt.Errorf("read_only can't be combined with retention, spill_after, or max_spans, which remove traces")
+	}
+
+	if cfg.IngestRate < 0 {
+		return fmt.Errorf("ingest_rate must not be negative")
+	}
+
+	if cfg.DefaultPageSize < 0 {
+		return fmt.Errorf("default_page_size must not be negative")
+	}
+
+	if cfg.MaxPageSize < 0 {
+		return fmt.Errorf("max_page_size must not be negative")
+	}
+
+	if cfg.MaxPageSize > 0 && cfg.DefaultPageSize > cfg.MaxPageSize {
+		return fmt.Errorf("default_page_size must not be greater than max_page_size")
+	}
+
+	if _, err := telemetry.NewRedactor(cfg.RedactAttributes, cfg.RedactPatterns); err != nil {
+		return err
+	}
+
+	if cfg.MaxBodyBytes <= 0 {
+		return fmt.Errorf("max_body_bytes must be positive")
+	}
+
+	if cfg.ShutdownTimeout <= 0 {
+		return fmt.Errorf("shutdown_timeout must be positive")
+	}
+
+	if cfg.IdleTimeout < 0 {
+		return fmt.Errorf("idle_timeout must not be negative")
+	}
+
+	if cfg.AuthUI && cfg.AuthToken == "" {
+		return fmt.Errorf("auth_ui requires auth_token to be set")
+	}
+
+	if (cfg.TLSCert == "") != (cfg.TLSKey == "") {
+		return fmt.Errorf("tls_cert and tls_key must be set together")
+	}
+
+	if cfg.TLSSelfSigned && cfg.TLSCert != "" {
+		return fmt.Errorf("tls_self_signed can't be combined with tls_cert and tls_key")
+	}
+
+	if _, err := newLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat); err != nil {
+		return err
+	}
+
+	for _, origin := range cfg.CORSOrigins {
+		if err := validateOrigin(origin); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOrigin checks an origin is "*" or looks like what browsers send in the Origin header,
+// a scheme and host with no path, so it can be compared as a string
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	originURL, err := url.Parse(origin)
+	if err != nil || originURL.Scheme == "" || originURL.Host == "" || originURL.Path != "" || originURL.RawQuery != "" {
+		return fmt.Errorf("cors origin %q must be * or a scheme and host, such as http://localhost:3000", origin)
+	}
 	return nil
 }
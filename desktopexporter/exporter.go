@@ -4,7 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"log/slog"
+	"os"
 
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/plog"
@@ -12,57 +13,161 @@ import (
 	"go.opentelemetry.io/collector/pdata/ptrace"
 
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/server"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
 )
 
 type desktopExporter struct {
 	server *server.Server
+	logger *slog.Logger
+
+	// reportStatus tells the collector about problems at runtime, and stops it on a fatal error
+	reportStatus func(*component.StatusEvent)
+
+	// stop asks the server to shut down, and stopped returns the result
+	stop    context.CancelFunc
+	stopped chan error
 }
 
-func newDesktopExporter(cfg *Config) *desktopExporter {
-	server := server.NewServer(cfg.Endpoint, cfg.DbPath)
-	return &desktopExporter{
-		server: server,
+func newDesktopExporter(cfg *Config, set component.TelemetrySettings) (*desktopExporter, error) {
+	retention, err := store.ParseRetentionPolicy(cfg.Retention)
+	if err != nil {
+		return nil, err
+	}
+
+	spillover, err := store.ParseRetentionPolicy(cfg.SpillAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	logger, err := newLogger(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []server.Option{
+		server.WithLogger(logger),
+		server.WithRetention(retention, cfg.RetentionInterval),
+		server.WithSpillover(spillover),
+		server.WithMaxSpans(cfg.MaxSpans),
+		server.WithIngestRate(cfg.IngestRate),
+		server.WithPageSizes(cfg.DefaultPageSize, cfg.MaxPageSize),
+		server.WithMaxBodyBytes(cfg.MaxBodyBytes),
+		server.WithDatabaseLimits(cfg.DbMemoryLimit, cfg.DbThreads),
+		server.WithGRPCEndpoint(cfg.GrpcEndpoint),
+		server.WithForwarding(cfg.ForwardTo),
+		server.WithGroupAttribute(cfg.GroupBy),
+		server.WithShutdownTimeout(cfg.ShutdownTimeout),
+		server.WithIdleTimeout(cfg.IdleTimeout),
+		server.WithAuthToken(cfg.AuthToken, cfg.AuthUI),
+		server.WithCORSOrigins(cfg.CORSOrigins...),
+	}
+	for _, spec := range cfg.Listeners {
+		listener, err := server.ParseListener(spec)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, server.WithListeners(listener))
+	}
+	if cfg.Metrics {
+		opts = append(opts, server.WithMetrics())
+	}
+	if cfg.ReadOnly {
+		opts = append(opts, server.WithReadOnly())
+	}
+	if len(cfg.RedactAttributes) > 0 || len(cfg.RedactPatterns) > 0 {
+		redactor, err := telemetry.NewRedactor(cfg.RedactAttributes, cfg.RedactPatterns)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, server.WithRedactor(redactor))
+	}
+	if len(cfg.AnonymizeAttributes) > 0 {
+		opts = append(opts, server.WithAnonymizeAttributes(cfg.AnonymizeAttributes...))
 	}
+	if cfg.TLSSelfSigned {
+		opts = append(opts, server.WithSelfSignedTLS())
+	} else if cfg.TLSCert != "" {
+		opts = append(opts, server.WithTLS(cfg.TLSCert, cfg.TLSKey))
+	}
+
+	server := server.NewServer(cfg.Endpoint, cfg.DbPath, opts...)
+	return &desktopExporter{
+		server:       server,
+		logger:       logger,
+		reportStatus: set.ReportStatus,
+	}, nil
 }
 
 func (exporter *desktopExporter) pushTraces(ctx context.Context, traces ptrace.Traces) error {
+	exporter.server.MarkActive()
 	spanDataSlice := telemetry.NewSpanPayload(traces).ExtractSpans()
-	exporter.server.Store.AddSpans(ctx, spanDataSlice)
+	exporter.server.RedactSpans(spanDataSlice)
+	limited, err := exporter.server.Store.IngestSpans(ctx, spanDataSlice)
+	if err != nil {
+		// The collector's receivers are still open, so senders are told their spans weren't
+		// kept, whether the store is read-only or couldn't take them
+		return err
+	}
+	exporter.logger.DebugContext(ctx, "spans added", slog.String("source", "collector"), slog.Int("spans", len(spanDataSlice)-limited), slog.Int("rateLimited", limited))
 
 	return nil
 }
 
 func (exporter *desktopExporter) pushMetrics(ctx context.Context, metrics pmetric.Metrics) error {
+	exporter.server.MarkActive()
 	metricsDataSlice := telemetry.NewMetricsPayload(metrics).ExtractMetrics()
-	for _, metricsData := range metricsDataSlice {
-		fmt.Println(metricsData.ID())
-	}
-	return nil
+	return exporter.server.Store.AddMetrics(ctx, metricsDataSlice)
 }
 
 func (exporter *desktopExporter) pushLogs(ctx context.Context, logs plog.Logs) error {
+	exporter.server.MarkActive()
 	logDataSlice := telemetry.NewLogsPayload(logs).ExtractLogs()
-	for _, logData := range logDataSlice {
-		fmt.Println(logData.ID())
-	}
-	return nil
+	return exporter.server.Store.AddLogs(ctx, logDataSlice)
 }
 
 func (exporter *desktopExporter) Start(ctx context.Context, host component.Host) error {
+	// ctx only covers starting up, so the server runs until Shutdown stops it
+	runCtx, stop := context.WithCancel(context.Background())
+	exporter.stop = stop
+	exporter.stopped = make(chan error, 1)
+
 	go func() {
-		err := exporter.server.Start()
+		err := exporter.server.Run(runCtx)
 
-		if errors.Is(err, http.ErrServerClosed) {
-			fmt.Printf("server closed\n")
+		if errors.Is(err, server.ErrIdleTimeout) {
+			fmt.Printf("server closed: %s\n", err)
+			exporter.stopped <- nil
+			// The server is gone, so the collector feeding it is stopped too
+			if exporter.reportStatus != nil {
+				exporter.reportStatus(component.NewFatalErrorEvent(err))
+			}
+			return
+		} else if errors.Is(err, server.ErrShutdownTimeout) {
+			fmt.Printf("server closed before in-flight requests finished: %s\n", err)
 		} else if err != nil {
 			fmt.Printf("error listening for server: %s\n", err)
+			err = nil
+		} else {
+			fmt.Printf("server closed\n")
 		}
-
+		exporter.stopped <- err
 	}()
 	return nil
 }
 
+// Shutdown drains the server, failing only if in-flight requests outlast its shutdown timeout
 func (exporter *desktopExporter) Shutdown(ctx context.Context) error {
-	return exporter.server.Close()
+	if exporter.stop == nil {
+		// Never started
+		return exporter.server.Close()
+	}
+
+	exporter.stop()
+	select {
+	case err := <-exporter.stopped:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
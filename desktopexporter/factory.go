@@ -3,6 +3,7 @@ package desktopexporter
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/metadata"
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/sharedcomponent"
@@ -13,7 +14,12 @@ import (
 )
 
 const (
-	defaultEndpoint = "localhost:8000"
+	defaultEndpoint          = "localhost:8000"
+	defaultRetentionInterval = time.Minute
+	defaultShutdownTimeout   = 5 * time.Second
+	defaultMaxBodyBytes      = 64 << 20
+	defaultLogLevel          = "info"
+	defaultLogFormat         = "text"
 )
 
 // Creates a factory for the Desktop Exporter
@@ -30,7 +36,12 @@ func NewFactory() exporter.Factory {
 // Create default configurations
 func createDefaultConfig() component.Config {
 	return &Config{
-		Endpoint: defaultEndpoint,
+		Endpoint:          defaultEndpoint,
+		RetentionInterval: defaultRetentionInterval,
+		ShutdownTimeout:   defaultShutdownTimeout,
+		MaxBodyBytes:      defaultMaxBodyBytes,
+		LogLevel:          defaultLogLevel,
+		LogFormat:         defaultLogFormat,
 	}
 }
 
@@ -46,7 +57,7 @@ func createMetricsExporter(ctx context.Context, set exporter.Settings, config co
 	}
 
 	exporter, err := exporters.GetOrAdd(desktopCfg, func() (*desktopExporter, error) {
-		return newDesktopExporter(desktopCfg), nil
+		return newDesktopExporter(desktopCfg, set.TelemetrySettings)
 	})
 	if err != nil {
 		return nil, err
@@ -72,7 +83,7 @@ func createLogsExporter(ctx context.Context, set exporter.Settings, config compo
 	}
 
 	e, err := exporters.GetOrAdd(cfg, func() (*desktopExporter, error) {
-		return newDesktopExporter(cfg), nil
+		return newDesktopExporter(cfg, set.TelemetrySettings)
 	})
 	if err != nil {
 		return nil, err
@@ -96,7 +107,7 @@ func createTracesExporter(ctx context.Context, set exporter.Settings, config com
 	}
 
 	e, err := exporters.GetOrAdd(cfg, func() (*desktopExporter, error) {
-		return newDesktopExporter(cfg), nil
+		return newDesktopExporter(cfg, set.TelemetrySettings)
 	})
 	if err != nil {
 		return nil, err
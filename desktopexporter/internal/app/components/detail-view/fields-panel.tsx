@@ -14,7 +14,7 @@ import {
 
 import { SpanData } from "../../types/api-types";
 import { SpanField } from "./span-field";
-import { getDurationNs, getDurationString } from "../../utils/duration";
+import { getDurationString } from "../../utils/duration";
 
 type FieldsPanelProps = {
   span: SpanData | undefined;
@@ -44,9 +44,7 @@ export function FieldsPanel(props: FieldsPanelProps) {
 
   // Duration: label in appropriate human-readable time unit (s, ms, μs, ns)
 
-  let durationString = getDurationString(
-    getDurationNs(span.startTime, span.endTime),
-  );
+  let durationString = getDurationString(span.durationNanos);
 
   // Attributes:
   let spanAttributes = Object.entries(span.attributes).map(([key, value]) => (
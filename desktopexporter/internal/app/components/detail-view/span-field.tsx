@@ -8,9 +8,11 @@ import {
   useColorModeValue,
 } from "@chakra-ui/react";
 
+import { AttributeValue } from "../../types/api-types";
+
 type SpanFieldProps = {
   fieldName: string;
-  fieldValue: number | string | boolean | null;
+  fieldValue: AttributeValue;
   hidden?: boolean;
 };
 
@@ -21,23 +23,31 @@ export function SpanField(props: SpanFieldProps) {
   if (hidden) {
     return null;
   }
-  let typeOfFieldValue = typeof fieldValue;
+  let typeOfFieldValue = Array.isArray(fieldValue)
+    ? "array"
+    : typeof fieldValue;
+
+  // Arrays and maps are shown as JSON
+  let displayValue =
+    fieldValue !== null && typeof fieldValue === "object"
+      ? JSON.stringify(fieldValue)
+      : fieldValue;
 
-  switch (fieldValue) {
+  switch (displayValue) {
     case true:
-      fieldValue = "true";
+      displayValue = "true";
       break;
     case false:
-      fieldValue = "false";
+      displayValue = "false";
       break;
     case null:
-      fieldValue = "null";
+      displayValue = "null";
       break;
     case undefined:
-      fieldValue = "undefined";
+      displayValue = "undefined";
       break;
     case "":
-      fieldValue = '""';
+      displayValue = '""';
       break;
   }
 
@@ -65,7 +75,7 @@ export function SpanField(props: SpanFieldProps) {
           fontSize="md"
           paddingY={2}
         >
-          {fieldValue}
+          {displayValue}
         </Text>
       </dd>
     </Box>
@@ -7,7 +7,7 @@ import { Sidebar } from "../components/sidebar-view/sidebar";
 import { EmptyStateView } from "../components/empty-state-view/empty-state-view";
 import { TraceSummaries, TraceSummary } from "../types/api-types";
 import { SidebarData, TraceSummaryWithUIData } from "../types/ui-types";
-import { getDurationNs, getDurationString } from "../utils/duration";
+import { getDurationString } from "../utils/duration";
 
 export async function mainLoader() {
   const response = await fetch("/api/traces");
@@ -125,12 +125,7 @@ function generateTraceSummaryWithUIData(
   traceSummary: TraceSummary,
 ): TraceSummaryWithUIData {
   if (traceSummary.hasRootSpan) {
-    let duration = getDurationNs(
-      traceSummary.rootStartTime,
-      traceSummary.rootEndTime,
-    );
-
-    let durationString = getDurationString(duration);
+    let durationString = getDurationString(traceSummary.rootDurationNanos);
     return {
       hasRootSpan: true,
       rootServiceName: traceSummary.rootServiceName,
@@ -4,17 +4,58 @@ export type TraceSummary = {
   rootName: string;
   rootStartTime: string;
   rootEndTime: string;
+  rootDurationNanos: number;
   spanCount: number;
   traceID: string;
+  // Only set on summaries searched or filtered by span event
+  matchedEvents?: EventMatch[];
+  annotations?: Record<string, unknown>;
+  pinned: boolean;
+};
+
+export type EventMatch = {
+  spanID: string;
+  spanName: string;
+  eventIndex: number;
+  eventName: string;
+  timestamp: string;
 };
 
 export type TraceSummaries = {
   traceSummaries: TraceSummary[];
+  totalCount: number;
+  nextOffset: number | null;
+};
+
+export type ImportedTraces = {
+  traceIDs: string[];
+  importedSpans: number;
+  duplicateSpans: number;
+};
+
+export type ClearedTraces = {
+  clearedTraces: number;
 };
 
 export type TraceData = {
   traceID: string;
   spans: SpanData[];
+  totalSpans?: number;
+  sampled?: boolean;
+  omittedSpans?: number;
+};
+
+// Span IDs are in the order the spans started
+export type SpanMatches = {
+  traceID: string;
+  query: string;
+  spanIDs: string[];
+  count: number;
+};
+
+// Trace IDs that weren't found are left out
+export type TraceBatch = {
+  traces: Record<string, TraceData>;
 };
 
 export type SpanData = {
@@ -27,8 +68,11 @@ export type SpanData = {
   kind: string;
   startTime: string;
   endTime: string;
+  durationNanos: number;
+  // Only set on the spans of a whole trace
+  selfDurationNanos?: number;
 
-  attributes: { [key: string]: number | string | boolean | null };
+  attributes: { [key: string]: AttributeValue };
   events: EventData[];
   links: LinkData[];
   resource: ResourceData;
@@ -42,22 +86,30 @@ export type SpanData = {
   statusMessage: string;
 };
 
+export type AttributeValue =
+  | number
+  | string
+  | boolean
+  | null
+  | AttributeValue[]
+  | { [key: string]: AttributeValue };
+
 export type ResourceData = {
-  attributes: { [key: string]: number | string | boolean | null };
+  attributes: { [key: string]: AttributeValue };
   droppedAttributesCount: number;
 };
 
 export type ScopeData = {
   name: string;
   version: string;
-  attributes: { [key: string]: number | string | boolean | null };
+  attributes: { [key: string]: AttributeValue };
   droppedAttributesCount: number;
 };
 
 export type EventData = {
   name: string;
   timestamp: string;
-  attributes: { [key: string]: number | string | boolean | null };
+  attributes: { [key: string]: AttributeValue };
   droppedAttributesCount: number;
 };
 
@@ -65,6 +117,389 @@ export type LinkData = {
   traceID: string;
   spanID: string;
   traceState: string;
-  attributes: { [key: string]: number | string | boolean | null };
+  attributes: { [key: string]: AttributeValue };
+  droppedAttributesCount: number;
+  resolved?: boolean;
+  rootServiceName?: string;
+  rootName?: string;
+};
+
+export type TraceLogs = {
+  traceID: string;
+  logs: LogData[];
+};
+
+export type LogData = {
+  body?: string;
+  traceID?: string;
+  spanID?: string;
+  timestamp: string;
+  observedTimestamp: string;
+  attributes?: { [key: string]: AttributeValue };
+  severityText?: string;
+  severityNumber?: number;
+  droppedAttributeCount?: number;
+  flags?: number;
+  resource: ResourceData;
+  scope: ScopeData;
+};
+
+export type MetricSeriesList = {
+  series: MetricSeries[];
+};
+
+export type MetricSeries = {
+  name: string;
+  description: string;
+  unit: string;
+  type: string;
+  dataPoints: MetricData[];
+};
+
+export type MetricData = {
+  name: string;
+  description: string;
+  unit: string;
+  type: string;
+  startTime: string;
+  timestamp: string;
+  attributes: { [key: string]: AttributeValue };
+  value: number | null;
+  isMonotonic: boolean;
+  aggregationTemporality: string;
+  histogram: HistogramData | null;
+  exponentialHistogram: ExponentialHistogramData | null;
+  exemplars: ExemplarData[];
+  resource: ResourceData;
+  scope: ScopeData;
+};
+
+export type ExemplarData = {
+  timestamp: string;
+  value: number;
+  traceID: string;
+  spanID: string;
+  filteredAttributes: { [key: string]: AttributeValue };
+  resolved?: boolean;
+};
+
+export type HistogramData = {
+  count: number;
+  sum: number | null;
+  min: number | null;
+  max: number | null;
+  bucketCounts: number[];
+  explicitBounds: number[];
+};
+
+// Bucket i covers (base^i, base^(i+1)], where base is 2^(2^-scale)
+export type ExponentialHistogramData = {
+  count: number;
+  sum: number | null;
+  min: number | null;
+  max: number | null;
+  scale: number;
+  zeroCount: number;
+  zeroThreshold: number;
+  positive: ExponentialHistogramBuckets;
+  negative: ExponentialHistogramBuckets;
+};
+
+export type ExponentialHistogramBuckets = {
+  offset: number;
+  bucketCounts: number[];
+};
+
+export type ReadBatch = {
+  responses: ReadBatchResponse[];
+};
+
+// body is the response's JSON, or its text as a string if it isn't JSON
+export type ReadBatchResponse = {
+  path: string;
+  status: number;
+  body: unknown;
+};
+
+export type TraceStats = {
+  traceID: string;
+  spanCount: number;
+  spanCountsByKind: { [kind: string]: number };
+  errorCount: number;
+  durationNanos: number;
+  selfDurationNanos: number;
+  maxDepth: number;
+  detachedSpanCount: number;
+  detachedSpansByReason: { [reason: string]: number };
+  droppedAttributesCount: number;
+  droppedEventsCount: number;
+  droppedLinksCount: number;
+  droppedBySpan?: SpanDroppedCounts[];
+};
+
+export type SpanDroppedCounts = {
+  spanID: string;
+  name: string;
   droppedAttributesCount: number;
+  droppedEventsCount: number;
+  droppedLinksCount: number;
+};
+
+export type SpanTree = {
+  traceID: string;
+  roots: SpanNode[];
+};
+
+// A node without a span stands in for a parent that isn't in the trace
+export type SpanNode = {
+  spanID: string;
+  span: SpanData | null;
+  detached?: "missingParent" | "parentInOtherTrace" | "parentCycle";
+  // Only set on placeholders whose span is in another trace
+  traceID?: string;
+  children: SpanNode[];
+};
+
+export type FlameGraph = {
+  traceID: string;
+  frames: FlameFrame[];
+};
+
+export type FlameFrame = {
+  name: string;
+  spanCount: number;
+  selfDurationNanos: number;
+  totalDurationNanos: number;
+  children: FlameFrame[];
+};
+
+export type TraceGaps = {
+  traceID: string;
+  durationNanos: number;
+  idleNanos: number;
+  gaps: TraceGap[];
+};
+
+export type TraceGap = {
+  start: string;
+  end: string;
+  durationNanos: number;
+  afterSpanID: string;
+  beforeSpanID: string;
+};
+
+export type CriticalPath = {
+  traceID: string;
+  durationNanos: number;
+  spans: CriticalPathSpan[];
 };
+
+export type CriticalPathSpan = {
+  spanID: string;
+  parentSpanID: string;
+  name: string;
+  serviceName: string;
+  startTime: string;
+  endTime: string;
+  durationNanos: number;
+  criticalDurationNanos: number;
+};
+
+export type TraceComparison = {
+  traceIDA: string;
+  traceIDB: string;
+  durationDeltaNanos: number;
+  operations: OperationDelta[];
+  matches: SpanMatch[];
+  onlyInA: SpanData[];
+  onlyInB: SpanData[];
+};
+
+export type OperationDelta = {
+  name: string;
+  spanCountA: number;
+  spanCountB: number;
+  totalDurationNanosA: number;
+  totalDurationNanosB: number;
+  deltaNanos: number;
+};
+
+export type SpanMatch = {
+  name: string;
+  spanIDA: string;
+  spanIDB: string;
+  durationNanosA: number;
+  durationNanosB: number;
+  deltaNanos: number;
+};
+
+// Each group has a value, possibly empty, for every attribute in groupBy
+export type ServiceGroups = {
+  groupBy: string[];
+  groups: ServiceGroup[];
+};
+
+export type ServiceGroup = {
+  attributes: Record<string, string>;
+  spanCount: number;
+};
+
+export type AttributeKeyList = {
+  keys: AttributeKey[];
+};
+
+export type AttributeKey = {
+  key: string;
+  spanCount: number;
+};
+
+// Values are listed most common first, and truncated is set when the key has more than were asked for
+export type AttributeCardinalityList = {
+  keys: AttributeCardinality[];
+};
+
+export type AttributeCardinality = {
+  key: string;
+  distinctValues: number;
+  spanCount: number;
+};
+
+export type AttributeValues = {
+  key: string;
+  values: AttributeValue[];
+  truncated: boolean;
+};
+
+export type AttributeValue = {
+  value: string;
+  spanCount: number;
+};
+
+export type ServiceDependencies = {
+  services: ServiceNode[];
+  dependencies: ServiceDependency[];
+};
+
+// errorRate is between 0 and 1, and the p95 is approximate
+export type ServiceNode = {
+  serviceName: string;
+  spanCount: number;
+  errorCount: number;
+  errorRate: number;
+  p95DurationNanos: number;
+};
+
+export type ServiceDependency = {
+  parent: string;
+  child: string;
+  callCount: number;
+  errorCount: number;
+};
+
+export type OperationStatsList = {
+  operations: OperationStats[];
+};
+
+// serviceName is only set when the stats are grouped by service
+export type OperationStats = {
+  serviceName?: string;
+  name: string;
+  spanCount: number;
+  p50DurationNanos: number;
+  p95DurationNanos: number;
+  p99DurationNanos: number;
+  maxDurationNanos: number;
+};
+
+export type SampleSetList = {
+  sets: SampleSet[];
+};
+
+export type SampleSet = {
+  name: string;
+  description: string;
+};
+
+// oldestSpan and newestSpan are left out while nothing is stored
+export type Status = {
+  traceCount: number;
+  spanCount: number;
+  oldestSpan?: string;
+  newestSpan?: string;
+  mode: "memory" | "file";
+  readOnly: boolean;
+  schemaVersion: number;
+  startedAt: string;
+  uptimeNanos: number;
+  forwarding?: ForwardingStatus;
+};
+
+// Only sent when spans are forwarded with --forward-to
+export type ForwardingStatus = {
+  endpoint: string;
+  forwardedSpans: number;
+  droppedSpans: number;
+};
+
+// The last bucket has no maxSpans
+export type TraceSizeHistogram = {
+  buckets: TraceSizeBucket[];
+  totalTraces: number;
+  largestTraceID?: string;
+  largestTraceSpans?: number;
+};
+
+export type TraceSizeBucket = {
+  label: string;
+  minSpans: number;
+  maxSpans?: number;
+  traceCount: number;
+};
+
+// Buckets are listed oldest first, every bucketNanos from start to end
+export type TraceVolume = {
+  start: string;
+  end: string;
+  bucketNanos: number;
+  buckets: TraceVolumeBucket[];
+};
+
+export type TraceVolumeBucket = {
+  start: string;
+  traceCount: number;
+  errorCount: number;
+};
+
+export type TraceGroups = {
+  attribute: string;
+  groups: TraceGroup[];
+};
+
+export type TraceGroup = {
+  name: string;
+  traceCount: number;
+};
+
+export type TraceAnnotations = {
+  traceID: string;
+  annotations: Record<string, unknown>;
+};
+
+export type TracePin = {
+  traceID: string;
+  pinned: boolean;
+};
+
+export type FollowedTraces = {
+  traceSummaries: TraceSummary[];
+  cursor: string;
+};
+
+export type StreamFilter = {
+  services?: string[];
+  status?: "error" | "ok";
+};
+
+export type StreamMessage =
+  | { type: "trace"; trace: TraceSummary }
+  | { type: "error"; error: string };
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// apiVersionHeader picks the shape of JSON responses. Version 1, the default, writes every field
+// of every response. Version 2 leaves out the fields in compactFields wherever they are empty.
+const apiVersionHeader = "X-API-Version"
+
+// compactFields are the fields a version 2 response leaves out when they are empty, zero, or null
+var compactFields = map[string]bool{
+	"traceState":             true,
+	"statusMessage":          true,
+	"events":                 true,
+	"links":                  true,
+	"attributes":             true,
+	"droppedAttributesCount": true,
+	"droppedEventsCount":     true,
+	"droppedLinksCount":      true,
+}
+
+// userDataFields hold keys chosen by whoever sent the telemetry, which are left as they are
+var userDataFields = map[string]bool{
+	"attributes":  true,
+	"annotations": true,
+}
+
+// apiVersionHandler checks the API version a request asks for, and has writeJSON compact the
+// responses of those asking for version 2
+func apiVersionHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch version := request.Header.Get(apiVersionHeader); version {
+		case "", "1":
+			next.ServeHTTP(writer, request)
+		case "2":
+			writer.Header().Set(apiVersionHeader, version)
+			next.ServeHTTP(&compactResponseWriter{ResponseWriter: writer}, request)
+		default:
+			http.Error(writer, fmt.Sprintf("invalid %s %q: must be 1 or 2", apiVersionHeader, version), http.StatusBadRequest)
+		}
+	})
+}
+
+// compactResponseWriter marks a response as one writeJSON should compact
+type compactResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w *compactResponseWriter) Flush() {
+	http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+func (w *compactResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *compactResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// wantsCompactJSON reports whether a response goes to a client that asked for version 2
+func wantsCompactJSON(writer http.ResponseWriter) bool {
+	for {
+		switch w := writer.(type) {
+		case *compactResponseWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			writer = w.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// compactJSON leaves the empty compactFields out of a JSON document. Numbers are copied as they
+// were written, so whole floats among attribute values keep their ".0".
+func compactJSON(data []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(compactValue(value))
+}
+
+func compactValue(value any) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			if compactFields[key] && isEmptyJSON(field) {
+				delete(v, key)
+			} else if !userDataFields[key] {
+				v[key] = compactValue(field)
+			}
+		}
+	case []any:
+		for i := range v {
+			v[i] = compactValue(v[i])
+		}
+	}
+	return value
+}
+
+func isEmptyJSON(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case json.Number:
+		return v == "0"
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
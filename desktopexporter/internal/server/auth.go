@@ -0,0 +1,72 @@
+package server
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+const (
+	// authCookie holds the token for browsers, which can't add a header to page loads,
+	// event streams, or websockets
+	authCookie = "otel_desktop_viewer_token"
+	// authQueryParam carries the token in the link we open the browser with
+	authQueryParam = "token"
+)
+
+// authHandler requires the token on /api routes, and on everything else too if gateUI is set.
+// Clients send it as a bearer token. Browsers can visit any page with ?token=<token> once, which
+// trades it for a cookie and redirects to the same page without it. An empty token turns auth off.
+func authHandler(token string, gateUI bool, next http.Handler) http.Handler {
+	if token == "" {
+		return next
+	}
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		queryToken := request.URL.Query().Get(authQueryParam)
+		if request.Method == http.MethodGet && queryToken != "" && tokensEqual(queryToken, token) {
+			http.SetCookie(writer, &http.Cookie{
+				Name:     authCookie,
+				Value:    token,
+				Path:     "/",
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+
+			redirect := *request.URL
+			query := redirect.Query()
+			query.Del(authQueryParam)
+			redirect.RawQuery = query.Encode()
+			http.Redirect(writer, request, redirect.String(), http.StatusFound)
+			return
+		}
+
+		// Zipkin reporters can't send a token, so the Zipkin endpoint is left open like the OTLP ones
+		protected := gateUI || (strings.HasPrefix(request.URL.Path, "/api/") && request.URL.Path != zipkinSpansPath)
+		if protected && !authorized(request, token) {
+			writer.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(writer, "missing or invalid bearer token", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
+
+func authorized(request *http.Request, token string) bool {
+	scheme, bearer, ok := strings.Cut(request.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && tokensEqual(strings.TrimSpace(bearer), token) {
+		return true
+	}
+
+	cookie, err := request.Cookie(authCookie)
+	return err == nil && tokensEqual(cookie.Value, token)
+}
+
+// tokensEqual compares digests of the tokens in constant time, so neither their
+// contents nor their lengths can be worked out from how long a comparison takes
+func tokensEqual(given string, token string) bool {
+	givenDigest := sha256.Sum256([]byte(given))
+	tokenDigest := sha256.Sum256([]byte(token))
+	return subtle.ConstantTimeCompare(givenDigest[:], tokenDigest[:]) == 1
+}
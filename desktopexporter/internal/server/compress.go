@@ -0,0 +1,160 @@
+package server
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// gzipMinSize is the smallest response worth compressing. Anything that fits in a
+// packet or two gains little from gzip and costs a round of CPU on both ends.
+const gzipMinSize = 1400
+
+// gzipHandler compresses responses for clients that accept gzip. A response is held back
+// until it reaches gzipMinSize and only then compressed, so small responses go out as they
+// are, with a Content-Length. A response flushed before then, like an event stream, is
+// never compressed, and neither are websocket upgrades.
+func gzipHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		writer.Header().Add("Vary", "Accept-Encoding")
+		if request.Method == http.MethodHead || !acceptsGzip(request.Header.Get("Accept-Encoding")) {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		gzipWriter := &gzipResponseWriter{ResponseWriter: writer}
+		defer gzipWriter.close()
+		next.ServeHTTP(gzipWriter, request)
+	})
+}
+
+// acceptsGzip reports whether an Accept-Encoding header lists gzip without ruling it out with q=0
+func acceptsGzip(acceptEncoding string) bool {
+	for _, coding := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(coding, ";")
+		if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+			continue
+		}
+
+		_, q, found := strings.Cut(params, "q=")
+		if !found {
+			return true
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(q), 64)
+		return err != nil || weight > 0
+	}
+	return false
+}
+
+// gzipResponseWriter buffers a response until it knows whether to compress it
+type gzipResponseWriter struct {
+	http.ResponseWriter
+
+	status  int
+	buffer  []byte
+	decided bool
+	gzip    *gzip.Writer
+}
+
+func (w *gzipResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *gzipResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+
+	if !w.decided {
+		w.buffer = append(w.buffer, p...)
+		if len(w.buffer) < gzipMinSize {
+			return len(p), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	if w.gzip != nil {
+		return w.gzip.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// decide writes the header and anything buffered so far, compressed if the response is large
+// enough and is a complete, successful body the handler didn't already encode itself
+func (w *gzipResponseWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+	compress := large &&
+		w.status == http.StatusOK &&
+		header.Get("Content-Encoding") == "" &&
+		header.Get("Content-Range") == ""
+
+	if compress {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.ResponseWriter.WriteHeader(w.status)
+		w.gzip = gzip.NewWriter(w.ResponseWriter)
+		_, err := w.gzip.Write(w.buffer)
+		w.buffer = nil
+		return err
+	}
+
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buffer) == 0 {
+		return nil
+	}
+	_, err := w.ResponseWriter.Write(w.buffer)
+	w.buffer = nil
+	return err
+}
+
+// Flush sends a response that hasn't reached gzipMinSize uncompressed, and lets streaming
+// handlers keep flushing after that
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if w.status == 0 {
+			w.status = http.StatusOK
+		}
+		if err := w.decide(false); err != nil {
+			return
+		}
+	}
+	if w.gzip != nil {
+		w.gzip.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response once the handler returns. A small response is written in one go,
+// which lets net/http set its Content-Length.
+func (w *gzipResponseWriter) close() error {
+	if !w.decided {
+		if w.status == 0 {
+			return nil
+		}
+		if err := w.decide(false); err != nil {
+			return err
+		}
+	}
+	if w.gzip != nil {
+		return w.gzip.Close()
+	}
+	return nil
+}
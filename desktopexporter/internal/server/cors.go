@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+const (
+	// corsAllowedMethods lists every method the API routes are served on
+	corsAllowedMethods = "GET, POST, PUT, DELETE"
+	// corsMaxAge lets browsers skip the preflight of repeated requests for ten minutes
+	corsMaxAge = "600"
+)
+
+// corsHandler lets pages served from the given origins call the /api routes, answering their
+// preflight requests itself. An origin of "*" allows any page. No origins leaves CORS off, and
+// browsers keep cross-origin pages from reading our responses.
+func corsHandler(origins []string, next http.Handler) http.Handler {
+	if len(origins) == 0 {
+		return next
+	}
+	anyOrigin := slices.Contains(origins, "*")
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if !strings.HasPrefix(request.URL.Path, "/api/") {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		header := writer.Header()
+		header.Add("Vary", "Origin")
+		origin := request.Header.Get("Origin")
+		if origin == "" || !(anyOrigin || slices.Contains(origins, origin)) {
+			next.ServeHTTP(writer, request)
+			return
+		}
+
+		if anyOrigin {
+			header.Set("Access-Control-Allow-Origin", "*")
+		} else {
+			header.Set("Access-Control-Allow-Origin", origin)
+		}
+
+		// Preflights come before the real request and never carry its credentials, so they're
+		// answered here rather than passed on to be refused
+		if request.Method == http.MethodOptions && request.Header.Get("Access-Control-Request-Method") != "" {
+			header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+			if requestHeaders := request.Header.Get("Access-Control-Request-Headers"); requestHeaders != "" {
+				header.Set("Access-Control-Allow-Headers", requestHeaders)
+			}
+			header.Set("Access-Control-Max-Age", corsMaxAge)
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
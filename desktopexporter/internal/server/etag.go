@@ -0,0 +1,25 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// notModified tags the response with an ETag of the data in the store, and answers 304 Not
+// Modified if the request's If-None-Match already names it. The ETag is taken before the data is
+// read, so that data written in between is fetched again next time. It is weak, as compressed
+// and uncompressed responses share it.
+func (s *Server) notModified(writer http.ResponseWriter, request *http.Request) bool {
+	etag := fmt.Sprintf(`W/"%s"`, s.Store.DataVersion())
+	writer.Header().Set("ETag", etag)
+
+	for _, candidate := range strings.Split(request.Header.Get("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			writer.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
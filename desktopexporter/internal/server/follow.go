@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+const (
+	// followWindow is how long the hub remembers that a trace was written; a long poll whose
+	// cursor is older than this may miss traces
+	followWindow = 5 * time.Minute
+
+	defaultFollowTimeout = 30 * time.Second
+	maxFollowTimeout     = 2 * time.Minute
+)
+
+// writtenSince returns the traces last written after the cursor, the cursor to poll from next,
+// and a channel that is closed once anything else is written
+func (h *hub) writtenSince(after time.Time) ([]string, time.Time, <-chan struct{}) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	traceIDs := []string{}
+	cursor := after
+	for traceID, at := range h.writtenAt {
+		if at.After(after) {
+			traceIDs = append(traceIDs, traceID)
+			if at.After(cursor) {
+				cursor = at
+			}
+		}
+	}
+	return traceIDs, cursor, h.written
+}
+
+// followHandler long-polls for traces written after the time in the after parameter, which
+// defaults to now. It answers as soon as there are any, or with none once the timeout has
+// passed, along with the cursor to send as after in the next poll.
+func (s *Server) followHandler(writer http.ResponseWriter, request *http.Request) {
+	after, err := timeQueryParam(request, "after")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if after.IsZero() {
+		after = time.Now().UTC()
+	}
+
+	timeout, err := durationQueryParam(request, "timeout")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if timeout > maxFollowTimeout {
+		http.Error(writer, fmt.Sprintf("invalid timeout %s: must be at most %s", timeout, maxFollowTimeout), http.StatusBadRequest)
+		return
+	}
+	if timeout == 0 {
+		timeout = defaultFollowTimeout
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		traceIDs, cursor, written := s.hub.writtenSince(after)
+		if len(traceIDs) > 0 {
+			summaries, _, err := s.Store.QueryTraceSummaries(request.Context(), store.SummaryQuery{TraceIDs: traceIDs})
+			// A client that gave up on the poll cancels the query, and is gone by the time it fails
+			if err != nil {
+				writeServerError(writer, request, err)
+				return
+			}
+			writeJSON(writer, telemetry.FollowedTraces{TraceSummaries: *summaries, Cursor: cursor})
+			return
+		}
+
+		select {
+		case <-written:
+		case <-timer.C:
+			writeJSON(writer, telemetry.FollowedTraces{TraceSummaries: []telemetry.TraceSummary{}, Cursor: cursor})
+			return
+		case <-s.hub.stop:
+			writeJSON(writer, telemetry.FollowedTraces{TraceSummaries: []telemetry.TraceSummary{}, Cursor: cursor})
+			return
+		case <-request.Context().Done():
+			return
+		}
+	}
+}
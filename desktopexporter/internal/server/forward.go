@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// forwardQueueLength is how many received payloads can wait to be forwarded before more are dropped
+const forwardQueueLength = 1000
+
+// forwardTimeout bounds each export to the upstream endpoint
+const forwardTimeout = 10 * time.Second
+
+// forwarder exports the spans the viewer receives to an upstream OTLP/grpc endpoint once they
+// are stored. It queues them rather than waiting on the upstream, and drops what doesn't fit
+// in the queue or couldn't be exported, so a slow or absent upstream never holds up ingestion.
+type forwarder struct {
+	endpoint string
+	conn     *grpc.ClientConn
+	client   ptraceotlp.GRPCClient
+	queue    chan []telemetry.SpanData
+
+	forwarded atomic.Int64
+	dropped   atomic.Int64
+	// onDrop is told about dropped spans too, for the viewer's own metrics
+	onDrop  func(count int)
+	failing bool
+	logger  *slog.Logger
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// WithForwarding exports every span received from instrumented services or the collector to the
+// OTLP/grpc endpoint as well, after storing it. Imports and sample data aren't forwarded.
+func WithForwarding(endpoint string) Option {
+	return func(s *Server) {
+		s.forwardEndpoint = endpoint
+	}
+}
+
+func newForwarder(endpoint string, logger *slog.Logger) (*forwarder, error) {
+	// The connection is made lazily, and remade by grpc whenever the upstream drops it
+	conn, err := grpc.NewClient(endpoint, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &forwarder{
+		endpoint: endpoint,
+		conn:     conn,
+		client:   ptraceotlp.NewGRPCClient(conn),
+		queue:    make(chan []telemetry.SpanData, forwardQueueLength),
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+		stopped:  make(chan struct{}),
+	}, nil
+}
+
+// enqueue queues spans to be forwarded, dropping them if the queue is full. It never blocks,
+// so the store can call it as it ingests spans.
+func (f *forwarder) enqueue(spans []telemetry.SpanData) {
+	select {
+	case f.queue <- spans:
+	default:
+		f.drop(len(spans))
+	}
+}
+
+// run exports queued spans one payload at a time until the forwarder is closed
+func (f *forwarder) run() {
+	defer close(f.stopped)
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case spans := <-f.queue:
+			f.export(spans)
+		}
+	}
+}
+
+func (f *forwarder) export(spans []telemetry.SpanData) {
+	ctx, cancel := context.WithTimeout(f.ctx, forwardTimeout)
+	defer cancel()
+
+	request := ptraceotlp.NewExportRequestFromTraces(telemetry.NewTracesFromSpans(spans))
+	_, err := f.client.Export(ctx, request)
+	if err != nil {
+		f.drop(len(spans))
+		// Only the first failure is logged, so a missing upstream doesn't flood the log
+		if !f.failing {
+			f.logger.Warn("could not forward spans, dropping them until the upstream is back", slog.String("endpoint", f.endpoint), slog.String("error", err.Error()))
+			f.failing = true
+		}
+		return
+	}
+
+	f.forwarded.Add(int64(len(spans)))
+	if f.failing {
+		f.logger.Info("forwarding spans again", slog.String("endpoint", f.endpoint))
+		f.failing = false
+	}
+}
+
+func (f *forwarder) drop(count int) {
+	f.dropped.Add(int64(count))
+	if f.onDrop != nil {
+		f.onDrop(count)
+	}
+}
+
+func (f *forwarder) status() *telemetry.ForwardingStatus {
+	return &telemetry.ForwardingStatus{
+		Endpoint:       f.endpoint,
+		ForwardedSpans: f.forwarded.Load(),
+		DroppedSpans:   f.dropped.Load(),
+	}
+}
+
+// close stops forwarding, abandoning any export in flight and the spans still queued
+func (f *forwarder) close() error {
+	f.cancel()
+	<-f.stopped
+	return f.conn.Close()
+}
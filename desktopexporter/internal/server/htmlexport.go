@@ -0,0 +1,24 @@
+package server
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+//go:embed templates/trace.html
+var traceHTML string
+
+// traceTemplate renders a trace as a single HTML page with its waterfall drawn by inline script.
+// html/template escapes the trace for the script context it's embedded in, and the page's
+// content security policy keeps it from reaching anything outside the file.
+var traceTemplate = template.Must(template.New("trace").Parse(traceHTML))
+
+func writeTraceHTML(writer io.Writer, trace telemetry.TraceData) error {
+	return traceTemplate.Execute(writer, struct {
+		TraceID string
+		Trace   telemetry.TraceData
+	}{trace.TraceID, trace})
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ErrIdleTimeout means Run shut the server down because nothing used it for the idle timeout
+var ErrIdleTimeout = errors.New("idle for too long")
+
+// maxIdleCheckInterval is the longest Run goes between checks for having been idle too long
+const maxIdleCheckInterval = time.Second
+
+// WithIdleTimeout has Run shut the server down once it has gone timeout without an API request
+// or a payload to ingest, so a viewer left running stops by itself
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.idleTimeout = timeout
+	}
+}
+
+// MarkActive restarts the idle timeout. Requests and payloads the server receives itself
+// restart it already, so this is for telemetry that reaches the store some other way, such
+// as through the collector pipeline.
+func (s *Server) MarkActive() {
+	s.lastActive.Store(s.now().UnixNano())
+}
+
+// trackActivity restarts the idle timeout as each request comes in and again once it is
+// answered. A stream left open doesn't count as activity in between.
+func (s *Server) trackActivity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		s.MarkActive()
+		defer s.MarkActive()
+		next.ServeHTTP(writer, request)
+	})
+}
+
+// trackGRPCActivity restarts the idle timeout for every OTLP grpc payload
+func (s *Server) trackGRPCActivity(ctx context.Context, request any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	s.MarkActive()
+	return handler(ctx, request)
+}
+
+// idle is closed once the server has gone the idle timeout without being used, and never
+// closed if there is no idle timeout or ctx is done first
+func (s *Server) idle(ctx context.Context) <-chan struct{} {
+	idle := make(chan struct{})
+	if s.idleTimeout <= 0 {
+		return idle
+	}
+
+	go func() {
+		ticker := time.NewTicker(min(s.idleTimeout/10, maxIdleCheckInterval))
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if s.now().Sub(time.Unix(0, s.lastActive.Load())) >= s.idleTimeout {
+					close(idle)
+					return
+				}
+			}
+		}
+	}()
+	return idle
+}
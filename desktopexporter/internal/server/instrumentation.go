@@ -0,0 +1,217 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+)
+
+const metricsNamespace = "otel_desktop_viewer"
+
+// instrumentation keeps track of how the viewer itself is doing, to be scraped by Prometheus.
+// It is the store's observer, and counts requests by the route they matched.
+type instrumentation struct {
+	registry *prometheus.Registry
+
+	spansReceived       prometheus.Counter
+	spansDropped        prometheus.Counter
+	spansLimited        prometheus.Counter
+	spansForwardDropped prometheus.Counter
+	spansStored         prometheus.Counter
+	tracesStored        prometheus.Counter
+	tracesEvicted       prometheus.Counter
+	insertDuration      prometheus.Histogram
+	requests            *prometheus.CounterVec
+	requestDuration     *prometheus.HistogramVec
+}
+
+func newInstrumentation() *instrumentation {
+	i := &instrumentation{
+		registry: prometheus.NewRegistry(),
+		spansReceived: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spans_received_total",
+			Help:      "Spans received, whether or not they have been written yet.",
+		}),
+		spansDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spans_dropped_total",
+			Help:      "Spans turned away for having a malformed trace or span ID.",
+		}),
+		spansLimited: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spans_rate_limited_total",
+			Help:      "Spans turned away for arriving faster than the ingest rate limit.",
+		}),
+		spansForwardDropped: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spans_forward_dropped_total",
+			Help:      "Spans that couldn't be forwarded upstream, for not fitting in the queue or failing to export.",
+		}),
+		spansStored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "spans_stored_total",
+			Help:      "Spans written to the store.",
+		}),
+		tracesStored: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "traces_stored_total",
+			Help:      "Traces written to, counted once for each batch of spans that includes them.",
+		}),
+		tracesEvicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "traces_evicted_total",
+			Help:      "Traces evicted by the retention policy or to stay under the span cap.",
+		}),
+		insertDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "store_insert_duration_seconds",
+			Help:      "How long writing a batch of spans to the store takes.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route and status code.",
+		}, []string{"route", "code"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "How long serving HTTP requests takes, by route.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route"}),
+	}
+
+	i.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		i.spansReceived,
+		i.spansDropped,
+		i.spansLimited,
+		i.spansForwardDropped,
+		i.spansStored,
+		i.tracesStored,
+		i.tracesEvicted,
+		i.insertDuration,
+		i.requests,
+		i.requestDuration,
+	)
+	return i
+}
+
+// watchTraceCount reports the number of traces in the store each time metrics are scraped
+func (i *instrumentation) watchTraceCount(s *store.Store) {
+	i.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "traces",
+		Help:      "Traces in the store.",
+	}, func() float64 {
+		count, err := s.CountTraces(context.Background())
+		if err != nil {
+			log.Printf("could not count traces: %s", err.Error())
+			return math.NaN()
+		}
+		return float64(count)
+	}))
+}
+
+func (i *instrumentation) SpansReceived(count int) {
+	i.spansReceived.Add(float64(count))
+}
+
+func (i *instrumentation) SpansDropped(count int) {
+	i.spansDropped.Add(float64(count))
+}
+
+func (i *instrumentation) SpansRateLimited(count int) {
+	i.spansLimited.Add(float64(count))
+}
+
+func (i *instrumentation) SpansForwardDropped(count int) {
+	i.spansForwardDropped.Add(float64(count))
+}
+
+func (i *instrumentation) BatchWritten(spans int, traces int, duration time.Duration) {
+	i.spansStored.Add(float64(spans))
+	i.tracesStored.Add(float64(traces))
+	i.insertDuration.Observe(duration.Seconds())
+}
+
+func (i *instrumentation) TracesEvicted(count int) {
+	i.tracesEvicted.Add(float64(count))
+}
+
+func (i *instrumentation) handler() http.Handler {
+	return promhttp.HandlerFor(i.registry, promhttp.HandlerOpts{})
+}
+
+// instrument counts and times requests by the pattern of the route they matched,
+// which the router sets on the request as it hands it on
+func (i *instrumentation) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: writer}
+		start := time.Now()
+		next.ServeHTTP(recorder, request)
+
+		route := request.Pattern
+		if route == "" {
+			route = "unmatched"
+		}
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		i.requests.WithLabelValues(route, strconv.Itoa(status)).Inc()
+		i.requestDuration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder remembers the status and size of a response, while still letting event streams
+// flush and websockets take over the connection
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(p []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += int64(n)
+	return n, err
+}
+
+func (r *statusRecorder) Flush() {
+	http.NewResponseController(r.ResponseWriter).Flush()
+}
+
+func (r *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if r.status == 0 {
+		r.status = http.StatusSwitchingProtocols
+	}
+	return http.NewResponseController(r.ResponseWriter).Hijack()
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
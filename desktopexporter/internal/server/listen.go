@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// unixSocketScheme prefixes endpoints that name a unix socket rather than a host and port
+const unixSocketScheme = "unix://"
+
+// unixSocketPath returns the path of the unix socket an endpoint such as unix:///tmp/viewer.sock names
+func unixSocketPath(endpoint string) (string, bool) {
+	return strings.CutPrefix(endpoint, unixSocketScheme)
+}
+
+// listen opens the listener the server is served on
+func (s *Server) listen() (net.Listener, error) {
+	if path, ok := unixSocketPath(s.server.Addr); ok {
+		return listenUnix(path)
+	}
+	return net.Listen("tcp", s.server.Addr)
+}
+
+// listenUnix listens on a unix socket, removing the socket file a viewer that didn't shut down
+// cleanly left behind. Closing the listener removes the socket file it created.
+func listenUnix(path string) (net.Listener, error) {
+	if path == "" {
+		return nil, fmt.Errorf("could not listen on unix socket: path must not be empty")
+	}
+
+	info, err := os.Lstat(path)
+	if err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("could not listen on unix socket: %s exists and is not a socket", path)
+		}
+		// A socket something still answers on isn't stale
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("could not listen on unix socket: %s is already in use", path)
+		}
+		if err = os.Remove(path); err != nil {
+			return nil, fmt.Errorf("could not remove stale unix socket: %s", err.Error())
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not check for stale unix socket: %s", err.Error())
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on unix socket: %s", err.Error())
+	}
+	return listener, nil
+}
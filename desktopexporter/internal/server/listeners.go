@@ -0,0 +1,82 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"google.golang.org/grpc"
+)
+
+// Listener receives OTLP grpc payloads on an endpoint of its own, like the grpc endpoint does,
+// and sets its resource attributes on every resource it receives that doesn't set them already
+type Listener struct {
+	Endpoint           string
+	ResourceAttributes map[string]string
+}
+
+// ParseListener reads a listener of the form endpoint,key=value,... such as
+// localhost:4320,env=staging,k8s.namespace.name=staging
+func ParseListener(spec string) (Listener, error) {
+	endpoint, attributes, _ := strings.Cut(spec, ",")
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		return Listener{}, fmt.Errorf("invalid listener %q: must start with a host and port, such as localhost:4320", spec)
+	}
+
+	listener := Listener{Endpoint: endpoint, ResourceAttributes: map[string]string{}}
+	if attributes == "" {
+		return listener, nil
+	}
+	for _, attribute := range strings.Split(attributes, ",") {
+		key, value, ok := strings.Cut(attribute, "=")
+		if !ok || key == "" {
+			return Listener{}, fmt.Errorf("invalid listener %q: %q must be a key=value resource attribute", spec, attribute)
+		}
+		if _, ok := listener.ResourceAttributes[key]; ok {
+			return Listener{}, fmt.Errorf("invalid listener %q: %s is set more than once", spec, key)
+		}
+		listener.ResourceAttributes[key] = value
+	}
+	return listener, nil
+}
+
+// WithListeners receives OTLP grpc payloads on the endpoint of each listener as well, tagging the
+// resources each receives with its resource attributes
+func WithListeners(listeners ...Listener) Option {
+	return func(s *Server) {
+		s.listeners = append(s.listeners, listeners...)
+	}
+}
+
+// startListeners serves each listener's grpc server on its endpoint
+func (s *Server) startListeners() error {
+	for i, listener := range s.listeners {
+		netListener, err := net.Listen("tcp", listener.Endpoint)
+		if err != nil {
+			return fmt.Errorf("could not listen for OTLP grpc payloads on %s: %s", listener.Endpoint, err.Error())
+		}
+		go s.listenerServers[i].Serve(netListener)
+	}
+	return nil
+}
+
+// grpcServers returns every grpc server there is to stop: the grpc endpoint's, if it has one,
+// and each listener's
+func (s *Server) grpcServers() []*grpc.Server {
+	servers := []*grpc.Server{}
+	if s.grpcServer != nil {
+		servers = append(servers, s.grpcServer)
+	}
+	return append(servers, s.listenerServers...)
+}
+
+// setResourceDefaults sets the attributes a resource doesn't already have, keeping the values
+// of those it does
+func setResourceDefaults(resource pcommon.Resource, attributes map[string]string) {
+	for key, value := range attributes {
+		if _, ok := resource.Attributes().Get(key); !ok {
+			resource.Attributes().PutStr(key, value)
+		}
+	}
+}
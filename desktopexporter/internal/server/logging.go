@@ -0,0 +1,52 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// logRequests logs each request once it has been served. Requests for the UI's assets only
+// log at debug, so loading a page doesn't bury the API requests it makes.
+func logRequests(logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		recorder := &statusRecorder{ResponseWriter: writer}
+		start := time.Now()
+		next.ServeHTTP(recorder, request)
+
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case status >= http.StatusInternalServerError:
+			level = slog.LevelError
+		case !isAPIPath(request.URL.Path):
+			level = slog.LevelDebug
+		}
+		logger.LogAttrs(request.Context(), level, "request",
+			slog.String("method", request.Method),
+			slog.String("path", request.URL.Path),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int64("bytes", recorder.bytes),
+		)
+	})
+}
+
+// isAPIPath tells the API and the receivers apart from the UI
+func isAPIPath(path string) bool {
+	return strings.HasPrefix(path, "/api/") || strings.HasPrefix(path, "/v1/")
+}
+
+// discardHandler drops every record, for servers that weren't given a logger
+type discardHandler struct{}
+
+func (discardHandler) Enabled(context.Context, slog.Level) bool  { return false }
+func (discardHandler) Handle(context.Context, slog.Record) error { return nil }
+func (h discardHandler) WithAttrs([]slog.Attr) slog.Handler      { return h }
+func (h discardHandler) WithGroup(string) slog.Handler           { return h }
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+)
+
+// maxReadBatchRequests is the most API requests that can be read in one batch
+const maxReadBatchRequests = 20
+
+// readBatch holds the response to each of the requests in a batch, in the order they were given
+type readBatch struct {
+	Responses []readBatchResponse `json:"responses"`
+}
+
+// readBatchResponse holds a response's body as it would have been sent, or as a string if it isn't JSON
+type readBatchResponse struct {
+	Path   string          `json:"path"`
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// readBatchHandler answers several GET requests to the API from one read session of the store,
+// so that no traces are evicted or cleared between them and they add up to a consistent view.
+// The requests are served by router as they are, without going through auth or compression again.
+func (s *Server) readBatchHandler(router http.Handler) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		payload, ok := s.readRequestBody(writer, request)
+		if !ok {
+			return
+		}
+
+		paths := []string{}
+		if err := json.Unmarshal(payload, &paths); err != nil {
+			http.Error(writer, fmt.Sprintf("requests must be a JSON array of paths: %s", err.Error()), http.StatusBadRequest)
+			return
+		}
+		if len(paths) > maxReadBatchRequests {
+			http.Error(writer, fmt.Sprintf("too many requests: at most %d can be read in one batch", maxReadBatchRequests), http.StatusBadRequest)
+			return
+		}
+		for _, path := range paths {
+			if !strings.HasPrefix(path, "/api/") {
+				http.Error(writer, fmt.Sprintf("invalid path %q: must start with /api/", path), http.StatusBadRequest)
+				return
+			}
+		}
+
+		batch := readBatch{Responses: []readBatchResponse{}}
+		err := s.Store.ReadSession(request.Context(), func(ctx context.Context) error {
+			for _, path := range paths {
+				subRequest, err := http.NewRequestWithContext(ctx, http.MethodGet, path, nil)
+				if err != nil {
+					return fmt.Errorf("invalid path %q: %s", path, err.Error())
+				}
+
+				recorder := newBatchRecorder()
+				router.ServeHTTP(recorder, subRequest)
+				batch.Responses = append(batch.Responses, recorder.response(path))
+			}
+			return nil
+		})
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(writer, batch)
+	}
+}
+
+// outsideReadBatches refuses a route within a read batch, for routes that write, stream, or wait on
+// writes, which would keep the batch's read session from ending or wait on it to end
+func outsideReadBatches(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if store.InReadSession(request.Context()) {
+			http.Error(writer, fmt.Sprintf("%s can't be read in a batch", request.URL.Path), http.StatusBadRequest)
+			return
+		}
+		next(writer, request)
+	}
+}
+
+// batchRecorder keeps a response in memory, to be sent as part of a batch
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: http.Header{}}
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(data []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(data)
+}
+
+func (r *batchRecorder) response(path string) readBatchResponse {
+	response := readBatchResponse{Path: path, Status: r.status, Body: r.body.Bytes()}
+	if response.Status == 0 {
+		response.Status = http.StatusOK
+	}
+	if !json.Valid(response.Body) {
+		response.Body, _ = json.Marshal(strings.TrimSpace(r.body.String()))
+	}
+	return response
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+)
+
+// readOnlyMessage is what requests that would change the data are refused with on a read-only server
+const readOnlyMessage = "the viewer is read-only: it serves an existing database file, and nothing can be added to it or removed from it"
+
+// WithReadOnly serves the traces in an existing database file without ever writing to it: the
+// file is opened read-only, retention is never enforced, and the routes that would add, import,
+// annotate, or remove data answer 405 Method Not Allowed, as the gRPC receiver answers Unimplemented.
+// The UI and the query routes work as usual.
+func WithReadOnly() Option {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// writes marks a route that changes the data, refusing it when the server is read-only, and
+// within a read batch
+func (s *Server) writes(next http.HandlerFunc) http.HandlerFunc {
+	if !s.readOnly {
+		return outsideReadBatches(next)
+	}
+	return func(writer http.ResponseWriter, request *http.Request) {
+		http.Error(writer, readOnlyMessage, http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,395 @@
+package server
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"mime"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+const (
+	protobufContentType = "application/x-protobuf"
+	jsonContentType     = "application/json"
+
+	// zipkinSpansPath is where Zipkin reporters send spans
+	zipkinSpansPath = "/api/v2/spans"
+)
+
+// traceService implements the OTLP TraceService, storing whatever it receives alongside
+// the spans handed to us by the collector. Resources that don't set its resource attributes
+// are given them first.
+type traceService struct {
+	ptraceotlp.UnimplementedGRPCServer
+	server             *Server
+	resourceAttributes map[string]string
+}
+
+// logsService implements the OTLP LogsService, the same way traceService does for spans
+type logsService struct {
+	plogotlp.UnimplementedGRPCServer
+	server             *Server
+	resourceAttributes map[string]string
+}
+
+// metricsService implements the OTLP MetricsService, the same way traceService does for spans
+type metricsService struct {
+	pmetricotlp.UnimplementedGRPCServer
+	server             *Server
+	resourceAttributes map[string]string
+}
+
+func newGRPCServer(s *Server, resourceAttributes map[string]string) *grpc.Server {
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(s.trackGRPCActivity))
+	ptraceotlp.RegisterGRPCServer(grpcServer, &traceService{server: s, resourceAttributes: resourceAttributes})
+	plogotlp.RegisterGRPCServer(grpcServer, &logsService{server: s, resourceAttributes: resourceAttributes})
+	pmetricotlp.RegisterGRPCServer(grpcServer, &metricsService{server: s, resourceAttributes: resourceAttributes})
+	return grpcServer
+}
+
+func (service *traceService) Export(ctx context.Context, request ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	if service.server.readOnly {
+		return ptraceotlp.NewExportResponse(), status.Error(codes.Unimplemented, readOnlyMessage)
+	}
+	for i := 0; i < request.Traces().ResourceSpans().Len(); i++ {
+		setResourceDefaults(request.Traces().ResourceSpans().At(i).Resource(), service.resourceAttributes)
+	}
+	response, err := service.server.receiveTraces(ctx, request.Traces())
+	if err != nil {
+		return response, status.Error(codes.Unavailable, err.Error())
+	}
+	return response, nil
+}
+
+func (service *logsService) Export(ctx context.Context, request plogotlp.ExportRequest) (plogotlp.ExportResponse, error) {
+	if service.server.readOnly {
+		return plogotlp.NewExportResponse(), status.Error(codes.Unimplemented, readOnlyMessage)
+	}
+	for i := 0; i < request.Logs().ResourceLogs().Len(); i++ {
+		setResourceDefaults(request.Logs().ResourceLogs().At(i).Resource(), service.resourceAttributes)
+	}
+	response, err := service.server.receiveLogs(ctx, request.Logs())
+	if err != nil {
+		return response, status.Error(codes.Unavailable, err.Error())
+	}
+	return response, nil
+}
+
+func (service *metricsService) Export(ctx context.Context, request pmetricotlp.ExportRequest) (pmetricotlp.ExportResponse, error) {
+	if service.server.readOnly {
+		return pmetricotlp.NewExportResponse(), status.Error(codes.Unimplemented, readOnlyMessage)
+	}
+	for i := 0; i < request.Metrics().ResourceMetrics().Len(); i++ {
+		setResourceDefaults(request.Metrics().ResourceMetrics().At(i).Resource(), service.resourceAttributes)
+	}
+	response, err := service.server.receiveMetrics(ctx, request.Metrics())
+	if err != nil {
+		return response, status.Error(codes.Unavailable, err.Error())
+	}
+	return response, nil
+}
+
+// receiveTraces queues the spans of an OTLP export request and reports back any spans
+// we had to reject as a partial success, as the OTLP specification asks of receivers
+func (s *Server) receiveTraces(ctx context.Context, traces ptrace.Traces) (ptraceotlp.ExportResponse, error) {
+	response := ptraceotlp.NewExportResponse()
+
+	spans := []telemetry.SpanData{}
+	rejected, duplicates := 0, 0
+	indexes := map[string]int{}
+	extracted := telemetry.NewSpanPayload(traces).ExtractSpans()
+	s.RedactSpans(extracted)
+	for _, span := range extracted {
+		// Without both IDs a span can't be placed in a trace. These are the spans the store
+		// would drop, so every one it doesn't store is counted here.
+		if span.ValidateIDs() != nil {
+			rejected++
+			continue
+		}
+
+		// The store keeps the last of the spans sent with the same IDs, and so do we
+		key := span.TraceID + span.SpanID
+		if i, ok := indexes[key]; ok {
+			spans[i] = span
+			duplicates++
+			continue
+		}
+		indexes[key] = len(spans)
+		spans = append(spans, span)
+	}
+
+	limited, err := s.Store.IngestSpans(ctx, spans)
+	if err != nil {
+		return response, fmt.Errorf("could not add spans: %s", err.Error())
+	}
+	s.logger.DebugContext(ctx, "spans added", slog.String("source", "otlp"), slog.Int("spans", len(spans)-limited), slog.Int("rejected", rejected), slog.Int("duplicates", duplicates), slog.Int("rateLimited", limited))
+
+	reasons := []string{}
+	if rejected > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d spans were rejected for missing a trace or span ID", rejected))
+	}
+	if duplicates > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d spans were dropped for having the same trace and span ID as a later span in the request", duplicates))
+	}
+	if limited > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d spans were dropped for arriving faster than the ingest rate limit", limited))
+	}
+	if len(reasons) > 0 {
+		response.PartialSuccess().SetRejectedSpans(int64(rejected + duplicates + limited))
+		response.PartialSuccess().SetErrorMessage(strings.Join(reasons, "; "))
+	}
+	return response, nil
+}
+
+// receiveLogs stores the logs of an OTLP export request. Logs needn't belong to a trace,
+// so none of them are rejected.
+func (s *Server) receiveLogs(ctx context.Context, logs plog.Logs) (plogotlp.ExportResponse, error) {
+	response := plogotlp.NewExportResponse()
+
+	if err := s.Store.AddLogs(ctx, telemetry.NewLogsPayload(logs).ExtractLogs()); err != nil {
+		return response, fmt.Errorf("could not add logs: %s", err.Error())
+	}
+	return response, nil
+}
+
+// receiveMetrics stores the data points of an OTLP export request
+func (s *Server) receiveMetrics(ctx context.Context, metrics pmetric.Metrics) (pmetricotlp.ExportResponse, error) {
+	response := pmetricotlp.NewExportResponse()
+
+	if err := s.Store.AddMetrics(ctx, telemetry.NewMetricsPayload(metrics).ExtractMetrics()); err != nil {
+		return response, fmt.Errorf("could not add metrics: %s", err.Error())
+	}
+	return response, nil
+}
+
+// otlpPayload is an OTLP/HTTP request or response, which can be encoded as either protobuf or JSON
+type otlpPayload interface {
+	MarshalProto() ([]byte, error)
+	UnmarshalProto(data []byte) error
+	MarshalJSON() ([]byte, error)
+	UnmarshalJSON(data []byte) error
+}
+
+// otlpTracesHandler receives OTLP/HTTP trace payloads
+func (s *Server) otlpTracesHandler(writer http.ResponseWriter, request *http.Request) {
+	exportRequest := ptraceotlp.NewExportRequest()
+	contentType, ok := s.readOTLPRequest(writer, request, exportRequest)
+	if !ok {
+		return
+	}
+
+	response, err := s.receiveTraces(request.Context(), exportRequest.Traces())
+	if err != nil {
+		// OTLP clients retry on 503, so nothing is lost if we received it mid-shutdown
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeOTLPResponse(writer, contentType, response)
+}
+
+// otlpLogsHandler receives OTLP/HTTP log payloads
+func (s *Server) otlpLogsHandler(writer http.ResponseWriter, request *http.Request) {
+	exportRequest := plogotlp.NewExportRequest()
+	contentType, ok := s.readOTLPRequest(writer, request, exportRequest)
+	if !ok {
+		return
+	}
+
+	response, err := s.receiveLogs(request.Context(), exportRequest.Logs())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeOTLPResponse(writer, contentType, response)
+}
+
+// otlpMetricsHandler receives OTLP/HTTP metric payloads
+func (s *Server) otlpMetricsHandler(writer http.ResponseWriter, request *http.Request) {
+	exportRequest := pmetricotlp.NewExportRequest()
+	contentType, ok := s.readOTLPRequest(writer, request, exportRequest)
+	if !ok {
+		return
+	}
+
+	response, err := s.receiveMetrics(request.Context(), exportRequest.Metrics())
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	writeOTLPResponse(writer, contentType, response)
+}
+
+// zipkinSpansHandler receives Zipkin v2 JSON span lists, so Zipkin reporters can send to us as they
+// would to a Zipkin server. Like Zipkin, it answers 202 with an empty body.
+func (s *Server) zipkinSpansHandler(writer http.ResponseWriter, request *http.Request) {
+	if header := request.Header.Get("Content-Type"); header != "" {
+		contentType, _, err := mime.ParseMediaType(header)
+		if err != nil || contentType != jsonContentType {
+			http.Error(writer, fmt.Sprintf("unsupported content type %q: must be %s", header, jsonContentType), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	payload, ok := s.readRequestBody(writer, request)
+	if !ok {
+		return
+	}
+
+	spans, err := telemetry.ParseZipkinSpans(payload)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.RedactSpans(spans)
+
+	limited, err := s.Store.IngestSpans(request.Context(), spans)
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not add spans: %s", err.Error()), http.StatusServiceUnavailable)
+		return
+	}
+	s.logger.DebugContext(request.Context(), "spans added", slog.String("source", "zipkin"), slog.Int("spans", len(spans)-limited), slog.Int("rateLimited", limited))
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// simpleSpansHandler takes spans in the minimal JSON shape telemetry.ParseSimpleSpans reads,
+// for scripts and demos that don't want to build OTLP
+func (s *Server) simpleSpansHandler(writer http.ResponseWriter, request *http.Request) {
+	if header := request.Header.Get("Content-Type"); header != "" {
+		contentType, _, err := mime.ParseMediaType(header)
+		if err != nil || contentType != jsonContentType {
+			http.Error(writer, fmt.Sprintf("unsupported content type %q: must be %s", header, jsonContentType), http.StatusUnsupportedMediaType)
+			return
+		}
+	}
+
+	payload, ok := s.readRequestBody(writer, request)
+	if !ok {
+		return
+	}
+
+	spans, err := telemetry.ParseSimpleSpans(payload)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.RedactSpans(spans)
+
+	limited, err := s.Store.IngestSpans(request.Context(), spans)
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not add spans: %s", err.Error()), http.StatusServiceUnavailable)
+		return
+	}
+	s.logger.DebugContext(request.Context(), "spans added", slog.String("source", "json"), slog.Int("spans", len(spans)-limited), slog.Int("rateLimited", limited))
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+// readOTLPRequest unmarshals an OTLP/HTTP request body in whichever encoding its Content-Type names,
+// and returns that content type. If it can't, it responds with an error and returns false.
+func (s *Server) readOTLPRequest(writer http.ResponseWriter, request *http.Request, exportRequest otlpPayload) (string, bool) {
+	contentType, _, err := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	if err != nil || (contentType != protobufContentType && contentType != jsonContentType) {
+		http.Error(writer, fmt.Sprintf("unsupported content type %q: must be %s or %s", request.Header.Get("Content-Type"), protobufContentType, jsonContentType), http.StatusUnsupportedMediaType)
+		return "", false
+	}
+
+	payload, ok := s.readRequestBody(writer, request)
+	if !ok {
+		return "", false
+	}
+
+	if contentType == protobufContentType {
+		err = exportRequest.UnmarshalProto(payload)
+	} else {
+		err = exportRequest.UnmarshalJSON(payload)
+	}
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not unmarshal OTLP payload: %s", err.Error()), http.StatusBadRequest)
+		return "", false
+	}
+	return contentType, true
+}
+
+// readRequestBody reads a request body, decompressing it if the client gzipped it.
+// Content codings are case-insensitive, and x-gzip is an old name for gzip.
+// If it can't, it responds with an error and returns false.
+func (s *Server) readRequestBody(writer http.ResponseWriter, request *http.Request) ([]byte, bool) {
+	body := request.Body
+	switch strings.ToLower(strings.TrimSpace(request.Header.Get("Content-Encoding"))) {
+	case "", "identity":
+	case "gzip", "x-gzip":
+		gzipReader, err := gzip.NewReader(request.Body)
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("could not decompress request body: %s", err.Error()), http.StatusBadRequest)
+			return nil, false
+		}
+		defer gzipReader.Close()
+		// A small body can decompress to any size, so the limit applies after decompressing too
+		body = http.MaxBytesReader(writer, gzipReader, s.maxBodyBytes)
+	default:
+		http.Error(writer, fmt.Sprintf("unsupported content encoding %q: must be gzip or omitted", request.Header.Get("Content-Encoding")), http.StatusUnsupportedMediaType)
+		return nil, false
+	}
+
+	payload, err := io.ReadAll(body)
+	if err != nil {
+		writeBodyError(writer, err)
+		return nil, false
+	}
+	return payload, true
+}
+
+// limitBody cuts off request bodies past the server's limit, so that an oversized payload
+// is answered with 413 rather than read into memory whole
+func (s *Server) limitBody(next http.HandlerFunc) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		request.Body = http.MaxBytesReader(writer, request.Body, s.maxBodyBytes)
+		next(writer, request)
+	}
+}
+
+// writeBodyError responds to a request whose body couldn't be read, with 413 if it was over the limit
+func writeBodyError(writer http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(writer, fmt.Sprintf("request body is larger than the limit of %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	http.Error(writer, fmt.Sprintf("could not read request body: %s", err.Error()), http.StatusBadRequest)
+}
+
+// writeOTLPResponse responds in the encoding the request used
+func writeOTLPResponse(writer http.ResponseWriter, contentType string, response otlpPayload) {
+	var responseBytes []byte
+	var err error
+	if contentType == protobufContentType {
+		responseBytes, err = response.MarshalProto()
+	} else {
+		responseBytes, err = response.MarshalJSON()
+	}
+	if err != nil {
+		log.Printf("could not marshal OTLP response: %s", err.Error())
+		http.Error(writer, fmt.Sprintf("could not marshal OTLP response: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", contentType)
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(responseBytes)
+}
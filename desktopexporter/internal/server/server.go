@@ -1,17 +1,34 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/fs"
 	"log"
+	"log/slog"
+	"mime"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/pkg/browser"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"google.golang.org/grpc"
 
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
@@ -20,17 +37,311 @@ import (
 //go:embed static/*
 var assets embed.FS
 
+// readyTimeout is how long the store has to answer a readiness probe
+const readyTimeout = time.Second
+
+// maxBatchTraceIDs is the most traces that can be fetched in one batch
+const maxBatchTraceIDs = 100
+
+// The trace volume is counted a minute at a time over the last hour unless asked otherwise,
+// in at most maxVolumeBuckets buckets
+const (
+	defaultVolumeBucket = time.Minute
+	defaultVolumeWindow = time.Hour
+	maxVolumeBuckets    = 1000
+)
+
+// exportFlushTraces is how many traces an export writes between flushes, so a download of a big
+// store keeps moving without flushing each small trace through compression on its own
+const exportFlushTraces = 100
+
+// maxGroupByKeys is the most resource attributes services can be grouped by at once
+const maxGroupByKeys = 8
+
+// defaultAttributeValues and maxAttributeValues are how many values of an attribute are listed
+// when no limit is asked for, and the most that can be asked for
+const (
+	defaultAttributeValues = 100
+	maxAttributeValues     = 1000
+)
+
+// defaultMaxBodyBytes is the largest request body accepted unless WithMaxBodyBytes says otherwise
+const defaultMaxBodyBytes = 64 << 20
+
+// defaultShutdownTimeout is how long Run waits for in-flight requests once it is asked to stop
+const defaultShutdownTimeout = 5 * time.Second
+
+// ErrShutdownTimeout means requests were still in flight when the shutdown timeout ran out
+var ErrShutdownTimeout = errors.New("timed out waiting for in-flight requests")
+
 type Server struct {
 	server http.Server
 	Store  *store.Store
+	hub    *hub
+
+	retention         store.RetentionPolicy
+	retentionInterval time.Duration
+	spillover         store.RetentionPolicy
+	maxSpans          int
+	ingestRate        int
+	dbMemoryLimit     string
+	dbThreads         int
+	stopRetention     chan struct{}
+	stopOnce          sync.Once
+	now               func() time.Time
+	started           time.Time
+	shutdownTimeout   time.Duration
+	idleTimeout       time.Duration
+	lastActive        atomic.Int64
+	maxBodyBytes      int64
+	defaultPageSize   int
+	maxPageSize       int
+	readOnly          bool
+
+	grpcEndpoint string
+	grpcServer   *grpc.Server
+
+	listeners       []Listener
+	listenerServers []*grpc.Server
+
+	forwardEndpoint string
+	forwarder       *forwarder
+
+	redactor       *telemetry.Redactor
+	anonymizeKeys  []string
+	groupAttribute string
+
+	authToken string
+	authUI    bool
+
+	tlsCertFile   string
+	tlsKeyFile    string
+	tlsSelfSigned bool
+
+	corsOrigins []string
+
+	instrumentation *instrumentation
+	logger          *slog.Logger
+}
+
+// Option configures optional Server behaviour
+type Option func(*Server)
+
+// WithRetention evicts traces the policy no longer allows us to keep, checking every interval
+func WithRetention(policy store.RetentionPolicy, interval time.Duration) Option {
+	return func(s *Server) {
+		s.retention = policy
+		s.retentionInterval = interval
+	}
+}
+
+// WithSpillover keeps recent traces in memory and moves those the policy no longer allows keeping
+// there to the database file, checking as often as the retention policy
+func WithSpillover(policy store.RetentionPolicy) Option {
+	return func(s *Server) {
+		s.spillover = policy
+	}
+}
+
+// WithMaxSpans caps the number of spans kept, evicting the least recently active traces to make room
+func WithMaxSpans(maxSpans int) Option {
+	return func(s *Server) {
+		s.maxSpans = maxSpans
+	}
+}
+
+// WithIngestRate drops the spans services send past spansPerSecond, so a flood of them
+// can't make the API unresponsive
+func WithIngestRate(spansPerSecond int) Option {
+	return func(s *Server) {
+		s.ingestRate = spansPerSecond
+	}
+}
+
+// WithPageSizes sets how many trace summaries the traces list and search return when the client
+// doesn't give a limit, and the most they return whatever limit it gives, clamping larger ones
+// rather than refusing them. Zero returns every summary.
+func WithPageSizes(defaultSize int, maxSize int) Option {
+	return func(s *Server) {
+		s.defaultPageSize = defaultSize
+		s.maxPageSize = maxSize
+	}
+}
+
+// WithMaxBodyBytes caps the size of the payloads that can be sent in to be stored, before and after
+// decompressing them. Zero or less keeps the default of defaultMaxBodyBytes.
+func WithMaxBodyBytes(limit int64) Option {
+	return func(s *Server) {
+		if limit > 0 {
+			s.maxBodyBytes = limit
+		}
+	}
+}
+
+// WithDatabaseLimits caps the memory and threads DuckDB may use, leaving its defaults in place
+// for an empty memory limit or zero threads
+func WithDatabaseLimits(memoryLimit string, threads int) Option {
+	return func(s *Server) {
+		s.dbMemoryLimit = memoryLimit
+		s.dbThreads = threads
+	}
+}
+
+// WithShutdownTimeout bounds how long Run waits for in-flight requests to finish once it is asked to stop
+func WithShutdownTimeout(timeout time.Duration) Option {
+	return func(s *Server) {
+		s.shutdownTimeout = timeout
+	}
+}
+
+// WithAuthToken requires the token on the API, and on the UI as well if gateUI is set
+func WithAuthToken(token string, gateUI bool) Option {
+	return func(s *Server) {
+		s.authToken = token
+		s.authUI = gateUI
+	}
+}
+
+// WithTLS serves HTTPS using the certificate and private key in the given PEM files
+func WithTLS(certFile string, keyFile string) Option {
+	return func(s *Server) {
+		s.tlsCertFile = certFile
+		s.tlsKeyFile = keyFile
+	}
+}
+
+// WithSelfSignedTLS serves HTTPS using a certificate generated on start, which browsers will warn about
+func WithSelfSignedTLS() Option {
+	return func(s *Server) {
+		s.tlsSelfSigned = true
+	}
+}
+
+// WithCORSOrigins lets pages served from the given origins, or any origin for "*", call the API
+func WithCORSOrigins(origins ...string) Option {
+	return func(s *Server) {
+		s.corsOrigins = origins
+	}
+}
+
+// WithMetrics serves metrics about the viewer itself on /metrics, in the Prometheus format
+func WithMetrics() Option {
+	return func(s *Server) {
+		s.instrumentation = newInstrumentation()
+	}
+}
+
+// WithLogger logs each request, and how many spans each payload we receive adds, with logger
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) {
+		s.logger = logger
+	}
 }
 
-func NewServer(endpoint string, dbPath string) *Server {
+// WithRedactor hides the attribute values redactor names as soon as spans are read from what
+// was received or imported, so the store, the forwarder and the logs only ever see them hidden
+func WithRedactor(redactor *telemetry.Redactor) Option {
+	return func(s *Server) {
+		s.redactor = redactor
+	}
+}
+
+// RedactSpans redacts spans just read from a payload, before they go anywhere else. Without a
+// redactor it leaves them as they are.
+func (s *Server) RedactSpans(spans []telemetry.SpanData) {
+	if s.redactor != nil {
+		s.redactor.RedactSpans(spans)
+	}
+}
+
+// WithAnonymizeAttributes hashes the values of the given attribute keys, along with service and
+// span names, in exports asked for with ?anonymize=true
+func WithAnonymizeAttributes(keys ...string) Option {
+	return func(s *Server) {
+		s.anonymizeKeys = append(s.anonymizeKeys, keys...)
+	}
+}
+
+// WithGroupAttribute sorts traces into groups by a resource attribute, such as tenant.id, which
+// /api/traces and /api/search filter on with group, and /api/groups lists
+func WithGroupAttribute(key string) Option {
+	return func(s *Server) {
+		s.groupAttribute = key
+	}
+}
+
+// WithGRPCEndpoint receives OTLP grpc payloads on endpoint, storing them with everything else
+func WithGRPCEndpoint(endpoint string) Option {
+	return func(s *Server) {
+		s.grpcEndpoint = endpoint
+	}
+}
+
+func NewServer(endpoint string, dbPath string, opts ...Option) *Server {
 	s := Server{
 		server: http.Server{
 			Addr: endpoint,
 		},
-		Store: store.NewStore(context.Background(), dbPath),
+		hub:             newHub(),
+		stopRetention:   make(chan struct{}),
+		now:             time.Now,
+		shutdownTimeout: defaultShutdownTimeout,
+		maxBodyBytes:    defaultMaxBodyBytes,
+		logger:          slog.New(discardHandler{}),
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	s.started = s.now()
+	s.MarkActive()
+
+	storeOpts := []store.Option{
+		store.WithWriteListener(s.hub.notify),
+		store.WithResourceLimits(s.dbMemoryLimit, s.dbThreads),
+	}
+	if s.instrumentation != nil {
+		storeOpts = append(storeOpts, store.WithObserver(s.instrumentation))
+	}
+	if s.maxSpans > 0 {
+		storeOpts = append(storeOpts, store.WithMaxSpans(s.maxSpans))
+	}
+	if s.spillover.Enabled() {
+		storeOpts = append(storeOpts, store.WithSpillover(s.spillover))
+	}
+	if s.ingestRate > 0 {
+		storeOpts = append(storeOpts, store.WithIngestRateLimit(s.ingestRate))
+	}
+	if s.readOnly {
+		storeOpts = append(storeOpts, store.WithReadOnly())
+	}
+	if s.groupAttribute != "" {
+		storeOpts = append(storeOpts, store.WithGroupAttribute(s.groupAttribute))
+	}
+	if s.forwardEndpoint != "" {
+		forwarder, err := newForwarder(s.forwardEndpoint, s.logger)
+		if err != nil {
+			log.Fatalf("could not forward spans to %s: %s", s.forwardEndpoint, err.Error())
+		}
+		s.forwarder = forwarder
+		if s.instrumentation != nil {
+			s.forwarder.onDrop = s.instrumentation.SpansForwardDropped
+		}
+		storeOpts = append(storeOpts, store.WithIngestListener(s.forwarder.enqueue))
+		go s.forwarder.run()
+	}
+	s.Store = store.NewStore(context.Background(), dbPath, storeOpts...)
+	s.hub.store = s.Store
+	go s.hub.run()
+
+	if s.instrumentation != nil {
+		s.instrumentation.watchTraceCount(s.Store)
+	}
+
+	if s.grpcEndpoint != "" {
+		s.grpcServer = newGRPCServer(&s, nil)
+	}
+	for _, listener := range s.listeners {
+		s.listenerServers = append(s.listenerServers, newGRPCServer(&s, listener.ResourceAttributes))
 	}
 
 	serveFromFS, err := strconv.ParseBool(os.Getenv("SERVE_FROM_FS"))
@@ -42,32 +353,258 @@ func NewServer(endpoint string, dbPath string) *Server {
 	return &s
 }
 
+// Start serves until the server is closed or shut down, which close the store once they are done
+// with it. If the server can't start, Start closes the store itself.
 func (s *Server) Start() error {
-	defer s.Store.Close()
+	err := s.serve()
+	if !errors.Is(err, http.ErrServerClosed) {
+		s.Store.Close()
+	}
+	return err
+}
+
+func (s *Server) serve() error {
+	scheme := "http"
+	if s.tlsEnabled() {
+		tlsConfig, err := s.tlsConfig()
+		if err != nil {
+			return err
+		}
+		s.server.TLSConfig = tlsConfig
+		scheme = "https"
+	}
+
+	if (s.retention.Enabled() || s.spillover.Enabled()) && s.retentionInterval > 0 && !s.readOnly {
+		go s.runRetention()
+	}
+
+	if s.grpcServer != nil {
+		listener, err := net.Listen("tcp", s.grpcEndpoint)
+		if err != nil {
+			return fmt.Errorf("could not listen for OTLP grpc payloads: %s", err.Error())
+		}
+		go s.grpcServer.Serve(listener)
+	}
+	if err := s.startListeners(); err != nil {
+		return err
+	}
+
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
 
+	// Browsers can't open pages served on a unix socket
+	_, onUnixSocket := unixSocketPath(s.server.Addr)
+	if onUnixSocket && (s.tlsCertFile != "" || s.tlsSelfSigned) {
+		log.Printf("serving plain HTTP on %s: TLS doesn't apply to unix sockets", s.server.Addr)
+	}
 	_, isCI := os.LookupEnv("CI")
-	if !isCI {
+	if !isCI && !onUnixSocket {
 		go func() {
 			// Wait a bit for the server to come up to avoid a 404 as a first experience
 			time.Sleep(250 * time.Millisecond)
 			endpoint := s.server.Addr
-			browser.OpenURL("http://" + endpoint + "/")
+			browser.OpenURL(s.browserURL(scheme + "://" + endpoint + "/"))
+		}()
+	}
+
+	if s.tlsEnabled() {
+		// The certificate is already in TLSConfig
+		return s.server.ServeTLS(listener, "", "")
+	}
+	return s.server.Serve(listener)
+}
+
+// Run serves until ctx is done, the process is sent SIGINT or SIGTERM, or the idle timeout passes,
+// then shuts down, giving in-flight requests up to the shutdown timeout to finish. A shutdown that
+// times out is returned as an error wrapping ErrShutdownTimeout, and one for being idle as an error
+// wrapping ErrIdleTimeout; anything else that goes wrong on the way out is logged.
+func (s *Server) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.Start()
+	}()
+
+	var startErr error
+	select {
+	case <-ctx.Done():
+	case <-s.idle(ctx):
+		log.Printf("shutting down after being idle for %s", s.idleTimeout)
+		startErr = fmt.Errorf("%w: nothing used the viewer for %s", ErrIdleTimeout, s.idleTimeout)
+	case err := <-served:
+		// The server couldn't start, but everything started alongside it still has to stop
+		startErr = err
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.shutdownTimeout)
+	defer cancel()
+
+	err := s.Shutdown(shutdownCtx)
+	if errors.Is(err, ErrShutdownTimeout) {
+		return err
+	}
+	if err != nil {
+		log.Printf("could not shut down cleanly: %s", err.Error())
+	}
+	return startErr
+}
+
+// Shutdown stops accepting connections and waits for in-flight requests to finish until ctx is
+// done, then writes any queued spans and closes the store. Streams and long polls are ended
+// rather than waited on. Requests still in flight when ctx is done are cut off, and the error
+// wraps ErrShutdownTimeout.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stopOnce.Do(func() {
+		close(s.stopRetention)
+		s.hub.close()
+	})
+
+	timedOut := false
+	err := s.server.Shutdown(ctx)
+	if err != nil && ctx.Err() != nil {
+		timedOut = true
+		err = s.server.Close()
+	}
+
+	for _, grpcServer := range s.grpcServers() {
+		stopped := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
 		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			timedOut = true
+			grpcServer.Stop()
+			<-stopped
+		}
+	}
+
+	// Only now that nothing else can write to the store is it safe to close
+	if closeErr := s.Store.Close(); err == nil {
+		err = closeErr
+	}
+	if s.forwarder != nil {
+		s.forwarder.close()
 	}
-	return s.server.ListenAndServe()
+
+	if timedOut {
+		return fmt.Errorf("%w: %s", ErrShutdownTimeout, ctx.Err().Error())
+	}
+	return err
 }
 
 func (s *Server) Close() error {
-	return s.server.Close()
+	s.stopOnce.Do(func() {
+		close(s.stopRetention)
+		s.hub.close()
+	})
+	err := s.server.Close()
+	for _, grpcServer := range s.grpcServers() {
+		grpcServer.Stop()
+	}
+
+	// Wait for spans that are still queued to be written before we go
+	if closeErr := s.Store.Close(); err == nil {
+		err = closeErr
+	}
+	if s.forwarder != nil {
+		s.forwarder.close()
+	}
+	return err
+}
+
+// runRetention applies the retention and spillover policies on every tick until the server is closed.
+// Eviction runs on its own goroutine so ingestion never waits on the ticker.
+func (s *Server) runRetention() {
+	ticker := time.NewTicker(s.retentionInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stopRetention:
+			return
+		case <-ticker.C:
+			s.applyRetention(context.Background())
+		}
+	}
+}
+
+func (s *Server) applyRetention(ctx context.Context) {
+	evicted, err := s.Store.ApplyRetention(ctx, s.retention, s.now())
+	if err != nil {
+		log.Printf("could not apply retention policy: %s", err.Error())
+		return
+	}
+	if evicted > 0 {
+		log.Printf("evicted %d traces according to the retention policy", evicted)
+	}
+
+	spilled, err := s.Store.Spill(ctx, s.now())
+	if err != nil {
+		log.Printf("could not spill traces to the database file: %s", err.Error())
+		return
+	}
+	if spilled > 0 {
+		log.Printf("spilled %d traces to the database file", spilled)
+	}
 }
 
 func (s *Server) Handler(serveFromFS bool) http.Handler {
 	router := http.NewServeMux()
 	router.HandleFunc("GET /api/traces", s.tracesHandler)
+	router.HandleFunc("GET /api/traces/since", s.followHandler)
+	router.HandleFunc("GET /api/traces/by-attr", s.traceByAttributeHandler)
 	router.HandleFunc("GET /api/traces/{id}", s.traceIDHandler)
-	router.HandleFunc("GET /api/sampleData", s.sampleDataHandler)
-	router.HandleFunc("GET /api/clearData", s.clearTracesHandler)
+	router.HandleFunc("POST /api/traces/batch", s.limitBody(s.traceBatchHandler))
+	router.HandleFunc("DELETE /api/traces/{id}", s.writes(s.deleteTraceHandler))
+	router.HandleFunc("GET /api/traces/{id}/export", s.exportTraceHandler)
+	router.HandleFunc("GET /api/traces/export", s.exportTracesHandler)
+	router.HandleFunc("GET /api/export/db", outsideReadBatches(s.exportDatabaseHandler))
+	router.HandleFunc("GET /api/traces/compare", s.compareTracesHandler)
+	router.HandleFunc("POST /api/traces/import", s.writes(s.importTracesHandler))
+	router.HandleFunc("GET /api/traces/{id}/logs", s.traceLogsHandler)
+	router.HandleFunc("GET /api/traces/{id}/stats", s.traceStatsHandler)
+	router.HandleFunc("GET /api/traces/{id}/tree", s.traceTreeHandler)
+	router.HandleFunc("GET /api/traces/{id}/flamegraph", s.traceFlameGraphHandler)
+	router.HandleFunc("GET /api/traces/{id}/gaps", s.traceGapsHandler)
+	router.HandleFunc("GET /api/traces/{id}/critical-path", s.traceCriticalPathHandler)
+	router.HandleFunc("GET /api/traces/{id}/spans/{spanID}", s.spanHandler)
+	router.HandleFunc("GET /api/traces/{id}/search", s.traceSearchHandler)
+	router.HandleFunc("GET /api/traces/{id}/annotations", s.annotationsHandler)
+	router.HandleFunc("PUT /api/traces/{id}/annotations", s.writes(s.limitBody(s.putAnnotationsHandler)))
+	router.HandleFunc("POST /api/traces/{id}/pin", s.writes(s.pinHandler))
+	router.HandleFunc("DELETE /api/traces/{id}/pin", s.writes(s.unpinHandler))
+	router.HandleFunc("GET /api/search", s.searchHandler)
+	router.HandleFunc("GET /api/services", s.servicesHandler)
+	router.HandleFunc("GET /api/groups", s.groupsHandler)
+	router.HandleFunc("GET /api/attributes/keys", s.attributeKeysHandler)
+	router.HandleFunc("GET /api/attributes/values", s.attributeValuesHandler)
+	router.HandleFunc("GET /api/dependencies", s.dependenciesHandler)
+	router.HandleFunc("GET /api/operations/stats", s.operationStatsHandler)
+	router.HandleFunc("GET /api/stats/trace-sizes", s.traceSizesHandler)
+	router.HandleFunc("GET /api/stats/attribute-cardinality", s.attributeCardinalityHandler)
+	router.HandleFunc("GET /api/stats/volume", s.traceVolumeHandler)
+	router.HandleFunc("GET /api/status", s.statusHandler)
+	router.HandleFunc("GET /api/stream", outsideReadBatches(s.streamHandler))
+	router.HandleFunc("GET /api/ws", outsideReadBatches(s.websocketHandler))
+	router.HandleFunc("GET /api/metrics", s.metricsHandler)
+	router.HandleFunc("GET /api/sampleData", s.writes(s.sampleDataHandler))
+	router.HandleFunc("GET /api/sampleData/list", sampleSetsHandler)
+	router.HandleFunc("POST /api/batch", s.limitBody(s.readBatchHandler(router)))
+	router.HandleFunc("GET /api/clearData", s.writes(s.clearTracesHandler))
 	router.HandleFunc("GET /traces/{id}", indexHandler)
+	router.HandleFunc("POST /v1/traces", s.writes(s.limitBody(s.otlpTracesHandler)))
+	router.HandleFunc("POST /v1/logs", s.writes(s.limitBody(s.otlpLogsHandler)))
+	router.HandleFunc("POST /v1/metrics", s.writes(s.limitBody(s.otlpMetricsHandler)))
+	router.HandleFunc("POST "+zipkinSpansPath, s.writes(s.limitBody(s.zipkinSpansHandler)))
+	router.HandleFunc("POST /api/spans", s.writes(s.limitBody(s.simpleSpansHandler)))
 
 	if serveFromFS {
 		router.Handle("/", http.FileServer(http.Dir("./static/")))
@@ -78,69 +615,1406 @@ func (s *Server) Handler(serveFromFS bool) http.Handler {
 		}
 		router.Handle("/", http.FileServerFS(staticContent))
 	}
-	return router
+	handler := apiVersionHandler(router)
+	if s.instrumentation != nil {
+		handler = s.instrumentation.instrument(handler)
+	}
+	handler = logRequests(s.logger, handler)
+	if s.idleTimeout > 0 {
+		handler = s.trackActivity(handler)
+	}
+
+	// Probes and scrapes are answered ahead of the rest, so neither auth nor CORS gets in their way,
+	// and they aren't logged
+	probes := http.NewServeMux()
+	probes.HandleFunc("GET /healthz", healthzHandler)
+	probes.HandleFunc("GET /readyz", s.readyzHandler)
+	if s.instrumentation != nil {
+		probes.Handle("GET /metrics", s.instrumentation.handler())
+	}
+	probes.Handle("/", corsHandler(s.corsOrigins, authHandler(s.authToken, s.authUI, gzipHandler(handler))))
+	return probes
+}
+
+// browserURL is the link we open the browser with, which logs it in if auth is on
+func (s *Server) browserURL(base string) string {
+	if s.authToken == "" {
+		return base
+	}
+	return base + "?" + authQueryParam + "=" + url.QueryEscape(s.authToken)
 }
 
 func (s *Server) tracesHandler(writer http.ResponseWriter, request *http.Request) {
-	summaries, err := s.Store.GetTraceSummaries(request.Context())
+	query, err := parseSummaryQuery(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if s.notModified(writer, request) {
+		return
+	}
+
+	s.writeTraceSummaries(writer, request, query)
+}
+
+func (s *Server) searchHandler(writer http.ResponseWriter, request *http.Request) {
+	query, err := parseSummaryQuery(request)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	query.Search = request.URL.Query().Get("q")
+	if query.Search == "" {
+		http.Error(writer, "missing search query: q must not be empty", http.StatusBadRequest)
+		return
+	}
+	if value := request.URL.Query().Get("events"); value != "" {
+		if query.SearchEvents, err = strconv.ParseBool(value); err != nil {
+			http.Error(writer, fmt.Sprintf("invalid events %q: must be true or false", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	s.writeTraceSummaries(writer, request, query)
+}
+
+// traceByAttributeHandler responds with the summaries of the traces whose root span has the
+// attribute key set to value, such as a request ID users report issues with. There may be
+// more than one, as nothing makes such IDs unique.
+func (s *Server) traceByAttributeHandler(writer http.ResponseWriter, request *http.Request) {
+	key := request.URL.Query().Get("key")
+	if key == "" {
+		http.Error(writer, "missing attribute key: key must not be empty", http.StatusBadRequest)
+		return
+	}
+	if !request.URL.Query().Has("value") {
+		http.Error(writer, "missing attribute value: value must be given", http.StatusBadRequest)
+		return
+	}
+
+	summaries, err := s.Store.FindTracesByRootAttribute(request.Context(), key, request.URL.Query().Get("value"))
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.TraceSummaries{
+		TraceSummaries: *summaries,
+		TotalCount:     len(*summaries),
+	})
+}
+
+// writeTraceSummaries responds with the page of trace summaries matching the query
+func (s *Server) writeTraceSummaries(writer http.ResponseWriter, request *http.Request, query store.SummaryQuery) {
+	query.Limit = s.pageLimit(request, query.Limit)
+	summaries, totalCount, err := s.Store.QueryTraceSummaries(request.Context(), query)
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		log.Fatal(err)
+		writeServerError(writer, request, err)
+		return
+	}
+
+	// Only hand out a next offset when the client asked for a page and there is more to fetch
+	var nextOffset *int
+	if query.Limit > 0 && query.Offset+len(*summaries) < totalCount {
+		next := query.Offset + len(*summaries)
+		nextOffset = &next
 	}
 
 	writeJSON(writer, telemetry.TraceSummaries{
 		TraceSummaries: *summaries,
+		TotalCount:     totalCount,
+		NextOffset:     nextOffset,
 	})
 }
 
+// pageLimit is the number of summaries to return: the limit the client asked for, or the default
+// page size if it didn't, no more than the max page size either way. Zero returns every summary.
+func (s *Server) pageLimit(request *http.Request, limit int) int {
+	if request.URL.Query().Get("limit") == "" {
+		limit = s.defaultPageSize
+	}
+	if s.maxPageSize > 0 && (limit == 0 || limit > s.maxPageSize) {
+		limit = s.maxPageSize
+	}
+	return limit
+}
+
+func (s *Server) servicesHandler(writer http.ResponseWriter, request *http.Request) {
+	if request.URL.Query().Has("groupBy") {
+		s.serviceGroupsHandler(writer, request)
+		return
+	}
+
+	withCounts := false
+	if value := request.URL.Query().Get("withCounts"); value != "" {
+		var err error
+		if withCounts, err = strconv.ParseBool(value); err != nil {
+			http.Error(writer, fmt.Sprintf("invalid withCounts %q: must be true or false", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if withCounts {
+		counts, err := s.Store.GetServiceSpanCounts(request.Context())
+		if err != nil {
+			writeServerError(writer, request, err)
+			return
+		}
+		writeJSON(writer, counts)
+		return
+	}
+
+	services, err := s.Store.GetServiceNames(request.Context())
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, services)
+}
+
+// serviceGroupsHandler counts the spans with each distinct combination of the resource attributes
+// listed in groupBy, separated by commas
+func (s *Server) serviceGroupsHandler(writer http.ResponseWriter, request *http.Request) {
+	keys := []string{}
+	for _, key := range strings.Split(request.URL.Query().Get("groupBy"), ",") {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			http.Error(writer, "invalid groupBy: must be a comma-separated list of resource attributes, such as service.namespace,service.name", http.StatusBadRequest)
+			return
+		}
+		if slices.Contains(keys, key) {
+			http.Error(writer, fmt.Sprintf("invalid groupBy: %q is listed more than once", key), http.StatusBadRequest)
+			return
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) > maxGroupByKeys {
+		http.Error(writer, fmt.Sprintf("invalid groupBy: must list at most %d resource attributes", maxGroupByKeys), http.StatusBadRequest)
+		return
+	}
+
+	groups, err := s.Store.GetServiceGroups(request.Context(), keys)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.ServiceGroups{GroupBy: keys, Groups: groups})
+}
+
+// clearTracesHandler clears every trace, log, and metric. Given any service, before, or after
+// parameters, it only clears the traces from one of those services that started between after and
+// before (RFC 3339 or Unix milliseconds). Either way it responds with the number of traces cleared.
 func (s *Server) clearTracesHandler(writer http.ResponseWriter, request *http.Request) {
-	if err := s.Store.ClearTraces(request.Context()); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		log.Fatal(err)
+	query := store.ClearQuery{
+		Services: request.URL.Query()["service"],
 	}
-	writer.WriteHeader(http.StatusOK)
+
+	var err error
+	if query.Before, err = timeQueryParam(request, "before"); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if query.After, err = timeQueryParam(request, "after"); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !query.Before.IsZero() && !query.After.IsZero() && !query.After.Before(query.Before) {
+		http.Error(writer, "invalid time range: after must be before before", http.StatusBadRequest)
+		return
+	}
+
+	cleared := 0
+	if len(query.Services) == 0 && query.Before.IsZero() && query.After.IsZero() {
+		cleared, err = s.Store.ClearTraces(request.Context())
+	} else {
+		cleared, err = s.Store.ClearMatchingTraces(request.Context(), query)
+	}
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.ClearedTraces{ClearedTraces: cleared})
 }
 
 func (s *Server) sampleDataHandler(writer http.ResponseWriter, request *http.Request) {
-	sample := telemetry.NewSampleTelemetry()
-	if err := s.Store.AddSpans(request.Context(), sample.Spans); err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		log.Fatal(err)
+	set := request.URL.Query().Get("set")
+	if set == "" {
+		set = telemetry.DefaultSampleSet
+	}
+	spans, err := telemetry.LoadSampleSet(set)
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.RedactSpans(spans)
+	if err := s.Store.AddSpans(request.Context(), spans); err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	// Make sure the sample traces are there by the time the UI asks for them
+	if err := s.Store.Flush(request.Context()); err != nil {
+		writeServerError(writer, request, err)
+		return
 	}
 
 	//TODO: Add sample logs and metrics
 	writer.WriteHeader(http.StatusOK)
 }
 
+func sampleSetsHandler(writer http.ResponseWriter, request *http.Request) {
+	writeJSON(writer, telemetry.SampleSets())
+}
+
 func (s *Server) traceIDHandler(writer http.ResponseWriter, request *http.Request) {
-	traceID := request.PathValue("id")
-	traceData, err := s.Store.GetTrace(request.Context(), traceID)
+	// A traceparent header value, as logs print them, can be pasted in place of the trace ID
+	traceID, err := telemetry.ParseTraceID(request.PathValue("id"))
 	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-	} else {
-		writeJSON(writer, traceData)
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
 	}
-}
 
-func indexHandler(writer http.ResponseWriter, request *http.Request) {
-	if os.Getenv("SERVE_FROM_FS") == "true" {
-		http.ServeFile(writer, request, "./desktopexporter/internal/server/static/index.html")
-	} else {
-		indexBytes, err := assets.ReadFile("static/index.html")
+	resolveLinks := false
+	if value := request.URL.Query().Get("resolveLinks"); value != "" {
+		var err error
+		resolveLinks, err = strconv.ParseBool(value)
 		if err != nil {
-			writer.WriteHeader(http.StatusInternalServerError)
-			log.Fatalf("could not read static assets: %s", err.Error())
+			http.Error(writer, "resolveLinks must be true or false", http.StatusBadRequest)
+			return
 		}
-		writer.Write(indexBytes)
 	}
-}
 
-func writeJSON(writer http.ResponseWriter, data any) {
-	jsonData, err := json.Marshal(data)
+	// Huge traces can be fetched a page of spans at a time
+	paged := request.URL.Query().Has("spanLimit") || request.URL.Query().Has("spanOffset")
+	spanLimit, err := intQueryParam(request, "spanLimit")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	spanOffset, err := intQueryParam(request, "spanOffset")
 	if err != nil {
-		writer.WriteHeader(http.StatusInternalServerError)
-		log.Fatalf("could not marshal json: %s", err.Error())
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
 
+	// Only spans of these kinds are kept, along with their ancestors
+	kinds := []string{}
+	for _, value := range request.URL.Query()["kind"] {
+		kind, err := store.ParseSpanKind(value)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) > 0 && paged {
+		http.Error(writer, "kind can't be combined with spanLimit or spanOffset", http.StatusBadRequest)
+		return
+	}
+
+	// Pathological traces can be cut down to a sample of their spans that keeps their shape
+	maxSpans, err := intQueryParam(request, "maxSpans")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if maxSpans > 0 && paged {
+		http.Error(writer, "maxSpans can't be combined with spanLimit or spanOffset", http.StatusBadRequest)
+		return
+	}
+	if s.notModified(writer, request) {
+		return
+	}
+
+	var traceData telemetry.TraceData
+	if paged {
+		traceData, err = s.Store.GetTracePage(request.Context(), traceID, spanLimit, spanOffset)
+	} else if len(kinds) > 0 {
+		traceData, err = s.Store.GetTraceOfKinds(request.Context(), traceID, kinds)
+	} else {
+		traceData, err = s.Store.GetTrace(request.Context(), traceID)
+	}
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	// Pages and kind filters leave out children, which would make self times look longer
+	if !paged && len(kinds) == 0 {
+		telemetry.SetSelfDurations(traceData.Spans)
+	}
+
+	if spans, omitted := telemetry.SampleSpans(traceData.Spans, maxSpans); omitted > 0 {
+		traceData.Spans = spans
+		traceData.Sampled = true
+		traceData.OmittedSpans = omitted
+	}
+
+	if resolveLinks {
+		if err := s.Store.ResolveLinks(request.Context(), &traceData); err != nil {
+			writeServerError(writer, request, err)
+			return
+		}
+	}
+	writeJSON(writer, traceData)
+}
+
+func (s *Server) traceLogsHandler(writer http.ResponseWriter, request *http.Request) {
+	traceID := traceIDParam(request)
+	logs, err := s.Store.GetLogsByTrace(request.Context(), traceID)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	writeJSON(writer, telemetry.TraceLogs{
+		TraceID: traceID,
+		Logs:    logs,
+	})
+}
+
+func (s *Server) traceStatsHandler(writer http.ResponseWriter, request *http.Request) {
+	stats, err := s.Store.GetTraceStats(request.Context(), traceIDParam(request))
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, stats)
+}
+
+// traceTreeHandler responds with the spans of a trace nested under their parents, marking those
+// that couldn't be with why, and which trace a missing parent turned up in
+func (s *Server) traceTreeHandler(writer http.ResponseWriter, request *http.Request) {
+	traceData, err := s.Store.GetTrace(request.Context(), traceIDParam(request))
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	roots := telemetry.BuildSpanTree(traceData.Spans)
+	if err = s.Store.LocateMissingParents(request.Context(), traceData.TraceID, roots); err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.SpanTree{
+		TraceID: traceData.TraceID,
+		Roots:   roots,
+	})
+}
+
+// traceFlameGraphHandler responds with the spans of a trace merged into flamegraph frames by name
+func (s *Server) traceFlameGraphHandler(writer http.ResponseWriter, request *http.Request) {
+	traceData, err := s.Store.GetTrace(request.Context(), traceIDParam(request))
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	writeJSON(writer, telemetry.FlameGraph{
+		TraceID: traceData.TraceID,
+		Frames:  telemetry.BuildFlameGraph(traceData.Spans),
+	})
+}
+
+// traceGapsHandler responds with the stretches of a trace during which none of its spans ran,
+// longest first, leaving out those shorter than minDuration
+func (s *Server) traceGapsHandler(writer http.ResponseWriter, request *http.Request) {
+	minDuration, err := durationQueryParam(request, "minDuration")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	traceData, err := s.Store.GetTrace(request.Context(), traceIDParam(request))
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	gaps := telemetry.FindGaps(traceData.Spans)
+	gaps.TraceID = traceData.TraceID
+	gaps.Gaps = slices.DeleteFunc(gaps.Gaps, func(gap telemetry.TraceGap) bool {
+		return gap.DurationNanos < minDuration.Nanoseconds()
+	})
+	writeJSON(writer, gaps)
+}
+
+// traceCriticalPathHandler responds with the chain of spans of a trace that determined its duration
+func (s *Server) traceCriticalPathHandler(writer http.ResponseWriter, request *http.Request) {
+	traceData, err := s.Store.GetTrace(request.Context(), traceIDParam(request))
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	criticalPath := telemetry.FindCriticalPath(traceData.Spans)
+	criticalPath.TraceID = traceData.TraceID
+	writeJSON(writer, criticalPath)
+}
+
+// spanHandler responds with a single span of a trace, attributes, events, links and all
+func (s *Server) spanHandler(writer http.ResponseWriter, request *http.Request) {
+	span, err := s.Store.GetSpan(request.Context(), traceIDParam(request), telemetry.NormalizeSpanID(request.PathValue("spanID")))
+	if errors.Is(err, telemetry.ErrSpanIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, span)
+}
+
+// traceSearchHandler responds with the IDs of the spans of a trace matching the q parameter
+func (s *Server) traceSearchHandler(writer http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query().Get("q")
+	if query == "" {
+		http.Error(writer, "missing search query: q must not be empty", http.StatusBadRequest)
+		return
+	}
+
+	matches, err := s.Store.SearchTrace(request.Context(), traceIDParam(request), query)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, matches)
+}
+
+func (s *Server) annotationsHandler(writer http.ResponseWriter, request *http.Request) {
+	traceID := traceIDParam(request)
+	annotations, err := s.Store.GetAnnotations(request.Context(), traceID)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.TraceAnnotations{TraceID: traceID, Annotations: annotations})
+}
+
+// putAnnotationsHandler replaces the annotations of a trace with the JSON object in the request
+// body, of any keys and values. An empty object removes them.
+func (s *Server) putAnnotationsHandler(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := s.readRequestBody(writer, request)
+	if !ok {
+		return
+	}
+
+	annotations := telemetry.Annotations{}
+	if err := json.Unmarshal(payload, &annotations); err != nil {
+		http.Error(writer, fmt.Sprintf("annotations must be a JSON object: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+
+	if annotations == nil {
+		annotations = telemetry.Annotations{}
+	}
+
+	traceID := traceIDParam(request)
+	err := s.Store.SetAnnotations(request.Context(), traceID, annotations)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.TraceAnnotations{TraceID: traceID, Annotations: annotations})
+}
+
+// traceBatchHandler responds with the traces whose IDs are listed in the JSON array it is sent,
+// keyed by trace ID, leaving out those that weren't found
+func (s *Server) traceBatchHandler(writer http.ResponseWriter, request *http.Request) {
+	payload, ok := s.readRequestBody(writer, request)
+	if !ok {
+		return
+	}
+
+	ids := []string{}
+	if err := json.Unmarshal(payload, &ids); err != nil {
+		http.Error(writer, fmt.Sprintf("trace IDs must be a JSON array of strings: %s", err.Error()), http.StatusBadRequest)
+		return
+	}
+	if len(ids) > maxBatchTraceIDs {
+		http.Error(writer, fmt.Sprintf("too many trace IDs: at most %d can be fetched at once", maxBatchTraceIDs), http.StatusBadRequest)
+		return
+	}
+
+	traceIDs := []string{}
+	for _, id := range ids {
+		traceID, err := telemetry.ParseTraceID(id)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if !slices.Contains(traceIDs, traceID) {
+			traceIDs = append(traceIDs, traceID)
+		}
+	}
+
+	traces, err := s.Store.GetTraces(request.Context(), traceIDs)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	for _, trace := range traces {
+		telemetry.SetSelfDurations(trace.Spans)
+	}
+	writeJSON(writer, telemetry.TraceBatch{Traces: traces})
+}
+
+// pinHandler keeps a trace from being evicted by the retention policy or the span cap
+func (s *Server) pinHandler(writer http.ResponseWriter, request *http.Request) {
+	s.writePinned(writer, request, s.Store.PinTrace, true)
+}
+
+// unpinHandler lets a pinned trace be evicted again
+func (s *Server) unpinHandler(writer http.ResponseWriter, request *http.Request) {
+	s.writePinned(writer, request, s.Store.UnpinTrace, false)
+}
+
+// writePinned pins or unpins the trace of a request with setPinned, and responds with whether
+// it is now pinned
+func (s *Server) writePinned(writer http.ResponseWriter, request *http.Request, setPinned func(context.Context, string) error, pinned bool) {
+	traceID := traceIDParam(request)
+	err := setPinned(request.Context(), traceID)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.TracePin{TraceID: traceID, Pinned: pinned})
+}
+
+// compareTracesHandler responds with the spans of traces a and b aligned by name, and the
+// difference in duration of each operation between them
+func (s *Server) compareTracesHandler(writer http.ResponseWriter, request *http.Request) {
+	// Either can be a traceparent, like the trace ID of any other endpoint
+	traceIDs := make([]string, 2)
+	for i, name := range []string{"a", "b"} {
+		traceID, err := telemetry.ParseTraceID(request.URL.Query().Get(name))
+		if err != nil {
+			http.Error(writer, fmt.Sprintf("%s: %s", name, err.Error()), http.StatusBadRequest)
+			return
+		}
+		traceIDs[i] = traceID
+	}
+
+	traces := make([]telemetry.TraceData, len(traceIDs))
+	for i, traceID := range traceIDs {
+		traceData, err := s.Store.GetTrace(request.Context(), traceID)
+		if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+			http.Error(writer, fmt.Sprintf("trace %s not found", traceID), http.StatusNotFound)
+			return
+		} else if err != nil {
+			writeServerError(writer, request, err)
+			return
+		}
+		traces[i] = traceData
+	}
+
+	writeJSON(writer, telemetry.CompareTraces(traces[0], traces[1]))
+}
+
+// metricsHandler responds with a time series per metric name, optionally filtered by
+// name, service, and a start and end time (RFC 3339, e.g. 2024-01-01T12:00:00Z). Exemplars
+// are marked resolved when the trace they point to is in the store.
+func (s *Server) metricsHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.MetricQuery{
+		Name:    request.URL.Query().Get("name"),
+		Service: request.URL.Query().Get("service"),
+	}
+
+	var err error
+	if query.Start, query.End, err = timeRangeQueryParams(request); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := s.Store.QueryMetrics(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	if err := s.Store.ResolveExemplars(request.Context(), metrics); err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.NewMetricSeriesList(metrics))
+}
+
+// attributeKeysHandler responds with every span attribute key and the number of spans that have it,
+// optionally only counting spans from some services
+func (s *Server) attributeKeysHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.AttributeQuery{
+		Services: request.URL.Query()["service"],
+	}
+
+	keys, err := s.Store.GetAttributeKeys(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.AttributeKeyList{Keys: keys})
+}
+
+// attributeValuesHandler responds with the most common values of the span attribute named by key,
+// up to limit of them, optionally only counting spans from some services
+func (s *Server) attributeValuesHandler(writer http.ResponseWriter, request *http.Request) {
+	key := request.URL.Query().Get("key")
+	if key == "" {
+		http.Error(writer, "missing key: must name a span attribute", http.StatusBadRequest)
+		return
+	}
+	query := store.AttributeQuery{
+		Services: request.URL.Query()["service"],
+	}
+
+	limit, err := intQueryParam(request, "limit")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if limit == 0 {
+		limit = defaultAttributeValues
+	} else if limit > maxAttributeValues {
+		http.Error(writer, fmt.Sprintf("invalid limit %d: must be at most %d", limit, maxAttributeValues), http.StatusBadRequest)
+		return
+	}
+
+	values, err := s.Store.GetAttributeValues(request.Context(), key, query, limit)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, values)
+}
+
+// dependenciesHandler responds with the service graph: the calls between services, and the span
+// count, error rate, and p95 duration of each service. Both can be limited to spans that started
+// between a start and end time (RFC 3339, e.g. 2024-01-01T12:00:00Z).
+func (s *Server) dependenciesHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.DependencyQuery{}
+
+	var err error
+	if query.Start, query.End, err = timeRangeQueryParams(request); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	services, err := s.Store.GetServiceNodes(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	dependencies, err := s.Store.GetServiceDependencies(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.ServiceDependencies{Services: services, Dependencies: dependencies})
+}
+
+// operationStatsHandler responds with duration percentiles per span name across every trace,
+// optionally grouped by service, limited to some services, or limited to spans that started
+// between a start and end time (RFC 3339, e.g. 2024-01-01T12:00:00Z)
+func (s *Server) operationStatsHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.OperationQuery{
+		Services: request.URL.Query()["service"],
+	}
+
+	var err error
+	if query.Start, query.End, err = timeRangeQueryParams(request); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if value := request.URL.Query().Get("groupByService"); value != "" {
+		if query.GroupByService, err = strconv.ParseBool(value); err != nil {
+			http.Error(writer, fmt.Sprintf("invalid groupByService %q: must be true or false", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	operations, err := s.Store.GetOperationStats(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.OperationStatsList{Operations: operations})
+}
+
+// traceSizesHandler responds with a histogram of span counts per trace, optionally limited to
+// traces with a span from some services, or to traces that started between a start and end time
+// (RFC 3339, e.g. 2024-01-01T12:00:00Z)
+func (s *Server) traceSizesHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.TraceSizeQuery{
+		Services: request.URL.Query()["service"],
+	}
+
+	var err error
+	if query.Start, query.End, err = timeRangeQueryParams(request); err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	histogram, err := s.Store.GetTraceSizes(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, histogram)
+}
+
+// attributeCardinalityHandler responds with span attribute keys and roughly how many distinct values
+// each takes, the most values first unless order is asc, up to limit of them. It can be limited to
+// spans from some services.
+func (s *Server) attributeCardinalityHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.AttributeQuery{
+		Services: request.URL.Query()["service"],
+	}
+
+	limit, err := intQueryParam(request, "limit")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	ascending := false
+	switch order := request.URL.Query().Get("order"); order {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		http.Error(writer, fmt.Sprintf("invalid order %q: must be asc or desc", order), http.StatusBadRequest)
+		return
+	}
+
+	keys, err := s.Store.GetAttributeCardinality(request.Context(), query, ascending, limit)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, telemetry.AttributeCardinalityList{Keys: keys})
+}
+
+// groupsHandler lists the groups traces fall into, with the number of traces in each
+func (s *Server) groupsHandler(writer http.ResponseWriter, request *http.Request) {
+	if s.notModified(writer, request) {
+		return
+	}
+
+	groups, err := s.Store.GetGroups(request.Context())
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, groups)
+}
+
+// traceVolumeHandler counts traces over time, a bucket at a time, up to end or now
+func (s *Server) traceVolumeHandler(writer http.ResponseWriter, request *http.Request) {
+	query := store.TraceVolumeQuery{
+		Bucket:   defaultVolumeBucket,
+		Window:   defaultVolumeWindow,
+		End:      s.now(),
+		Services: request.URL.Query()["service"],
+	}
+
+	for _, param := range []struct {
+		key   string
+		value *time.Duration
+	}{{"bucket", &query.Bucket}, {"window", &query.Window}} {
+		d, err := durationQueryParam(request, param.key)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if d > 0 {
+			*param.value = d
+		}
+	}
+	if query.Bucket > query.Window {
+		http.Error(writer, fmt.Sprintf("invalid bucket %s: must not be longer than the window of %s", query.Bucket, query.Window), http.StatusBadRequest)
+		return
+	}
+	if buckets := (query.Window + query.Bucket - 1) / query.Bucket; buckets > maxVolumeBuckets {
+		http.Error(writer, fmt.Sprintf("too many buckets: a window of %s takes %d buckets of %s, and at most %d are counted at once", query.Window, buckets, query.Bucket, maxVolumeBuckets), http.StatusBadRequest)
+		return
+	}
+
+	end, err := timeQueryParam(request, "end")
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !end.IsZero() {
+		query.End = end
+	}
+
+	volume, err := s.Store.GetTraceVolume(request.Context(), query)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, volume)
+}
+
+// statusHandler sums up what the store holds, how it was opened, and how long we've been running
+func (s *Server) statusHandler(writer http.ResponseWriter, request *http.Request) {
+	storeStatus, err := s.Store.GetStatus(request.Context())
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	status := telemetry.Status{
+		StoreStatus: storeStatus,
+		StartedAt:   s.started.UTC(),
+		UptimeNanos: s.now().Sub(s.started).Nanoseconds(),
+	}
+	if s.forwarder != nil {
+		status.Forwarding = s.forwarder.status()
+	}
+	writeJSON(writer, status)
+}
+
+func (s *Server) deleteTraceHandler(writer http.ResponseWriter, request *http.Request) {
+	traceID := traceIDParam(request)
+	err := s.Store.DeleteTrace(request.Context(), traceID)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	} else {
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// exportTraceHandler downloads a trace as an OTLP JSON file that any OTLP-aware tool can read, or
+// with ?format=html as a standalone page that draws its waterfall without the viewer
+func (s *Server) exportTraceHandler(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	if format != "" && format != "otlp" && format != "html" {
+		http.Error(writer, fmt.Sprintf("invalid format %q: must be otlp or html", format), http.StatusBadRequest)
+		return
+	}
+	anonymizer, ok := s.exportAnonymizer(writer, request)
+	if !ok {
+		return
+	}
+
+	traceID := traceIDParam(request)
+	traceData, err := s.Store.GetTrace(request.Context(), traceID)
+	if errors.Is(err, telemetry.ErrTraceIDNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	} else if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	if anonymizer != nil {
+		anonymizer.AnonymizeSpans(traceData.Spans)
+	}
+
+	if format == "html" {
+		var page bytes.Buffer
+		if err := writeTraceHTML(&page, traceData); err != nil {
+			writeServerError(writer, request, err)
+			return
+		}
+		writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+		writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "trace-" + traceID + ".html"}))
+		writer.WriteHeader(http.StatusOK)
+		writer.Write(page.Bytes())
+		return
+	}
+
+	marshaler := ptrace.JSONMarshaler{}
+	exportBytes, err := marshaler.MarshalTraces(telemetry.NewTracesFromSpans(traceData.Spans))
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "trace-" + traceID + ".json"}))
+	writer.WriteHeader(http.StatusOK)
+	writer.Write(exportBytes)
+}
+
+// exportAnonymizer returns a new Anonymizer when an export asks for ?anonymize=true, and nil
+// otherwise. Each export gets its own, so its tokens can't be matched against another's.
+func (s *Server) exportAnonymizer(writer http.ResponseWriter, request *http.Request) (*telemetry.Anonymizer, bool) {
+	value := request.URL.Query().Get("anonymize")
+	if value == "" {
+		return nil, true
+	}
+	anonymize, err := strconv.ParseBool(value)
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("invalid anonymize %q: must be true or false", value), http.StatusBadRequest)
+		return nil, false
+	}
+	if !anonymize {
+		return nil, true
+	}
+	return telemetry.NewAnonymizer(s.anonymizeKeys), true
+}
+
+// exportDatabaseHandler downloads a snapshot of the whole database as a DuckDB file, for offline
+// analysis or to open with --db. The snapshot is written to a temporary file first, whether the
+// store is in memory or in a file, so nothing is read mid-write.
+func (s *Server) exportDatabaseHandler(writer http.ResponseWriter, request *http.Request) {
+	dir, err := os.MkdirTemp("", "otel-desktop-viewer-export-")
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not create a temporary directory: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "traces.db")
+	if err = s.Store.Snapshot(request.Context(), path); err != nil {
+		http.Error(writer, fmt.Sprintf("could not snapshot the database: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	snapshot, err := os.Open(path)
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not open the snapshot: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	defer snapshot.Close()
+	info, err := snapshot.Stat()
+	if err != nil {
+		http.Error(writer, fmt.Sprintf("could not open the snapshot: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/octet-stream")
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "traces.db"}))
+	writer.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	writer.WriteHeader(http.StatusOK)
+	io.Copy(writer, snapshot)
+}
+
+// exportTracesHandler streams every trace in the store as newline-delimited JSON, one TraceData
+// per line, for importTracesHandler to restore with ?format=ndjson
+func (s *Server) exportTracesHandler(writer http.ResponseWriter, request *http.Request) {
+	// One anonymizer for the whole stream, so a service gets the same token in every trace
+	anonymizer, ok := s.exportAnonymizer(writer, request)
+	if !ok {
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/x-ndjson")
+	writer.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": "traces.ndjson"}))
+	writer.WriteHeader(http.StatusOK)
+	flusher, _ := writer.(http.Flusher)
+	if flusher != nil {
+		flusher.Flush()
+	}
+
+	encoder := json.NewEncoder(writer)
+	exported := 0
+	err := s.Store.EachTrace(request.Context(), func(trace telemetry.TraceData) error {
+		if anonymizer != nil {
+			anonymizer.AnonymizeSpans(trace.Spans)
+		}
+		if err := encoder.Encode(trace); err != nil {
+			return err
+		}
+		exported++
+		if flusher != nil && exported%exportFlushTraces == 0 {
+			flusher.Flush()
+		}
+		return nil
+	})
+	if err == nil {
+		return
+	}
+
+	// The status has already been sent, so all we can do is cut the stream short. Aborting the
+	// connection, rather than ending the response, keeps a partial backup from looking complete.
+	// A client that went away has nothing to be told.
+	if request.Context().Err() == nil {
+		log.Printf("could not export traces: %s", err.Error())
+	}
+	panic(http.ErrAbortHandler)
+}
+
+// importTracesHandler loads the traces of an OTLP JSON file, such as one downloaded from exportTraceHandler.
+// With ?format=jaeger it loads Jaeger JSON instead, and with ?format=ndjson the traces of a backup
+// downloaded from exportTracesHandler. OTLP and Jaeger files are read whole, so they are held to
+// the body limit, while a backup is held to it a line at a time, since it can be as big as the store.
+func (s *Server) importTracesHandler(writer http.ResponseWriter, request *http.Request) {
+	format := request.URL.Query().Get("format")
+	switch format {
+	case "", "otlp", "jaeger":
+	case "ndjson":
+		s.importNDJSON(writer, request)
+		return
+	default:
+		http.Error(writer, fmt.Sprintf("invalid format %q: must be otlp, jaeger, or ndjson", format), http.StatusBadRequest)
+		return
+	}
+
+	payload, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, s.maxBodyBytes))
+	if err != nil {
+		writeBodyError(writer, err)
+		return
+	}
+
+	var spans []telemetry.SpanData
+	if format == "jaeger" {
+		spans, err = telemetry.ParseJaegerTraces(payload)
+	} else {
+		spans, err = parseOTLPJSON(payload)
+	}
+	if err != nil {
+		http.Error(writer, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.RedactSpans(spans)
+
+	imported, err := s.Store.ImportSpans(request.Context(), spans)
+	if err != nil {
+		writeServerError(writer, request, err)
+		return
+	}
+	writeJSON(writer, imported)
+}
+
+// parseOTLPJSON reads the spans of an OTLP JSON file, making sure it has some and that each can be stored
+func parseOTLPJSON(payload []byte) ([]telemetry.SpanData, error) {
+	unmarshaler := ptrace.JSONUnmarshaler{}
+	traces, err := unmarshaler.UnmarshalTraces(payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP JSON: %s", err.Error())
+	}
+
+	spans := telemetry.NewSpanPayload(traces).ExtractSpans()
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("invalid OTLP JSON: no spans found under resourceSpans")
+	}
+	for _, span := range spans {
+		if span.TraceID == "" || span.SpanID == "" {
+			return nil, fmt.Errorf("invalid OTLP JSON: span %q is missing a trace or span ID", span.Name)
+		}
+	}
+	return spans, nil
+}
+
+// importNDJSON imports one trace per line as it reads them, so a backup never has to fit in memory
+// all at once. Spans already in the store are skipped, so the same backup can be restored twice.
+// Should a line be invalid, or longer than the body limit, the traces before it stay imported.
+func (s *Server) importNDJSON(writer http.ResponseWriter, request *http.Request) {
+	imported := telemetry.ImportedTraces{
+		TraceIDs:       []string{},
+		ImportedSpans:  0,
+		DuplicateSpans: 0,
+	}
+
+	seen := map[string]bool{}
+	decoder := json.NewDecoder(&lineLimitReader{reader: request.Body, limit: s.maxBodyBytes})
+	for line := 1; ; line++ {
+		trace := telemetry.TraceData{}
+		var tooLarge *http.MaxBytesError
+		if err := decoder.Decode(&trace); err == io.EOF {
+			break
+		} else if errors.As(err, &tooLarge) {
+			http.Error(writer, fmt.Sprintf("line %d is larger than the limit of %d bytes", line, tooLarge.Limit), http.StatusRequestEntityTooLarge)
+			return
+		} else if err != nil {
+			http.Error(writer, fmt.Sprintf("invalid NDJSON on line %d: %s", line, err.Error()), http.StatusBadRequest)
+			return
+		}
+
+		for i := range trace.Spans {
+			span := &trace.Spans[i]
+			if span.TraceID == "" {
+				span.TraceID = trace.TraceID
+			}
+			if span.TraceID == "" || span.SpanID == "" {
+				http.Error(writer, fmt.Sprintf("invalid NDJSON on line %d: span %q is missing a trace or span ID", line, span.Name), http.StatusBadRequest)
+				return
+			}
+			if span.Resource == nil {
+				span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{}}
+			}
+			if span.Scope == nil {
+				span.Scope = &telemetry.ScopeData{Attributes: telemetry.Attributes{}}
+			}
+		}
+		s.RedactSpans(trace.Spans)
+
+		result, err := s.Store.ImportSpans(request.Context(), trace.Spans)
+		if err != nil {
+			writeServerError(writer, request, err)
+			return
+		}
+		for _, traceID := range result.TraceIDs {
+			if !seen[traceID] {
+				seen[traceID] = true
+				imported.TraceIDs = append(imported.TraceIDs, traceID)
+			}
+		}
+		imported.ImportedSpans += result.ImportedSpans
+		imported.DuplicateSpans += result.DuplicateSpans
+	}
+	writeJSON(writer, imported)
+}
+
+// lineLimitReader fails with a MaxBytesError once a line of what it reads runs past limit bytes, so
+// a stream of any length can be read as long as no one line is too long to hold in memory. Like a
+// MaxBytesReader, it keeps failing once it has, in case the error is read past.
+type lineLimitReader struct {
+	reader io.Reader
+	limit  int64
+	line   int64
+	err    error
+}
+
+func (r *lineLimitReader) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(p)
+	for i, b := range p[:n] {
+		if b == '\n' {
+			r.line = 0
+			continue
+		}
+		r.line++
+		if r.line > r.limit {
+			r.err = &http.MaxBytesError{Limit: r.limit}
+			return i, r.err
+		}
+	}
+	return n, err
+}
+
+// healthzHandler answers as long as the server is up
+func healthzHandler(writer http.ResponseWriter, request *http.Request) {
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Write([]byte("ok\n"))
+}
+
+// readyzHandler answers only while the store is open and responsive
+func (s *Server) readyzHandler(writer http.ResponseWriter, request *http.Request) {
+	ctx, cancel := context.WithTimeout(request.Context(), readyTimeout)
+	defer cancel()
+
+	if err := s.Store.Ping(ctx); err != nil {
+		http.Error(writer, fmt.Sprintf("not ready: %s", err.Error()), http.StatusServiceUnavailable)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	writer.Write([]byte("ok\n"))
+}
+
+func indexHandler(writer http.ResponseWriter, request *http.Request) {
+	if os.Getenv("SERVE_FROM_FS") == "true" {
+		http.ServeFile(writer, request, "./desktopexporter/internal/server/static/index.html")
+	} else {
+		indexBytes, err := assets.ReadFile("static/index.html")
+		if err != nil {
+			writeServerError(writer, request, fmt.Errorf("could not read static assets: %s", err.Error()))
+			return
+		}
+		writer.Write(indexBytes)
+	}
+}
+
+// parseSummaryQuery reads the pagination, sorting, and filter parameters of a trace summaries request
+func parseSummaryQuery(request *http.Request) (store.SummaryQuery, error) {
+	query := store.SummaryQuery{}
+	var err error
+
+	if query.Limit, err = intQueryParam(request, "limit"); err != nil {
+		return query, err
+	}
+	if query.Offset, err = intQueryParam(request, "offset"); err != nil {
+		return query, err
+	}
+
+	if sortBy := request.URL.Query().Get("sort"); sortBy != "" {
+		if query.SortBy, err = store.ParseSortKey(sortBy); err != nil {
+			return query, err
+		}
+	}
+
+	switch order := request.URL.Query().Get("order"); order {
+	case "", "desc":
+		query.Ascending = false
+	case "asc":
+		query.Ascending = true
+	default:
+		return query, fmt.Errorf("invalid order %q: must be asc or desc", order)
+	}
+
+	query.Services = request.URL.Query()["service"]
+
+	if query.MinDuration, err = durationQueryParam(request, "minDuration"); err != nil {
+		return query, err
+	}
+	if query.MaxDuration, err = durationQueryParam(request, "maxDuration"); err != nil {
+		return query, err
+	}
+	if query.MaxDuration > 0 && query.MinDuration > query.MaxDuration {
+		return query, fmt.Errorf("invalid duration range: minDuration %s is greater than maxDuration %s", query.MinDuration, query.MaxDuration)
+	}
+
+	if query.Start, query.End, err = timeRangeQueryParams(request); err != nil {
+		return query, err
+	}
+
+	if status := request.URL.Query().Get("status"); status != "" {
+		if query.Status, err = store.ParseStatusFilter(status); err != nil {
+			return query, err
+		}
+	}
+
+	query.EventNames = request.URL.Query()["event"]
+
+	query.Operation = request.URL.Query().Get("operation")
+	if match := request.URL.Query().Get("operationMatch"); match != "" {
+		if query.Operation == "" {
+			return query, fmt.Errorf("invalid operationMatch %q: requires operation", match)
+		}
+		if query.OperationMatch, err = store.ParseOperationMatch(match); err != nil {
+			return query, err
+		}
+	}
+
+	for _, attr := range request.URL.Query()["attr"] {
+		filter, err := store.ParseAttributeFilter(attr)
+		if err != nil {
+			return query, err
+		}
+		query.Attributes = append(query.Attributes, filter)
+	}
+
+	query.Groups = request.URL.Query()["group"]
+
+	return query, nil
+}
+
+// traceIDParam is the trace ID in the request path, normalized as spans store them, so a 64-bit
+// trace ID finds its trace. IDs that aren't valid are passed on as they are, for the store not to find.
+func traceIDParam(request *http.Request) string {
+	return telemetry.NormalizeTraceID(request.PathValue("id"))
+}
+
+// intQueryParam parses an optional non-negative integer query parameter, returning zero when it is absent
+func intQueryParam(request *http.Request, key string) (int, error) {
+	value := request.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative integer", key, value)
+	}
+	return n, nil
+}
+
+// durationQueryParam parses an optional non-negative Go duration query parameter (e.g. "250ms"), returning zero when it is absent
+func durationQueryParam(request *http.Request, key string) (time.Duration, error) {
+	value := request.URL.Query().Get(key)
+	if value == "" {
+		return 0, nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q: must be a non-negative duration such as 250ms or 2s", key, value)
+	}
+	return d, nil
+}
+
+// timeQueryParam parses an optional time query parameter, given either in RFC 3339 or in
+// milliseconds since the Unix epoch, returning the zero time when it is absent
+func timeQueryParam(request *http.Request, key string) (time.Time, error) {
+	value := request.URL.Query().Get(key)
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if millis, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(millis).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s %q: must be an RFC 3339 time such as 2024-01-01T12:00:00Z, or milliseconds since the Unix epoch", key, value)
+	}
+	return t, nil
+}
+
+// timeRangeQueryParams parses the optional start and end query parameters of a time range
+func timeRangeQueryParams(request *http.Request) (time.Time, time.Time, error) {
+	start, err := timeQueryParam(request, "start")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	end, err := timeQueryParam(request, "end")
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	if !start.IsZero() && !end.IsZero() && start.After(end) {
+		return time.Time{}, time.Time{}, errors.New("invalid time range: start must not be after end")
+	}
+	return start, end, nil
+}
+
+// writeServerError answers a request that failed on our side, such as a store query, with 500,
+// logging why. The process carries on serving everything else. A request whose client has gone
+// away, cancelling its queries, has no one to answer, so it is dropped without a word.
+func writeServerError(writer http.ResponseWriter, request *http.Request, err error) {
+	if request.Context().Err() != nil {
+		return
+	}
+	log.Printf("could not handle %s %s: %s", request.Method, request.URL.Path, err.Error())
+	http.Error(writer, err.Error(), http.StatusInternalServerError)
+}
+
+func writeJSON(writer http.ResponseWriter, data any) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("could not marshal json: %s", err.Error())
+		http.Error(writer, fmt.Sprintf("could not marshal json: %s", err.Error()), http.StatusInternalServerError)
+		return
+	}
+	if wantsCompactJSON(writer) {
+		if jsonData, err = compactJSON(jsonData); err != nil {
+			log.Printf("could not compact json: %s", err.Error())
+			http.Error(writer, fmt.Sprintf("could not compact json: %s", err.Error()), http.StatusInternalServerError)
+			return
+		}
 	}
 
 	writer.WriteHeader(http.StatusOK)
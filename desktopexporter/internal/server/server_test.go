@@ -1,18 +1,42 @@
 package server
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"time"
 
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
 	"testing"
 
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/pmetric/pmetricotlp"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/collector/pdata/ptrace/ptraceotlp"
+	"golang.org/x/net/websocket"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 func setupEmpty() (*httptest.Server, func()) {
@@ -55,6 +79,8 @@ func setupWithTrace(t *testing.T) (*httptest.Server, func(*testing.T)) {
 
 	err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{testSpanData})
 	assert.Nilf(t, err, "could not create  test span: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
 
 	testServer := httptest.NewServer(server.Handler(false))
 
@@ -115,12 +141,593 @@ func TestTracesHandler(t *testing.T) {
 		err = json.Unmarshal(b, &testSummaries)
 		assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
 
-		assert.Equal(t, "1234567890", testSummaries.TraceSummaries[0].TraceID)
+		// Short IDs are stored zero-padded to full length
+		assert.Equal(t, "00000000000000000000001234567890", testSummaries.TraceSummaries[0].TraceID)
 		assert.Equal(t, true, testSummaries.TraceSummaries[0].HasRootSpan)
 		assert.Equal(t, "test", testSummaries.TraceSummaries[0].RootName)
 		assert.Equal(t, "pumpkin.pie", testSummaries.TraceSummaries[0].RootServiceName)
 		assert.Equal(t, uint32(1), testSummaries.TraceSummaries[0].SpanCount)
+
+		summary := testSummaries.TraceSummaries[0]
+		assert.Equal(t, summary.RootEndTime.Sub(summary.RootStartTime).Nanoseconds(), summary.RootDurationNanos)
+	})
+}
+
+func TestTracesHandlerPagination(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// The sample data contains two traces
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	getSummaries := func(t *testing.T, query string) (int, telemetry.TraceSummaries) {
+		res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", query))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+
+		testSummaries := telemetry.TraceSummaries{}
+		if res.StatusCode == http.StatusOK {
+			err = json.Unmarshal(b, &testSummaries)
+			assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+		}
+		return res.StatusCode, testSummaries
+	}
+
+	t.Run("Traces Handler (No Params)", func(t *testing.T) {
+		status, testSummaries := getSummaries(t, "")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, testSummaries.TraceSummaries, 2)
+		assert.Equal(t, 2, testSummaries.TotalCount)
+		assert.Nil(t, testSummaries.NextOffset)
+	})
+
+	t.Run("Traces Handler (First Page)", func(t *testing.T) {
+		status, testSummaries := getSummaries(t, "?limit=1")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, testSummaries.TraceSummaries, 1)
+		assert.Equal(t, 2, testSummaries.TotalCount)
+		if assert.NotNil(t, testSummaries.NextOffset) {
+			assert.Equal(t, 1, *testSummaries.NextOffset)
+		}
+	})
+
+	t.Run("Traces Handler (Last Page)", func(t *testing.T) {
+		status, testSummaries := getSummaries(t, "?limit=1&offset=1")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, testSummaries.TraceSummaries, 1)
+		assert.Nil(t, testSummaries.NextOffset)
+	})
+
+	t.Run("Traces Handler (Offset Out Of Range)", func(t *testing.T) {
+		status, testSummaries := getSummaries(t, "?limit=10&offset=10")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, testSummaries.TraceSummaries, 0)
+		assert.Equal(t, 2, testSummaries.TotalCount)
+		assert.Nil(t, testSummaries.NextOffset)
+	})
+
+	t.Run("Traces Handler (Invalid Limit)", func(t *testing.T) {
+		status, _ := getSummaries(t, "?limit=lots")
+		assert.Equal(t, http.StatusBadRequest, status)
+	})
+}
+
+func TestTracesHandlerPageSizes(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithPageSizes(2, 3))
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	spans := []telemetry.SpanData{}
+	for i := 1; i <= 5; i++ {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = fmt.Sprintf("%032x", i)
+		span.ParentSpanID = ""
+		spans = append(spans, span)
+	}
+	err := server.Store.AddSpans(context.Background(), spans)
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name               string
+		path               string
+		expectedCount      int
+		expectedNextOffset int
+	}{
+		{name: "Default", path: "/api/traces", expectedCount: 2, expectedNextOffset: 2},
+		{name: "Empty Limit", path: "/api/traces?limit=", expectedCount: 2, expectedNextOffset: 2},
+		{name: "Under The Max", path: "/api/traces?limit=1", expectedCount: 1, expectedNextOffset: 1},
+		{name: "Clamped", path: "/api/traces?limit=1000000", expectedCount: 3, expectedNextOffset: 3},
+		{name: "Every Trace Clamped", path: "/api/traces?limit=0", expectedCount: 3, expectedNextOffset: 3},
+		{name: "Search", path: "/api/search?q=sample&limit=1000000", expectedCount: 3, expectedNextOffset: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", tt.name), func(t *testing.T) {
+			res, err := http.Get(testServer.URL + tt.path)
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+			assert.Len(t, testSummaries.TraceSummaries, tt.expectedCount)
+			assert.Equal(t, 5, testSummaries.TotalCount)
+			if assert.NotNil(t, testSummaries.NextOffset) {
+				assert.Equal(t, tt.expectedNextOffset, *testSummaries.NextOffset)
+			}
+		})
+	}
+}
+
+func TestTracesHandlerSort(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// The sample currency trace is older, shorter, and has fewer spans than the HTTP POST trace
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	sortTests := []struct {
+		query          string
+		expectedStatus int
+		expectedFirst  string
+	}{
+		{"?sort=start", http.StatusOK, "42957c7c2fca940a0d32a0cdd38c06a4"},
+		{"?sort=start&order=asc", http.StatusOK, "7979cec4d1c04222fa9a3c7c97c0a99c"},
+		{"?sort=duration&order=desc", http.StatusOK, "42957c7c2fca940a0d32a0cdd38c06a4"},
+		{"?sort=duration&order=asc", http.StatusOK, "7979cec4d1c04222fa9a3c7c97c0a99c"},
+		{"?sort=spanCount&order=asc", http.StatusOK, "7979cec4d1c04222fa9a3c7c97c0a99c"},
+		{"?sort=spanCount&order=asc&limit=1&offset=1", http.StatusOK, "42957c7c2fca940a0d32a0cdd38c06a4"},
+		{"?sort=name", http.StatusBadRequest, ""},
+		{"?sort=start&order=sideways", http.StatusBadRequest, ""},
+	}
+
+	for _, test := range sortTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.Unmarshal(b, &testSummaries)
+			assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+
+			if assert.NotEmpty(t, testSummaries.TraceSummaries) {
+				assert.Equal(t, test.expectedFirst, testSummaries.TraceSummaries[0].TraceID)
+			}
+		})
+	}
+}
+
+func TestTracesHandlerServiceFilter(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	filterTests := []struct {
+		query         string
+		expectedCount int
+	}{
+		{"?service=sample-loadgenerator", 1},
+		{"?service=sample.currencyservice", 1},
+		{"?service=sample-loadgenerator&service=sample.currencyservice", 2},
+		// The frontend only produces child spans, so it never matches as a root service
+		{"?service=sample-frontend", 0},
+		{"?service=sample-loadgenerator&service=sample.currencyservice&limit=1", 2},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.Unmarshal(b, &testSummaries)
+			assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+
+			assert.Equal(t, test.expectedCount, testSummaries.TotalCount)
+			for _, summary := range testSummaries.TraceSummaries {
+				assert.Contains(t, test.query, summary.RootServiceName)
+			}
+		})
+	}
+}
+
+func TestTracesHandlerAttributeFilter(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	filterTests := []struct {
+		path           string
+		expectedStatus int
+		expectedCount  int
+	}{
+		{"/api/traces?attr=rpc.system", http.StatusOK, 1},
+		{"/api/traces?attr=rpc.system=grpc", http.StatusOK, 1},
+		{"/api/traces?attr=http.status_code=200", http.StatusOK, 1},
+		{"/api/traces?attr=http.method=GET", http.StatusOK, 0},
+		{"/api/traces?attr=rpc.system&attr=http.method=POST", http.StatusOK, 0},
+		{"/api/traces?attr=rpc.system&attr=currency.conversion.to=CAD", http.StatusOK, 1},
+		{"/api/search?q=SAMPLE&attr=http.method=POST", http.StatusOK, 1},
+		{"/api/traces?attr=", http.StatusBadRequest, 0},
+		{"/api/traces?attr==grpc", http.StatusBadRequest, 0},
+		{"/api/traces?attr=$.%22rpc.system%22=grpc", http.StatusOK, 1},
+		{"/api/traces?attr=$.%22rpc.system", http.StatusBadRequest, 0},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.path), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, test.path))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+			assert.Equal(t, test.expectedCount, testSummaries.TotalCount)
+		})
+	}
+}
+
+func TestTraceByAttributeHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name             string
+		query            string
+		expectedStatus   int
+		expectedTraceIDs []string
+	}{
+		{name: "Root Attribute", query: "?key=http.method&value=POST", expectedStatus: http.StatusOK, expectedTraceIDs: []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{name: "Number", query: "?key=http.status_code&value=200", expectedStatus: http.StatusOK, expectedTraceIDs: []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{name: "Single Span Trace", query: "?key=rpc.system&value=grpc", expectedStatus: http.StatusOK, expectedTraceIDs: []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{name: "Child Attribute", query: "?key=http.host&value=frontend:8080", expectedStatus: http.StatusOK, expectedTraceIDs: []string{}},
+		{name: "Missing Key", query: "?value=POST", expectedStatus: http.StatusBadRequest},
+		{name: "Missing Value", query: "?key=http.method", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Trace By Attribute Handler (%s)", tt.name), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/by-attr", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			summaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&summaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+			traceIDs := []string{}
+			for _, summary := range summaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, tt.expectedTraceIDs, traceIDs)
+			assert.Equal(t, len(tt.expectedTraceIDs), summaries.TotalCount)
+		})
+	}
+}
+
+func TestTracesHandlerStatusFilter(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	// Alongside the sample traces, none of which failed, add a two second trace with a failed span
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	root := telemetry.NewSampleTelemetry().Spans[0]
+	root.TraceID = "00000000000000000000000000000001"
+	root.SpanID = "0000000000000001"
+	root.ParentSpanID = ""
+	root.StartTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	root.EndTime = root.StartTime.Add(2 * time.Second)
+	root.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": "pumpkin.pie"}}
+	failed := root
+	failed.SpanID = "0000000000000002"
+	failed.ParentSpanID = root.SpanID
+	failed.StatusCode = "Error"
+
+	err = server.Store.AddSpans(context.Background(), []telemetry.SpanData{root, failed})
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	filterTests := []struct {
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{"?status=error", http.StatusOK, []string{root.TraceID}},
+		{"?status=ok", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?status=error&service=pumpkin.pie&minDuration=1s", http.StatusOK, []string{root.TraceID}},
+		{"?status=error&service=sample-loadgenerator", http.StatusOK, []string{}},
+		{"?status=ok&minDuration=1ms", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?status=failed", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, test.expectedIDs, traceIDs)
+			assert.Equal(t, len(test.expectedIDs), testSummaries.TotalCount)
+		})
+	}
+}
+
+func TestGroupsHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithGroupAttribute("process.runtime.name"))
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	// Only the sample frontend, which the load generator's trace passes through, runs on node
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	t.Run("Groups", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/groups"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		groups := telemetry.TraceGroups{}
+		err = json.NewDecoder(res.Body).Decode(&groups)
+		assert.Nilf(t, err, "could not decode trace groups: %v", err)
+		assert.Equal(t, telemetry.TraceGroups{
+			Attribute: "process.runtime.name",
+			Groups: []telemetry.TraceGroup{
+				{Name: "nodejs", TraceCount: 1},
+				{Name: "default", TraceCount: 1},
+			},
+		}, groups)
 	})
+
+	filterTests := []struct {
+		query       string
+		expectedIDs []string
+	}{
+		{"?group=nodejs", []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?group=default", []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?group=nodejs&group=default", []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?group=python", []string{}},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, test.expectedIDs, traceIDs)
+			assert.Equal(t, len(test.expectedIDs), testSummaries.TotalCount)
+		})
+	}
+}
+
+func TestTracesHandlerOperationFilter(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	filterTests := []struct {
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{"?operation=SAMPLE+HTTP+POST", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?operation=sample.CurrencyService/&operationMatch=prefix", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?operation=*/Convert&operationMatch=glob", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?operation=SAMPLE*&operationMatch=glob&service=sample-loadgenerator", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?operation=SAMPLE*&operationMatch=glob&service=sample.currencyservice", http.StatusOK, []string{}},
+		{"?operation=SAMPLE&operationMatch=exact", http.StatusOK, []string{}},
+		{"?operation=SAMPLE&operationMatch=regex", http.StatusBadRequest, nil},
+		{"?operationMatch=prefix", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.ElementsMatch(t, test.expectedIDs, traceIDs)
+			assert.Equal(t, len(test.expectedIDs), testSummaries.TotalCount)
+		})
+	}
+}
+
+func TestTracesHandlerDurationFilter(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// The sample currency trace takes ~26µs and the HTTP POST trace takes ~14ms
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	filterTests := []struct {
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		{"?minDuration=1ms", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?maxDuration=1ms", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?minDuration=10us&maxDuration=1s", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"?minDuration=1s", http.StatusOK, []string{}},
+		{"?minDuration=1ms&service=sample-loadgenerator", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{"?minDuration=1ms&service=sample.currencyservice", http.StatusOK, []string{}},
+		{"?minDuration=slow", http.StatusBadRequest, nil},
+		{"?minDuration=2s&maxDuration=1s", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.Unmarshal(b, &testSummaries)
+			assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, test.expectedIDs, traceIDs)
+			assert.Equal(t, len(test.expectedIDs), testSummaries.TotalCount)
+		})
+	}
+}
+
+func TestTracesHandlerTimeRange(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// The sample currency trace starts on 2023-02-01 and the HTTP POST trace on 2023-02-02
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	filterTests := []struct {
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+		expectedTotal  int
+	}{
+		{"?start=2023-02-02T00:00:00Z", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}, 1},
+		{"?end=2023-02-02T00:00:00Z", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}, 1},
+		{"?start=1675209600000&end=1675382400000", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c"}, 2},
+		{"?start=1675209600000&limit=1", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}, 2},
+		{"?start=2023-02-02T00:00:00Z&service=sample.currencyservice", http.StatusOK, []string{}, 0},
+		{"?end=2023-02-02T00:00:00Z&maxDuration=1ms&status=ok", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}, 1},
+		{"?start=2024-01-01T00:00:00Z", http.StatusOK, []string{}, 0},
+		{"?start=yesterday", http.StatusBadRequest, nil, 0},
+		{"?start=2023-02-03T00:00:00Z&end=2023-02-01T00:00:00Z", http.StatusBadRequest, nil, 0},
+	}
+
+	for _, test := range filterTests {
+		t.Run(fmt.Sprintf("Traces Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, test.expectedIDs, traceIDs)
+			assert.Equal(t, test.expectedTotal, testSummaries.TotalCount)
+		})
+	}
 }
 
 func TestTraceIDHandler(t *testing.T) {
@@ -132,7 +739,7 @@ func TestTraceIDHandler(t *testing.T) {
 		assert.Nilf(t, err, "could not send GET request: %v", err)
 		defer res.Body.Close()
 
-		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
 	})
 
 	t.Run("Traces ID Handler (ID Found)", func(t *testing.T) {
@@ -149,71 +756,4310 @@ func TestTraceIDHandler(t *testing.T) {
 		err = json.Unmarshal(b, &testTrace)
 		assert.Nilf(t, err, "could not unmarshal bytes to trace data: %v", err)
 
-		assert.Equal(t, "1234567890", testTrace.TraceID)
-		assert.Equal(t, "12345", testTrace.Spans[0].SpanID)
+		assert.Equal(t, "00000000000000000000001234567890", testTrace.TraceID)
+		assert.Equal(t, "0000000000012345", testTrace.Spans[0].SpanID)
 		assert.Equal(t, "test", testTrace.Spans[0].Name)
 		assert.Equal(t, "pumpkin.pie", testTrace.Spans[0].Resource.Attributes["service.name"])
+		assert.Equal(t, testTrace.Spans[0].EndTime.Sub(testTrace.Spans[0].StartTime).Nanoseconds(), testTrace.Spans[0].DurationNanos)
+		if assert.NotNil(t, testTrace.Spans[0].SelfDurationNanos) {
+			assert.Equal(t, testTrace.Spans[0].DurationNanos, *testTrace.Spans[0].SelfDurationNanos)
+		}
 		assert.Equal(t, 1, len(testTrace.Spans))
 	})
 }
 
-func TestClearTracesHandler(t *testing.T) {
-	testServer, teardown := setupWithTrace(t)
-	defer teardown(t)
+func TestTraceBatchHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
 
-	// Clear dat data
-	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/clearData"))
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
 	assert.Nilf(t, err, "could not send GET request: %v", err)
 	defer res.Body.Close()
 
-	assert.Equal(t, http.StatusOK, res.StatusCode)
+	postBatch := func(t *testing.T, body string) *http.Response {
+		res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/batch"), "application/json", strings.NewReader(body))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		return res
+	}
 
-	// Get trace summaries
-	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
-	assert.Nilf(t, err, "could not send GET request: %v", err)
+	t.Run("Trace Batch Handler (Found And Not Found)", func(t *testing.T) {
+		// A traceparent can stand in for a trace ID, and repeats are only fetched once
+		res := postBatch(t, `["42957c7c2fca940a0d32a0cdd38c06a4", "00-7979cec4d1c04222fa9a3c7c97c0a99c-0123456789abcdef-01", "42957c7c2fca940a0d32a0cdd38c06a4", "00000000000000000000000000000001"]`)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
 
-	assert.Equal(t, http.StatusOK, res.StatusCode)
+		batch := telemetry.TraceBatch{}
+		err := json.NewDecoder(res.Body).Decode(&batch)
+		assert.Nilf(t, err, "could not decode trace batch: %v", err)
+		if assert.Len(t, batch.Traces, 2) {
+			trace := batch.Traces["42957c7c2fca940a0d32a0cdd38c06a4"]
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", trace.TraceID)
+			if assert.Len(t, trace.Spans, 3) {
+				assert.NotNil(t, trace.Spans[0].SelfDurationNanos)
+			}
+			assert.Len(t, batch.Traces["7979cec4d1c04222fa9a3c7c97c0a99c"].Spans, 1)
+		}
+	})
 
-	b, err := io.ReadAll(res.Body)
-	assert.Nilf(t, err, "could not read response body: %v", err)
+	t.Run("Trace Batch Handler (Empty)", func(t *testing.T) {
+		res := postBatch(t, `[]`)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
 
-	testSummaries := telemetry.TraceSummaries{}
-	err = json.Unmarshal(b, &testSummaries)
-	assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.JSONEq(t, `{"traces": {}}`, string(b))
+	})
 
-	// Check that there are no traces in store
-	assert.Len(t, testSummaries.TraceSummaries, 0)
+	t.Run("Trace Batch Handler (Invalid)", func(t *testing.T) {
+		tooMany := make([]string, maxBatchTraceIDs+1)
+		for i := range tooMany {
+			tooMany[i] = fmt.Sprintf("%032x", i+1)
+		}
+		tooManyJSON, err := json.Marshal(tooMany)
+		assert.Nilf(t, err, "could not marshal trace IDs: %v", err)
+
+		for _, body := range []string{`{"traceIDs": []}`, `[42]`, `["not a trace ID"]`, string(tooManyJSON)} {
+			res := postBatch(t, body)
+			res.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		}
+	})
 }
 
-func TestSampleHandler(t *testing.T) {
+func TestReadBatchHandler(t *testing.T) {
 	testServer, teardown := setupEmpty()
 	defer teardown()
 
-	// Populate sample data
 	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
 	assert.Nilf(t, err, "could not send GET request: %v", err)
 	defer res.Body.Close()
 
-	assert.Equal(t, http.StatusOK, res.StatusCode)
+	postBatch := func(t *testing.T, body string) *http.Response {
+		res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/api/batch"), "application/json", strings.NewReader(body))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		return res
+	}
 
-	t.Run("Sample Data Handler (Traces)", func(t *testing.T) {
-		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"))
-		assert.Nilf(t, err, "could not send GET request: %v", err)
+	t.Run("Read Batch Handler (Reads)", func(t *testing.T) {
+		res := postBatch(t, `["/api/traces?limit=1", "/api/status", "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4", "/api/traces/00000000000000000000000000000001"]`)
 		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		batch := struct {
+			Responses []struct {
+				Path   string          `json:"path"`
+				Status int             `json:"status"`
+				Body   json.RawMessage `json:"body"`
+			} `json:"responses"`
+		}{}
+		err := json.NewDecoder(res.Body).Decode(&batch)
+		assert.Nilf(t, err, "could not decode read batch: %v", err)
+		if !assert.Len(t, batch.Responses, 4) {
+			return
+		}
+
+		assert.Equal(t, "/api/traces?limit=1", batch.Responses[0].Path)
+		assert.Equal(t, http.StatusOK, batch.Responses[0].Status)
+		summaries := telemetry.TraceSummaries{}
+		err = json.Unmarshal(batch.Responses[0].Body, &summaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		assert.Len(t, summaries.TraceSummaries, 1)
+		assert.Equal(t, 2, summaries.TotalCount)
+
+		status := telemetry.Status{}
+		err = json.Unmarshal(batch.Responses[1].Body, &status)
+		assert.Nilf(t, err, "could not decode status: %v", err)
+		assert.Equal(t, summaries.TotalCount, status.TraceCount)
+
+		trace := telemetry.TraceData{}
+		err = json.Unmarshal(batch.Responses[2].Body, &trace)
+		assert.Nilf(t, err, "could not decode trace: %v", err)
+		assert.Len(t, trace.Spans, 3)
+
+		assert.Equal(t, http.StatusNotFound, batch.Responses[3].Status)
+	})
 
+	t.Run("Read Batch Handler (Writes Refused)", func(t *testing.T) {
+		res := postBatch(t, `["/api/clearData", "/api/stream", "/api/export/db"]`)
+		defer res.Body.Close()
 		assert.Equal(t, http.StatusOK, res.StatusCode)
 
 		b, err := io.ReadAll(res.Body)
 		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.JSONEq(t, `{"responses": [
+			{"path": "/api/clearData", "status": 400, "body": "/api/clearData can't be read in a batch"},
+			{"path": "/api/stream", "status": 400, "body": "/api/stream can't be read in a batch"},
+			{"path": "/api/export/db", "status": 400, "body": "/api/export/db can't be read in a batch"}
+		]}`, string(b))
 
-		testTrace := telemetry.TraceData{}
-		err = json.Unmarshal(b, &testTrace)
-		assert.Nilf(t, err, "could not unmarshal bytes to trace data: %v", err)
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		summaries := telemetry.TraceSummaries{}
+		err = json.NewDecoder(res.Body).Decode(&summaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		assert.Equal(t, 2, summaries.TotalCount)
+	})
 
-		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", testTrace.TraceID)
-		assert.Equal(t, "37fd1349bf83d330", testTrace.Spans[0].SpanID)
-		assert.Equal(t, "SAMPLE HTTP POST", testTrace.Spans[0].Name)
-		assert.Equal(t, "sample-loadgenerator", testTrace.Spans[0].Resource.Attributes["service.name"])
-		assert.Equal(t, 3, len(testTrace.Spans))
+	t.Run("Read Batch Handler (Invalid)", func(t *testing.T) {
+		tooMany := make([]string, maxReadBatchRequests+1)
+		for i := range tooMany {
+			tooMany[i] = "/api/status"
+		}
+		tooManyJSON, err := json.Marshal(tooMany)
+		assert.Nilf(t, err, "could not marshal paths: %v", err)
+
+		for _, body := range []string{`{"paths": []}`, `["/healthz"]`, `["http://example.com/api/status"]`, string(tooManyJSON)} {
+			res := postBatch(t, body)
+			res.Body.Close()
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, body)
+		}
 	})
 }
+
+func TestTimestampSerialization(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	zone := time.FixedZone("UTC-5", -5*60*60)
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.StartTime = time.Date(2024, 1, 1, 7, 0, 0, 123456789, zone)
+	span.EndTime = time.Date(2024, 1, 1, 7, 0, 0, 900000001, zone)
+	err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{span})
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	// Every endpoint writes times in RFC 3339 with nanoseconds, in UTC
+	for _, path := range []string{"/api/traces", "/api/traces/" + span.TraceID} {
+		res, err := http.Get(testServer.URL + path)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Contains(t, string(b), `"2024-01-01T12:00:00.123456789Z"`, path)
+		assert.Contains(t, string(b), `"2024-01-01T12:00:00.900000001Z"`, path)
+	}
+}
+
+func TestSearchHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	searchTests := []struct {
+		query          string
+		expectedStatus int
+		expectedIDs    []string
+	}{
+		// Span name
+		{"?q=sample%20http", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		// Attribute value, matched case-insensitively
+		{"?q=cad", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		// Every span in the HTTP POST trace matches, but the trace is listed once
+		{"?q=frontend:8080", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		// Matches both traces
+		{"?q=sample", http.StatusOK, []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		// Attribute keys are not searched
+		{"?q=conversion.from", http.StatusOK, []string{}},
+		// LIKE wildcards are matched literally
+		{"?q=%25", http.StatusOK, []string{}},
+		{"?q=sample&service=sample.currencyservice", http.StatusOK, []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}},
+		{"", http.StatusBadRequest, nil},
+	}
+
+	for _, test := range searchTests {
+		t.Run(fmt.Sprintf("Search Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/search", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.Unmarshal(b, &testSummaries)
+			assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+
+			traceIDs := []string{}
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.Equal(t, test.expectedIDs, traceIDs)
+		})
+	}
+}
+
+func TestEventSearchHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// Only the currency span has events
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedIDs     []string
+		expectedMatches []int
+	}{
+		{name: "Events Not Searched", path: "/api/search?q=successful", expectedStatus: http.StatusOK, expectedIDs: []string{}},
+		{name: "Event Name Searched", path: "/api/search?q=successful&events=true", expectedStatus: http.StatusOK, expectedIDs: []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}, expectedMatches: []int{1}},
+		{name: "Trace Matched By Span", path: "/api/search?q=sample%20http&events=true", expectedStatus: http.StatusOK, expectedIDs: []string{"42957c7c2fca940a0d32a0cdd38c06a4"}},
+		{name: "Event Filter", path: "/api/traces?event=Processing%20currency%20conversion%20request", expectedStatus: http.StatusOK, expectedIDs: []string{"7979cec4d1c04222fa9a3c7c97c0a99c"}, expectedMatches: []int{0}},
+		{name: "Event Filter On Search", path: "/api/search?q=sample&event=exception", expectedStatus: http.StatusOK, expectedIDs: []string{}},
+		{name: "Invalid Events", path: "/api/search?q=sample&events=maybe", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, tt.path))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			testSummaries := telemetry.TraceSummaries{}
+			err = json.NewDecoder(res.Body).Decode(&testSummaries)
+			assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+
+			traceIDs := []string{}
+			var matches []int
+			for _, summary := range testSummaries.TraceSummaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+				for _, match := range summary.MatchedEvents {
+					assert.Equal(t, "sample.CurrencyService/Convert", match.SpanName)
+					matches = append(matches, match.EventIndex)
+				}
+			}
+			assert.Equal(t, tt.expectedIDs, traceIDs)
+			assert.Equal(t, tt.expectedMatches, matches)
+		})
+	}
+}
+
+func TestServicesHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	t.Run("Services Handler (Empty)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/services"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.JSONEq(t, "[]", string(b))
+	})
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	t.Run("Services Handler (Names)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/services"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+
+		services := []string{}
+		err = json.Unmarshal(b, &services)
+		assert.Nilf(t, err, "could not unmarshal bytes to service names: %v", err)
+
+		// sample-frontend never produces a root span but should still be listed
+		assert.Equal(t, []string{"sample-frontend", "sample-loadgenerator", "sample.currencyservice"}, services)
+	})
+
+	t.Run("Services Handler (With Counts)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/services?withCounts=true"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+
+		counts := []telemetry.ServiceSpanCount{}
+		err = json.Unmarshal(b, &counts)
+		assert.Nilf(t, err, "could not unmarshal bytes to service span counts: %v", err)
+
+		assert.Equal(t, []telemetry.ServiceSpanCount{
+			{ServiceName: "sample-frontend", SpanCount: 1},
+			{ServiceName: "sample-loadgenerator", SpanCount: 2},
+			{ServiceName: "sample.currencyservice", SpanCount: 1},
+		}, counts)
+	})
+
+	t.Run("Services Handler (Group By)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/services?groupBy=service.name,%20telemetry.sdk.language"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		groups := telemetry.ServiceGroups{}
+		err = json.NewDecoder(res.Body).Decode(&groups)
+		assert.Nilf(t, err, "could not decode service groups: %v", err)
+		assert.Equal(t, telemetry.ServiceGroups{
+			GroupBy: []string{"service.name", "telemetry.sdk.language"},
+			Groups: []telemetry.ServiceGroup{
+				{Attributes: map[string]string{"service.name": "sample-frontend", "telemetry.sdk.language": "nodejs"}, SpanCount: 1},
+				{Attributes: map[string]string{"service.name": "sample-loadgenerator", "telemetry.sdk.language": "python"}, SpanCount: 2},
+				{Attributes: map[string]string{"service.name": "sample.currencyservice", "telemetry.sdk.language": "cpp"}, SpanCount: 1},
+			},
+		}, groups)
+	})
+
+	t.Run("Services Handler (Invalid Group By)", func(t *testing.T) {
+		for _, groupBy := range []string{"", "service.name,,host.name", "service.name,service.name", "a,b,c,d,e,f,g,h,i"} {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/services?groupBy=", groupBy))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			res.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, groupBy)
+		}
+	})
+
+	t.Run("Services Handler (Invalid)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/services?withCounts=maybe"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestAttributesHandlers(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	t.Run("Attribute Keys Handler", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/attributes/keys?service=sample-loadgenerator"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		keys := telemetry.AttributeKeyList{}
+		err = json.NewDecoder(res.Body).Decode(&keys)
+		assert.Nilf(t, err, "could not decode attribute keys: %v", err)
+		if assert.NotEmpty(t, keys.Keys) {
+			for _, key := range keys.Keys {
+				assert.NotEmpty(t, key.Key)
+				assert.LessOrEqual(t, key.SpanCount, uint32(2))
+			}
+		}
+	})
+
+	t.Run("Attribute Values Handler", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/attributes/keys"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		keys := telemetry.AttributeKeyList{}
+		err = json.NewDecoder(res.Body).Decode(&keys)
+		assert.Nilf(t, err, "could not decode attribute keys: %v", err)
+		if !assert.NotEmpty(t, keys.Keys) {
+			return
+		}
+
+		// Every value of a key adds up to the spans counted with it
+		key := keys.Keys[0]
+		res, err = http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/attributes/values?key=", url.QueryEscape(key.Key)))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		values := telemetry.AttributeValues{}
+		err = json.NewDecoder(res.Body).Decode(&values)
+		assert.Nilf(t, err, "could not decode attribute values: %v", err)
+		assert.Equal(t, key.Key, values.Key)
+		assert.False(t, values.Truncated)
+		total := uint32(0)
+		for _, value := range values.Values {
+			total += value.SpanCount
+		}
+		assert.Equal(t, key.SpanCount, total)
+	})
+
+	t.Run("Attribute Cardinality Handler", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/stats/attribute-cardinality?limit=3"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		cardinality := telemetry.AttributeCardinalityList{}
+		err = json.NewDecoder(res.Body).Decode(&cardinality)
+		assert.Nilf(t, err, "could not decode attribute cardinality: %v", err)
+		if assert.Len(t, cardinality.Keys, 3) {
+			for i, key := range cardinality.Keys {
+				assert.NotEmpty(t, key.Key)
+				assert.LessOrEqual(t, key.DistinctValues, uint64(key.SpanCount))
+				if i > 0 {
+					assert.LessOrEqual(t, key.DistinctValues, cardinality.Keys[i-1].DistinctValues)
+				}
+			}
+		}
+	})
+
+	t.Run("Attribute Cardinality Handler (Invalid)", func(t *testing.T) {
+		for _, query := range []string{"?limit=-1", "?order=up"} {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/stats/attribute-cardinality", query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			res.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+		}
+	})
+
+	t.Run("Attribute Values Handler (Invalid)", func(t *testing.T) {
+		for _, query := range []string{"", "?limit=5", "?key=http.method&limit=-1", "?key=http.method&limit=1001"} {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/attributes/values", query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			res.Body.Close()
+
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+		}
+	})
+}
+
+func TestDeleteTraceHandler(t *testing.T) {
+	testServer, teardown := setupWithTrace(t)
+	defer teardown(t)
+
+	deleteTrace := func(t *testing.T, traceID string) int {
+		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/", traceID), nil)
+		assert.Nilf(t, err, "could not create DELETE request: %v", err)
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send DELETE request: %v", err)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+
+	t.Run("Delete Trace Handler (Not Found)", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, deleteTrace(t, "987654321"))
+	})
+
+	t.Run("Delete Trace Handler (ID Found)", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, deleteTrace(t, "1234567890"))
+
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/1234567890"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+		// Deleting it a second time finds nothing
+		assert.Equal(t, http.StatusNotFound, deleteTrace(t, "1234567890"))
+	})
+}
+
+func TestClearTracesHandler(t *testing.T) {
+	testServer, teardown := setupWithTrace(t)
+	defer teardown(t)
+
+	// Clear dat data
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/clearData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cleared := telemetry.ClearedTraces{}
+	err = json.NewDecoder(res.Body).Decode(&cleared)
+	assert.Nilf(t, err, "could not decode cleared traces: %v", err)
+	assert.Equal(t, 1, cleared.ClearedTraces)
+
+	// Get trace summaries
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	b, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+
+	testSummaries := telemetry.TraceSummaries{}
+	err = json.Unmarshal(b, &testSummaries)
+	assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+
+	// Check that there are no traces in store
+	assert.Len(t, testSummaries.TraceSummaries, 0)
+}
+
+func TestClearTracesHandlerFilters(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedTraces int
+	}{
+		{name: "By Service", query: "?service=sample.currencyservice", expectedStatus: http.StatusOK, expectedTraces: 1},
+		{name: "By Service Of A Child Span", query: "?service=sample-frontend", expectedStatus: http.StatusOK, expectedTraces: 1},
+		{name: "Before", query: "?before=2023-02-02T00:00:00Z", expectedStatus: http.StatusOK, expectedTraces: 1},
+		{name: "After In Unix Milliseconds", query: "?after=1675296000000", expectedStatus: http.StatusOK, expectedTraces: 1},
+		{name: "Nothing Matches", query: "?service=sample.currencyservice&after=2023-02-02T00:00:00Z", expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "Invalid Before", query: "?before=yesterday", expectedStatus: http.StatusBadRequest, expectedTraces: 2},
+		{name: "After Not Before Before", query: "?after=2023-02-02T00:00:00Z&before=2023-02-01T00:00:00Z", expectedStatus: http.StatusBadRequest, expectedTraces: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("localhost:8000", "")
+			defer server.Close()
+			testServer := httptest.NewServer(server.Handler(false))
+			defer testServer.Close()
+
+			res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			res.Body.Close()
+
+			res, err = http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/clearData", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			if tt.expectedStatus == http.StatusOK {
+				cleared := telemetry.ClearedTraces{}
+				err = json.NewDecoder(res.Body).Decode(&cleared)
+				assert.Nilf(t, err, "could not decode cleared traces: %v", err)
+				assert.Equal(t, 2-tt.expectedTraces, cleared.ClearedTraces)
+			}
+
+			count, err := server.Store.CountTraces(context.Background())
+			assert.Nilf(t, err, "could not count traces: %v", err)
+			assert.Equal(t, tt.expectedTraces, count)
+		})
+	}
+}
+
+func TestSampleHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	// Populate sample data
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	t.Run("Sample Data Handler (Traces)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+
+		testTrace := telemetry.TraceData{}
+		err = json.Unmarshal(b, &testTrace)
+		assert.Nilf(t, err, "could not unmarshal bytes to trace data: %v", err)
+
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", testTrace.TraceID)
+		assert.Equal(t, "37fd1349bf83d330", testTrace.Spans[0].SpanID)
+		assert.Equal(t, "SAMPLE HTTP POST", testTrace.Spans[0].Name)
+		assert.Equal(t, "sample-loadgenerator", testTrace.Spans[0].Resource.Attributes["service.name"])
+		assert.Equal(t, 3, len(testTrace.Spans))
+	})
+}
+
+func TestRetention(t *testing.T) {
+	sample := telemetry.NewSampleTelemetry()
+	server := NewServer("localhost:8000", "", WithRetention(store.RetentionPolicy{MaxAge: time.Hour}, 10*time.Millisecond))
+	defer server.Store.Close()
+
+	// Start the clock right after the last sample span ends
+	lastEnd := time.Time{}
+	for _, span := range sample.Spans {
+		if span.EndTime.After(lastEnd) {
+			lastEnd = span.EndTime
+		}
+	}
+	var clock atomic.Int64
+	clock.Store(lastEnd.Add(time.Minute).UnixNano())
+	server.now = func() time.Time { return time.Unix(0, clock.Load()) }
+
+	err := server.Store.AddSpans(context.Background(), sample.Spans)
+	assert.Nilf(t, err, "could not add sample spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	countTraces := func() int {
+		summaries, _, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+		assert.Nilf(t, err, "could not get trace summaries: %v", err)
+		return len(*summaries)
+	}
+
+	go server.runRetention()
+	defer server.Close()
+
+	// A few ticks go by, evicting only the sample trace from the day before
+	assert.Eventually(t, func() bool { return countTraces() == 1 }, time.Second, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, 1, countTraces())
+
+	// Advance the clock past the retention window and the next tick evicts the other one
+	clock.Store(lastEnd.Add(2 * time.Hour).UnixNano())
+	assert.Eventually(t, func() bool { return countTraces() == 0 }, time.Second, 10*time.Millisecond)
+}
+
+// newTestTraces builds two traces from two services using different scopes,
+// plus one span that is missing its trace ID
+func newTestTraces() ptrace.Traces {
+	traces := ptrace.NewTraces()
+
+	for i, serviceName := range []string{"pumpkin.pie", "apple.crumble"} {
+		resourceSpans := traces.ResourceSpans().AppendEmpty()
+		resourceSpans.Resource().Attributes().PutStr("service.name", serviceName)
+
+		scopeSpans := resourceSpans.ScopeSpans().AppendEmpty()
+		scopeSpans.Scope().SetName(serviceName + ".scope")
+		scopeSpans.Scope().SetVersion("1")
+
+		span := scopeSpans.Spans().AppendEmpty()
+		span.SetTraceID(pcommon.TraceID([16]byte{byte(i + 1)}))
+		span.SetSpanID(pcommon.SpanID([8]byte{byte(i + 1)}))
+		span.SetName("bake")
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Now().Add(time.Second)))
+		span.Attributes().PutInt("oven.temperature", 180)
+	}
+
+	orphan := traces.ResourceSpans().At(0).ScopeSpans().At(0).Spans().AppendEmpty()
+	orphan.SetSpanID(pcommon.SpanID([8]byte{9}))
+	orphan.SetName("no trace")
+
+	return traces
+}
+
+// newTestLogs builds two logs recorded as part of the first trace in newTestTraces, and one outside any trace
+func newTestLogs() plog.Logs {
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("service.name", "pumpkin.pie")
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName("pumpkin.pie.logger")
+
+	for i, body := range []string{"preheating", "baking", "cooling"} {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.Body().SetStr(body)
+		record.SetTimestamp(pcommon.NewTimestampFromTime(time.Now().Add(time.Duration(i) * time.Second)))
+		record.SetSeverityNumber(plog.SeverityNumberInfo)
+		if body != "cooling" {
+			record.SetTraceID(pcommon.TraceID([16]byte{1}))
+			record.SetSpanID(pcommon.SpanID([8]byte{1}))
+		}
+	}
+	return logs
+}
+
+func getTraceLogs(t *testing.T, baseURL string, traceID string) telemetry.TraceLogs {
+	res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/logs", baseURL, traceID))
+	assert.Nilf(t, err, "could not send GET request %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	traceLogs := telemetry.TraceLogs{}
+	err = json.NewDecoder(res.Body).Decode(&traceLogs)
+	assert.Nilf(t, err, "could not decode trace logs: %v", err)
+	return traceLogs
+}
+
+func TestReadOnlyHandler(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "traces.db")
+	writable := NewServer("localhost:8000", dbPath)
+	err := writable.Store.AddSpans(context.Background(), telemetry.NewSampleTelemetry().Spans)
+	assert.Nilf(t, err, "could not add sample spans: %v", err)
+	assert.Nil(t, writable.Store.Close())
+
+	server := NewServer("localhost:8000", dbPath, WithReadOnly())
+	defer server.Store.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	t.Run("Read Only Handler (Reads)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		trace := telemetry.TraceData{}
+		err = json.NewDecoder(res.Body).Decode(&trace)
+		assert.Nilf(t, err, "could not decode trace: %v", err)
+		assert.Len(t, trace.Spans, 3)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+	}{
+		{name: "OTLP Traces", method: http.MethodPost, path: "/v1/traces"},
+		{name: "OTLP Logs", method: http.MethodPost, path: "/v1/logs"},
+		{name: "OTLP Metrics", method: http.MethodPost, path: "/v1/metrics"},
+		{name: "Zipkin", method: http.MethodPost, path: zipkinSpansPath},
+		{name: "Import", method: http.MethodPost, path: "/api/traces/import"},
+		{name: "Annotations", method: http.MethodPut, path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/annotations"},
+		{name: "Pin", method: http.MethodPost, path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/pin"},
+		{name: "Delete", method: http.MethodDelete, path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"},
+		{name: "Clear", method: http.MethodGet, path: "/api/clearData"},
+		{name: "Sample Data", method: http.MethodGet, path: "/api/sampleData"},
+	}
+	for _, tc := range tests {
+		t.Run(fmt.Sprintf("Read Only Handler (%s)", tc.name), func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, testServer.URL+tc.path, strings.NewReader("{}"))
+			assert.Nilf(t, err, "could not create request: %v", err)
+			res, err := http.DefaultClient.Do(req)
+			assert.Nilf(t, err, "could not send request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusMethodNotAllowed, res.StatusCode)
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+			assert.Contains(t, string(b), "read-only")
+		})
+	}
+
+	// Nothing was removed by the refused requests
+	_, totalCount, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+	assert.Nilf(t, err, "could not get trace summaries: %v", err)
+	assert.Equal(t, 2, totalCount)
+}
+
+func TestReceiverPartialSuccess(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	traces := ptrace.NewTraces()
+	resourceSpans := traces.ResourceSpans().AppendEmpty()
+	resourceSpans.Resource().Attributes().PutStr("service.name", "pumpkin.pie")
+	spans := resourceSpans.ScopeSpans().AppendEmpty().Spans()
+	for i, name := range []string{"bake", "cool", "bake again", "no trace", "no span"} {
+		span := spans.AppendEmpty()
+		span.SetName(name)
+		if name != "no trace" {
+			span.SetTraceID(pcommon.TraceID([16]byte{1}))
+		}
+		if name != "no span" {
+			// The third span repeats the IDs of the first
+			span.SetSpanID(pcommon.SpanID([8]byte{byte(i%2 + 1)}))
+		}
+		span.SetStartTimestamp(pcommon.NewTimestampFromTime(time.Now()))
+		span.SetEndTimestamp(pcommon.NewTimestampFromTime(time.Now().Add(time.Second)))
+	}
+
+	payload, err := ptraceotlp.NewExportRequestFromTraces(traces).MarshalProto()
+	assert.Nilf(t, err, "could not marshal traces: %v", err)
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	b, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+	response := ptraceotlp.NewExportResponse()
+	err = response.UnmarshalProto(b)
+	assert.Nilf(t, err, "could not unmarshal export response: %v", err)
+	assert.Equal(t, int64(3), response.PartialSuccess().RejectedSpans())
+	assert.Contains(t, response.PartialSuccess().ErrorMessage(), "2 spans were rejected for missing a trace or span ID")
+	assert.Contains(t, response.PartialSuccess().ErrorMessage(), "1 spans were dropped for having the same trace and span ID")
+
+	// Every span that wasn't rejected is stored, and the repeated one is the last sent
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+	trace, err := server.Store.GetTrace(context.Background(), pcommon.TraceID([16]byte{1}).String())
+	if assert.Nilf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 2) {
+		names := []string{trace.Spans[0].Name, trace.Spans[1].Name}
+		assert.ElementsMatch(t, []string{"bake again", "cool"}, names)
+	}
+}
+
+func TestGRPCReceiver(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithGRPCEndpoint("localhost:0"))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nilf(t, err, "could not listen for grpc: %v", err)
+	go server.grpcServer.Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nilf(t, err, "could not create grpc client: %v", err)
+	defer conn.Close()
+
+	client := ptraceotlp.NewGRPCClient(conn)
+	response, err := client.Export(context.Background(), ptraceotlp.NewExportRequestFromTraces(newTestTraces()))
+	assert.Nilf(t, err, "could not export traces: %v", err)
+	assert.Equal(t, int64(1), response.PartialSuccess().RejectedSpans())
+	assert.NotEmpty(t, response.PartialSuccess().ErrorMessage())
+
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+	assert.Nilf(t, err, "could not send GET request %v", err)
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+
+	testSummaries := telemetry.TraceSummaries{}
+	err = json.Unmarshal(b, &testSummaries)
+	assert.Nilf(t, err, "could not unmarshal bytes to trace summaries: %v", err)
+	assert.Equal(t, 2, testSummaries.TotalCount)
+
+	// Each span keeps the resource and scope it was grouped under
+	trace, err := server.Store.GetTrace(context.Background(), pcommon.TraceID([16]byte{2}).String())
+	if assert.Nilf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+		span := trace.Spans[0]
+		assert.Equal(t, "apple.crumble", span.GetServiceName())
+		assert.Equal(t, "apple.crumble.scope", span.Scope.Name)
+		assert.Equal(t, "1", span.Scope.Version)
+	}
+
+	// Logs arrive on the same connection
+	logsClient := plogotlp.NewGRPCClient(conn)
+	_, err = logsClient.Export(context.Background(), plogotlp.NewExportRequestFromLogs(newTestLogs()))
+	assert.Nilf(t, err, "could not export logs: %v", err)
+
+	traceLogs := getTraceLogs(t, testServer.URL, pcommon.TraceID([16]byte{1}).String())
+	if assert.Len(t, traceLogs.Logs, 2) {
+		assert.Equal(t, "preheating", traceLogs.Logs[0].Body)
+		assert.Equal(t, "baking", traceLogs.Logs[1].Body)
+		assert.Equal(t, "pumpkin.pie.logger", traceLogs.Logs[1].Scope.Name)
+	}
+}
+
+func TestParseListener(t *testing.T) {
+	tests := []struct {
+		name             string
+		spec             string
+		expectedListener Listener
+		expectedErr      bool
+	}{
+		{name: "Endpoint Only", spec: "localhost:4320", expectedListener: Listener{Endpoint: "localhost:4320", ResourceAttributes: map[string]string{}}},
+		{name: "Attributes", spec: "localhost:4320,env=staging,k8s.namespace.name=staging", expectedListener: Listener{Endpoint: "localhost:4320", ResourceAttributes: map[string]string{"env": "staging", "k8s.namespace.name": "staging"}}},
+		{name: "Empty Value", spec: "0.0.0.0:4320,env=", expectedListener: Listener{Endpoint: "0.0.0.0:4320", ResourceAttributes: map[string]string{"env": ""}}},
+		{name: "Missing Port", spec: "localhost,env=staging", expectedErr: true},
+		{name: "Missing Value", spec: "localhost:4320,env", expectedErr: true},
+		{name: "Missing Key", spec: "localhost:4320,=staging", expectedErr: true},
+		{name: "Repeated Key", spec: "localhost:4320,env=staging,env=dev", expectedErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener, err := ParseListener(tt.spec)
+			if tt.expectedErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedListener, listener)
+		})
+	}
+}
+
+func TestListeners(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithListeners(Listener{
+		Endpoint:           "localhost:0",
+		ResourceAttributes: map[string]string{"env": "staging", "service.name": "unknown"},
+	}))
+	defer server.Close()
+
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nilf(t, err, "could not listen for grpc: %v", err)
+	go server.listenerServers[0].Serve(listener)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.Nilf(t, err, "could not create grpc client: %v", err)
+	defer conn.Close()
+
+	// The second service already sets env, which the listener leaves as it is
+	traces := newTestTraces()
+	traces.ResourceSpans().At(1).Resource().Attributes().PutStr("env", "prod")
+	_, err = ptraceotlp.NewGRPCClient(conn).Export(context.Background(), ptraceotlp.NewExportRequestFromTraces(traces))
+	assert.Nilf(t, err, "could not export traces: %v", err)
+	_, err = plogotlp.NewGRPCClient(conn).Export(context.Background(), plogotlp.NewExportRequestFromLogs(newTestLogs()))
+	assert.Nilf(t, err, "could not export logs: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	expected := map[string]telemetry.Attributes{
+		pcommon.TraceID([16]byte{1}).String(): {"service.name": "pumpkin.pie", "env": "staging"},
+		pcommon.TraceID([16]byte{2}).String(): {"service.name": "apple.crumble", "env": "prod"},
+	}
+	for traceID, attributes := range expected {
+		trace, err := server.Store.GetTrace(context.Background(), traceID)
+		if assert.Nilf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+			assert.Equal(t, attributes, trace.Spans[0].Resource.Attributes)
+		}
+	}
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	traceLogs := getTraceLogs(t, testServer.URL, pcommon.TraceID([16]byte{1}).String())
+	if assert.NotEmpty(t, traceLogs.Logs) {
+		assert.Equal(t, "staging", traceLogs.Logs[0].Resource.Attributes["env"])
+	}
+}
+
+func TestOTLPHTTPLogsReceiver(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	payload, err := plogotlp.NewExportRequestFromLogs(newTestLogs()).MarshalJSON()
+	assert.Nilf(t, err, "could not marshal json payload: %v", err)
+
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/logs"), "application/json", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	traceID := pcommon.TraceID([16]byte{1}).String()
+	traceLogs := getTraceLogs(t, testServer.URL, traceID)
+	assert.Equal(t, traceID, traceLogs.TraceID)
+	assert.Len(t, traceLogs.Logs, 2)
+
+	res, err = http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/logs"), "text/plain", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, res.StatusCode)
+}
+
+func TestOTLPHTTPReceiver(t *testing.T) {
+	exportRequest := ptraceotlp.NewExportRequestFromTraces(newTestTraces())
+	protoPayload, err := exportRequest.MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+	jsonPayload, err := exportRequest.MarshalJSON()
+	assert.Nilf(t, err, "could not marshal json payload: %v", err)
+
+	gzipped := bytes.Buffer{}
+	gzipWriter := gzip.NewWriter(&gzipped)
+	gzipWriter.Write(protoPayload)
+	gzipWriter.Close()
+
+	gzippedJSON := bytes.Buffer{}
+	gzipWriter = gzip.NewWriter(&gzippedJSON)
+	gzipWriter.Write(jsonPayload)
+	gzipWriter.Close()
+
+	tests := []struct {
+		name            string
+		contentType     string
+		contentEncoding string
+		payload         []byte
+		expectedStatus  int
+		expectedTraces  int
+	}{
+		{name: "Protobuf", contentType: "application/x-protobuf", payload: protoPayload, expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "JSON", contentType: "application/json", payload: jsonPayload, expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "JSON With Charset", contentType: "application/json; charset=utf-8", payload: jsonPayload, expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "Gzipped Protobuf", contentType: "application/x-protobuf", contentEncoding: "gzip", payload: gzipped.Bytes(), expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "Gzipped JSON", contentType: "application/json", contentEncoding: "gzip", payload: gzippedJSON.Bytes(), expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "Uppercase Content Encoding", contentType: "application/x-protobuf", contentEncoding: "GZIP", payload: gzipped.Bytes(), expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "X-Gzip Content Encoding", contentType: "application/x-protobuf", contentEncoding: "x-gzip", payload: gzipped.Bytes(), expectedStatus: http.StatusOK, expectedTraces: 2},
+		{name: "Corrupt Gzip", contentType: "application/x-protobuf", contentEncoding: "gzip", payload: protoPayload, expectedStatus: http.StatusBadRequest},
+		{name: "Truncated Gzip", contentType: "application/x-protobuf", contentEncoding: "gzip", payload: gzipped.Bytes()[:gzipped.Len()/2], expectedStatus: http.StatusBadRequest},
+		{name: "Unsupported Content Type", contentType: "text/plain", payload: jsonPayload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Missing Content Type", payload: jsonPayload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Unsupported Content Encoding", contentType: "application/json", contentEncoding: "br", payload: jsonPayload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Malformed Payload", contentType: "application/json", payload: []byte("{not json"), expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("localhost:8000", "")
+			defer server.Close()
+
+			testServer := httptest.NewServer(server.Handler(false))
+			defer testServer.Close()
+
+			request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), bytes.NewReader(tt.payload))
+			assert.Nilf(t, err, "could not create POST request: %v", err)
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.contentEncoding != "" {
+				request.Header.Set("Content-Encoding", tt.contentEncoding)
+			}
+
+			res, err := http.DefaultClient.Do(request)
+			assert.Nilf(t, err, "could not send POST request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			if tt.expectedStatus == http.StatusOK {
+				b, err := io.ReadAll(res.Body)
+				assert.Nilf(t, err, "could not read response body: %v", err)
+
+				// The response comes back in the encoding of the request
+				response := ptraceotlp.NewExportResponse()
+				if tt.contentType == "application/x-protobuf" {
+					assert.Equal(t, "application/x-protobuf", res.Header.Get("Content-Type"))
+					err = response.UnmarshalProto(b)
+				} else {
+					assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+					err = response.UnmarshalJSON(b)
+				}
+				assert.Nilf(t, err, "could not unmarshal export response: %v", err)
+				assert.Equal(t, int64(1), response.PartialSuccess().RejectedSpans())
+			}
+
+			err = server.Store.Flush(context.Background())
+			assert.Nilf(t, err, "could not flush spans: %v", err)
+
+			_, totalCount, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+			assert.Nilf(t, err, "could not get trace summaries: %v", err)
+			assert.Equal(t, tt.expectedTraces, totalCount)
+		})
+	}
+}
+
+func TestZipkinReceiver(t *testing.T) {
+	payload := `[
+		{"traceId": "a1b2c3d4e5f60718", "id": "01", "name": "get /checkout", "kind": "SERVER",
+		 "timestamp": 1700000000000000, "duration": 2000, "localEndpoint": {"serviceName": "checkout"}},
+		{"traceId": "a1b2c3d4e5f60718", "id": "02", "parentId": "01", "name": "charge", "kind": "CLIENT",
+		 "timestamp": 1700000000000500, "duration": 1000, "localEndpoint": {"serviceName": "checkout"}}
+	]`
+
+	tests := []struct {
+		name           string
+		contentType    string
+		payload        string
+		expectedStatus int
+		expectedTraces int
+	}{
+		{name: "JSON", contentType: "application/json", payload: payload, expectedStatus: http.StatusAccepted, expectedTraces: 1},
+		{name: "Missing Content Type", payload: payload, expectedStatus: http.StatusAccepted, expectedTraces: 1},
+		{name: "Protobuf", contentType: "application/x-protobuf", payload: payload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Malformed Payload", contentType: "application/json", payload: "{not json", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Zipkin reporters can't send a token, so the endpoint must stay open with auth on
+			server := NewServer("localhost:8000", "", WithAuthToken("s3cret", false))
+			defer server.Close()
+
+			testServer := httptest.NewServer(server.Handler(false))
+			defer testServer.Close()
+
+			request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s", testServer.URL, "/api/v2/spans"), strings.NewReader(tt.payload))
+			assert.Nilf(t, err, "could not create POST request: %v", err)
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			res, err := http.DefaultClient.Do(request)
+			assert.Nilf(t, err, "could not send POST request: %v", err)
+			res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			err = server.Store.Flush(context.Background())
+			assert.Nilf(t, err, "could not flush spans: %v", err)
+
+			summaries, totalCount, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+			assert.Nilf(t, err, "could not get trace summaries: %v", err)
+			assert.Equal(t, tt.expectedTraces, totalCount)
+			if totalCount == 1 {
+				assert.Equal(t, "checkout", (*summaries)[0].RootServiceName)
+				assert.Equal(t, "get /checkout", (*summaries)[0].RootName)
+				assert.Equal(t, uint32(2), (*summaries)[0].SpanCount)
+			}
+		})
+	}
+}
+
+func TestSimpleSpansHandler(t *testing.T) {
+	payload := `[
+		{"traceId": "a1b2c3d4e5f60718", "spanId": "01", "name": "get /checkout",
+		 "startUnixNano": 1700000000000000000, "endUnixNano": 1700000000002000000},
+		{"traceId": "a1b2c3d4e5f60718", "spanId": "02", "parentSpanId": "01", "name": "charge",
+		 "startUnixNano": 1700000000000500000, "endUnixNano": 1700000000001500000}
+	]`
+
+	tests := []struct {
+		name           string
+		contentType    string
+		payload        string
+		expectedStatus int
+		expectedTraces int
+	}{
+		{name: "JSON", contentType: "application/json", payload: payload, expectedStatus: http.StatusAccepted, expectedTraces: 1},
+		{name: "Missing Content Type", payload: payload, expectedStatus: http.StatusAccepted, expectedTraces: 1},
+		{name: "Form", contentType: "application/x-www-form-urlencoded", payload: payload, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Missing Span ID", contentType: "application/json", payload: `[{"traceId": "a1", "name": "a", "startUnixNano": 1, "endUnixNano": 2}]`, expectedStatus: http.StatusBadRequest},
+		{name: "Malformed Payload", contentType: "application/json", payload: "{not json", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := NewServer("localhost:8000", "")
+			defer server.Close()
+
+			testServer := httptest.NewServer(server.Handler(false))
+			defer testServer.Close()
+
+			request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%s", testServer.URL, "/api/spans"), strings.NewReader(tt.payload))
+			assert.Nilf(t, err, "could not create POST request: %v", err)
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+
+			res, err := http.DefaultClient.Do(request)
+			assert.Nilf(t, err, "could not send POST request: %v", err)
+			res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+
+			err = server.Store.Flush(context.Background())
+			assert.Nilf(t, err, "could not flush spans: %v", err)
+
+			summaries, totalCount, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+			assert.Nilf(t, err, "could not get trace summaries: %v", err)
+			assert.Equal(t, tt.expectedTraces, totalCount)
+			if totalCount == 1 {
+				assert.Equal(t, telemetry.DefaultServiceName, (*summaries)[0].RootServiceName)
+				assert.Equal(t, "get /checkout", (*summaries)[0].RootName)
+				assert.Equal(t, uint32(2), (*summaries)[0].SpanCount)
+			}
+		})
+	}
+}
+
+func TestExportTraceHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithAnonymizeAttributes("oven.temperature"))
+	defer server.Close()
+
+	_, err := server.receiveTraces(context.Background(), newTestTraces())
+	assert.Nilf(t, err, "could not receive traces: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	traceID := pcommon.TraceID([16]byte{1}).String()
+
+	t.Run("Export Trace", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?format=otlp", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "application/json", res.Header.Get("Content-Type"))
+		assert.Equal(t, fmt.Sprintf("attachment; filename=trace-%s.json", traceID), res.Header.Get("Content-Disposition"))
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+
+		unmarshaler := ptrace.JSONUnmarshaler{}
+		traces, err := unmarshaler.UnmarshalTraces(b)
+		if assert.Nilf(t, err, "could not unmarshal exported traces: %v", err) && assert.Equal(t, 1, traces.SpanCount()) {
+			resourceSpans := traces.ResourceSpans().At(0)
+			serviceName, _ := resourceSpans.Resource().Attributes().Get("service.name")
+			assert.Equal(t, "pumpkin.pie", serviceName.Str())
+
+			scopeSpans := resourceSpans.ScopeSpans().At(0)
+			assert.Equal(t, "pumpkin.pie.scope", scopeSpans.Scope().Name())
+
+			span := scopeSpans.Spans().At(0)
+			assert.Equal(t, traceID, span.TraceID().String())
+			assert.Equal(t, "bake", span.Name())
+
+			// Attribute types survive the trip through the database
+			temperature, _ := span.Attributes().Get("oven.temperature")
+			assert.Equal(t, pcommon.ValueTypeInt, temperature.Type())
+			assert.Equal(t, int64(180), temperature.Int())
+		}
+	})
+
+	t.Run("Export Trace (HTML)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?format=html", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+		assert.Equal(t, fmt.Sprintf("attachment; filename=trace-%s.html", traceID), res.Header.Get("Content-Disposition"))
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		page := string(b)
+		assert.Contains(t, page, traceID)
+		assert.Contains(t, page, `"name":"bake"`)
+		assert.Contains(t, page, `"service.name":"pumpkin.pie"`)
+
+		// Everything the page needs is inline, and its policy forbids fetching anything else
+		assert.Contains(t, page, "default-src 'none'")
+		assert.NotContains(t, page, "src=")
+		assert.NotContains(t, page, "href=")
+	})
+
+	t.Run("Export Trace (Anonymized)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?anonymize=true", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.NotContains(t, string(b), "pumpkin.pie\"")
+		assert.NotContains(t, string(b), "bake")
+
+		unmarshaler := ptrace.JSONUnmarshaler{}
+		traces, err := unmarshaler.UnmarshalTraces(b)
+		if assert.Nilf(t, err, "could not unmarshal exported traces: %v", err) && assert.Equal(t, 1, traces.SpanCount()) {
+			resourceSpans := traces.ResourceSpans().At(0)
+			serviceName, _ := resourceSpans.Resource().Attributes().Get("service.name")
+			assert.True(t, strings.HasPrefix(serviceName.Str(), "service-"))
+
+			span := resourceSpans.ScopeSpans().At(0).Spans().At(0)
+			assert.Equal(t, traceID, span.TraceID().String())
+			assert.True(t, strings.HasPrefix(span.Name(), "span-"))
+			temperature, _ := span.Attributes().Get("oven.temperature")
+			assert.True(t, strings.HasPrefix(temperature.Str(), "value-"))
+		}
+	})
+
+	t.Run("Export Trace (Anonymized HTML)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?format=html&anonymize=true", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Contains(t, string(b), traceID)
+		assert.NotContains(t, string(b), `"service.name":"pumpkin.pie"`)
+		assert.NotContains(t, string(b), `"name":"bake"`)
+	})
+
+	t.Run("Invalid Anonymize", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?anonymize=maybe", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+
+	t.Run("Unknown Trace", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export", testServer.URL, "notatrace"))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/export?format=zipkin", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestImportTracesHandler(t *testing.T) {
+	marshaler := ptrace.JSONMarshaler{}
+	payload, err := marshaler.MarshalTraces(telemetry.NewTracesFromSpans(telemetry.NewSampleTelemetry().Spans))
+	assert.Nilf(t, err, "could not marshal sample traces: %v", err)
+
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	postImport := func(t *testing.T, payload []byte) (*http.Response, []byte) {
+		res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/import"), "application/json", bytes.NewReader(payload))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		return res, b
+	}
+
+	t.Run("Import Traces", func(t *testing.T) {
+		res, b := postImport(t, payload)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		imported := telemetry.ImportedTraces{}
+		err := json.Unmarshal(b, &imported)
+		assert.Nilf(t, err, "could not unmarshal bytes to imported traces: %v", err)
+		assert.ElementsMatch(t, []string{"7979cec4d1c04222fa9a3c7c97c0a99c", "42957c7c2fca940a0d32a0cdd38c06a4"}, imported.TraceIDs)
+		assert.Equal(t, 4, imported.ImportedSpans)
+		assert.Equal(t, 0, imported.DuplicateSpans)
+	})
+
+	t.Run("Import Traces Again", func(t *testing.T) {
+		res, b := postImport(t, payload)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		imported := telemetry.ImportedTraces{}
+		err := json.Unmarshal(b, &imported)
+		assert.Nilf(t, err, "could not unmarshal bytes to imported traces: %v", err)
+		assert.Equal(t, 0, imported.ImportedSpans)
+		assert.Equal(t, 4, imported.DuplicateSpans)
+
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"))
+		assert.Nilf(t, err, "could not send GET request %v", err)
+		defer res.Body.Close()
+
+		traceData := telemetry.TraceData{}
+		err = json.NewDecoder(res.Body).Decode(&traceData)
+		assert.Nilf(t, err, "could not decode trace: %v", err)
+		assert.Len(t, traceData.Spans, 3)
+	})
+
+	invalid := []struct {
+		name    string
+		payload string
+	}{
+		{name: "Not JSON", payload: "pumpkin pie"},
+		{name: "No Spans", payload: "{}"},
+		{name: "Missing Trace ID", payload: `{"resourceSpans":[{"scopeSpans":[{"spans":[{"spanId":"0102030405060708","name":"orphan"}]}]}]}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			res, b := postImport(t, []byte(tt.payload))
+			assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			assert.Contains(t, string(b), "invalid OTLP JSON")
+		})
+	}
+}
+
+func TestImportJaegerTraces(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	postImport := func(t *testing.T, payload string) (*http.Response, []byte) {
+		res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/import?format=jaeger"), "application/json", strings.NewReader(payload))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		return res, b
+	}
+
+	t.Run("Import Jaeger Trace", func(t *testing.T) {
+		res, b := postImport(t, `{"data": [{
+			"traceID": "a1b2c3d4e5f60718",
+			"spans": [
+				{"traceID": "a1b2c3d4e5f60718", "spanID": "01", "operationName": "GET /checkout", "startTime": 1700000000000000, "duration": 2000, "processID": "p1",
+				 "tags": [{"key": "span.kind", "type": "string", "value": "server"}]},
+				{"traceID": "a1b2c3d4e5f60718", "spanID": "02", "operationName": "charge", "startTime": 1700000000000500, "duration": 1000, "processID": "p1",
+				 "references": [{"refType": "CHILD_OF", "traceID": "a1b2c3d4e5f60718", "spanID": "01"}]}
+			],
+			"processes": {"p1": {"serviceName": "checkout", "tags": []}}
+		}]}`)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		imported := telemetry.ImportedTraces{}
+		err := json.Unmarshal(b, &imported)
+		assert.Nilf(t, err, "could not unmarshal bytes to imported traces: %v", err)
+		assert.Equal(t, []string{"0000000000000000a1b2c3d4e5f60718"}, imported.TraceIDs)
+		assert.Equal(t, 2, imported.ImportedSpans)
+
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		summaries := telemetry.TraceSummaries{}
+		err = json.NewDecoder(res.Body).Decode(&summaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		if assert.Len(t, summaries.TraceSummaries, 1) {
+			assert.Equal(t, "checkout", summaries.TraceSummaries[0].RootServiceName)
+			assert.Equal(t, "GET /checkout", summaries.TraceSummaries[0].RootName)
+			assert.Equal(t, uint32(2), summaries.TraceSummaries[0].SpanCount)
+		}
+	})
+
+	t.Run("Not Jaeger JSON", func(t *testing.T) {
+		marshaler := ptrace.JSONMarshaler{}
+		payload, err := marshaler.MarshalTraces(telemetry.NewTracesFromSpans(telemetry.NewSampleTelemetry().Spans))
+		assert.Nilf(t, err, "could not marshal sample traces: %v", err)
+
+		res, b := postImport(t, string(payload))
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Contains(t, string(b), "invalid Jaeger JSON")
+	})
+}
+
+func newTestMetrics(timestamp time.Time) pmetric.Metrics {
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	resourceMetrics.Resource().Attributes().PutStr("service.name", "pumpkin.pie")
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("pumpkin.pie.meter")
+
+	gauge := scopeMetrics.Metrics().AppendEmpty()
+	gauge.SetName("oven.temperature")
+	gauge.SetUnit("Cel")
+	gauge.SetEmptyGauge()
+	for i, value := range []float64{180, 175} {
+		dataPoint := gauge.Gauge().DataPoints().AppendEmpty()
+		dataPoint.SetDoubleValue(value)
+		dataPoint.SetTimestamp(pcommon.NewTimestampFromTime(timestamp.Add(time.Duration(i) * time.Minute)))
+	}
+
+	sum := scopeMetrics.Metrics().AppendEmpty()
+	sum.SetName("pies.baked")
+	sum.SetEmptySum().SetIsMonotonic(true)
+	dataPoint := sum.Sum().DataPoints().AppendEmpty()
+	dataPoint.SetIntValue(12)
+	dataPoint.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	return metrics
+}
+
+func TestBackupAndRestore(t *testing.T) {
+	source, teardownSource := setupEmpty()
+	defer teardownSource()
+	destination, teardownDestination := setupEmpty()
+	defer teardownDestination()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", source.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	getTrace := func(t *testing.T, testServer *httptest.Server, traceID string) telemetry.TraceData {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		trace := telemetry.TraceData{}
+		err = json.NewDecoder(res.Body).Decode(&trace)
+		assert.Nilf(t, err, "could not decode trace: %v", err)
+		return trace
+	}
+
+	postRestore := func(t *testing.T, backup []byte) (*http.Response, telemetry.ImportedTraces) {
+		res, err := http.Post(fmt.Sprintf("%s%s", destination.URL, "/api/traces/import?format=ndjson"), "application/x-ndjson", bytes.NewReader(backup))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+
+		imported := telemetry.ImportedTraces{}
+		if res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&imported)
+			assert.Nilf(t, err, "could not decode imported traces: %v", err)
+		}
+		return res, imported
+	}
+
+	res, err = http.Get(fmt.Sprintf("%s%s", source.URL, "/api/traces/export"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/x-ndjson", res.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=traces.ndjson", res.Header.Get("Content-Disposition"))
+
+	backup, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+	lines := strings.Split(strings.TrimSuffix(string(backup), "\n"), "\n")
+	if assert.Len(t, lines, 2) {
+		for _, line := range lines {
+			trace := telemetry.TraceData{}
+			err = json.Unmarshal([]byte(line), &trace)
+			assert.Nilf(t, err, "could not unmarshal backup line: %v", err)
+			assert.NotEmpty(t, trace.Spans)
+		}
+	}
+
+	t.Run("Restore", func(t *testing.T) {
+		res, imported := postRestore(t, backup)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.ElementsMatch(t, []string{"7979cec4d1c04222fa9a3c7c97c0a99c", "42957c7c2fca940a0d32a0cdd38c06a4"}, imported.TraceIDs)
+		assert.Equal(t, 4, imported.ImportedSpans)
+		assert.Equal(t, 0, imported.DuplicateSpans)
+
+		expected := getTrace(t, source, "42957c7c2fca940a0d32a0cdd38c06a4")
+		restored := getTrace(t, destination, "42957c7c2fca940a0d32a0cdd38c06a4")
+		assert.ElementsMatch(t, expected.Spans, restored.Spans)
+	})
+
+	t.Run("Restore Again", func(t *testing.T) {
+		res, imported := postRestore(t, backup)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, 0, imported.ImportedSpans)
+		assert.Equal(t, 4, imported.DuplicateSpans)
+	})
+
+	t.Run("Anonymized Backup", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", source.URL, "/api/traces/export?anonymize=true"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		backup, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.NotContains(t, string(backup), "sample-loadgenerator")
+
+		// Spans keep their IDs and parents, and a service gets one token across every trace
+		serviceNames := map[string]bool{}
+		for _, line := range strings.Split(strings.TrimSuffix(string(backup), "\n"), "\n") {
+			trace := telemetry.TraceData{}
+			err = json.Unmarshal([]byte(line), &trace)
+			assert.Nilf(t, err, "could not unmarshal backup line: %v", err)
+
+			expected := getTrace(t, source, trace.TraceID)
+			parents := map[string]string{}
+			for _, span := range expected.Spans {
+				parents[span.SpanID] = span.ParentSpanID
+			}
+			for _, span := range trace.Spans {
+				assert.Equal(t, parents[span.SpanID], span.ParentSpanID)
+				serviceNames[span.GetServiceName()] = true
+			}
+		}
+		expectedServiceNames := map[string]bool{}
+		for _, line := range lines {
+			trace := telemetry.TraceData{}
+			json.Unmarshal([]byte(line), &trace)
+			for _, span := range trace.Spans {
+				expectedServiceNames[span.GetServiceName()] = true
+			}
+		}
+		assert.Len(t, serviceNames, len(expectedServiceNames))
+	})
+
+	t.Run("Invalid Line", func(t *testing.T) {
+		backup := lines[0] + "\n" + `{"traceID":"1234567890","spans":[{"name":"orphan"}]}` + "\n"
+		res, err := http.Post(fmt.Sprintf("%s%s", destination.URL, "/api/traces/import?format=ndjson"), "application/x-ndjson", strings.NewReader(backup))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Contains(t, string(b), "line 2")
+	})
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		res, err := http.Post(fmt.Sprintf("%s%s", destination.URL, "/api/traces/import?format=zipkin"), "application/json", bytes.NewReader(backup))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestExportDatabaseHandler(t *testing.T) {
+	testServer, teardown := setupWithTrace(t)
+	defer teardown(t)
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/export/db"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "application/octet-stream", res.Header.Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=traces.db", res.Header.Get("Content-Disposition"))
+
+	path := filepath.Join(t.TempDir(), "traces.db")
+	file, err := os.Create(path)
+	assert.Nilf(t, err, "could not create database file: %v", err)
+	_, err = io.Copy(file, res.Body)
+	assert.Nilf(t, err, "could not download database file: %v", err)
+	file.Close()
+
+	// The download opens like any other database file
+	server := NewServer("localhost:8000", path, WithReadOnly())
+	defer server.Close()
+	trace, err := server.Store.GetTrace(context.Background(), "00000000000000000000001234567890")
+	if assert.Nilf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, "test", trace.Spans[0].Name)
+	}
+}
+
+func TestStoreErrors(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	// Traces written before the database closes are still followed, so the poll has to query them
+	after := time.Now().UTC().Format(time.RFC3339Nano)
+	err := server.Store.AddSpans(context.Background(), telemetry.NewSampleTelemetry().Spans)
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	// Every query fails once the database is closed, which each request is answered with,
+	// while the server carries on
+	err = server.Store.Close()
+	assert.Nilf(t, err, "could not close the store: %v", err)
+
+	for _, path := range []string{"/api/traces", "/api/services", "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4", "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4?spanLimit=1", "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4?kind=CLIENT", "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/stats", "/api/traces/since?after=" + after} {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, path))
+		if assert.Nilf(t, err, "could not send GET request: %v", err) {
+			res.Body.Close()
+			assert.Equal(t, http.StatusInternalServerError, res.StatusCode, path)
+		}
+	}
+
+	// An export has sent its status before it fails, so it is cut off rather than ended cleanly
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/export"))
+	if assert.Nilf(t, err, "could not send GET request: %v", err) {
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		_, err = io.ReadAll(res.Body)
+		assert.Error(t, err)
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	timestamp := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	payload, err := pmetricotlp.NewExportRequestFromMetrics(newTestMetrics(timestamp)).MarshalJSON()
+	assert.Nilf(t, err, "could not marshal json payload: %v", err)
+
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/metrics"), "application/json", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSeries map[string]int
+	}{
+		{name: "All", query: "", expectedStatus: http.StatusOK, expectedSeries: map[string]int{"oven.temperature": 2, "pies.baked": 1}},
+		{name: "By Name", query: "?name=oven.temperature", expectedStatus: http.StatusOK, expectedSeries: map[string]int{"oven.temperature": 2}},
+		{name: "By Service", query: "?service=apple.crumble", expectedStatus: http.StatusOK, expectedSeries: map[string]int{}},
+		{name: "By Time Range", query: "?start=2024-01-01T12:01:00Z&end=2024-01-01T13:00:00Z", expectedStatus: http.StatusOK, expectedSeries: map[string]int{"oven.temperature": 1}},
+		{name: "Invalid Start", query: "?start=yesterday", expectedStatus: http.StatusBadRequest},
+		{name: "Start After End", query: "?start=2024-01-02T00:00:00Z&end=2024-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/metrics", tt.query))
+			assert.Nilf(t, err, "could not send GET request %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			seriesList := telemetry.MetricSeriesList{}
+			err = json.NewDecoder(res.Body).Decode(&seriesList)
+			assert.Nilf(t, err, "could not decode metric series: %v", err)
+
+			series := map[string]int{}
+			for _, s := range seriesList.Series {
+				series[s.Name] = len(s.DataPoints)
+			}
+			assert.Equal(t, tt.expectedSeries, series)
+		})
+	}
+}
+
+func TestMetricsHandlerExemplars(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	// One exemplar points at a sample trace, the other at a trace that never arrived
+	timestamp := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	metrics := newTestMetrics(timestamp)
+	dataPoint := metrics.ResourceMetrics().At(0).ScopeMetrics().At(0).Metrics().At(1).Sum().DataPoints().At(0)
+	traceID, err := hex.DecodeString("42957c7c2fca940a0d32a0cdd38c06a4")
+	assert.Nilf(t, err, "could not decode trace ID: %v", err)
+	found := dataPoint.Exemplars().AppendEmpty()
+	found.SetIntValue(1)
+	found.SetTraceID(pcommon.TraceID(traceID))
+	found.SetSpanID(pcommon.SpanID([8]byte{1}))
+	missing := dataPoint.Exemplars().AppendEmpty()
+	missing.SetIntValue(1)
+	missing.SetTraceID(pcommon.TraceID([16]byte{1}))
+	missing.SetSpanID(pcommon.SpanID([8]byte{1}))
+
+	payload, err := pmetricotlp.NewExportRequestFromMetrics(metrics).MarshalJSON()
+	assert.Nilf(t, err, "could not marshal json payload: %v", err)
+	res, err = http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/metrics"), "application/json", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/metrics?name=pies.baked"))
+	assert.Nilf(t, err, "could not send GET request %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	seriesList := telemetry.MetricSeriesList{}
+	err = json.NewDecoder(res.Body).Decode(&seriesList)
+	assert.Nilf(t, err, "could not decode metric series: %v", err)
+	if assert.Len(t, seriesList.Series, 1) && assert.Len(t, seriesList.Series[0].DataPoints, 1) {
+		exemplars := seriesList.Series[0].DataPoints[0].Exemplars
+		if assert.Len(t, exemplars, 2) {
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", exemplars[0].TraceID)
+			if assert.NotNil(t, exemplars[0].Resolved) {
+				assert.True(t, *exemplars[0].Resolved)
+			}
+			if assert.NotNil(t, exemplars[1].Resolved) {
+				assert.False(t, *exemplars[1].Resolved)
+			}
+		}
+	}
+}
+
+func TestNestedAttributes(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	traces := ptrace.NewTraces()
+	span := traces.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(pcommon.TraceID([16]byte{1}))
+	span.SetSpanID(pcommon.SpanID([8]byte{1}))
+	span.SetName("bake")
+
+	ingredients := span.Attributes().PutEmptySlice("pie.ingredients")
+	ingredients.AppendEmpty().SetStr("pumpkin")
+	ingredients.AppendEmpty().SetStr("cinnamon")
+
+	oven := span.Attributes().PutEmptyMap("pie.oven")
+	oven.PutInt("temperature", 180)
+	rack := oven.PutEmptySlice("racks").AppendEmpty().SetEmptyMap()
+	rack.PutStr("position", "middle")
+	rack.PutEmptySlice("pies").AppendEmpty().SetDouble(1.5)
+
+	payload, err := ptraceotlp.NewExportRequestFromTraces(traces).MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/"+pcommon.TraceID([16]byte{1}).String()))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Compare the attributes as plain JSON, the way the UI sees them
+	trace := struct {
+		Spans []struct {
+			Attributes map[string]any `json:"attributes"`
+		} `json:"spans"`
+	}{}
+	err = json.NewDecoder(res.Body).Decode(&trace)
+	assert.Nilf(t, err, "could not decode trace: %v", err)
+
+	expected := map[string]any{}
+	err = json.Unmarshal([]byte(`{
+		"pie.ingredients": ["pumpkin", "cinnamon"],
+		"pie.oven": {"temperature": 180, "racks": [{"position": "middle", "pies": [1.5]}]}
+	}`), &expected)
+	assert.Nilf(t, err, "could not unmarshal expected attributes: %v", err)
+
+	if assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, expected, trace.Spans[0].Attributes)
+	}
+}
+
+func TestTraceStatsHandler(t *testing.T) {
+	testServer, teardown := setupWithTrace(t)
+	defer teardown(t)
+
+	t.Run("Trace Stats Handler (Not Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/987654321/stats"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Trace Stats Handler (ID Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/1234567890/stats"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		stats := telemetry.TraceStats{}
+		err = json.NewDecoder(res.Body).Decode(&stats)
+		assert.Nilf(t, err, "could not decode trace stats: %v", err)
+
+		assert.Equal(t, "00000000000000000000001234567890", stats.TraceID)
+		assert.Equal(t, 1, stats.SpanCount)
+		assert.Equal(t, 1, stats.MaxDepth)
+		assert.Equal(t, stats.DurationNanos, stats.SelfDurationNanos)
+	})
+}
+
+func TestTraceIDHandlerSpanPages(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	pageTests := []struct {
+		query              string
+		expectedStatus     int
+		expectedSpans      int
+		expectedTotalSpans int
+	}{
+		{"", http.StatusOK, 3, 0},
+		{"?spanLimit=1", http.StatusOK, 1, 3},
+		{"?spanLimit=2&spanOffset=2", http.StatusOK, 1, 3},
+		{"?spanOffset=1", http.StatusOK, 2, 3},
+		{"?spanLimit=-1", http.StatusBadRequest, 0, 0},
+		{"?spanOffset=first", http.StatusBadRequest, 0, 0},
+		{"?kind=CLIENT", http.StatusOK, 2, 3},
+		{"?kind=server&kind=client", http.StatusOK, 3, 3},
+		{"?kind=sideways", http.StatusBadRequest, 0, 0},
+		{"?kind=CLIENT&spanLimit=1", http.StatusBadRequest, 0, 0},
+		{"?maxSpans=2", http.StatusOK, 2, 0},
+		{"?maxSpans=3", http.StatusOK, 3, 0},
+		{"?maxSpans=2&spanOffset=1", http.StatusBadRequest, 0, 0},
+	}
+
+	for _, test := range pageTests {
+		t.Run(fmt.Sprintf("Trace ID Handler (%s)", test.query), func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4", test.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, test.expectedStatus, res.StatusCode)
+			if test.expectedStatus != http.StatusOK {
+				return
+			}
+
+			trace := telemetry.TraceData{}
+			err = json.NewDecoder(res.Body).Decode(&trace)
+			assert.Nilf(t, err, "could not decode trace data: %v", err)
+			assert.Len(t, trace.Spans, test.expectedSpans)
+			assert.Equal(t, test.expectedTotalSpans, trace.TotalSpans)
+			if strings.Contains(test.query, "spanLimit") && !strings.Contains(test.query, "spanOffset") {
+				assert.Equal(t, "37fd1349bf83d330", trace.Spans[0].SpanID, "the root span should be on the first page")
+			}
+
+			// Self times are only worked out when the whole trace is there to subtract children
+			wholeTrace := !strings.Contains(test.query, "span") && !strings.Contains(test.query, "kind")
+			for _, span := range trace.Spans {
+				assert.Equal(t, wholeTrace, span.SelfDurationNanos != nil, span.SpanID)
+			}
+		})
+	}
+
+	t.Run("Trace ID Handler (Not Found)", func(t *testing.T) {
+		// Whole, paged and kind filtered lookups all tell a missing trace apart from a bad request
+		for _, query := range []string{"", "?spanLimit=10", "?kind=CLIENT"} {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/987654321", query))
+			if assert.Nilf(t, err, "could not send GET request: %v", err) {
+				res.Body.Close()
+				assert.Equal(t, http.StatusNotFound, res.StatusCode, query)
+			}
+		}
+	})
+}
+
+func TestTraceIDHandlerTraceparent(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name           string
+		id             string
+		expectedStatus int
+	}{
+		{"Bare Trace ID", "42957c7c2fca940a0d32a0cdd38c06a4", http.StatusOK},
+		{"Traceparent", "00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01", http.StatusOK},
+		{"Invalid Format", "00-42957c7c2fca940a0d32a0cdd38c06a4", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/", tt.id))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				b, err := io.ReadAll(res.Body)
+				assert.Nilf(t, err, "could not read response body: %v", err)
+				assert.Contains(t, string(b), "traceparent")
+				return
+			}
+
+			trace := telemetry.TraceData{}
+			err = json.NewDecoder(res.Body).Decode(&trace)
+			assert.Nilf(t, err, "could not decode trace data: %v", err)
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", trace.TraceID)
+			assert.Len(t, trace.Spans, 3)
+		})
+	}
+}
+
+func TestTraceIDHandlerShortIDs(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.TraceID = "0d32a0cdd38c06a4"
+	span.SpanID = "37fd1349bf83d330"
+	span.ParentSpanID = ""
+	err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{span})
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	for _, path := range []string{
+		"/api/traces/0d32a0cdd38c06a4",
+		"/api/traces/0D32A0CDD38C06A4",
+		"/api/traces/00000000000000000d32a0cdd38c06a4",
+		"/api/traces/00-00000000000000000d32a0cdd38c06a4-37fd1349bf83d330-01",
+		"/api/traces/0d32a0cdd38c06a4/spans/37fd1349bf83d330",
+		"/api/traces/0d32a0cdd38c06a4/stats",
+	} {
+		t.Run(fmt.Sprintf("Trace ID Handler (%s)", path), func(t *testing.T) {
+			res, err := http.Get(testServer.URL + path)
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+
+			body := struct {
+				TraceID string `json:"traceID"`
+			}{}
+			err = json.NewDecoder(res.Body).Decode(&body)
+			assert.Nilf(t, err, "could not decode response: %v", err)
+			assert.Equal(t, "00000000000000000d32a0cdd38c06a4", body.TraceID)
+		})
+	}
+}
+
+func TestTraceIDHandlerResolveLinks(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	linkingSpan := telemetry.SpanData{
+		TraceID:    "1234567890",
+		SpanID:     "12345",
+		Name:       "linking",
+		StartTime:  time.Now(),
+		EndTime:    time.Now().Add(time.Second),
+		Attributes: map[string]interface{}{},
+		Events:     []telemetry.EventData{},
+		Links: []telemetry.LinkData{
+			{TraceID: "42957c7c2fca940a0d32a0cdd38c06a4", SpanID: "37fd1349bf83d330", Attributes: map[string]any{}},
+			{TraceID: "987654321", SpanID: "54321", Attributes: map[string]any{}},
+		},
+		Resource: &telemetry.ResourceData{Attributes: map[string]any{"service.name": "pumpkin.pie"}},
+		Scope:    &telemetry.ScopeData{Attributes: map[string]any{}},
+	}
+	err = server.Store.AddSpans(context.Background(), []telemetry.SpanData{linkingSpan})
+	assert.Nilf(t, err, "could not add span: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	getLinks := func(t *testing.T, query string) []telemetry.LinkData {
+		res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/1234567890", query))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		trace := telemetry.TraceData{}
+		err = json.NewDecoder(res.Body).Decode(&trace)
+		assert.Nilf(t, err, "could not decode trace data: %v", err)
+		if !assert.Len(t, trace.Spans, 1) {
+			return nil
+		}
+		return trace.Spans[0].Links
+	}
+
+	t.Run("Links Left Alone", func(t *testing.T) {
+		links := getLinks(t, "")
+		if assert.Len(t, links, 2) {
+			assert.Nil(t, links[0].Resolved)
+			assert.Empty(t, links[0].RootServiceName)
+			assert.Nil(t, links[1].Resolved)
+		}
+	})
+
+	t.Run("Links Resolved", func(t *testing.T) {
+		links := getLinks(t, "?resolveLinks=true")
+		if assert.Len(t, links, 2) {
+			if assert.NotNil(t, links[0].Resolved) {
+				assert.True(t, *links[0].Resolved)
+			}
+			assert.Equal(t, "sample-loadgenerator", links[0].RootServiceName)
+			assert.Equal(t, "SAMPLE HTTP POST", links[0].RootName)
+
+			if assert.NotNil(t, links[1].Resolved) {
+				assert.False(t, *links[1].Resolved)
+			}
+			assert.Empty(t, links[1].RootServiceName)
+			assert.Empty(t, links[1].RootName)
+		}
+	})
+
+	t.Run("Invalid Parameter", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/1234567890?resolveLinks=maybe"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestTraceTreeHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	t.Run("Trace Tree Handler (Not Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/987654321/tree"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Trace Tree Handler (ID Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/tree"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		tree := telemetry.SpanTree{}
+		err = json.NewDecoder(res.Body).Decode(&tree)
+		assert.Nilf(t, err, "could not decode span tree: %v", err)
+
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", tree.TraceID)
+		if assert.Len(t, tree.Roots, 1) {
+			root := tree.Roots[0]
+			assert.Equal(t, "37fd1349bf83d330", root.SpanID)
+			assert.Equal(t, "sample-loadgenerator", root.Span.GetServiceName())
+
+			// Every span is somewhere under the root
+			count := 0
+			nodes := []*telemetry.SpanNode{root}
+			for len(nodes) > 0 {
+				node := nodes[0]
+				nodes = append(nodes[1:], node.Children...)
+				count++
+			}
+			assert.Equal(t, 3, count)
+		}
+	})
+}
+
+func TestTraceTreeHandlerDetachedSpans(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	// The parent of one span is in another trace, and that of another never arrived
+	root := telemetry.NewSampleTelemetry().Spans[0]
+	root.TraceID = "00000000000000000000000000000001"
+	root.SpanID = "0000000000000001"
+	root.ParentSpanID = ""
+	elsewhere := root
+	elsewhere.TraceID = "00000000000000000000000000000002"
+	elsewhere.SpanID = "0000000000000002"
+	crossed := root
+	crossed.SpanID = "0000000000000003"
+	crossed.ParentSpanID = elsewhere.SpanID
+	orphan := root
+	orphan.SpanID = "0000000000000004"
+	orphan.ParentSpanID = "0000000000000009"
+	orphan.StartTime = crossed.StartTime.Add(time.Millisecond)
+
+	err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{root, elsewhere, crossed, orphan})
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	t.Run("Tree", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/tree", testServer.URL, root.TraceID))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		tree := telemetry.SpanTree{}
+		err = json.NewDecoder(res.Body).Decode(&tree)
+		assert.Nilf(t, err, "could not decode span tree: %v", err)
+
+		if assert.Len(t, tree.Roots, 3) && assert.Len(t, tree.Roots[1].Children, 1) && assert.Len(t, tree.Roots[2].Children, 1) {
+			assert.Empty(t, tree.Roots[0].Detached)
+			assert.Equal(t, elsewhere.TraceID, tree.Roots[1].TraceID)
+			assert.Equal(t, telemetry.DetachedParentInOtherTrace, tree.Roots[1].Children[0].Detached)
+			assert.Empty(t, tree.Roots[2].TraceID)
+			assert.Equal(t, telemetry.DetachedMissingParent, tree.Roots[2].Children[0].Detached)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/stats", testServer.URL, root.TraceID))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		stats := telemetry.TraceStats{}
+		err = json.NewDecoder(res.Body).Decode(&stats)
+		assert.Nilf(t, err, "could not decode trace stats: %v", err)
+		assert.Equal(t, 2, stats.DetachedSpanCount)
+		assert.Equal(t, map[string]int{
+			telemetry.DetachedMissingParent:      1,
+			telemetry.DetachedParentInOtherTrace: 1,
+		}, stats.DetachedSpansByReason)
+	})
+}
+
+func TestTraceFlameGraphHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	t.Run("Trace Flame Graph Handler (Not Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/987654321/flamegraph"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Trace Flame Graph Handler (ID Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/flamegraph"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		flameGraph := telemetry.FlameGraph{}
+		err = json.NewDecoder(res.Body).Decode(&flameGraph)
+		assert.Nilf(t, err, "could not decode flame graph: %v", err)
+
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", flameGraph.TraceID)
+		if assert.Len(t, flameGraph.Frames, 1) {
+			// Every span in the sample trace is named the same, so they all fold into the root's frame
+			root := flameGraph.Frames[0]
+			assert.Equal(t, "SAMPLE HTTP POST", root.Name)
+			assert.Equal(t, 3, root.SpanCount)
+			assert.Empty(t, root.Children)
+			assert.Equal(t, root.SelfDurationNanos, root.TotalDurationNanos)
+		}
+	})
+}
+
+func TestTraceGapsHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	// A trace whose root waits 300ms before a first call, then 100ms before a second
+	root := telemetry.NewSampleTelemetry().Spans[0]
+	root.TraceID = "00000000000000000000000000000001"
+	root.SpanID = "0000000000000001"
+	root.ParentSpanID = ""
+	root.StartTime = time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	root.EndTime = root.StartTime.Add(20 * time.Millisecond)
+	first := root
+	first.SpanID = "0000000000000002"
+	first.StartTime = root.EndTime.Add(300 * time.Millisecond)
+	first.EndTime = first.StartTime.Add(50 * time.Millisecond)
+	second := root
+	second.SpanID = "0000000000000003"
+	second.StartTime = first.EndTime.Add(100 * time.Millisecond)
+	second.EndTime = second.StartTime.Add(50 * time.Millisecond)
+
+	err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{root, first, second})
+	assert.Nilf(t, err, "could not add spans: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name            string
+		query           string
+		expectedStatus  int
+		expectedSpanIDs [][2]string
+	}{
+		{name: "Every Gap", query: "", expectedStatus: http.StatusOK, expectedSpanIDs: [][2]string{{root.SpanID, first.SpanID}, {first.SpanID, second.SpanID}}},
+		{name: "Min Duration", query: "?minDuration=200ms", expectedStatus: http.StatusOK, expectedSpanIDs: [][2]string{{root.SpanID, first.SpanID}}},
+		{name: "Invalid Min Duration", query: "?minDuration=long", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/gaps%s", testServer.URL, root.TraceID, tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			gaps := telemetry.TraceGaps{}
+			err = json.NewDecoder(res.Body).Decode(&gaps)
+			assert.Nilf(t, err, "could not decode trace gaps: %v", err)
+
+			assert.Equal(t, root.TraceID, gaps.TraceID)
+			assert.Equal(t, (520 * time.Millisecond).Nanoseconds(), gaps.DurationNanos)
+			assert.Equal(t, (400 * time.Millisecond).Nanoseconds(), gaps.IdleNanos)
+			spanIDs := [][2]string{}
+			for _, gap := range gaps.Gaps {
+				spanIDs = append(spanIDs, [2]string{gap.AfterSpanID, gap.BeforeSpanID})
+			}
+			assert.Equal(t, tt.expectedSpanIDs, spanIDs)
+		})
+	}
+
+	t.Run("Not Found", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/987654321/gaps"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestTraceCriticalPathHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	t.Run("Critical Path Handler", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/critical-path"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		criticalPath := telemetry.CriticalPath{}
+		err = json.NewDecoder(res.Body).Decode(&criticalPath)
+		assert.Nilf(t, err, "could not decode critical path: %v", err)
+
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", criticalPath.TraceID)
+		if assert.NotEmpty(t, criticalPath.Spans) {
+			root := criticalPath.Spans[0]
+			assert.Empty(t, root.ParentSpanID)
+			assert.Equal(t, "sample-loadgenerator", root.ServiceName)
+			assert.Equal(t, root.DurationNanos, criticalPath.DurationNanos)
+		}
+
+		// Each span after the root is waited on by one before it, and the path adds up to the root
+		var total int64
+		onPath := map[string]bool{}
+		for i, span := range criticalPath.Spans {
+			if i > 0 {
+				assert.True(t, onPath[span.ParentSpanID], span.SpanID)
+			}
+			onPath[span.SpanID] = true
+			total += span.CriticalDurationNanos
+		}
+		assert.Equal(t, criticalPath.DurationNanos, total)
+	})
+
+	t.Run("Critical Path Handler (Not Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/987654321/critical-path"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+}
+
+func TestSampleDataSets(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	getSummaries := func(t *testing.T) telemetry.TraceSummaries {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		testSummaries := telemetry.TraceSummaries{}
+		err = json.NewDecoder(res.Body).Decode(&testSummaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		return testSummaries
+	}
+
+	t.Run("Sample Data List", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData/list"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		list := telemetry.SampleSetList{}
+		err = json.NewDecoder(res.Body).Decode(&list)
+		assert.Nilf(t, err, "could not decode sample sets: %v", err)
+
+		names := []string{}
+		for _, set := range list.Sets {
+			names = append(names, set.Name)
+			assert.NotEmpty(t, set.Description)
+		}
+		assert.Equal(t, []string{"default", "microservices", "errors", "deep-trace"}, names)
+	})
+
+	t.Run("Sample Data (Unknown Set)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData?set=nonsense"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+		assert.Empty(t, getSummaries(t).TraceSummaries)
+	})
+
+	t.Run("Sample Data (Errors Set)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData?set=errors"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		summaries := getSummaries(t)
+		assert.Len(t, summaries.TraceSummaries, 2)
+		for _, summary := range summaries.TraceSummaries {
+			assert.NotEqual(t, "42957c7c2fca940a0d32a0cdd38c06a4", summary.TraceID)
+		}
+	})
+
+	t.Run("Sample Data (Default Set)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData?set=default"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Len(t, getSummaries(t).TraceSummaries, 4)
+	})
+}
+
+func TestConditionalGet(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	get := func(t *testing.T, path string, etag string) (int, string) {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, path), nil)
+		assert.Nilf(t, err, "could not create GET request: %v", err)
+		if etag != "" {
+			request.Header.Set("If-None-Match", etag)
+		}
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		if res.StatusCode == http.StatusNotModified {
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+			assert.Empty(t, b)
+		}
+		return res.StatusCode, res.Header.Get("ETag")
+	}
+
+	tracePath := "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"
+	status, etag := get(t, "/api/traces", "")
+	assert.Equal(t, http.StatusOK, status)
+	if !assert.NotEmpty(t, etag) {
+		return
+	}
+
+	t.Run("Unchanged Data", func(t *testing.T) {
+		for _, path := range []string{"/api/traces", "/api/traces?limit=1", tracePath} {
+			status, unchanged := get(t, path, etag)
+			assert.Equal(t, http.StatusNotModified, status, path)
+			assert.Equal(t, etag, unchanged, path)
+		}
+
+		// Any of a list of ETags will do, and so will a strong copy of the weak one
+		status, _ := get(t, "/api/traces", `"stale", `+etag)
+		assert.Equal(t, http.StatusNotModified, status)
+		status, _ = get(t, "/api/traces", strings.TrimPrefix(etag, "W/"))
+		assert.Equal(t, http.StatusNotModified, status)
+	})
+
+	t.Run("Stale ETag", func(t *testing.T) {
+		status, _ := get(t, tracePath, `W/"stale"`)
+		assert.Equal(t, http.StatusOK, status)
+	})
+
+	t.Run("After Writing Spans", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData?set=errors"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		status, written := get(t, tracePath, etag)
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotEqual(t, etag, written)
+		etag = written
+	})
+
+	t.Run("After Clearing Data", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/clearData"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		status, cleared := get(t, "/api/traces", etag)
+		assert.Equal(t, http.StatusOK, status)
+		assert.NotEqual(t, etag, cleared)
+	})
+}
+
+func TestAnnotationsHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	traceID := "42957c7c2fca940a0d32a0cdd38c06a4"
+	putAnnotations := func(t *testing.T, traceID string, body string) int {
+		request, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/api/traces/%s/annotations", testServer.URL, traceID), strings.NewReader(body))
+		assert.Nilf(t, err, "could not create PUT request: %v", err)
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send PUT request: %v", err)
+		defer res.Body.Close()
+		return res.StatusCode
+	}
+	getAnnotations := func(t *testing.T, traceID string) (int, telemetry.TraceAnnotations) {
+		res, err := http.Get(fmt.Sprintf("%s/api/traces/%s/annotations", testServer.URL, traceID))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		annotations := telemetry.TraceAnnotations{}
+		if res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&annotations)
+			assert.Nilf(t, err, "could not decode annotations: %v", err)
+		}
+		return res.StatusCode, annotations
+	}
+
+	t.Run("Annotations Handler (None Yet)", func(t *testing.T) {
+		status, annotations := getAnnotations(t, traceID)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, telemetry.TraceAnnotations{TraceID: traceID, Annotations: telemetry.Annotations{}}, annotations)
+	})
+
+	t.Run("Annotations Handler (Set)", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, putAnnotations(t, traceID, `{"status": "investigated", "ticket": 123}`))
+
+		status, annotations := getAnnotations(t, traceID)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, telemetry.Annotations{"status": "investigated", "ticket": float64(123)}, annotations.Annotations)
+
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		summaries := telemetry.TraceSummaries{}
+		err = json.NewDecoder(res.Body).Decode(&summaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		for _, summary := range summaries.TraceSummaries {
+			if summary.TraceID == traceID {
+				assert.Equal(t, annotations.Annotations, summary.Annotations)
+			} else {
+				assert.Empty(t, summary.Annotations)
+			}
+		}
+	})
+
+	t.Run("Annotations Handler (Invalid)", func(t *testing.T) {
+		for _, body := range []string{"", "not json", `["a", "list"]`} {
+			assert.Equal(t, http.StatusBadRequest, putAnnotations(t, traceID, body), body)
+		}
+	})
+
+	t.Run("Annotations Handler (Not Found)", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, putAnnotations(t, "987654321", `{"status": "investigated"}`))
+		status, _ := getAnnotations(t, "987654321")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+
+	t.Run("Annotations Handler (Trace Deleted)", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodDelete, fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/", traceID), nil)
+		assert.Nilf(t, err, "could not create DELETE request: %v", err)
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send DELETE request: %v", err)
+		res.Body.Close()
+
+		status, _ := getAnnotations(t, traceID)
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestPinHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	traceID := "42957c7c2fca940a0d32a0cdd38c06a4"
+	setPinned := func(t *testing.T, method string, traceID string) (int, telemetry.TracePin) {
+		request, err := http.NewRequest(method, fmt.Sprintf("%s/api/traces/%s/pin", testServer.URL, traceID), nil)
+		assert.Nilf(t, err, "could not create %s request: %v", method, err)
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send %s request: %v", method, err)
+		defer res.Body.Close()
+
+		pin := telemetry.TracePin{}
+		if res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&pin)
+			assert.Nilf(t, err, "could not decode pin: %v", err)
+		}
+		return res.StatusCode, pin
+	}
+	getPinned := func(t *testing.T) map[string]bool {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		summaries := telemetry.TraceSummaries{}
+		err = json.NewDecoder(res.Body).Decode(&summaries)
+		assert.Nilf(t, err, "could not decode trace summaries: %v", err)
+		pins := map[string]bool{}
+		for _, summary := range summaries.TraceSummaries {
+			pins[summary.TraceID] = summary.Pinned
+		}
+		return pins
+	}
+
+	t.Run("Pin Handler (Pin)", func(t *testing.T) {
+		status, pin := setPinned(t, http.MethodPost, traceID)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, telemetry.TracePin{TraceID: traceID, Pinned: true}, pin)
+
+		pins := getPinned(t)
+		assert.Len(t, pins, 2)
+		for id, pinned := range pins {
+			assert.Equal(t, id == traceID, pinned, id)
+		}
+	})
+
+	t.Run("Pin Handler (Unpin)", func(t *testing.T) {
+		status, pin := setPinned(t, http.MethodDelete, traceID)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Equal(t, telemetry.TracePin{TraceID: traceID, Pinned: false}, pin)
+		assert.False(t, getPinned(t)[traceID])
+	})
+
+	t.Run("Pin Handler (Not Found)", func(t *testing.T) {
+		status, _ := setPinned(t, http.MethodPost, "987654321")
+		assert.Equal(t, http.StatusNotFound, status)
+		status, _ = setPinned(t, http.MethodDelete, "987654321")
+		assert.Equal(t, http.StatusNotFound, status)
+	})
+}
+
+func TestSpanHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	t.Run("Span Handler (Not Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/spans/987654321"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Span Handler (ID Found)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/spans/37fd1349bf83d330"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		span := telemetry.SpanData{}
+		err = json.NewDecoder(res.Body).Decode(&span)
+		assert.Nilf(t, err, "could not decode span data: %v", err)
+
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", span.TraceID)
+		assert.Equal(t, "37fd1349bf83d330", span.SpanID)
+		assert.Equal(t, "SAMPLE HTTP POST", span.Name)
+	})
+}
+
+func TestTraceSearchHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name            string
+		path            string
+		expectedStatus  int
+		expectedSpanIDs []string
+	}{
+		{name: "Name", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search?q=http+post", expectedStatus: http.StatusOK, expectedSpanIDs: []string{"37fd1349bf83d330", "a24ac1588d52a6fc", "355dc9bea1ec64d8"}},
+		{name: "Attribute Value", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search?q=PYTHON-REQUESTS", expectedStatus: http.StatusOK, expectedSpanIDs: []string{"355dc9bea1ec64d8"}},
+		{name: "Number Attribute Value", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search?q=102", expectedStatus: http.StatusOK, expectedSpanIDs: []string{"355dc9bea1ec64d8"}},
+		{name: "Other Traces Left Out", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search?q=currency", expectedStatus: http.StatusOK, expectedSpanIDs: []string{}},
+		{name: "Wildcards Are Literal", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search?q=%25", expectedStatus: http.StatusOK, expectedSpanIDs: []string{}},
+		{name: "Missing Query", path: "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4/search", expectedStatus: http.StatusBadRequest},
+		{name: "Unknown Trace", path: "/api/traces/987654321/search?q=post", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("Trace Search Handler (%s)", tt.name), func(t *testing.T) {
+			res, err := http.Get(testServer.URL + tt.path)
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			matches := telemetry.SpanMatches{}
+			err = json.NewDecoder(res.Body).Decode(&matches)
+			assert.Nilf(t, err, "could not decode span matches: %v", err)
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", matches.TraceID)
+			assert.Equal(t, tt.expectedSpanIDs, matches.SpanIDs)
+			assert.Equal(t, len(tt.expectedSpanIDs), matches.Count)
+		})
+	}
+}
+
+func TestCompareTracesHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+	}{
+		{
+			name:           "Missing Trace ID",
+			query:          "?a=42957c7c2fca940a0d32a0cdd38c06a4",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Invalid Trace ID",
+			query:          "?a=42957c7c2fca940a0d32a0cdd38c06a4&b=not-a-trace",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Not Found",
+			query:          "?a=42957c7c2fca940a0d32a0cdd38c06a4&b=987654321",
+			expectedStatus: http.StatusNotFound,
+		},
+		{
+			name:           "Both Found",
+			query:          "?a=42957c7c2fca940a0d32a0cdd38c06a4&b=7979cec4d1c04222fa9a3c7c97c0a99c",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Traceparent",
+			query:          "?a=00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01&b=7979cec4d1c04222fa9a3c7c97c0a99c",
+			expectedStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/compare", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			comparison := telemetry.TraceComparison{}
+			err = json.NewDecoder(res.Body).Decode(&comparison)
+			assert.Nilf(t, err, "could not decode trace comparison: %v", err)
+
+			// The sample traces share no span names, so nothing lines up
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", comparison.TraceIDA)
+			assert.Empty(t, comparison.Matches)
+			assert.Len(t, comparison.OnlyInA, 3)
+			assert.Len(t, comparison.OnlyInB, 1)
+			assert.Len(t, comparison.Operations, 2)
+		})
+	}
+}
+
+func TestDependenciesHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name                 string
+		query                string
+		expectedStatus       int
+		expectedServices     []string
+		expectedDependencies []telemetry.ServiceDependency
+	}{
+		{
+			name:             "All Traces",
+			query:            "",
+			expectedStatus:   http.StatusOK,
+			expectedServices: []string{"sample-frontend", "sample-loadgenerator", "sample.currencyservice"},
+			expectedDependencies: []telemetry.ServiceDependency{
+				{Parent: "sample-loadgenerator", Child: "sample-frontend", CallCount: 1, ErrorCount: 0},
+			},
+		},
+		{
+			name:                 "Before The Sample Data",
+			query:                "?end=2023-01-01T00:00:00Z",
+			expectedStatus:       http.StatusOK,
+			expectedServices:     []string{},
+			expectedDependencies: []telemetry.ServiceDependency{},
+		},
+		{name: "Invalid End", query: "?end=tomorrow", expectedStatus: http.StatusBadRequest},
+		{name: "Start After End", query: "?start=2024-01-02T00:00:00Z&end=2024-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/dependencies", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			dependencies := telemetry.ServiceDependencies{}
+			err = json.NewDecoder(res.Body).Decode(&dependencies)
+			assert.Nilf(t, err, "could not decode service dependencies: %v", err)
+			assert.Equal(t, tt.expectedDependencies, dependencies.Dependencies)
+
+			services := []string{}
+			for _, node := range dependencies.Services {
+				services = append(services, node.ServiceName)
+				assert.Positive(t, node.SpanCount)
+				assert.LessOrEqual(t, node.ErrorRate, 1.0)
+			}
+			assert.Equal(t, tt.expectedServices, services)
+		})
+	}
+}
+
+func TestOperationStatsHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	tests := []struct {
+		name               string
+		query              string
+		expectedStatus     int
+		expectedOperations int
+	}{
+		{name: "All Operations", query: "", expectedStatus: http.StatusOK, expectedOperations: 2},
+		{name: "Grouped By Service", query: "?groupByService=true&service=sample-loadgenerator", expectedStatus: http.StatusOK, expectedOperations: 1},
+		{name: "Before The Sample Data", query: "?end=2023-01-01T00:00:00Z", expectedStatus: http.StatusOK, expectedOperations: 0},
+		{name: "Invalid Group By", query: "?groupByService=maybe", expectedStatus: http.StatusBadRequest},
+		{name: "Start After End", query: "?start=2024-01-02T00:00:00Z&end=2024-01-01T00:00:00Z", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/operations/stats", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			operations := telemetry.OperationStatsList{}
+			err = json.NewDecoder(res.Body).Decode(&operations)
+			assert.Nilf(t, err, "could not decode operation stats: %v", err)
+			assert.Len(t, operations.Operations, tt.expectedOperations)
+		})
+	}
+
+	t.Run("Sample Operation", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/operations/stats?groupByService=true&service=sample-loadgenerator"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		operations := telemetry.OperationStatsList{}
+		err = json.NewDecoder(res.Body).Decode(&operations)
+		assert.Nilf(t, err, "could not decode operation stats: %v", err)
+		if assert.Len(t, operations.Operations, 1) {
+			operation := operations.Operations[0]
+			assert.Equal(t, "sample-loadgenerator", operation.ServiceName)
+			assert.Equal(t, "SAMPLE HTTP POST", operation.Name)
+			assert.Equal(t, uint32(2), operation.SpanCount)
+			assert.LessOrEqual(t, operation.P50DurationNanos, operation.P99DurationNanos)
+			assert.LessOrEqual(t, operation.P99DurationNanos, operation.MaxDurationNanos)
+		}
+	})
+}
+
+func TestTraceSizesHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	// The sample data holds a trace of one span and a trace of three
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCounts []int
+	}{
+		{name: "All Traces", query: "", expectedStatus: http.StatusOK, expectedCounts: []int{1, 1, 0, 0, 0}},
+		{name: "By Service", query: "?service=sample-frontend", expectedStatus: http.StatusOK, expectedCounts: []int{0, 1, 0, 0, 0}},
+		{name: "Before The Sample Data", query: "?end=2023-01-01T00:00:00Z", expectedStatus: http.StatusOK, expectedCounts: []int{0, 0, 0, 0, 0}},
+		{name: "Invalid Start", query: "?start=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/stats/trace-sizes", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			histogram := telemetry.TraceSizeHistogram{}
+			err = json.NewDecoder(res.Body).Decode(&histogram)
+			assert.Nilf(t, err, "could not decode trace sizes: %v", err)
+
+			counts := []int{}
+			for _, bucket := range histogram.Buckets {
+				counts = append(counts, bucket.TraceCount)
+			}
+			assert.Equal(t, tt.expectedCounts, counts)
+		})
+	}
+}
+
+func TestTraceVolumeHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Store.Close()
+	server.now = func() time.Time { return time.Date(2023, 02, 02, 18, 20, 0, 0, time.UTC) }
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	// Of the two sample traces, only the one started at 18:17:54 is within a day of now
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedCounts []int
+	}{
+		{name: "Defaults", query: "", expectedStatus: http.StatusOK, expectedCounts: append(append(make([]int, 56), 1), 0, 0, 0)},
+		{name: "Bucket And Window", query: "?bucket=10m&window=30m", expectedStatus: http.StatusOK, expectedCounts: []int{0, 1, 0}},
+		{name: "Window Of A Day", query: "?bucket=6h&window=24h", expectedStatus: http.StatusOK, expectedCounts: []int{0, 0, 0, 1}},
+		{name: "End", query: "?bucket=10m&window=30m&end=2023-02-02T18:15:00Z", expectedStatus: http.StatusOK, expectedCounts: []int{0, 0, 1}},
+		{name: "By Service", query: "?bucket=10m&window=30m&service=sample.currencyservice", expectedStatus: http.StatusOK, expectedCounts: []int{0, 0, 0}},
+		{name: "Bucket Longer Than Window", query: "?bucket=2h", expectedStatus: http.StatusBadRequest},
+		{name: "Too Many Buckets", query: "?bucket=1s", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid Bucket", query: "?bucket=soon", expectedStatus: http.StatusBadRequest},
+		{name: "Invalid End", query: "?end=yesterday", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/stats/volume", tt.query))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			defer res.Body.Close()
+			assert.Equal(t, tt.expectedStatus, res.StatusCode)
+			if tt.expectedStatus != http.StatusOK {
+				return
+			}
+
+			volume := telemetry.TraceVolume{}
+			err = json.NewDecoder(res.Body).Decode(&volume)
+			assert.Nilf(t, err, "could not decode trace volume: %v", err)
+
+			counts := []int{}
+			for _, bucket := range volume.Buckets {
+				counts = append(counts, bucket.TraceCount)
+			}
+			assert.Equal(t, tt.expectedCounts, counts)
+		})
+	}
+}
+
+func TestStatusHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Store.Close()
+	started := server.started
+	server.now = func() time.Time { return started.Add(90 * time.Second) }
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/status"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	status := telemetry.Status{}
+	err = json.NewDecoder(res.Body).Decode(&status)
+	assert.Nilf(t, err, "could not decode status: %v", err)
+	assert.Equal(t, 2, status.TraceCount)
+	assert.Equal(t, 4, status.SpanCount)
+	assert.NotNil(t, status.OldestSpan)
+	assert.NotNil(t, status.NewestSpan)
+	assert.Equal(t, store.ModeMemory, status.Mode)
+	assert.Positive(t, status.SchemaVersion)
+	assert.True(t, started.Equal(status.StartedAt))
+	assert.Equal(t, (90 * time.Second).Nanoseconds(), status.UptimeNanos)
+}
+
+func TestStreamHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	subscriberCount := func() int {
+		server.hub.mut.Lock()
+		defer server.hub.mut.Unlock()
+		return len(server.hub.subscribers)
+	}
+
+	t.Run("Stream Handler (New Spans)", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/api/stream"), nil)
+		assert.Nilf(t, err, "could not create GET request: %v", err)
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+		// The response headers are only sent once we're subscribed
+		spans := telemetry.NewSampleTelemetry().Spans
+		err = server.Store.AddSpans(context.Background(), spans[1:])
+		assert.Nilf(t, err, "could not add spans: %v", err)
+		err = server.Store.Flush(context.Background())
+		assert.Nilf(t, err, "could not flush spans: %v", err)
+
+		reader := bufio.NewReader(res.Body)
+		event, err := reader.ReadString('\n')
+		assert.Nilf(t, err, "could not read event: %v", err)
+		assert.Equal(t, "event: trace\n", event)
+
+		data, err := reader.ReadString('\n')
+		assert.Nilf(t, err, "could not read event data: %v", err)
+		summary := telemetry.TraceSummary{}
+		err = json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &summary)
+		assert.Nilf(t, err, "could not unmarshal trace summary: %v", err)
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", summary.TraceID)
+		assert.Equal(t, uint32(3), summary.SpanCount)
+
+		// Disconnecting unsubscribes
+		cancel()
+		res.Body.Close()
+		assert.Eventually(t, func() bool { return subscriberCount() == 0 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Stream Handler (Slow Subscriber)", func(t *testing.T) {
+		// A subscriber that never reads doesn't hold up ingestion, it just misses summaries
+		summaries := server.hub.subscribe(store.SummaryQuery{})
+		defer server.hub.unsubscribe(summaries)
+
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		for i := 0; i < 2*subscriberBufferSize; i++ {
+			span.TraceID = fmt.Sprintf("%032x", i+1)
+			err := server.Store.AddSpans(context.Background(), []telemetry.SpanData{span})
+			assert.Nilf(t, err, "could not add spans: %v", err)
+			err = server.Store.Flush(context.Background())
+			assert.Nilf(t, err, "could not flush spans: %v", err)
+		}
+
+		assert.Eventually(t, func() bool { return len(summaries) == subscriberBufferSize }, time.Second, 10*time.Millisecond)
+		assert.Never(t, func() bool { return len(summaries) > subscriberBufferSize }, 50*time.Millisecond, 10*time.Millisecond)
+	})
+
+	t.Run("Stream Handler (Server Closed)", func(t *testing.T) {
+		summaries := server.hub.subscribe(store.SummaryQuery{})
+		server.Close()
+
+		_, ok := <-summaries
+		assert.False(t, ok, "closing the hub should end every stream")
+		_, ok = <-server.hub.subscribe(store.SummaryQuery{})
+		assert.False(t, ok, "streams opened after the hub closed should end straight away")
+	})
+}
+
+func TestFollowHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	follow := func(t *testing.T, query string) (int, telemetry.FollowedTraces) {
+		res, err := http.Get(fmt.Sprintf("%s%s%s", testServer.URL, "/api/traces/since?", query))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		followed := telemetry.FollowedTraces{}
+		if res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&followed)
+			assert.Nilf(t, err, "could not decode followed traces: %v", err)
+		}
+		return res.StatusCode, followed
+	}
+	addSpans := func(t *testing.T, spans []telemetry.SpanData) {
+		err := server.Store.AddSpans(context.Background(), spans)
+		assert.Nilf(t, err, "could not add spans: %v", err)
+		err = server.Store.Flush(context.Background())
+		assert.Nilf(t, err, "could not flush spans: %v", err)
+	}
+
+	start := time.Now().UTC()
+	cursor := start.Format(time.RFC3339Nano)
+
+	t.Run("Follow Handler (Timeout)", func(t *testing.T) {
+		status, followed := follow(t, "after="+cursor+"&timeout=50ms")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, followed.TraceSummaries)
+		assert.True(t, start.Equal(followed.Cursor))
+	})
+
+	t.Run("Follow Handler (New Spans)", func(t *testing.T) {
+		type result struct {
+			status   int
+			followed telemetry.FollowedTraces
+		}
+		results := make(chan result)
+		go func() {
+			status, followed := follow(t, "after="+cursor+"&timeout=5s")
+			results <- result{status, followed}
+		}()
+
+		time.Sleep(50 * time.Millisecond)
+		addSpans(t, telemetry.NewSampleTelemetry().Spans[1:])
+
+		select {
+		case result := <-results:
+			assert.Equal(t, http.StatusOK, result.status)
+			if assert.Len(t, result.followed.TraceSummaries, 1) {
+				assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", result.followed.TraceSummaries[0].TraceID)
+			}
+			assert.True(t, result.followed.Cursor.After(start))
+
+			// Polling from the new cursor only finds what comes after it
+			status, followed := follow(t, "after="+result.followed.Cursor.Format(time.RFC3339Nano)+"&timeout=50ms")
+			assert.Equal(t, http.StatusOK, status)
+			assert.Empty(t, followed.TraceSummaries)
+			assert.True(t, result.followed.Cursor.Equal(followed.Cursor))
+		case <-time.After(2 * time.Second):
+			t.Fatal("the long poll should answer once spans are written")
+		}
+	})
+
+	t.Run("Follow Handler (Already Written)", func(t *testing.T) {
+		addSpans(t, telemetry.NewSampleTelemetry().Spans[:1])
+
+		// Traces written since the cursor are returned straight away
+		status, followed := follow(t, "after="+cursor)
+		assert.Equal(t, http.StatusOK, status)
+		assert.Len(t, followed.TraceSummaries, 2)
+	})
+
+	t.Run("Follow Handler (Invalid)", func(t *testing.T) {
+		for _, query := range []string{"after=yesterday", "timeout=forever", "timeout=10m"} {
+			status, _ := follow(t, query)
+			assert.Equal(t, http.StatusBadRequest, status, query)
+		}
+	})
+
+	t.Run("Follow Handler (Server Closed)", func(t *testing.T) {
+		time.AfterFunc(50*time.Millisecond, func() { server.Close() })
+
+		status, followed := follow(t, "timeout=5s")
+		assert.Equal(t, http.StatusOK, status)
+		assert.Empty(t, followed.TraceSummaries)
+	})
+}
+
+func TestWebsocketHandler(t *testing.T) {
+	server := NewServer("localhost:8000", "")
+	defer server.Close()
+
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	wsURL := "ws" + strings.TrimPrefix(testServer.URL, "http") + "/api/ws"
+	subscriberCount := func() int {
+		server.hub.mut.Lock()
+		defer server.hub.mut.Unlock()
+		return len(server.hub.subscribers)
+	}
+
+	t.Run("Websocket Handler (Other Origin)", func(t *testing.T) {
+		_, err := websocket.Dial(wsURL, "", "http://example.com")
+		assert.Error(t, err)
+	})
+
+	t.Run("Websocket Handler (Filtered)", func(t *testing.T) {
+		ws, err := websocket.Dial(wsURL, "", testServer.URL)
+		assert.Nilf(t, err, "could not open websocket: %v", err)
+
+		err = websocket.Message.Send(ws, `{"status": "failed"}`)
+		assert.Nilf(t, err, "could not send filter: %v", err)
+		message := streamMessage{}
+		err = websocket.JSON.Receive(ws, &message)
+		assert.Nilf(t, err, "could not receive message: %v", err)
+		assert.Equal(t, "error", message.Type)
+		assert.Contains(t, message.Error, "invalid status")
+
+		err = websocket.Message.Send(ws, `{"services": ["sample-loadgenerator"]}`)
+		assert.Nilf(t, err, "could not send filter: %v", err)
+		assert.Eventually(t, func() bool { return subscriberCount() == 1 }, time.Second, 10*time.Millisecond)
+
+		// Only one of the sample traces has a root span from the load generator
+		err = server.Store.AddSpans(context.Background(), telemetry.NewSampleTelemetry().Spans)
+		assert.Nilf(t, err, "could not add spans: %v", err)
+		err = server.Store.Flush(context.Background())
+		assert.Nilf(t, err, "could not flush spans: %v", err)
+
+		message = streamMessage{}
+		err = websocket.JSON.Receive(ws, &message)
+		assert.Nilf(t, err, "could not receive message: %v", err)
+		assert.Equal(t, "trace", message.Type)
+		if assert.NotNil(t, message.Trace) {
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", message.Trace.TraceID)
+		}
+
+		ws.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		err = websocket.JSON.Receive(ws, &message)
+		assert.Error(t, err, "the other sample trace should have been filtered out")
+
+		// Closing the socket unsubscribes
+		ws.Close()
+		assert.Eventually(t, func() bool { return subscriberCount() == 0 }, time.Second, 10*time.Millisecond)
+	})
+}
+
+func TestGzipHandler(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	get := func(t *testing.T, path string, acceptEncoding string) *http.Response {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, path), nil)
+		assert.Nilf(t, err, "could not create GET request: %v", err)
+		// Setting Accept-Encoding by hand stops the client from decompressing the response itself
+		request.Header.Set("Accept-Encoding", acceptEncoding)
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		return res
+	}
+
+	t.Run("Gzip Handler (Large Response)", func(t *testing.T) {
+		res := get(t, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4", "gzip, deflate")
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "gzip", res.Header.Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", res.Header.Get("Vary"))
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		if res.ContentLength != -1 {
+			assert.Equal(t, int64(len(b)), res.ContentLength)
+		}
+
+		reader, err := gzip.NewReader(bytes.NewReader(b))
+		assert.Nilf(t, err, "could not read gzip response: %v", err)
+
+		testTrace := telemetry.TraceData{}
+		err = json.NewDecoder(reader).Decode(&testTrace)
+		assert.Nilf(t, err, "could not decode trace data: %v", err)
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", testTrace.TraceID)
+		assert.Equal(t, 3, len(testTrace.Spans))
+	})
+
+	t.Run("Gzip Handler (Small Response)", func(t *testing.T) {
+		res := get(t, "/api/services", "gzip")
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("Content-Encoding"))
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Equal(t, fmt.Sprint(len(b)), res.Header.Get("Content-Length"))
+		assert.True(t, json.Valid(b))
+	})
+
+	t.Run("Gzip Handler (Not Accepted)", func(t *testing.T) {
+		for _, acceptEncoding := range []string{"identity", "gzip;q=0, deflate"} {
+			res := get(t, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4", acceptEncoding)
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Empty(t, res.Header.Get("Content-Encoding"))
+
+			testTrace := telemetry.TraceData{}
+			err := json.NewDecoder(res.Body).Decode(&testTrace)
+			assert.Nilf(t, err, "could not decode trace data: %v", err)
+			assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", testTrace.TraceID)
+		}
+	})
+
+	t.Run("Gzip Handler (Transparent)", func(t *testing.T) {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.True(t, res.Uncompressed)
+
+		testTrace := telemetry.TraceData{}
+		err = json.NewDecoder(res.Body).Decode(&testTrace)
+		assert.Nilf(t, err, "could not decode trace data: %v", err)
+		assert.Equal(t, 3, len(testTrace.Spans))
+	})
+}
+
+// freeEndpoint finds a port nothing is listening on, for tests that need the server to listen itself
+func freeEndpoint(t *testing.T) string {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nilf(t, err, "could not find a free port: %v", err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+// waitForListener waits for a server started in the background to accept connections
+func waitForListener(t *testing.T, endpoint string) {
+	assert.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", endpoint)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestRun(t *testing.T) {
+	// Keep Run from opening a browser
+	t.Setenv("CI", "true")
+
+	exportRequest := ptraceotlp.NewExportRequestFromTraces(newTestTraces())
+	protoPayload, err := exportRequest.MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+
+	// start runs a server and begins posting spans to it, holding the request open until the
+	// returned writer is closed
+	start := func(t *testing.T, dbPath string, opts ...Option) (context.CancelFunc, chan error, *io.PipeWriter, chan *http.Response) {
+		endpoint := freeEndpoint(t)
+		server := NewServer(endpoint, dbPath, opts...)
+
+		active := make(chan struct{}, 1)
+		server.server.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateActive {
+				select {
+				case active <- struct{}{}:
+				default:
+				}
+			}
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		ran := make(chan error, 1)
+		go func() {
+			ran <- server.Run(ctx)
+		}()
+
+		waitForListener(t, endpoint)
+
+		body, bodyWriter := io.Pipe()
+		request, err := http.NewRequest(http.MethodPost, fmt.Sprintf("http://%s%s", endpoint, "/v1/traces"), body)
+		assert.Nilf(t, err, "could not create POST request: %v", err)
+		request.Header.Set("Content-Type", "application/x-protobuf")
+
+		responses := make(chan *http.Response, 1)
+		go func() {
+			// A request that is cut off gets no response
+			res, _ := http.DefaultClient.Do(request)
+			responses <- res
+		}()
+
+		_, err = bodyWriter.Write(protoPayload)
+		assert.Nilf(t, err, "could not write request body: %v", err)
+		<-active
+		return cancel, ran, bodyWriter, responses
+	}
+
+	t.Run("Run (Drains In-Flight Requests)", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "traces.db")
+		cancel, ran, bodyWriter, responses := start(t, dbPath)
+
+		cancel()
+		select {
+		case err := <-ran:
+			t.Fatalf("run returned before the in-flight request finished: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		bodyWriter.Close()
+		res := <-responses
+		if assert.NotNil(t, res) {
+			res.Body.Close()
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		}
+		assert.Nil(t, <-ran)
+
+		// The spans of the drained request were written before the store was closed
+		reopened := store.NewStore(context.Background(), dbPath)
+		defer reopened.Close()
+		_, totalCount, err := reopened.GetTraceSummaries(context.Background(), 0, 0)
+		assert.Nilf(t, err, "could not get trace summaries: %v", err)
+		assert.Equal(t, 2, totalCount)
+	})
+
+	t.Run("Run (Drain Times Out)", func(t *testing.T) {
+		cancel, ran, bodyWriter, responses := start(t, "", WithShutdownTimeout(50*time.Millisecond))
+
+		cancel()
+		err := <-ran
+		assert.ErrorIs(t, err, ErrShutdownTimeout)
+
+		// The request that outlasted the timeout was cut off, so finishing it gets no response
+		bodyWriter.Close()
+		assert.Nil(t, <-responses)
+	})
+	t.Run("Run (Idle Timeout)", func(t *testing.T) {
+		endpoint := freeEndpoint(t)
+		server := NewServer(endpoint, "", WithIdleTimeout(300*time.Millisecond))
+		ran := make(chan error, 1)
+		go func() {
+			ran <- server.Run(context.Background())
+		}()
+		waitForListener(t, endpoint)
+
+		get := func(path string) {
+			res, err := http.Get(fmt.Sprintf("http://%s%s", endpoint, path))
+			if assert.Nilf(t, err, "could not send GET request: %v", err) {
+				res.Body.Close()
+			}
+		}
+
+		// Requests keep the server up for longer than the timeout
+		for i := 0; i < 6; i++ {
+			get("/api/traces")
+			select {
+			case err := <-ran:
+				t.Fatalf("run returned while the server was in use: %v", err)
+			case <-time.After(100 * time.Millisecond):
+			}
+		}
+
+		// Health checks don't, so once the API is left alone the server shuts itself down
+		get("/healthz")
+		select {
+		case err := <-ran:
+			assert.ErrorIs(t, err, ErrIdleTimeout)
+		case <-time.After(2 * time.Second):
+			t.Fatal("run didn't return once the server was idle")
+		}
+	})
+}
+
+func TestAuthHandler(t *testing.T) {
+	// Don't follow the redirect that trades a token for a cookie, so it can be checked
+	client := &http.Client{
+		CheckRedirect: func(request *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	get := func(t *testing.T, url string, authorization string, cookie *http.Cookie) *http.Response {
+		request, err := http.NewRequest(http.MethodGet, url, nil)
+		assert.Nilf(t, err, "could not create GET request: %v", err)
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		if cookie != nil {
+			request.AddCookie(cookie)
+		}
+
+		res, err := client.Do(request)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("Auth Handler (Disabled)", func(t *testing.T) {
+		testServer, teardown := setupEmpty()
+		defer teardown()
+
+		res := get(t, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), "", nil)
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	t.Run("Auth Handler (API)", func(t *testing.T) {
+		server := NewServer("localhost:8000", "", WithAuthToken("s3cret", false))
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		tests := []struct {
+			name           string
+			path           string
+			authorization  string
+			expectedStatus int
+		}{
+			{name: "Missing Token", path: "/api/traces", expectedStatus: http.StatusUnauthorized},
+			{name: "Wrong Token", path: "/api/traces", authorization: "Bearer guess", expectedStatus: http.StatusUnauthorized},
+			{name: "Wrong Scheme", path: "/api/traces", authorization: "Basic s3cret", expectedStatus: http.StatusUnauthorized},
+			{name: "Bearer Token", path: "/api/traces", authorization: "Bearer s3cret", expectedStatus: http.StatusOK},
+			{name: "Lowercase Scheme", path: "/api/services", authorization: "bearer s3cret", expectedStatus: http.StatusOK},
+			{name: "Database Export", path: "/api/export/db", expectedStatus: http.StatusUnauthorized},
+			{name: "Public UI", path: "/", expectedStatus: http.StatusOK},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				res := get(t, fmt.Sprintf("%s%s", testServer.URL, tt.path), tt.authorization, nil)
+				assert.Equal(t, tt.expectedStatus, res.StatusCode)
+				if tt.expectedStatus == http.StatusUnauthorized {
+					assert.Equal(t, "Bearer", res.Header.Get("WWW-Authenticate"))
+				}
+			})
+		}
+	})
+
+	t.Run("Auth Handler (UI)", func(t *testing.T) {
+		server := NewServer("localhost:8000", "", WithAuthToken("s3cret", true))
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		res := get(t, fmt.Sprintf("%s%s", testServer.URL, "/"), "", nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		res = get(t, fmt.Sprintf("%s%s", testServer.URL, "/traces/1234?token=guess"), "", nil)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+		// Visiting with the token sets a cookie and drops the token from the address
+		res = get(t, fmt.Sprintf("%s%s", testServer.URL, "/traces/1234?token=s3cret"), "", nil)
+		assert.Equal(t, http.StatusFound, res.StatusCode)
+		assert.Equal(t, "/traces/1234", res.Header.Get("Location"))
+
+		cookies := res.Cookies()
+		if assert.Len(t, cookies, 1) {
+			assert.True(t, cookies[0].HttpOnly)
+
+			res = get(t, fmt.Sprintf("%s%s", testServer.URL, "/"), "", cookies[0])
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			res = get(t, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), "", cookies[0])
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+		}
+	})
+}
+
+func TestTLS(t *testing.T) {
+	// Keep Run from opening a browser
+	t.Setenv("CI", "true")
+
+	certPEM, keyPEM, err := generateSelfSignedPEM("localhost:0", time.Now())
+	assert.Nilf(t, err, "could not generate certificate: %v", err)
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	assert.Nil(t, os.WriteFile(certFile, certPEM, 0o600))
+	assert.Nil(t, os.WriteFile(keyFile, keyPEM, 0o600))
+
+	roots := x509.NewCertPool()
+	assert.True(t, roots.AppendCertsFromPEM(certPEM))
+
+	tests := []struct {
+		name      string
+		opt       Option
+		tlsConfig *tls.Config
+	}{
+		{name: "Certificate Files", opt: WithTLS(certFile, keyFile), tlsConfig: &tls.Config{RootCAs: roots}},
+		{name: "Self-Signed", opt: WithSelfSignedTLS(), tlsConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			endpoint := freeEndpoint(t)
+			server := NewServer(endpoint, "", tt.opt)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			ran := make(chan error, 1)
+			go func() {
+				ran <- server.Run(ctx)
+			}()
+			defer func() {
+				cancel()
+				assert.Nil(t, <-ran)
+			}()
+			waitForListener(t, endpoint)
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tt.tlsConfig}}
+			res, err := client.Get(fmt.Sprintf("https://%s%s", endpoint, "/api/traces"))
+			if assert.Nilf(t, err, "could not send GET request: %v", err) {
+				res.Body.Close()
+				assert.Equal(t, http.StatusOK, res.StatusCode)
+				assert.NotNil(t, res.TLS)
+			}
+
+			// Plain HTTP isn't served alongside
+			res, err = http.Get(fmt.Sprintf("http://%s%s", endpoint, "/api/traces"))
+			if err == nil {
+				res.Body.Close()
+				assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Missing Certificate", func(t *testing.T) {
+		server := NewServer(freeEndpoint(t), "", WithTLS(filepath.Join(dir, "missing.pem"), keyFile))
+		err := server.Run(context.Background())
+		assert.ErrorContains(t, err, "could not load TLS certificate")
+	})
+}
+
+func TestUnixSocket(t *testing.T) {
+	// Keep Run from opening a browser
+	t.Setenv("CI", "true")
+
+	dir := t.TempDir()
+	socketPath := filepath.Join(dir, "viewer.sock")
+
+	// A viewer that didn't shut down cleanly left its socket behind
+	stale, err := net.Listen("unix", socketPath)
+	if !assert.Nilf(t, err, "could not listen on unix socket: %v", err) {
+		return
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	// TLS doesn't apply, so plain HTTP is served regardless
+	server := NewServer("unix://"+socketPath, "", WithSelfSignedTLS())
+	ctx, cancel := context.WithCancel(context.Background())
+	ran := make(chan error, 1)
+	go func() {
+		ran <- server.Run(ctx)
+	}()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network string, addr string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socketPath)
+		},
+	}}
+	assert.Eventually(t, func() bool {
+		res, err := client.Get("http://viewer/api/traces")
+		if err != nil {
+			return false
+		}
+		res.Body.Close()
+		return res.StatusCode == http.StatusOK
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// A second viewer can't take over a socket that is in use
+	err = NewServer("unix://"+socketPath, "").Run(context.Background())
+	assert.ErrorContains(t, err, "already in use")
+
+	cancel()
+	assert.Nil(t, <-ran)
+	_, err = os.Stat(socketPath)
+	assert.ErrorIs(t, err, os.ErrNotExist, "the socket should be removed on shutdown")
+
+	t.Run("Not A Socket", func(t *testing.T) {
+		filePath := filepath.Join(dir, "traces.txt")
+		assert.Nil(t, os.WriteFile(filePath, []byte("keep me"), 0o600))
+
+		err := NewServer("unix://"+filePath, "").Run(context.Background())
+		assert.ErrorContains(t, err, "is not a socket")
+		_, err = os.Stat(filePath)
+		assert.Nil(t, err, "a file that isn't a socket should be left alone")
+	})
+}
+
+func TestIngestRateLimit(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithIngestRate(1), WithMetrics())
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	payload, err := ptraceotlp.NewExportRequestFromTraces(newTestTraces()).MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// Of the two spans with IDs, only one fits under the limit, and both kinds of rejection are reported
+	b, err := io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+	response := ptraceotlp.NewExportResponse()
+	err = response.UnmarshalProto(b)
+	assert.Nilf(t, err, "could not unmarshal export response: %v", err)
+	assert.Equal(t, int64(2), response.PartialSuccess().RejectedSpans())
+	assert.Contains(t, response.PartialSuccess().ErrorMessage(), "ingest rate limit")
+
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	// Sample data isn't sent by a service, so it isn't limited
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	_, totalCount, err := server.Store.GetTraceSummaries(context.Background(), 0, 0)
+	assert.Nilf(t, err, "could not get trace summaries: %v", err)
+	assert.Equal(t, 3, totalCount)
+
+	res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/metrics"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	defer res.Body.Close()
+	b, err = io.ReadAll(res.Body)
+	assert.Nilf(t, err, "could not read response body: %v", err)
+	assert.Contains(t, string(b), "otel_desktop_viewer_spans_rate_limited_total 1\n")
+	assert.Contains(t, string(b), "otel_desktop_viewer_spans_dropped_total 0\n")
+}
+
+// upstreamTraceService stands in for the collector spans are forwarded to
+type upstreamTraceService struct {
+	ptraceotlp.UnimplementedGRPCServer
+	received chan ptrace.Traces
+}
+
+func (service *upstreamTraceService) Export(ctx context.Context, request ptraceotlp.ExportRequest) (ptraceotlp.ExportResponse, error) {
+	service.received <- request.Traces()
+	return ptraceotlp.NewExportResponse(), nil
+}
+
+func TestForwarding(t *testing.T) {
+	getStatus := func(t *testing.T, url string) telemetry.Status {
+		res, err := http.Get(fmt.Sprintf("%s%s", url, "/api/status"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		status := telemetry.Status{}
+		err = json.NewDecoder(res.Body).Decode(&status)
+		assert.Nilf(t, err, "could not decode status: %v", err)
+		return status
+	}
+	postTraces := func(t *testing.T, url string) {
+		payload, err := ptraceotlp.NewExportRequestFromTraces(newTestTraces()).MarshalProto()
+		assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+		res, err := http.Post(fmt.Sprintf("%s%s", url, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	t.Run("Forward Spans", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "localhost:0")
+		assert.Nilf(t, err, "could not listen for grpc: %v", err)
+		upstream := &upstreamTraceService{received: make(chan ptrace.Traces, 10)}
+		grpcServer := grpc.NewServer()
+		ptraceotlp.RegisterGRPCServer(grpcServer, upstream)
+		go grpcServer.Serve(listener)
+		defer grpcServer.Stop()
+
+		server := NewServer("localhost:8000", "", WithForwarding(listener.Addr().String()))
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		postTraces(t, testServer.URL)
+		select {
+		case traces := <-upstream.received:
+			// The span without IDs isn't stored, so it isn't forwarded either
+			assert.Equal(t, 2, traces.SpanCount())
+			serviceName, _ := traces.ResourceSpans().At(0).Resource().Attributes().Get("service.name")
+			assert.NotEmpty(t, serviceName.Str())
+		case <-time.After(5 * time.Second):
+			t.Fatal("spans were not forwarded")
+		}
+
+		// Sample data isn't sent by a service, so it stays in the viewer
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		// The upstream has the spans just before the export returns and they are counted
+		assert.Eventually(t, func() bool {
+			forwarding := getStatus(t, testServer.URL).Forwarding
+			return forwarding != nil && forwarding.ForwardedSpans == 2
+		}, 5*time.Second, 10*time.Millisecond)
+		if forwarding := getStatus(t, testServer.URL).Forwarding; assert.NotNil(t, forwarding) {
+			assert.Equal(t, listener.Addr().String(), forwarding.Endpoint)
+			assert.Equal(t, int64(0), forwarding.DroppedSpans)
+		}
+		assert.Empty(t, upstream.received)
+	})
+
+	t.Run("Upstream Down", func(t *testing.T) {
+		// Nothing listens on the port once the listener is closed
+		listener, err := net.Listen("tcp", "localhost:0")
+		assert.Nilf(t, err, "could not listen: %v", err)
+		listener.Close()
+
+		server := NewServer("localhost:8000", "", WithForwarding(listener.Addr().String()), WithMetrics())
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		// The spans are still stored
+		postTraces(t, testServer.URL)
+		err = server.Store.Flush(context.Background())
+		assert.Nilf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, 2, getStatus(t, testServer.URL).SpanCount)
+
+		assert.Eventually(t, func() bool {
+			forwarding := getStatus(t, testServer.URL).Forwarding
+			return forwarding != nil && forwarding.DroppedSpans == 2
+		}, 5*time.Second, 10*time.Millisecond)
+
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/metrics"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Contains(t, string(b), "otel_desktop_viewer_spans_forward_dropped_total 2\n")
+	})
+
+	t.Run("Not Forwarding", func(t *testing.T) {
+		testServer, teardown := setupEmpty()
+		defer teardown()
+		assert.Nil(t, getStatus(t, testServer.URL).Forwarding)
+	})
+}
+
+func TestRedaction(t *testing.T) {
+	listener, err := net.Listen("tcp", "localhost:0")
+	assert.Nilf(t, err, "could not listen for grpc: %v", err)
+	upstream := &upstreamTraceService{received: make(chan ptrace.Traces, 10)}
+	grpcServer := grpc.NewServer()
+	ptraceotlp.RegisterGRPCServer(grpcServer, upstream)
+	go grpcServer.Serve(listener)
+	defer grpcServer.Stop()
+
+	redactor, err := telemetry.NewRedactor([]string{"oven.temperature"}, []string{`pumpkin`})
+	assert.Nilf(t, err, "could not build redactor: %v", err)
+	server := NewServer("localhost:8000", "", WithRedactor(redactor), WithForwarding(listener.Addr().String()))
+	defer server.Close()
+
+	traces := newTestTraces()
+	_, err = server.receiveTraces(context.Background(), traces)
+	assert.Nilf(t, err, "could not receive traces: %v", err)
+	err = server.Store.Flush(context.Background())
+	assert.Nilf(t, err, "could not flush spans: %v", err)
+
+	trace, err := server.Store.GetTrace(context.Background(), pcommon.TraceID([16]byte{1}).String())
+	if assert.Nilf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, telemetry.RedactedValue, trace.Spans[0].Attributes["oven.temperature"])
+		assert.Equal(t, "***.pie", trace.Spans[0].GetServiceName())
+	}
+
+	// The value is gone from the database, so it can't be searched for either
+	matches, err := server.Store.SearchTrace(context.Background(), trace.TraceID, "pumpkin")
+	assert.Nilf(t, err, "could not search trace: %v", err)
+	assert.Equal(t, 0, matches.Count)
+
+	// Spans are redacted before they are forwarded, too
+	select {
+	case forwarded := <-upstream.received:
+		serviceName, _ := forwarded.ResourceSpans().At(0).Resource().Attributes().Get("service.name")
+		assert.Equal(t, "***.pie", serviceName.Str())
+		temperature, _ := forwarded.ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes().Get("oven.temperature")
+		assert.Equal(t, telemetry.RedactedValue, temperature.Str())
+	case <-time.After(5 * time.Second):
+		t.Fatal("spans were not forwarded")
+	}
+
+	// The payload the spans were read from is left as it was
+	serviceName, _ := traces.ResourceSpans().At(0).Resource().Attributes().Get("service.name")
+	assert.Equal(t, "pumpkin.pie", serviceName.Str())
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	payload, err := ptraceotlp.NewExportRequestFromTraces(newTestTraces()).MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+
+	server := NewServer("localhost:8000", "", WithMaxBodyBytes(int64(len(payload))))
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	post := func(t *testing.T, path string, contentType string, encoding string, body []byte) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, testServer.URL+path, bytes.NewReader(body))
+		assert.Nilf(t, err, "could not create POST request: %v", err)
+		req.Header.Set("Content-Type", contentType)
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		res, err := http.DefaultClient.Do(req)
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		return res
+	}
+
+	t.Run("Max Body Bytes (Under Limit)", func(t *testing.T) {
+		res := post(t, "/v1/traces", "application/x-protobuf", "", payload)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	})
+
+	// Whitespace is read to the end by every decoder, so it is only the limit that stops them
+	oversized := bytes.Repeat([]byte(" "), len(payload)+1)
+	for _, path := range []string{"/v1/traces", "/api/v2/spans", "/api/traces/import"} {
+		t.Run(fmt.Sprintf("Max Body Bytes (Over Limit at %s)", path), func(t *testing.T) {
+			res := post(t, path, "application/json", "", oversized)
+			defer res.Body.Close()
+			assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+
+			b, err := io.ReadAll(res.Body)
+			assert.Nilf(t, err, "could not read response body: %v", err)
+			assert.Contains(t, string(b), fmt.Sprintf("larger than the limit of %d bytes", len(payload)))
+		})
+	}
+
+	t.Run("Max Body Bytes (NDJSON Backup)", func(t *testing.T) {
+		// A backup holds the whole store, so only each of its lines is held to the limit
+		var backup bytes.Buffer
+		for i := 1; backup.Len() <= len(payload); i++ {
+			fmt.Fprintf(&backup, `{"traceID":"%x","spans":[{"spanID":"%x","name":"bake"}]}`+"\n", i, i)
+		}
+		res := post(t, "/api/traces/import?format=ndjson", "application/x-ndjson", "", backup.Bytes())
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res = post(t, "/api/traces/import?format=ndjson", "application/x-ndjson", "", append(backup.Bytes(), oversized...))
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		assert.Contains(t, string(b), fmt.Sprintf("larger than the limit of %d bytes", len(payload)))
+	})
+
+	t.Run("Max Body Bytes (Over Limit Once Decompressed)", func(t *testing.T) {
+		var compressed bytes.Buffer
+		gzipWriter := gzip.NewWriter(&compressed)
+		_, err := gzipWriter.Write(make([]byte, 10*len(payload)))
+		assert.Nilf(t, err, "could not compress payload: %v", err)
+		assert.Nilf(t, gzipWriter.Close(), "could not compress payload")
+		assert.Less(t, compressed.Len(), len(payload))
+
+		res := post(t, "/v1/traces", "application/x-protobuf", "gzip", compressed.Bytes())
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	})
+}
+
+func TestAPIVersion(t *testing.T) {
+	testServer, teardown := setupEmpty()
+	defer teardown()
+
+	res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/sampleData"))
+	assert.Nilf(t, err, "could not send GET request: %v", err)
+	res.Body.Close()
+
+	get := func(t *testing.T, path string, version string) (*http.Response, map[string]any) {
+		request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, path), nil)
+		assert.Nilf(t, err, "could not create GET request: %v", err)
+		if version != "" {
+			request.Header.Set(apiVersionHeader, version)
+		}
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+
+		body := map[string]any{}
+		if res.StatusCode == http.StatusOK {
+			err = json.NewDecoder(res.Body).Decode(&body)
+			assert.Nilf(t, err, "could not decode response body: %v", err)
+		}
+		return res, body
+	}
+	spans := func(body map[string]any) []map[string]any {
+		spans := []map[string]any{}
+		for _, span := range body["spans"].([]any) {
+			spans = append(spans, span.(map[string]any))
+		}
+		return spans
+	}
+
+	tracePath := "/api/traces/42957c7c2fca940a0d32a0cdd38c06a4"
+
+	t.Run("API Version (Default)", func(t *testing.T) {
+		for _, version := range []string{"", "1"} {
+			res, body := get(t, tracePath, version)
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Empty(t, res.Header.Get(apiVersionHeader))
+			for _, span := range spans(body) {
+				for _, field := range []string{"events", "links", "droppedAttributesCount", "traceState"} {
+					assert.Contains(t, span, field)
+				}
+			}
+		}
+	})
+
+	t.Run("API Version (Compact)", func(t *testing.T) {
+		_, full := get(t, tracePath, "1")
+		res, body := get(t, tracePath, "2")
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "2", res.Header.Get(apiVersionHeader))
+
+		fullSpans := spans(full)
+		for i, span := range spans(body) {
+			assert.NotContains(t, span, "droppedAttributesCount")
+			assert.NotContains(t, span, "traceState")
+			assert.NotContains(t, span["resource"], "droppedAttributesCount")
+
+			// Empty lists are left out, and everything else is still there, attributes and all
+			for _, field := range []string{"spanID", "parentSpanID", "attributes", "events", "durationNanos"} {
+				if value := fullSpans[i][field]; assert.ObjectsAreEqual([]any{}, value) {
+					assert.NotContains(t, span, field)
+				} else {
+					assert.Equal(t, value, span[field], field)
+				}
+			}
+
+			// Links are compacted too, but keep the counts that aren't zero
+			if links, ok := span["links"].([]any); ok {
+				link := links[0].(map[string]any)
+				assert.NotContains(t, link, "traceState")
+				assert.Equal(t, float64(5), link["droppedAttributesCount"])
+			} else {
+				assert.Empty(t, fullSpans[i]["links"])
+			}
+		}
+
+		// Lists that are always there stay, even when they are empty
+		_, summaries := get(t, "/api/traces?service=nonsense", "2")
+		assert.Equal(t, []any{}, summaries["traceSummaries"])
+	})
+
+	t.Run("API Version (Invalid)", func(t *testing.T) {
+		res, _ := get(t, tracePath, "3")
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	})
+}
+
+func TestCORSHandler(t *testing.T) {
+	send := func(t *testing.T, method string, url string, headers map[string]string) *http.Response {
+		request, err := http.NewRequest(method, url, nil)
+		assert.Nilf(t, err, "could not create request: %v", err)
+		for name, value := range headers {
+			request.Header.Set(name, value)
+		}
+
+		res, err := http.DefaultClient.Do(request)
+		assert.Nilf(t, err, "could not send request: %v", err)
+		res.Body.Close()
+		return res
+	}
+
+	t.Run("CORS Handler (Disabled)", func(t *testing.T) {
+		testServer, teardown := setupEmpty()
+		defer teardown()
+
+		res := send(t, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), map[string]string{"Origin": "http://dashboard.internal:3000"})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("CORS Handler (Allowed Origins)", func(t *testing.T) {
+		server := NewServer("localhost:8000", "", WithCORSOrigins("http://dashboard.internal:3000"), WithAuthToken("s3cret", false))
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		// The preflight doesn't carry the token, and is answered anyway
+		res := send(t, http.MethodOptions, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), map[string]string{
+			"Origin":                         "http://dashboard.internal:3000",
+			"Access-Control-Request-Method":  "GET",
+			"Access-Control-Request-Headers": "authorization",
+		})
+		assert.Equal(t, http.StatusNoContent, res.StatusCode)
+		assert.Equal(t, "http://dashboard.internal:3000", res.Header.Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, res.Header.Get("Access-Control-Allow-Methods"), "GET")
+		assert.Equal(t, "authorization", res.Header.Get("Access-Control-Allow-Headers"))
+
+		res = send(t, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), map[string]string{
+			"Origin":        "http://dashboard.internal:3000",
+			"Authorization": "Bearer s3cret",
+		})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "http://dashboard.internal:3000", res.Header.Get("Access-Control-Allow-Origin"))
+		assert.Contains(t, res.Header.Values("Vary"), "Origin")
+
+		res = send(t, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), map[string]string{
+			"Origin":        "http://elsewhere.internal",
+			"Authorization": "Bearer s3cret",
+		})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+
+		res = send(t, http.MethodOptions, fmt.Sprintf("%s%s", testServer.URL, "/api/traces"), map[string]string{
+			"Origin":                        "http://elsewhere.internal",
+			"Access-Control-Request-Method": "GET",
+		})
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("CORS Handler (Any Origin)", func(t *testing.T) {
+		server := NewServer("localhost:8000", "", WithCORSOrigins("*"))
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		res := send(t, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/api/services"), map[string]string{"Origin": "http://anywhere.internal"})
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+		assert.Equal(t, "*", res.Header.Get("Access-Control-Allow-Origin"))
+
+		// The UI isn't part of the API
+		res = send(t, http.MethodGet, fmt.Sprintf("%s%s", testServer.URL, "/"), map[string]string{"Origin": "http://anywhere.internal"})
+		assert.Empty(t, res.Header.Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestProbeHandlers(t *testing.T) {
+	server := NewServer("localhost:8000", "", WithAuthToken("s3cret", true))
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+	defer testServer.Close()
+
+	t.Run("Probe Handlers (Open)", func(t *testing.T) {
+		for _, path := range []string{"/healthz", "/readyz"} {
+			res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, path))
+			assert.Nilf(t, err, "could not send GET request: %v", err)
+			res.Body.Close()
+
+			// Probes don't need the token, even with the UI gated
+			assert.Equal(t, http.StatusOK, res.StatusCode, path)
+		}
+	})
+
+	t.Run("Probe Handlers (Store Closed)", func(t *testing.T) {
+		server.Store.Close()
+
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/healthz"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/readyz"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusServiceUnavailable, res.StatusCode)
+	})
+}
+
+func TestPrometheusHandler(t *testing.T) {
+	t.Run("Prometheus Handler (Disabled)", func(t *testing.T) {
+		testServer, teardown := setupEmpty()
+		defer teardown()
+
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, "/metrics"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+		assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	})
+
+	t.Run("Prometheus Handler (Enabled)", func(t *testing.T) {
+		server := NewServer("localhost:8000", "", WithMetrics())
+		defer server.Close()
+		testServer := httptest.NewServer(server.Handler(false))
+		defer testServer.Close()
+
+		payload, err := ptraceotlp.NewExportRequestFromTraces(newTestTraces()).MarshalProto()
+		assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+		res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+		assert.Nilf(t, err, "could not send POST request: %v", err)
+		res.Body.Close()
+
+		err = server.Store.Flush(context.Background())
+		assert.Nilf(t, err, "could not flush spans: %v", err)
+
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/api/traces/0123/stats"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+
+		res, err = http.Get(fmt.Sprintf("%s%s", testServer.URL, "/metrics"))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+
+		b, err := io.ReadAll(res.Body)
+		assert.Nilf(t, err, "could not read response body: %v", err)
+		exposition := string(b)
+
+		// The span without a trace ID is rejected before it reaches the store
+		assert.Contains(t, exposition, "otel_desktop_viewer_spans_received_total 2\n")
+		assert.Contains(t, exposition, "otel_desktop_viewer_spans_stored_total 2\n")
+		assert.Contains(t, exposition, "otel_desktop_viewer_traces_stored_total 2\n")
+		assert.Contains(t, exposition, "otel_desktop_viewer_store_insert_duration_seconds_count 1\n")
+		assert.Contains(t, exposition, "otel_desktop_viewer_traces 2\n")
+		assert.Contains(t, exposition, `otel_desktop_viewer_http_requests_total{code="200",route="POST /v1/traces"} 1`)
+		assert.Contains(t, exposition, `otel_desktop_viewer_http_requests_total{code="404",route="GET /api/traces/{id}/stats"} 1`)
+		assert.Contains(t, exposition, "go_goroutines")
+	})
+}
+
+func TestRequestLogging(t *testing.T) {
+	logs := bytes.Buffer{}
+	logger := slog.New(slog.NewJSONHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	server := NewServer("localhost:8000", "", WithLogger(logger))
+	defer server.Close()
+	testServer := httptest.NewServer(server.Handler(false))
+
+	payload, err := ptraceotlp.NewExportRequestFromTraces(newTestTraces()).MarshalProto()
+	assert.Nilf(t, err, "could not marshal protobuf payload: %v", err)
+	res, err := http.Post(fmt.Sprintf("%s%s", testServer.URL, "/v1/traces"), "application/x-protobuf", bytes.NewReader(payload))
+	assert.Nilf(t, err, "could not send POST request: %v", err)
+	res.Body.Close()
+
+	for _, path := range []string{"/api/traces", "/api/traces/0123/stats", "/healthz", "/readyz", "/"} {
+		res, err := http.Get(fmt.Sprintf("%s%s", testServer.URL, path))
+		assert.Nilf(t, err, "could not send GET request: %v", err)
+		res.Body.Close()
+	}
+
+	// Closing waits for the requests to finish, and so for their logs to be written
+	testServer.Close()
+
+	type logRecord struct {
+		Level    string `json:"level"`
+		Msg      string `json:"msg"`
+		Method   string `json:"method"`
+		Path     string `json:"path"`
+		Status   int    `json:"status"`
+		Duration int64  `json:"duration"`
+		Bytes    int64  `json:"bytes"`
+		Source   string `json:"source"`
+		Spans    int    `json:"spans"`
+	}
+	records := map[string]logRecord{}
+	decoder := json.NewDecoder(&logs)
+	for decoder.More() {
+		record := logRecord{}
+		err := decoder.Decode(&record)
+		assert.Nilf(t, err, "could not decode log record: %v", err)
+		records[record.Msg+" "+record.Path] = record
+	}
+
+	spansAdded := records["spans added "]
+	assert.Equal(t, "DEBUG", spansAdded.Level)
+	assert.Equal(t, "otlp", spansAdded.Source)
+	assert.Equal(t, 2, spansAdded.Spans)
+
+	traces := records["request /api/traces"]
+	assert.Equal(t, "INFO", traces.Level)
+	assert.Equal(t, http.MethodGet, traces.Method)
+	assert.Equal(t, http.StatusOK, traces.Status)
+	assert.Positive(t, traces.Bytes)
+	assert.Positive(t, traces.Duration)
+
+	assert.Equal(t, http.StatusNotFound, records["request /api/traces/0123/stats"].Status)
+	assert.Equal(t, "INFO", records["request /v1/traces"].Level)
+	assert.Equal(t, "DEBUG", records["request /"].Level)
+
+	// Probes are left out
+	assert.NotContains(t, records, "request /healthz")
+	assert.NotContains(t, records, "request /readyz")
+}
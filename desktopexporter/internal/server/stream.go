@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// subscriberBufferSize is how many summaries a stream can fall behind by before it misses some
+const subscriberBufferSize = 64
+
+// hub sends the summary of each trace whose spans were just written to every stream subscriber
+// whose filter it matches. Traces written while the hub is busy are coalesced into its next round,
+// and a subscriber that falls behind misses summaries rather than holding up the others, so
+// ingestion never waits on a slow client. A later summary of the same trace supersedes any that
+// were missed.
+//
+// It also remembers when each trace was last written, for followWindow, so that long polls can
+// ask which traces were written since their cursor, and closes written to wake them.
+type hub struct {
+	store *store.Store
+
+	mut         sync.Mutex
+	pending     map[string]bool
+	subscribers map[chan telemetry.TraceSummary]store.SummaryQuery
+	writtenAt   map[string]time.Time
+	written     chan struct{}
+	pruned      time.Time
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newHub() *hub {
+	return &hub{
+		pending:     map[string]bool{},
+		subscribers: map[chan telemetry.TraceSummary]store.SummaryQuery{},
+		writtenAt:   map[string]time.Time{},
+		written:     make(chan struct{}),
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+}
+
+// notify is the store's write listener, and must not block
+func (h *hub) notify(traceIDs []string) {
+	h.mut.Lock()
+	now := time.Now().UTC().Round(0)
+	for _, traceID := range traceIDs {
+		h.pending[traceID] = true
+		h.writtenAt[traceID] = now
+	}
+	if now.Sub(h.pruned) > followWindow/10 {
+		for traceID, at := range h.writtenAt {
+			if now.Sub(at) > followWindow {
+				delete(h.writtenAt, traceID)
+			}
+		}
+		h.pruned = now
+	}
+	close(h.written)
+	h.written = make(chan struct{})
+	h.mut.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default:
+	}
+}
+
+// subscribe sends the summaries of new spans' traces that match the filters of the
+// query (but not its page or order) to the returned channel, until unsubscribed
+func (h *hub) subscribe(filter store.SummaryQuery) chan telemetry.TraceSummary {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	summaries := make(chan telemetry.TraceSummary, subscriberBufferSize)
+	if h.subscribers == nil {
+		// The hub has stopped
+		close(summaries)
+		return summaries
+	}
+	h.subscribers[summaries] = filter
+	return summaries
+}
+
+func (h *hub) unsubscribe(summaries chan telemetry.TraceSummary) {
+	h.mut.Lock()
+	defer h.mut.Unlock()
+
+	if _, ok := h.subscribers[summaries]; ok {
+		delete(h.subscribers, summaries)
+		close(summaries)
+	}
+}
+
+func (h *hub) run() {
+	defer close(h.done)
+
+	for {
+		select {
+		case <-h.wake:
+			h.publish()
+		case <-h.stop:
+			h.mut.Lock()
+			for summaries := range h.subscribers {
+				close(summaries)
+			}
+			h.subscribers = nil
+			h.mut.Unlock()
+			return
+		}
+	}
+}
+
+// close stops the hub and ends every stream
+func (h *hub) close() {
+	close(h.stop)
+	<-h.done
+}
+
+// publish queries the summaries of the pending traces once for each distinct filter
+// among the subscribers, then hands each subscriber those matching its own
+func (h *hub) publish() {
+	h.mut.Lock()
+	traceIDs := []string{}
+	for traceID := range h.pending {
+		traceIDs = append(traceIDs, traceID)
+	}
+	h.pending = map[string]bool{}
+	filters := map[string]store.SummaryQuery{}
+	for _, filter := range h.subscribers {
+		filters[filterKey(filter)] = filter
+	}
+	h.mut.Unlock()
+
+	if len(filters) == 0 || len(traceIDs) == 0 {
+		return
+	}
+
+	matches := map[string][]telemetry.TraceSummary{}
+	for key, filter := range filters {
+		filter.TraceIDs = traceIDs
+		summaries, _, err := h.store.QueryTraceSummaries(context.Background(), filter)
+		if err != nil {
+			log.Printf("could not retrieve summaries of new spans: %s", err.Error())
+			continue
+		}
+		matches[key] = *summaries
+	}
+
+	h.mut.Lock()
+	defer h.mut.Unlock()
+	for subscriber, filter := range h.subscribers {
+		for _, summary := range matches[filterKey(filter)] {
+			select {
+			case subscriber <- summary:
+			default:
+			}
+		}
+	}
+}
+
+// filterKey tells apart the subscribers' filters; only the fields a stream can filter by matter
+func filterKey(filter store.SummaryQuery) string {
+	return fmt.Sprintf("%q %q", filter.Services, filter.Status)
+}
+
+// streamHandler sends the summary of a trace as a server-sent event whenever spans of it are written
+func (s *Server) streamHandler(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		http.Error(writer, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	summaries := s.hub.subscribe(store.SummaryQuery{})
+	defer s.hub.unsubscribe(summaries)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case summary, ok := <-summaries:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(summary)
+			if err != nil {
+				log.Printf("could not marshal trace summary: %s", err.Error())
+				continue
+			}
+			fmt.Fprintf(writer, "event: trace\ndata: %s\n\n", data)
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"time"
+)
+
+// selfSignedValidity is how long a generated certificate is valid for. A new one is generated
+// every time the viewer starts, so this only has to outlast a single run.
+const selfSignedValidity = 30 * 24 * time.Hour
+
+// tlsEnabled reports whether the server is served over HTTPS. TLS doesn't apply to unix sockets,
+// which only local processes can connect to, so it is never enabled on one.
+func (s *Server) tlsEnabled() bool {
+	if _, ok := unixSocketPath(s.server.Addr); ok {
+		return false
+	}
+	return s.tlsCertFile != "" || s.tlsSelfSigned
+}
+
+// tlsConfig loads the certificate to serve HTTPS with, or generates one if asked to
+func (s *Server) tlsConfig() (*tls.Config, error) {
+	var certificate tls.Certificate
+	var err error
+	if s.tlsSelfSigned {
+		certificate, err = selfSignedCertificate(s.server.Addr, s.now())
+	} else {
+		certificate, err = tls.LoadX509KeyPair(s.tlsCertFile, s.tlsKeyFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load TLS certificate: %s", err.Error())
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func selfSignedCertificate(endpoint string, now time.Time) (tls.Certificate, error) {
+	certPEM, keyPEM, err := generateSelfSignedPEM(endpoint, now)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// generateSelfSignedPEM creates a certificate and key for the host of endpoint. Localhost
+// names and addresses are always included, since that is where the browser is opened.
+func generateSelfSignedPEM(endpoint string, now time.Time) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate private key: %s", err.Error())
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not generate serial number: %s", err.Error())
+	}
+
+	template := x509.Certificate{
+		SerialNumber:          serialNumber,
+		Subject:               pkix.Name{Organization: []string{"otel-desktop-viewer"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	if host, _, err := net.SplitHostPort(endpoint); err == nil && host != "" && host != "localhost" {
+		if ip := net.ParseIP(host); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else {
+			template.DNSNames = append(template.DNSNames, host)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not create certificate: %s", err.Error())
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not marshal private key: %s", err.Error())
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
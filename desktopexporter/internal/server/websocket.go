@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/store"
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+const (
+	// websocketPingInterval keeps idle sockets from being closed by anything in between
+	websocketPingInterval = 30 * time.Second
+	// websocketWriteTimeout gives up on clients that stop taking messages
+	websocketWriteTimeout = 10 * time.Second
+)
+
+// streamFilter is what a websocket client sends to choose which traces it is sent.
+// Services and Status work like the service and status query parameters of /api/traces.
+type streamFilter struct {
+	Services []string `json:"services"`
+	Status   string   `json:"status"`
+}
+
+// streamMessage is what the server sends over a websocket: a trace summary, or an error
+// if the client sent a filter we couldn't use
+type streamMessage struct {
+	Type  string                  `json:"type"`
+	Trace *telemetry.TraceSummary `json:"trace,omitempty"`
+	Error string                  `json:"error,omitempty"`
+}
+
+func parseStreamFilter(message []byte) (store.SummaryQuery, error) {
+	filter := streamFilter{}
+	if err := json.Unmarshal(message, &filter); err != nil {
+		return store.SummaryQuery{}, err
+	}
+
+	query := store.SummaryQuery{Services: filter.Services}
+	if filter.Status != "" {
+		status, err := store.ParseStatusFilter(filter.Status)
+		if err != nil {
+			return query, err
+		}
+		query.Status = status
+	}
+	return query, nil
+}
+
+// websocketHandler streams the summaries of new spans' traces, like streamHandler, but only those
+// matching the filter the client sends as its first message. Any later message replaces the filter.
+func (s *Server) websocketHandler(writer http.ResponseWriter, request *http.Request) {
+	server := websocket.Server{
+		Handshake: checkOrigin(s.corsOrigins),
+		Handler:   s.serveWebsocket,
+	}
+	server.ServeHTTP(writer, request)
+}
+
+// checkOrigin refuses sockets opened by pages served from elsewhere, other than the CORS origins
+// we were given. Unlike fetch requests, browsers let any page open a websocket to us, and read
+// whatever it sends back.
+func checkOrigin(corsOrigins []string) func(*websocket.Config, *http.Request) error {
+	anyOrigin := slices.Contains(corsOrigins, "*")
+
+	return func(config *websocket.Config, request *http.Request) error {
+		origin := request.Header.Get("Origin")
+		if origin == "" {
+			return nil
+		}
+
+		originURL, err := url.Parse(origin)
+		if err != nil {
+			return fmt.Errorf("origin %q is not allowed", origin)
+		}
+		if originURL.Host != request.Host && !anyOrigin && !slices.Contains(corsOrigins, origin) {
+			return fmt.Errorf("origin %q is not allowed", origin)
+		}
+		config.Origin = originURL
+		return nil
+	}
+}
+
+// serveWebsocket does all of the writing to the socket, which isn't safe to write to concurrently,
+// while a second goroutine reads filters from the client until it closes the socket
+func (s *Server) serveWebsocket(ws *websocket.Conn) {
+	defer ws.Close()
+
+	messages := make(chan []byte)
+	closed := make(chan struct{})
+	stopped := make(chan struct{})
+	defer close(stopped)
+	go func() {
+		defer close(closed)
+		for {
+			var message []byte
+			if err := websocket.Message.Receive(ws, &message); err != nil {
+				return
+			}
+			select {
+			case messages <- message:
+			case <-stopped:
+				return
+			}
+		}
+	}()
+
+	// Until the client sends a filter, summaries is nil and nothing is sent
+	var summaries chan telemetry.TraceSummary
+	defer func() {
+		if summaries != nil {
+			s.hub.unsubscribe(summaries)
+		}
+	}()
+
+	send := func(message streamMessage) error {
+		ws.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+		return websocket.JSON.Send(ws, message)
+	}
+
+	ping := time.NewTicker(websocketPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+
+		case message := <-messages:
+			query, err := parseStreamFilter(message)
+			if err != nil {
+				if err = send(streamMessage{Type: "error", Error: fmt.Sprintf("invalid filter: %s", err.Error())}); err != nil {
+					return
+				}
+				continue
+			}
+
+			if summaries != nil {
+				s.hub.unsubscribe(summaries)
+			}
+			summaries = s.hub.subscribe(query)
+
+		case summary, ok := <-summaries:
+			if !ok {
+				// The server is closing
+				return
+			}
+			if err := send(streamMessage{Type: "trace", Trace: &summary}); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			ws.SetWriteDeadline(time.Now().Add(websocketWriteTimeout))
+			ws.PayloadType = websocket.PingFrame
+			_, err := ws.Write(nil)
+			ws.PayloadType = websocket.TextFrame
+			if err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// SetAnnotations replaces the annotations of a trace. Setting none removes them. Annotations are
+// deleted along with their trace, so a trace has to be in the store to be annotated, though its
+// spans may still be waiting to be written.
+func (s *Store) SetAnnotations(ctx context.Context, traceID string, annotations telemetry.Annotations) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, TRACE_EXISTS, traceID).Scan(&exists); err != nil {
+		return fmt.Errorf("could not look up trace: %s", err.Error())
+	}
+	if !exists {
+		return telemetry.ErrTraceIDNotFound
+	}
+	defer s.changes.Add(1)
+
+	if len(annotations) == 0 {
+		if _, err := s.db.ExecContext(ctx, DELETE_ANNOTATIONS, traceID); err != nil {
+			return fmt.Errorf("could not delete annotations: %s", err.Error())
+		}
+		return nil
+	}
+
+	annotationsJSON, err := json.Marshal(annotations)
+	if err != nil {
+		return fmt.Errorf("could not marshal annotations: %s", err.Error())
+	}
+	if _, err = s.db.ExecContext(ctx, UPSERT_ANNOTATIONS, traceID, string(annotationsJSON), time.Now()); err != nil {
+		return fmt.Errorf("could not store annotations: %s", err.Error())
+	}
+	return nil
+}
+
+// GetAnnotations returns the annotations of a trace, which are empty if it has none.
+// Traces that aren't in the store return ErrTraceIDNotFound.
+func (s *Store) GetAnnotations(ctx context.Context, traceID string) (telemetry.Annotations, error) {
+	annotations := telemetry.Annotations{}
+
+	var annotationsJSON []byte
+	err := s.db.QueryRowContext(ctx, SELECT_ANNOTATIONS, traceID).Scan(&annotationsJSON)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err = s.db.QueryRowContext(ctx, TRACE_EXISTS, traceID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("could not look up trace: %s", err.Error())
+		}
+		if !exists {
+			return nil, telemetry.ErrTraceIDNotFound
+		}
+		return annotations, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve annotations: %s", err.Error())
+	}
+
+	if err = json.Unmarshal(annotationsJSON, &annotations); err != nil {
+		return nil, fmt.Errorf("could not unmarshal annotations: %s", err.Error())
+	}
+	return annotations, nil
+}
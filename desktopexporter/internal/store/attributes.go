@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// AttributeQuery scopes attribute keys and values to spans from one of a set of services.
+// Zero values don't filter anything.
+type AttributeQuery struct {
+	Services []string
+}
+
+// GetAttributeKeys returns every span attribute key in the store, sorted, with the number of spans that have it
+func (s *Store) GetAttributeKeys(ctx context.Context, query AttributeQuery) ([]telemetry.AttributeKey, error) {
+	keys := []telemetry.AttributeKey{}
+
+	conditions, args := query.conditions()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_ATTRIBUTE_KEYS, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve attribute keys: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key := telemetry.AttributeKey{}
+		if err = rows.Scan(&key.Key, &key.SpanCount); err != nil {
+			return nil, fmt.Errorf("could not scan attribute key: %s", err.Error())
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// GetAttributeValues returns up to limit distinct values of a span attribute, the most common first.
+// One more value than the limit is looked up, to tell whether the list was truncated.
+func (s *Store) GetAttributeValues(ctx context.Context, key string, query AttributeQuery, limit int) (telemetry.AttributeValues, error) {
+	values := telemetry.AttributeValues{Key: key, Values: []telemetry.AttributeValue{}}
+	if limit <= 0 {
+		return values, errors.New("could not retrieve attribute values: limit must be positive")
+	}
+
+	conditions, args := query.conditions()
+	args = append([]any{key}, args...)
+	args = append(args, limit+1)
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_ATTRIBUTE_VALUES, conditions), args...)
+	if err != nil {
+		return values, fmt.Errorf("could not retrieve attribute values: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		value := telemetry.AttributeValue{}
+		if err = rows.Scan(&value.Value, &value.SpanCount); err != nil {
+			return values, fmt.Errorf("could not scan attribute value: %s", err.Error())
+		}
+		if len(values.Values) == limit {
+			values.Truncated = true
+			break
+		}
+		values.Values = append(values.Values, value)
+	}
+	return values, rows.Err()
+}
+
+// GetAttributeCardinality returns up to limit span attribute keys with their approximate number
+// of distinct values, the most values first unless ascending is set. A limit of zero returns
+// every key.
+func (s *Store) GetAttributeCardinality(ctx context.Context, query AttributeQuery, ascending bool, limit int) ([]telemetry.AttributeCardinality, error) {
+	keys := []telemetry.AttributeCardinality{}
+
+	order := "DESC"
+	if ascending {
+		order = "ASC"
+	}
+	conditions, args := query.conditions()
+	statement, limitArgs := paginate(fmt.Sprintf(SELECT_ATTRIBUTE_CARDINALITY, conditions, order), limit, 0)
+	rows, err := s.db.QueryContext(ctx, statement, append(args, limitArgs...)...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve attribute cardinality: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key := telemetry.AttributeCardinality{}
+		if err = rows.Scan(&key.Key, &key.DistinctValues, &key.SpanCount); err != nil {
+			return nil, fmt.Errorf("could not scan attribute cardinality: %s", err.Error())
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (query AttributeQuery) conditions() (string, []any) {
+	conditions := ""
+	args := []any{}
+
+	if len(query.Services) > 0 {
+		conditions += fmt.Sprintf(" AND serviceName IN (%s)", placeholders(len(query.Services)))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+	return conditions, args
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+const (
+	defaultBatchWindow   = 100 * time.Millisecond
+	defaultBatchMaxSpans = 1000
+	batchQueueSize       = 64
+)
+
+// Option configures optional Store behaviour
+type Option func(*Store)
+
+// WithBatching coalesces spans added within window of each other into a single write,
+// writing early once maxSpans spans are pending
+func WithBatching(window time.Duration, maxSpans int) Option {
+	return func(s *Store) {
+		s.batchWindow = window
+		s.batchMaxSpans = maxSpans
+	}
+}
+
+// WithWriteListener calls listener with the IDs of the traces each batch wrote spans to,
+// once the batch is written. It runs on the goroutine that writes batches, so it must not block.
+func WithWriteListener(listener func(traceIDs []string)) Option {
+	return func(s *Store) {
+		s.writeListener = listener
+	}
+}
+
+// Observer is told about the spans the store receives and writes, to instrument it. Its methods
+// are called on ingestion paths and on the goroutine that writes batches, so they must not block.
+type Observer interface {
+	// SpansReceived is called with the number of spans each call to AddSpans queues
+	SpansReceived(count int)
+	// SpansDropped is called with the number of spans each call to AddSpans turns away
+	// for having a malformed trace or span ID
+	SpansDropped(count int)
+	// SpansRateLimited is called with the number of spans each call to IngestSpans turns away
+	// for arriving faster than the ingest rate limit
+	SpansRateLimited(count int)
+	// BatchWritten is called once a batch is written, with its number of spans and distinct traces
+	// and how long the write took
+	BatchWritten(spans int, traces int, duration time.Duration)
+	// TracesEvicted is called with the number of traces each eviction deleted, whether it was
+	// asked for by a retention policy or needed to keep the store under its span cap
+	TracesEvicted(count int)
+}
+
+// WithObserver reports the spans the store receives and writes to observer
+func WithObserver(observer Observer) Option {
+	return func(s *Store) {
+		s.observer = observer
+	}
+}
+
+// AddSpans queues spans to be written by the next batch. Use Flush to wait for them to be written.
+func (s *Store) AddSpans(ctx context.Context, spans []telemetry.SpanData) error {
+	_, err := s.queueSpans(ctx, spans)
+	return err
+}
+
+// queueSpans queues spans for AddSpans, and returns those it queued: the well-formed ones, with
+// their IDs normalized
+func (s *Store) queueSpans(ctx context.Context, spans []telemetry.SpanData) ([]telemetry.SpanData, error) {
+	s.closeMut.RLock()
+	defer s.closeMut.RUnlock()
+
+	if s.closed {
+		return nil, ErrStoreClosed
+	}
+	if err := s.writable(); err != nil {
+		return nil, err
+	}
+
+	spans = s.dropMalformedSpans(spans)
+	if len(spans) == 0 {
+		return spans, nil
+	}
+
+	select {
+	case s.batches <- spans:
+		if s.observer != nil {
+			s.observer.SpansReceived(len(spans))
+		}
+		return spans, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// dropMalformedSpans returns the spans whose trace and span IDs are well-formed, with their IDs
+// normalized. A misconfigured SDK tends to send every span without them, so the rest are logged
+// once per service.
+func (s *Store) dropMalformedSpans(spans []telemetry.SpanData) []telemetry.SpanData {
+	valid := make([]telemetry.SpanData, 0, len(spans))
+	dropped := map[string]int{}
+	reasons := map[string]error{}
+	services := []string{}
+	for _, span := range spans {
+		err := span.ValidateIDs()
+		if err == nil {
+			span.NormalizeIDs()
+			valid = append(valid, span)
+			continue
+		}
+
+		service := span.GetServiceName()
+		if _, ok := dropped[service]; !ok {
+			services = append(services, service)
+			reasons[service] = err
+		}
+		dropped[service]++
+	}
+	if len(services) == 0 {
+		return valid
+	}
+
+	for _, service := range services {
+		log.Printf("dropped %d spans from service %q with malformed IDs: %s", dropped[service], service, reasons[service].Error())
+	}
+	if s.observer != nil {
+		s.observer.SpansDropped(len(spans) - len(valid))
+	}
+	return valid
+}
+
+// Flush writes every span queued before it was called, and returns the error of that write
+func (s *Store) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+
+	select {
+	case s.flushes <- reply:
+	case <-s.batcherDone:
+		return ErrStoreClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// runBatcher accumulates queued spans and writes them once the batch window has passed
+// since the first of them arrived, the batch is full, a flush is requested, or the store closes
+func (s *Store) runBatcher() {
+	defer close(s.batcherDone)
+
+	batch := []telemetry.SpanData{}
+	var windowElapsed <-chan time.Time
+
+	write := func() error {
+		windowElapsed = nil
+		if len(batch) == 0 {
+			return nil
+		}
+
+		start := time.Now()
+		err := s.writeSpans(context.Background(), batch)
+		if err == nil && (s.writeListener != nil || s.observer != nil) {
+			traceIDs := batchTraceIDs(batch)
+			if s.writeListener != nil {
+				s.writeListener(traceIDs)
+			}
+			if s.observer != nil {
+				s.observer.BatchWritten(len(batch), len(traceIDs), time.Since(start))
+			}
+		}
+		batch = []telemetry.SpanData{}
+		return err
+	}
+
+	// drain picks up everything already queued, so a flush covers every span added before it
+	drain := func() {
+		for {
+			select {
+			case spans := <-s.batches:
+				batch = append(batch, spans...)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case spans := <-s.batches:
+			if len(batch) == 0 {
+				windowElapsed = time.After(s.batchWindow)
+			}
+			batch = append(batch, spans...)
+			if len(batch) >= s.batchMaxSpans {
+				if err := write(); err != nil {
+					log.Printf("could not write batch of spans: %s", err.Error())
+				}
+			}
+
+		case <-windowElapsed:
+			if err := write(); err != nil {
+				log.Printf("could not write batch of spans: %s", err.Error())
+			}
+
+		case reply := <-s.flushes:
+			drain()
+			reply <- write()
+
+		case <-s.stopBatcher:
+			drain()
+			if err := write(); err != nil {
+				log.Printf("could not write batch of spans: %s", err.Error())
+			}
+			return
+		}
+	}
+}
+
+// batchTraceIDs lists the distinct trace IDs of a batch, in the order they first appear
+func batchTraceIDs(batch []telemetry.SpanData) []string {
+	traceIDs := []string{}
+	seen := map[string]bool{}
+	for _, span := range batch {
+		if !seen[span.TraceID] {
+			seen[span.TraceID] = true
+			traceIDs = append(traceIDs, span.TraceID)
+		}
+	}
+	return traceIDs
+}
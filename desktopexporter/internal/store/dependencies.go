@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// DependencyQuery limits the service graph to calls that started within a time window.
+// Zero values don't filter anything.
+type DependencyQuery struct {
+	Start time.Time
+	End   time.Time
+}
+
+// GetServiceDependencies joins each span to its parent to find the calls between services,
+// counting them per pair of caller and callee services
+func (s *Store) GetServiceDependencies(ctx context.Context, query DependencyQuery) ([]telemetry.ServiceDependency, error) {
+	dependencies := []telemetry.ServiceDependency{}
+
+	conditions, args := query.conditions()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_SERVICE_DEPENDENCIES, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve service dependencies: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dependency := telemetry.ServiceDependency{}
+		if err = rows.Scan(&dependency.Parent, &dependency.Child, &dependency.CallCount, &dependency.ErrorCount); err != nil {
+			return nil, fmt.Errorf("could not scan service dependency: %s", err.Error())
+		}
+		dependencies = append(dependencies, dependency)
+	}
+	return dependencies, rows.Err()
+}
+
+// GetServiceNodes sums up the spans of each service in the service graph: how many there are,
+// how many failed, and roughly how long the slowest of them took
+func (s *Store) GetServiceNodes(ctx context.Context, query DependencyQuery) ([]telemetry.ServiceNode, error) {
+	nodes := []telemetry.ServiceNode{}
+
+	conditions, args := query.conditions()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_SERVICE_NODES, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve service nodes: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		node := telemetry.ServiceNode{}
+		if err = rows.Scan(&node.ServiceName, &node.SpanCount, &node.ErrorCount, &node.P95DurationNanos); err != nil {
+			return nil, fmt.Errorf("could not scan service node: %s", err.Error())
+		}
+		if node.SpanCount > 0 {
+			node.ErrorRate = float64(node.ErrorCount) / float64(node.SpanCount)
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+func (query DependencyQuery) conditions() (string, []any) {
+	conditions := ""
+	args := []any{}
+
+	if !query.Start.IsZero() {
+		conditions += " AND child.startTime >= ?"
+		args = append(args, query.Start)
+	}
+	if !query.End.IsZero() {
+		conditions += " AND child.startTime <= ?"
+		args = append(args, query.End)
+	}
+	return conditions, args
+}
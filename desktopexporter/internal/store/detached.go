@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// LocateMissingParents looks for the parents missing from the span tree of a trace among the
+// spans of every other trace. A placeholder whose span is found gets the ID of the trace it was
+// found in, and the orphans under it are marked as detached for that reason instead.
+func (s *Store) LocateMissingParents(ctx context.Context, traceID string, roots []*telemetry.SpanNode) error {
+	missing := map[string]*telemetry.SpanNode{}
+	args := []any{traceID}
+	for _, root := range roots {
+		if root.Span == nil {
+			missing[root.SpanID] = root
+			args = append(args, root.SpanID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_SPAN_TRACE_IDS, placeholders(len(missing))), args...)
+	if err != nil {
+		return fmt.Errorf("could not look up missing parents: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var spanID, parentTraceID string
+		if err = rows.Scan(&spanID, &parentTraceID); err != nil {
+			return fmt.Errorf("could not scan missing parent: %s", err.Error())
+		}
+		placeholder := missing[spanID]
+		placeholder.TraceID = parentTraceID
+		for _, orphan := range placeholder.Children {
+			orphan.Detached = telemetry.DetachedParentInOtherTrace
+		}
+	}
+	return rows.Err()
+}
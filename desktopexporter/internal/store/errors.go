@@ -0,0 +1,12 @@
+package store
+
+import (
+	"errors"
+)
+
+var ErrDatabaseNotFound = errors.New("database file does not exist")
+var ErrDatabaseInUse = errors.New("database file is already in use by another otel-desktop-viewer")
+var ErrSchemaMismatch = errors.New("database schema does not match this version of otel-desktop-viewer")
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of otel-desktop-viewer")
+var ErrStoreClosed = errors.New("store is closed")
+var ErrReadOnly = errors.New("store is read-only")
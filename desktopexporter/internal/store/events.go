@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// addMatchedEvents lists the events in each summary's trace that the query's event search or
+// filters matched. The database has already picked the traces, so this only reads the events
+// of the traces on the page, matching them the same way without going through SQL.
+func (s *Store) addMatchedEvents(ctx context.Context, summaries []telemetry.TraceSummary, query SummaryQuery) error {
+	if len(summaries) == 0 {
+		return nil
+	}
+
+	traceIDs := make([]any, len(summaries))
+	for i, summary := range summaries {
+		traceIDs[i] = summary.TraceID
+	}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_TRACE_EVENTS, placeholders(len(traceIDs))), traceIDs...)
+	if err != nil {
+		return fmt.Errorf("could not retrieve span events: %s", err.Error())
+	}
+	defer rows.Close()
+
+	search := strings.ToLower(query.Search)
+	matches := map[string][]telemetry.EventMatch{}
+	for rows.Next() {
+		var traceID, spanID, spanName string
+		eventBytes := []byte{}
+		if err = rows.Scan(&traceID, &spanID, &spanName, &eventBytes); err != nil {
+			return fmt.Errorf("could not scan span events: %s", err.Error())
+		}
+		events := []telemetry.EventData{}
+		if err = json.Unmarshal(eventBytes, &events); err != nil {
+			return fmt.Errorf("could not unmarshal span events: %s", err.Error())
+		}
+
+		for i, event := range events {
+			matched := slices.Contains(query.EventNames, event.Name)
+			if !matched && query.SearchEvents && search != "" {
+				matched = eventContains(event, search)
+			}
+			if matched {
+				matches[traceID] = append(matches[traceID], telemetry.EventMatch{
+					SpanID:     spanID,
+					SpanName:   spanName,
+					EventIndex: i,
+					EventName:  event.Name,
+					Timestamp:  event.Timestamp,
+				})
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not retrieve span events: %s", err.Error())
+	}
+
+	for i := range summaries {
+		summaries[i].MatchedEvents = matches[summaries[i].TraceID]
+	}
+	return nil
+}
+
+// eventContains reports whether the event's name or any of its attribute values contains
+// the lowercased search text
+func eventContains(event telemetry.EventData, search string) bool {
+	if strings.Contains(strings.ToLower(event.Name), search) {
+		return true
+	}
+	for _, value := range event.Attributes {
+		if strings.Contains(strings.ToLower(fmt.Sprint(value)), search) {
+			return true
+		}
+	}
+	return false
+}
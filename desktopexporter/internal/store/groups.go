@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// DefaultGroup holds the traces none of whose spans have the grouping attribute,
+// and every trace when there isn't one
+const DefaultGroup = "default"
+
+// WithGroupAttribute groups traces by the value of a resource attribute, such as tenant.id, for
+// several tenants sharing a viewer to keep to their own traces. The value is looked up when
+// traces are read rather than when they are written, so the attribute can change between runs.
+func WithGroupAttribute(key string) Option {
+	return func(s *Store) {
+		s.groupAttribute = key
+	}
+}
+
+// GroupAttribute is the resource attribute traces are grouped by, or empty if they aren't
+func (s *Store) GroupAttribute() string {
+	return s.groupAttribute
+}
+
+// GetGroups counts the traces in each group. A trace whose spans came from resources in
+// different groups counts towards each of them.
+func (s *Store) GetGroups(ctx context.Context) (telemetry.TraceGroups, error) {
+	groups := telemetry.TraceGroups{Attribute: s.groupAttribute, Groups: []telemetry.TraceGroup{}}
+
+	if s.groupAttribute == "" {
+		count, err := s.CountTraces(ctx)
+		if err != nil {
+			return groups, err
+		}
+		if count > 0 {
+			groups.Groups = append(groups.Groups, telemetry.TraceGroup{Name: DefaultGroup, TraceCount: count})
+		}
+		return groups, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_TRACE_GROUPS, s.groupAttribute)
+	if err != nil {
+		return groups, fmt.Errorf("could not retrieve trace groups: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		group := telemetry.TraceGroup{}
+		var name *string
+		if err = rows.Scan(&name, &group.TraceCount); err != nil {
+			return groups, fmt.Errorf("could not scan trace groups: %s", err.Error())
+		}
+		group.Name = DefaultGroup
+		if name != nil {
+			group.Name = *name
+		}
+		groups.Groups = append(groups.Groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// groupsCondition picks the traces in any of the groups, for the WHERE clause of SELECT_TRACE_SUMMARIES
+func groupsCondition(attribute string, groups []string) (string, []any) {
+	conditions := []string{}
+	args := []any{}
+	for _, group := range groups {
+		switch {
+		case group == DefaultGroup && attribute == "":
+			return "TRUE", nil
+		case group == DefaultGroup:
+			conditions = append(conditions, "NOT "+TRACE_IN_ANY_GROUP_CONDITION)
+			args = append(args, attribute)
+		case attribute == "":
+			conditions = append(conditions, "FALSE")
+		default:
+			conditions = append(conditions, TRACE_IN_GROUP_CONDITION)
+			args = append(args, attribute, group)
+		}
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/marcboeker/go-duckdb"
+	"go.opentelemetry.io/collector/pdata/plog"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// AddLogs writes logs straight away; unlike spans they are not batched
+func (s *Store) AddLogs(ctx context.Context, logs []telemetry.LogData) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	appender, err := duckdb.NewAppenderFromConn(s.conn, "", "logs")
+	if err != nil {
+		return fmt.Errorf("could not create new appender for logs: %s", err.Error())
+	}
+	defer appender.Close()
+
+	for _, logData := range logs {
+		attributes, err := json.Marshal(logData.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal log attributes: %s", err.Error())
+		}
+
+		resourceAttributes, err := json.Marshal(logData.Resource.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal resource attributes: %s", err.Error())
+		}
+
+		scopeAttributes, err := json.Marshal(logData.Scope.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal scope attributes: %s", err.Error())
+		}
+
+		if err := appender.AppendRow(
+			logData.Timestamp,
+			logData.ObservedTimestamp,
+			logData.TraceID,
+			logData.SpanID,
+			logData.SeverityText,
+			int32(logData.SeverityNumber),
+			logData.Body,
+			string(attributes),
+			string(resourceAttributes),
+			logData.Resource.DroppedAttributesCount,
+			logData.Scope.Name,
+			logData.Scope.Version,
+			string(scopeAttributes),
+			logData.Scope.DroppedAttributesCount,
+			logData.DroppedAttributesCount,
+			uint32(logData.Flags),
+		); err != nil {
+			return fmt.Errorf("could not append row to logs: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// GetLogsByTrace returns the logs recorded as part of a trace, oldest first
+func (s *Store) GetLogsByTrace(ctx context.Context, traceID string) ([]telemetry.LogData, error) {
+	logs := []telemetry.LogData{}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_LOGS_BY_TRACE, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve logs: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		logData := telemetry.LogData{}
+		logData.Resource = &telemetry.ResourceData{
+			Attributes:             map[string]interface{}{},
+			DroppedAttributesCount: 0,
+		}
+		logData.Scope = &telemetry.ScopeData{
+			Name:                   "",
+			Version:                "",
+			Attributes:             map[string]interface{}{},
+			DroppedAttributesCount: 0,
+		}
+
+		// Placeholders for JSON
+		attrBytes := []byte{}
+		rAttrBytes := []byte{}
+		sAttrBytes := []byte{}
+
+		var severityNumber int32
+		var flags uint32
+
+		if err = rows.Scan(
+			&logData.Timestamp,
+			&logData.ObservedTimestamp,
+			&logData.TraceID,
+			&logData.SpanID,
+			&logData.SeverityText,
+			&severityNumber,
+			&logData.Body,
+			&attrBytes,
+			&rAttrBytes,
+			&logData.Resource.DroppedAttributesCount,
+			&logData.Scope.Name,
+			&logData.Scope.Version,
+			&sAttrBytes,
+			&logData.Scope.DroppedAttributesCount,
+			&logData.DroppedAttributesCount,
+			&flags,
+		); err != nil {
+			return nil, fmt.Errorf("could not scan logs: %s", err.Error())
+		}
+		logData.SeverityNumber = plog.SeverityNumber(severityNumber)
+		logData.Flags = plog.LogRecordFlags(flags)
+
+		if err = json.Unmarshal(attrBytes, &logData.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal log attributes: %s", err.Error())
+		}
+
+		if err = json.Unmarshal(rAttrBytes, &logData.Resource.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal resource attributes: %s", err.Error())
+		}
+
+		if err = json.Unmarshal(sAttrBytes, &logData.Scope.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal scope attributes: %s", err.Error())
+		}
+
+		logs = append(logs, logData)
+	}
+	return logs, rows.Err()
+}
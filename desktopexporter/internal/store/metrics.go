@@ -0,0 +1,287 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/marcboeker/go-duckdb"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// MetricQuery selects the metric data points to return. Zero values don't filter anything.
+type MetricQuery struct {
+	Name    string
+	Service string
+	Start   time.Time
+	End     time.Time
+}
+
+// AddMetrics writes metric data points straight away; unlike spans they are not batched
+func (s *Store) AddMetrics(ctx context.Context, metrics []telemetry.MetricData) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	appender, err := duckdb.NewAppenderFromConn(s.conn, "", "metrics")
+	if err != nil {
+		return fmt.Errorf("could not create new appender for metrics: %s", err.Error())
+	}
+	defer appender.Close()
+
+	for _, metric := range metrics {
+		attributes, err := json.Marshal(metric.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal metric attributes: %s", err.Error())
+		}
+
+		// Both kinds of histogram share a column, and are told apart by the metric type
+		var histogram any
+		if metric.Histogram != nil || metric.ExponentialHistogram != nil {
+			var histogramBytes []byte
+			if metric.ExponentialHistogram != nil {
+				histogramBytes, err = json.Marshal(metric.ExponentialHistogram)
+			} else {
+				histogramBytes, err = json.Marshal(metric.Histogram)
+			}
+			if err != nil {
+				return fmt.Errorf("could not marshal metric histogram: %s", err.Error())
+			}
+			histogram = string(histogramBytes)
+		}
+
+		var value any
+		if metric.Value != nil {
+			value = *metric.Value
+		}
+
+		resourceAttributes, err := json.Marshal(metric.Resource.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal resource attributes: %s", err.Error())
+		}
+
+		scopeAttributes, err := json.Marshal(metric.Scope.Attributes)
+		if err != nil {
+			return fmt.Errorf("could not marshal scope attributes: %s", err.Error())
+		}
+
+		exemplars, err := json.Marshal(metric.Exemplars)
+		if err != nil {
+			return fmt.Errorf("could not marshal metric exemplars: %s", err.Error())
+		}
+
+		if err := appender.AppendRow(
+			metric.Name,
+			metric.Description,
+			metric.Unit,
+			metric.Type,
+			nullableTime(metric.StartTime),
+			nullableTime(metric.Timestamp),
+			string(attributes),
+			value,
+			metric.IsMonotonic,
+			metric.AggregationTemporality,
+			histogram,
+			string(resourceAttributes),
+			metric.Resource.DroppedAttributesCount,
+			metric.Scope.Name,
+			metric.Scope.Version,
+			string(scopeAttributes),
+			metric.Scope.DroppedAttributesCount,
+			string(exemplars),
+		); err != nil {
+			return fmt.Errorf("could not append row to metrics: %s", err.Error())
+		}
+	}
+	return nil
+}
+
+// QueryMetrics returns the metric data points matching the query, ordered by name and then time
+func (s *Store) QueryMetrics(ctx context.Context, query MetricQuery) ([]telemetry.MetricData, error) {
+	metrics := []telemetry.MetricData{}
+
+	where, args := query.where()
+	rows, err := s.db.QueryContext(ctx, SELECT_METRICS+where+" ORDER BY name, timestamp", args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve metrics: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		metric := telemetry.MetricData{}
+		metric.Resource = &telemetry.ResourceData{
+			Attributes:             map[string]interface{}{},
+			DroppedAttributesCount: 0,
+		}
+		metric.Scope = &telemetry.ScopeData{
+			Name:                   "",
+			Version:                "",
+			Attributes:             map[string]interface{}{},
+			DroppedAttributesCount: 0,
+		}
+
+		// Placeholders for JSON
+		attrBytes := []byte{}
+		histBytes := []byte{}
+		rAttrBytes := []byte{}
+		sAttrBytes := []byte{}
+		exemplarBytes := []byte{}
+
+		var startTime, timestamp sql.NullTime
+		var value sql.NullFloat64
+
+		if err = rows.Scan(
+			&metric.Name,
+			&metric.Description,
+			&metric.Unit,
+			&metric.Type,
+			&startTime,
+			&timestamp,
+			&attrBytes,
+			&value,
+			&metric.IsMonotonic,
+			&metric.AggregationTemporality,
+			&histBytes,
+			&rAttrBytes,
+			&metric.Resource.DroppedAttributesCount,
+			&metric.Scope.Name,
+			&metric.Scope.Version,
+			&sAttrBytes,
+			&metric.Scope.DroppedAttributesCount,
+			&exemplarBytes,
+		); err != nil {
+			return nil, fmt.Errorf("could not scan metrics: %s", err.Error())
+		}
+
+		if startTime.Valid {
+			metric.StartTime = startTime.Time
+		}
+		if timestamp.Valid {
+			metric.Timestamp = timestamp.Time
+		}
+		if value.Valid {
+			metric.Value = &value.Float64
+		}
+
+		if err = json.Unmarshal(attrBytes, &metric.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal metric attributes: %s", err.Error())
+		}
+
+		if len(histBytes) > 0 {
+			if metric.Type == pmetric.MetricTypeExponentialHistogram.String() {
+				metric.ExponentialHistogram = &telemetry.ExponentialHistogramData{}
+				err = json.Unmarshal(histBytes, metric.ExponentialHistogram)
+			} else {
+				metric.Histogram = &telemetry.HistogramData{}
+				err = json.Unmarshal(histBytes, metric.Histogram)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("could not unmarshal metric histogram: %s", err.Error())
+			}
+		}
+
+		if err = json.Unmarshal(rAttrBytes, &metric.Resource.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal resource attributes: %s", err.Error())
+		}
+
+		if err = json.Unmarshal(sAttrBytes, &metric.Scope.Attributes); err != nil {
+			return nil, fmt.Errorf("could not unmarshal scope attributes: %s", err.Error())
+		}
+
+		// Data points stored before exemplars were kept have none
+		if len(exemplarBytes) > 0 {
+			if err = json.Unmarshal(exemplarBytes, &metric.Exemplars); err != nil {
+				return nil, fmt.Errorf("could not unmarshal metric exemplars: %s", err.Error())
+			}
+		}
+		if metric.Exemplars == nil {
+			metric.Exemplars = []telemetry.ExemplarData{}
+		}
+
+		metrics = append(metrics, metric)
+	}
+	return metrics, rows.Err()
+}
+
+// ResolveExemplars marks every exemplar with a trace ID as resolved or not, depending on
+// whether its trace is in the store, so the UI knows which ones it can link to. The traces
+// of all the exemplars are looked up at once.
+func (s *Store) ResolveExemplars(ctx context.Context, metrics []telemetry.MetricData) error {
+	traceIDs := []string{}
+	for _, metric := range metrics {
+		for _, exemplar := range metric.Exemplars {
+			if exemplar.TraceID != "" && !slices.Contains(traceIDs, exemplar.TraceID) {
+				traceIDs = append(traceIDs, exemplar.TraceID)
+			}
+		}
+	}
+	if len(traceIDs) == 0 {
+		return nil
+	}
+
+	summaries, _, err := s.QueryTraceSummaries(ctx, SummaryQuery{TraceIDs: traceIDs})
+	if err != nil {
+		return fmt.Errorf("could not resolve metric exemplars: %s", err.Error())
+	}
+	found := map[string]bool{}
+	for _, summary := range *summaries {
+		found[summary.TraceID] = true
+	}
+
+	for i := range metrics {
+		for j := range metrics[i].Exemplars {
+			exemplar := &metrics[i].Exemplars[j]
+			if exemplar.TraceID != "" {
+				resolved := found[exemplar.TraceID]
+				exemplar.Resolved = &resolved
+			}
+		}
+	}
+	return nil
+}
+
+func (query MetricQuery) where() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+
+	if query.Name != "" {
+		conditions = append(conditions, "name = ?")
+		args = append(args, query.Name)
+	}
+	if query.Service != "" {
+		conditions = append(conditions, "(resourceAttributes->>'service.name') = ?")
+		args = append(args, query.Service)
+	}
+
+	// Data points without a timestamp never match a time range
+	if !query.Start.IsZero() {
+		conditions = append(conditions, "timestamp >= ?")
+		args = append(args, query.Start)
+	}
+	if !query.End.IsZero() {
+		conditions = append(conditions, "timestamp <= ?")
+		args = append(args, query.End)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// nullableTime stores unset times as NULL, as the zero time is out of range for TIMESTAMP_NS
+func nullableTime(t time.Time) any {
+	if t.IsZero() {
+		return nil
+	}
+	return t
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// OperationQuery scopes operation stats to spans that started within a time window and came
+// from one of a set of services. Zero values don't filter anything.
+type OperationQuery struct {
+	Services []string
+	Start    time.Time
+	End      time.Time
+	// GroupByService keeps operations of the same name apart when they come from different services
+	GroupByService bool
+}
+
+// GetOperationStats has DuckDB work out the approximate duration percentiles of every span name,
+// slowest first
+func (s *Store) GetOperationStats(ctx context.Context, query OperationQuery) ([]telemetry.OperationStats, error) {
+	operations := []telemetry.OperationStats{}
+
+	serviceName := "''"
+	if query.GroupByService {
+		serviceName = "serviceName"
+	}
+	conditions, args := query.conditions()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_OPERATION_STATS, serviceName, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve operation stats: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		operation := telemetry.OperationStats{}
+		err = rows.Scan(
+			&operation.ServiceName,
+			&operation.Name,
+			&operation.SpanCount,
+			&operation.P50DurationNanos,
+			&operation.P95DurationNanos,
+			&operation.P99DurationNanos,
+			&operation.MaxDurationNanos,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not scan operation stats: %s", err.Error())
+		}
+		operations = append(operations, operation)
+	}
+	return operations, rows.Err()
+}
+
+func (query OperationQuery) conditions() (string, []any) {
+	conditions := ""
+	args := []any{}
+
+	if len(query.Services) > 0 {
+		conditions += fmt.Sprintf(" AND serviceName IN (%s)", placeholders(len(query.Services)))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+	if !query.Start.IsZero() {
+		conditions += " AND startTime >= ?"
+		args = append(args, query.Start)
+	}
+	if !query.End.IsZero() {
+		conditions += " AND startTime <= ?"
+		args = append(args, query.End)
+	}
+	return conditions, args
+}
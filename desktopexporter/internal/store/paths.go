@@ -0,0 +1,66 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseAttributePath reads a path into nested attribute values from the start of text, and
+// returns it as the JSONPath DuckDB extracts with, along with whatever text follows it.
+// A path is $ followed by any number of steps, each of which is either .key, ."key" for
+// keys with dots or other punctuation in them, or [n] for the nth item of a list, from 0.
+// Keys may not contain double quotes, and there are no wildcards.
+func parseAttributePath(text string) (string, string, error) {
+	if !strings.HasPrefix(text, "$") {
+		return "", text, fmt.Errorf("must start with $")
+	}
+
+	path := strings.Builder{}
+	path.WriteString("$")
+	count := 0
+	rest := text[1:]
+steps:
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+			var key string
+			if strings.HasPrefix(rest, `"`) {
+				end := strings.IndexByte(rest[1:], '"')
+				if end < 0 {
+					return "", rest, fmt.Errorf("unterminated quoted key")
+				}
+				key, rest = rest[1:end+1], rest[end+2:]
+			} else {
+				end := strings.IndexAny(rest, `.[="`)
+				if end < 0 {
+					end = len(rest)
+				}
+				key, rest = rest[:end], rest[end:]
+			}
+			if key == "" {
+				return "", rest, fmt.Errorf("empty key")
+			}
+			path.WriteString(`."` + key + `"`)
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return "", rest, fmt.Errorf("unterminated index")
+			}
+			index := rest[1:end]
+			if index == "" || strings.Trim(index, "0123456789") != "" {
+				return "", rest, fmt.Errorf("index %q must be a whole number", index)
+			}
+			path.WriteString(rest[:end+1])
+			rest = rest[end+1:]
+		default:
+			break steps
+		}
+		count++
+	}
+
+	if count == 0 {
+		return "", rest, fmt.Errorf("must have at least one step after $")
+	}
+	return path.String(), rest, nil
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// PinTrace keeps a trace from being evicted, by the retention policy or to make room under the
+// span cap, until it is unpinned. Pinning a trace twice does nothing. Pins are deleted along
+// with their trace, so a trace has to be in the store to be pinned.
+func (s *Store) PinTrace(ctx context.Context, traceID string) error {
+	return s.setPinned(ctx, traceID, true)
+}
+
+// UnpinTrace lets a trace be evicted again. Unpinning a trace that isn't pinned does nothing.
+func (s *Store) UnpinTrace(ctx context.Context, traceID string) error {
+	return s.setPinned(ctx, traceID, false)
+}
+
+// setPinned writes any spans still waiting to be written first, so a trace that was only just
+// received can be pinned, or unpinned, like any other
+func (s *Store) setPinned(ctx context.Context, traceID string, pinned bool) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	var exists bool
+	if err := s.db.QueryRowContext(ctx, TRACE_EXISTS, traceID).Scan(&exists); err != nil {
+		return fmt.Errorf("could not look up trace: %s", err.Error())
+	}
+	if !exists {
+		return telemetry.ErrTraceIDNotFound
+	}
+	defer s.changes.Add(1)
+
+	if !pinned {
+		if _, err := s.db.ExecContext(ctx, DELETE_PIN, traceID); err != nil {
+			return fmt.Errorf("could not unpin trace: %s", err.Error())
+		}
+		return nil
+	}
+	if _, err := s.db.ExecContext(ctx, INSERT_PIN, traceID, time.Now()); err != nil {
+		return fmt.Errorf("could not pin trace: %s", err.Error())
+	}
+	return nil
+}
@@ -27,32 +27,718 @@ const (
 		statusMessage VARCHAR)
 	`
 
-	SELECT_ORDERED_TRACES = `
-		SELECT traceID 
+	CREATE_LOGS_TABLE string = `
+		CREATE TABLE IF NOT EXISTS logs
+		(timestamp TIMESTAMP_NS,
+		observedTimestamp TIMESTAMP_NS,
+		traceID VARCHAR,
+		spanID VARCHAR,
+		severityText VARCHAR,
+		severityNumber INTEGER,
+		body VARCHAR,
+		attributes JSON,
+		resourceAttributes JSON,
+		resourceDroppedAttributesCount UINTEGER,
+		scopeName VARCHAR,
+		scopeVersion VARCHAR,
+		scopeAttributes JSON,
+		scopeDroppedAttributesCount UINTEGER,
+		droppedAttributesCount UINTEGER,
+		flags UINTEGER)
+	`
+
+	CREATE_METRICS_TABLE string = `
+		CREATE TABLE IF NOT EXISTS metrics
+		(name VARCHAR,
+		description VARCHAR,
+		unit VARCHAR,
+		type VARCHAR,
+		startTime TIMESTAMP_NS,
+		timestamp TIMESTAMP_NS,
+		attributes JSON,
+		value DOUBLE,
+		isMonotonic BOOLEAN,
+		aggregationTemporality VARCHAR,
+		histogram JSON,
+		resourceAttributes JSON,
+		resourceDroppedAttributesCount UINTEGER,
+		scopeName VARCHAR,
+		scopeVersion VARCHAR,
+		scopeAttributes JSON,
+		scopeDroppedAttributesCount UINTEGER)
+	`
+	ADD_METRIC_EXEMPLARS string = `
+		ALTER TABLE metrics ADD COLUMN IF NOT EXISTS exemplars JSON
+	`
+
+	// Rebuilds the spans table keyed by trace and span ID, keeping the last copy to end of any
+	// span that was stored more than once. Spans without either ID can't be keyed, and are dropped.
+	KEY_SPANS_BY_ID string = `
+		CREATE OR REPLACE TABLE keyed_spans
+		(traceID VARCHAR, 
+		traceState VARCHAR, 
+		spanID VARCHAR, 
+		parentSpanID VARCHAR,
+		name VARCHAR, 
+		kind VARCHAR, 
+		startTime TIMESTAMP_NS, 
+		endTime TIMESTAMP_NS,
+		attributes JSON, 
+		events JSON,
+		links JSON,
+		resourceAttributes JSON,
+		resourceDroppedAttributesCount UINTEGER,
+		scopeName VARCHAR,
+		scopeVersion VARCHAR,
+		scopeAttributes JSON,
+		scopeDroppedAttributesCount UINTEGER, 
+		droppedAttributesCount UINTEGER, 
+		droppedEventsCount UINTEGER, 
+		droppedLinksCount UINTEGER,
+		statusCode VARCHAR, 
+		statusMessage VARCHAR,
+		PRIMARY KEY (traceID, spanID));
+		INSERT INTO keyed_spans
+		SELECT * FROM spans
+		WHERE traceID IS NOT NULL AND spanID IS NOT NULL
+		QUALIFY row_number() OVER (PARTITION BY traceID, spanID ORDER BY endTime DESC) = 1;
+		DROP TABLE spans;
+		ALTER TABLE keyed_spans RENAME TO spans;
+	`
+
+	CREATE_ANNOTATIONS_TABLE string = `
+		CREATE TABLE IF NOT EXISTS annotations
+		(traceID VARCHAR PRIMARY KEY,
+		annotations JSON,
+		updatedAt TIMESTAMP_NS)
+	`
+	TRACE_EXISTS string = `
+		SELECT EXISTS (SELECT 1 FROM spans WHERE traceID = ?)
+	`
+	UPSERT_ANNOTATIONS string = `
+		INSERT OR REPLACE INTO annotations
+		VALUES (?, ?, ?)
+	`
+	SELECT_ANNOTATIONS string = `
+		SELECT annotations
+		FROM annotations
+		WHERE traceID = ?
+	`
+	DELETE_ANNOTATIONS string = `
+		DELETE FROM annotations
+		WHERE traceID = ?
+	`
+	DELETE_SELECTED_ANNOTATIONS string = `
+		DELETE FROM annotations
+		WHERE traceID IN (SELECT traceID FROM selected_traces)
+	`
+	TRUNCATE_ANNOTATIONS string = `
+		TRUNCATE annotations;
+	`
+	CREATE_PINS_TABLE string = `
+		CREATE TABLE IF NOT EXISTS pins
+		(traceID VARCHAR PRIMARY KEY,
+		pinnedAt TIMESTAMP_NS)
+	`
+	// A trace pinned again keeps the time it was first pinned
+	INSERT_PIN string = `
+		INSERT OR IGNORE INTO pins
+		VALUES (?, ?)
+	`
+	DELETE_PIN string = `
+		DELETE FROM pins
+		WHERE traceID = ?
+	`
+	DELETE_SELECTED_PINS string = `
+		DELETE FROM pins
+		WHERE traceID IN (SELECT traceID FROM selected_traces)
+	`
+	TRUNCATE_PINS string = `
+		TRUNCATE pins;
+	`
+	// Stands in for the spans table in the eviction selections, so pinned traces are never picked
+	// and don't count towards how many traces are kept
+	UNPINNED_SPANS string = `
+		(SELECT * FROM spans WHERE traceID NOT IN (SELECT traceID FROM pins)) AS unpinned_spans
+	`
+
+	CREATE_SCHEMA_VERSION_TABLE string = `
+		CREATE TABLE IF NOT EXISTS schema_version
+		(version INTEGER,
+		appliedAt TIMESTAMP DEFAULT current_timestamp)
+	`
+	SELECT_SCHEMA_VERSION string = `
+		SELECT max(version)
+		FROM schema_version
+	`
+	INSERT_SCHEMA_VERSION string = `
+		INSERT INTO schema_version (version)
+		VALUES (?)
+	`
+	SELECT_SCHEMA_VERSION_TABLE_EXISTS string = `
+		SELECT count(*) > 0
+		FROM information_schema.tables
+		WHERE table_schema = 'main' AND table_name = 'schema_version'
+	`
+	SELECT_SPANS_TABLE_EXISTS string = `
+		SELECT count(*) > 0
+		FROM information_schema.tables
+		WHERE table_schema = 'main' AND table_name = 'spans'
+	`
+	SELECT_TABLE_COLUMNS string = `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = 'main' AND table_name = ?
+		ORDER BY ordinal_position
+	`
+
+	SELECT_TRACE_SUMMARIES string = `
+		WITH traces AS (
+			SELECT traceID, count(*) AS spanCount, MAX(startTime) AS lastStartTime
+			FROM spans
+			GROUP BY traceID
+		),
+		roots AS (
+			SELECT DISTINCT ON (traceID)
+				traceID,
+				ifnull(resourceAttributes->>'service.name', '') AS rootServiceName,
+				name AS rootName,
+				startTime AS rootStartTime,
+				endTime AS rootEndTime
+			FROM spans
+			WHERE parentSpanID = ''
+			ORDER BY traceID, startTime
+		)
+		SELECT traces.traceID, traces.spanCount, roots.rootServiceName, roots.rootName, roots.rootStartTime, roots.rootEndTime, annotations.annotations, pins.traceID IS NOT NULL AS pinned
+		FROM traces
+		LEFT JOIN roots ON traces.traceID = roots.traceID
+		LEFT JOIN annotations ON traces.traceID = annotations.traceID
+		LEFT JOIN pins ON traces.traceID = pins.traceID
+	`
+	COUNT_TRACE_SUMMARIES string = `
+		SELECT count(*)
+		FROM (%s)
+	`
+	// Attribute values are joined with a unit separator so a match can't straddle two values
+	SEARCH_SPANS_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE name ILIKE ? ESCAPE '\'
+			OR statusMessage ILIKE ? ESCAPE '\'
+			OR array_to_string(json_extract_string(attributes, '$.*'), chr(31)) ILIKE ? ESCAPE '\'
+		)
+	`
+	// Attribute values are joined as SEARCH_SPANS_CONDITION joins them
+	SELECT_TRACE_SPAN_MATCHES string = `
+		SELECT spanID
+		FROM spans
+		WHERE traceID = ?
+		AND (
+			name ILIKE ? ESCAPE '\'
+			OR array_to_string(json_extract_string(attributes, '$.*'), chr(31)) ILIKE ? ESCAPE '\'
+		)
+		ORDER BY startTime, spanID
+	`
+	// Also matches the names and attribute values of span events
+	SEARCH_SPANS_AND_EVENTS_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE name ILIKE ? ESCAPE '\'
+			OR statusMessage ILIKE ? ESCAPE '\'
+			OR array_to_string(json_extract_string(attributes, '$.*'), chr(31)) ILIKE ? ESCAPE '\'
+			OR array_to_string(json_extract_string(events, '$[*].name'), chr(31)) ILIKE ? ESCAPE '\'
+			OR array_to_string(json_extract_string(events, '$[*].attributes.*'), chr(31)) ILIKE ? ESCAPE '\'
+		)
+	`
+	EVENT_NAME_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE list_contains(json_extract_string(events, '$[*].name'), ?)
+		)
+	`
+	// Keys are looked up whole, so an attribute like http.target isn't read as a JSON path,
+	// unless they start with $, which only the paths from parseAttributePath do
+	// The placeholder of each attribute condition takes ROOT_SPANS_ONLY to only match
+	// the attributes of root spans, or nothing to match those of any span
+	ATTRIBUTE_EXISTS_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE %s(attributes->>?) IS NOT NULL
+		)
+	`
+	ATTRIBUTE_EQUALS_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE %s(attributes->>?) = ?
+		)
+	`
+	ATTRIBUTE_EQUALS_NUMBER_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE %sTRY_CAST((attributes->>?) AS DOUBLE) = ?
+		)
+	`
+	ROOT_SPANS_ONLY string = "parentSpanID = '' AND "
+	// The first placeholder of both takes the resource attribute traces are grouped by
+	TRACE_IN_GROUP_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE (resourceAttributes->>?) = ?
+		)
+	`
+	TRACE_IN_ANY_GROUP_CONDITION string = `
+		traces.traceID IN (
+			SELECT traceID
+			FROM spans
+			WHERE (resourceAttributes->>?) IS NOT NULL
+		)
+	`
+	// The placeholder takes the resource attribute traces are grouped by. Traces in no group
+	// are counted under a NULL name, which sorts last.
+	SELECT_TRACE_GROUPS string = `
+		WITH trace_groups AS (
+			SELECT DISTINCT traceID, resourceAttributes->>? AS groupName
+			FROM spans
+		)
+		SELECT groupName, count(*)
+		FROM trace_groups
+		WHERE groupName IS NOT NULL
+		OR traceID NOT IN (SELECT traceID FROM trace_groups WHERE groupName IS NOT NULL)
+		GROUP BY groupName
+		ORDER BY groupName NULLS LAST
+	`
+	// The placeholder takes a comparison of operations.name, as OperationMatch.comparison returns it
+	OPERATION_CONDITION string = `
+		EXISTS (
+			SELECT 1
+			FROM spans AS operations
+			WHERE operations.traceID = traces.traceID AND %s
+		)
+	`
+	TRACE_HAS_ERROR_CONDITION string = `
+		EXISTS (
+			SELECT 1
+			FROM spans AS errors
+			WHERE errors.traceID = traces.traceID AND errors.statusCode = 'Error'
+		)
+	`
+	// The placeholder takes a placeholder for each trace ID
+	SELECT_TRACE_EVENTS string = `
+		SELECT traceID, spanID, name, events
+		FROM spans
+		WHERE traceID IN (%s)
+		ORDER BY traceID, startTime, spanID
+	`
+	COUNT_TRACES string = `
+		SELECT count(DISTINCT traceID)
+		FROM spans
+	`
+	SELECT_STORE_STATUS string = `
+		SELECT count(DISTINCT traceID), count(*), min(startTime), max(endTime)
 		FROM spans
-		GROUP BY traceID
-		ORDER BY MAX(startTime) DESC
 	`
 	SELECT_TRACE string = `
 		SELECT *
 		FROM spans 
 		WHERE traceID = ?
 	`
-	SELECT_ROOT_SPAN string = `
-		SELECT ifnull(resourceAttributes->>'service.name', ''), name, startTime, endTime
+	// The placeholder takes a placeholder for each trace ID
+	SELECT_TRACES string = `
+		SELECT *
+		FROM spans
+		WHERE traceID IN (%s)
+	`
+	// Root spans come first so the first page always has them, then the rest in the order they started
+	SELECT_TRACE_PAGE string = `
+		SELECT *
+		FROM spans
+		WHERE traceID = ?
+		ORDER BY parentSpanID <> '', startTime, spanID
+	`
+	// Walks up from the spans of the kinds in %s to their ancestors, so the spans kept still form a tree.
+	// UNION rather than UNION ALL stops the walk at spans already kept, should parent IDs form a cycle.
+	SELECT_TRACE_OF_KINDS string = `
+		WITH RECURSIVE kept AS (
+			SELECT spanID, parentSpanID
+			FROM spans
+			WHERE traceID = ? AND kind IN (%s)
+			UNION
+			SELECT spans.spanID, spans.parentSpanID
+			FROM spans
+			JOIN kept ON spans.spanID = kept.parentSpanID
+			WHERE spans.traceID = ?
+		)
+		SELECT *
+		FROM spans
+		WHERE traceID = ? AND spanID IN (SELECT spanID FROM kept)
+	`
+	SELECT_SPAN string = `
+		SELECT *
+		FROM spans
+		WHERE traceID = ? AND spanID = ?
+	`
+	SELECT_ALL_SPANS string = `
+		SELECT *
+		FROM spans
+		ORDER BY traceID, startTime, spanID
+	`
+	COUNT_TRACE_SPANS string = `
+		SELECT count(*)
+		FROM spans
+		WHERE traceID = ?
+	`
+
+	SELECT_TRACE_KIND_COUNTS string = `
+		SELECT kind, count(*), count(*) FILTER (WHERE statusCode = 'Error')
 		FROM spans
 		WHERE traceID = ?
-		AND parentSpanID = '' 
+		GROUP BY kind
+	`
+	SELECT_TRACE_SPAN_TREE string = `
+		SELECT spanID, parentSpanID, startTime, endTime
+		FROM spans
+		WHERE traceID = ?
+	`
+	// Span IDs should be unique, so should one turn up in more than one other trace, the first
+	// of those traces is as good as any
+	SELECT_SPAN_TRACE_IDS string = `
+		SELECT spanID, min(traceID)
+		FROM spans
+		WHERE traceID != ? AND spanID IN (%s)
+		GROUP BY spanID
 	`
-	SELECT_SPAN_COUNT string = `
-		SELECT count(*) 
+	SELECT_TRACE_DROPPED_COUNTS string = `
+		SELECT spanID, name, droppedAttributesCount, droppedEventsCount, droppedLinksCount
 		FROM spans
 		WHERE traceID = ?
+			AND (droppedAttributesCount > 0 OR droppedEventsCount > 0 OR droppedLinksCount > 0)
+		ORDER BY startTime, spanID
+	`
+
+	SELECT_SPAN_IDS string = `
+		SELECT traceID, spanID
+		FROM spans
+		WHERE traceID IN (%s)
+	`
+
+	SELECT_LOGS_BY_TRACE string = `
+		SELECT *
+		FROM logs
+		WHERE traceID = ?
+		ORDER BY timestamp, observedTimestamp
+	`
+
+	SELECT_METRICS string = `
+		SELECT *
+		FROM metrics
+	`
+
+	SELECT_SERVICE_NAMES string = `
+		SELECT DISTINCT resourceAttributes->>'service.name' AS serviceName
+		FROM spans
+		WHERE resourceAttributes->>'service.name' IS NOT NULL
+		ORDER BY serviceName
+	`
+	SELECT_SERVICE_SPAN_COUNTS string = `
+		SELECT resourceAttributes->>'service.name' AS serviceName, count(*)
+		FROM spans
+		WHERE resourceAttributes->>'service.name' IS NOT NULL
+		GROUP BY serviceName
+		ORDER BY serviceName
+	`
+	// One column is added for each resource attribute key grouped by, missing keys grouping as ''
+	SELECT_SERVICE_GROUPS string = `
+		SELECT %s, count(*)
+		FROM spans
+		GROUP BY ALL
+		ORDER BY ALL
+	`
+	SERVICE_GROUP_COLUMN string = `ifnull(resourceAttributes->>?, '')`
+
+	// The placeholders in the attribute selections take the conditions, which can refer to serviceName
+	SELECT_ATTRIBUTE_KEYS string = `
+		SELECT key, count(*)
+		FROM (
+			SELECT unnest(json_keys(attributes)) AS key
+			FROM (
+				SELECT attributes, resourceAttributes->>'service.name' AS serviceName
+				FROM spans
+			)
+			WHERE TRUE %s
+		)
+		GROUP BY key
+		ORDER BY key
+	`
+	SELECT_ATTRIBUTE_VALUES string = `
+		SELECT value, count(*) AS spanCount
+		FROM (
+			SELECT attributes->>? AS value, resourceAttributes->>'service.name' AS serviceName
+			FROM spans
+		)
+		WHERE value IS NOT NULL %s
+		GROUP BY value
+		ORDER BY spanCount DESC, value
+		LIMIT ?
+	`
+	// Cardinalities are estimated with HyperLogLog, which is close enough to tell the keys
+	// with thousands of values from those with a handful at a fraction of the cost
+	SELECT_ATTRIBUTE_CARDINALITY string = `
+		SELECT key, approx_count_distinct(attributes->>key) AS cardinality, count(*)
+		FROM (
+			SELECT attributes, unnest(json_keys(attributes)) AS key, resourceAttributes->>'service.name' AS serviceName
+			FROM spans
+		)
+		WHERE TRUE %s
+		GROUP BY key
+		ORDER BY cardinality %s, key
 	`
 
+	// Spans whose parent is in another service are calls between services. Root spans,
+	// and spans whose parent hasn't arrived, have nothing to join and are left out.
+	SELECT_SERVICE_DEPENDENCIES string = `
+		SELECT
+			ifnull(parent.resourceAttributes->>'service.name', '') AS parentService,
+			ifnull(child.resourceAttributes->>'service.name', '') AS childService,
+			count(*),
+			count(*) FILTER (WHERE child.statusCode = 'Error')
+		FROM spans AS child
+		JOIN spans AS parent ON child.traceID = parent.traceID AND child.parentSpanID = parent.spanID
+		WHERE parentService != childService %s
+		GROUP BY parentService, childService
+		ORDER BY parentService, childService
+	`
+
+	// The placeholder takes the same conditions as SELECT_SERVICE_DEPENDENCIES, so the
+	// services are summed up over the same window as the calls between them
+	SELECT_SERVICE_NODES string = `
+		SELECT
+			ifnull(child.resourceAttributes->>'service.name', '') AS serviceName,
+			count(*),
+			count(*) FILTER (WHERE child.statusCode = 'Error'),
+			approx_quantile(epoch_ns(child.endTime) - epoch_ns(child.startTime), 0.95)
+		FROM spans AS child
+		WHERE true %s
+		GROUP BY serviceName
+		ORDER BY serviceName
+	`
+
+	// The first placeholder is the service name to group by, or '' to group by name alone.
+	// The second takes the conditions, which can refer to serviceName.
+	SELECT_OPERATION_STATS string = `
+		SELECT
+			%s AS operationService,
+			name,
+			count(*),
+			approx_quantile(durationNanos, 0.5) AS p50,
+			approx_quantile(durationNanos, 0.95) AS p95,
+			approx_quantile(durationNanos, 0.99) AS p99,
+			max(durationNanos)
+		FROM (
+			SELECT
+				name,
+				startTime,
+				ifnull(resourceAttributes->>'service.name', '') AS serviceName,
+				epoch_ns(endTime) - epoch_ns(startTime) AS durationNanos
+			FROM spans
+		)
+		WHERE TRUE %s
+		GROUP BY operationService, name
+		ORDER BY p95 DESC, operationService, name
+	`
+
+	// The placeholders take the CASE sorting span counts into buckets, and the conditions,
+	// which are ANDed into the HAVING clause
+	SELECT_TRACE_SIZES string = `
+		SELECT
+			%s AS bucket,
+			count(*),
+			max(spanCount),
+			arg_max(traceID, spanCount)
+		FROM (
+			SELECT traceID, count(*) AS spanCount
+			FROM (
+				SELECT
+					traceID,
+					startTime,
+					ifnull(resourceAttributes->>'service.name', '') AS serviceName
+				FROM spans
+			)
+			GROUP BY traceID
+			HAVING TRUE %s
+		)
+		GROUP BY bucket
+		ORDER BY bucket
+	`
+	// The placeholder takes conditions ANDed into the HAVING clause of the spans grouped by trace.
+	// The bucket size is given in microseconds, and the buckets start from the first argument
+	// after it.
+	SELECT_TRACE_VOLUME string = `
+		SELECT
+			time_bucket(to_microseconds(?), traceStart, ?::TIMESTAMP) AS bucket,
+			count(*),
+			count(*) FILTER (WHERE failed)
+		FROM (
+			SELECT
+				traceID,
+				min(startTime)::TIMESTAMP AS traceStart,
+				bool_or(statusCode = 'Error') AS failed
+			FROM (
+				SELECT
+					traceID,
+					startTime,
+					statusCode,
+					ifnull(resourceAttributes->>'service.name', '') AS serviceName
+				FROM spans
+			)
+			GROUP BY traceID
+			HAVING min(startTime) >= ? AND min(startTime) < ? %s
+		)
+		GROUP BY bucket
+	`
+
+	// The trace selections below pick the trace IDs deleteTracesLocked deletes and Spill moves
+	SELECT_TRACE_ID string = `
+		SELECT traceID
+		FROM spans
+		WHERE traceID = ?
+	`
+	// The placeholder takes the conditions, which are ANDed into the HAVING clause
+	SELECT_MATCHING_TRACES string = `
+		SELECT traceID
+		FROM spans
+		GROUP BY traceID
+		HAVING TRUE %s
+	`
+	// The placeholders in the selections below take the spans table to choose traces from
+	SELECT_TRACES_OLDER_THAN string = `
+		SELECT traceID
+		FROM %s
+		GROUP BY traceID
+		HAVING MAX(endTime) < ?
+	`
+	SELECT_TRACES_BEYOND_COUNT string = `
+		SELECT traceID
+		FROM %s
+		GROUP BY traceID
+		ORDER BY MAX(startTime) DESC, traceID
+		OFFSET ?
+	`
+	// Selects the least recently active traces holding at least ? spans between them: a trace is
+	// selected if the traces selected before it don't hold enough spans on their own
+	SELECT_OLDEST_TRACES_BY_SPANS string = `
+		SELECT traceID
+		FROM (
+			SELECT traceID, sum(count(*)) OVER (ORDER BY MAX(startTime), traceID) - count(*) AS spansBefore
+			FROM %s
+			GROUP BY traceID
+		)
+		WHERE spansBefore < ?
+	`
+	// Spans are appended to a staging table first, as the appender can only insert. The placeholder
+	// takes the spans table to copy the columns of.
+	CREATE_STAGED_SPANS string = `
+		CREATE TEMP TABLE IF NOT EXISTS staged_spans AS
+		SELECT * FROM %s LIMIT 0
+	`
+	// INSERT OR REPLACE is DuckDB's shorthand for ON CONFLICT DO UPDATE of every column, so a span
+	// that is sent again replaces the copy already stored. The placeholder takes the spans table.
+	UPSERT_STAGED_SPANS string = `
+		INSERT OR REPLACE INTO %s
+		SELECT * FROM staged_spans
+	`
+	TRUNCATE_STAGED_SPANS string = `
+		TRUNCATE staged_spans
+	`
+	// The placeholder takes a trace selection, which is held on to so every tier deletes the same traces
+	CREATE_SELECTED_TRACES string = `
+		CREATE OR REPLACE TEMP TABLE selected_traces AS %s
+	`
+	DROP_SELECTED_TRACES string = `
+		DROP TABLE IF EXISTS selected_traces
+	`
+	// The placeholder takes the spans table to delete from
+	DELETE_SELECTED_TRACES string = `
+		DELETE FROM %s
+		WHERE traceID IN (SELECT traceID FROM selected_traces)
+		RETURNING traceID
+	`
+	// The placeholder takes the path of the database file, with any single quotes doubled
+	ATTACH_SPILL_FILE string = `
+		ATTACH '%s' AS cold
+	`
+	// The placeholder takes the path of the snapshot file, with any single quotes doubled
+	ATTACH_SNAPSHOT_FILE string = `
+		ATTACH '%s' AS snapshot
+	`
+	DETACH_SNAPSHOT_FILE string = `
+		DETACH snapshot
+	`
+	// Both placeholders take the name of the table to copy. Spans are read through the view of
+	// both tiers when there are two, so the snapshot has every span in its one table.
+	COPY_TO_SNAPSHOT string = `
+		INSERT INTO snapshot.%s SELECT * FROM %s
+	`
+	// Queries keep reading spans, which becomes a view of both tiers
+	SPLIT_SPANS_INTO_TIERS string = `
+		ALTER TABLE spans RENAME TO hot_spans;
+		CREATE VIEW spans AS
+			SELECT * FROM hot_spans
+			UNION ALL
+			SELECT * FROM cold.spans;
+	`
+	LOAD_SPILLED_TABLES string = `
+		INSERT INTO logs SELECT * FROM cold.logs;
+		INSERT INTO metrics SELECT * FROM cold.metrics;
+		INSERT INTO annotations SELECT * FROM cold.annotations;
+		INSERT INTO pins SELECT * FROM cold.pins;
+	`
+	// A span sent again after it was spilled replaces the spilled copy once it is spilled in turn
+	SPILL_SELECTED_TRACES string = `
+		INSERT OR REPLACE INTO cold.spans
+		SELECT * FROM hot_spans
+		WHERE traceID IN (SELECT traceID FROM selected_traces)
+	`
+	SPILL_EVERYTHING string = `
+		INSERT OR REPLACE INTO cold.spans SELECT * FROM hot_spans;
+		TRUNCATE cold.logs;
+		INSERT INTO cold.logs SELECT * FROM logs;
+		TRUNCATE cold.metrics;
+		INSERT INTO cold.metrics SELECT * FROM metrics;
+		TRUNCATE cold.annotations;
+		INSERT INTO cold.annotations SELECT * FROM annotations;
+		TRUNCATE cold.pins;
+		INSERT INTO cold.pins SELECT * FROM pins;
+	`
+	COUNT_SPANS string = `
+		SELECT count(*)
+		FROM spans
+	`
+
+	PING string = `
+		SELECT 1
+	`
+
+	// The placeholder takes the spans table to truncate
 	TRUNCATE_SPANS string = `
-		TRUNCATE spans;
+		TRUNCATE %s;
+	`
+	TRUNCATE_LOGS string = `
+		TRUNCATE logs;
 	`
+	TRUNCATE_METRICS string = `
+		TRUNCATE metrics;
+	`
+	// The placeholders take a validated memory limit, such as 2GB, and a thread count
+	SET_MEMORY_LIMIT string = `PRAGMA memory_limit = '%s'`
+	SET_THREADS      string = `PRAGMA threads = %d`
+
 	ENABLE_JSON string = `
 		INSTALL json;
 		LOAD json;
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// WithIngestRateLimit drops the spans IngestSpans receives past spansPerSecond, allowing bursts
+// of up to a second's worth. Zero or less doesn't limit anything.
+func WithIngestRateLimit(spansPerSecond int) Option {
+	return func(s *Store) {
+		if spansPerSecond > 0 {
+			s.ingestLimiter = newRateLimiter(spansPerSecond, time.Now)
+		}
+	}
+}
+
+// rateLimiter is a token bucket holding up to a second's worth of tokens, refilled continuously
+type rateLimiter struct {
+	mut    sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newRateLimiter(perSecond int, now func() time.Time) *rateLimiter {
+	return &rateLimiter{
+		rate:   float64(perSecond),
+		tokens: float64(perSecond),
+		last:   now(),
+		now:    now,
+	}
+}
+
+// take spends a token on each of up to count spans, and returns how many it could pay for
+func (l *rateLimiter) take(count int) int {
+	l.mut.Lock()
+	defer l.mut.Unlock()
+
+	now := l.now()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = math.Min(l.rate, l.tokens+elapsed.Seconds()*l.rate)
+	}
+	l.last = now
+
+	allowed := min(count, int(l.tokens))
+	l.tokens -= float64(allowed)
+	return allowed
+}
+
+// WithIngestListener calls listener with the spans each call to IngestSpans queues, once they
+// are queued. Spans the store drops, for being over the rate limit or having malformed IDs,
+// aren't passed on. It runs on the ingestion path, so it must not block.
+func WithIngestListener(listener func(spans []telemetry.SpanData)) Option {
+	return func(s *Store) {
+		s.ingestListener = listener
+	}
+}
+
+// IngestSpans adds spans sent in by instrumented services, dropping those over the ingest rate
+// limit so that a flood of them can't starve the queries behind it. Imports and sample data
+// go through AddSpans instead, and are never limited. It returns the number of spans dropped.
+func (s *Store) IngestSpans(ctx context.Context, spans []telemetry.SpanData) (int, error) {
+	allowed := len(spans)
+	if s.ingestLimiter != nil {
+		allowed = s.ingestLimiter.take(len(spans))
+	}
+	limited := len(spans) - allowed
+	if limited > 0 && s.observer != nil {
+		s.observer.SpansRateLimited(limited)
+	}
+	if allowed == 0 && s.ingestLimiter != nil {
+		return limited, nil
+	}
+
+	queued, err := s.queueSpans(ctx, spans[:allowed])
+	if err != nil {
+		return limited, err
+	}
+	if s.ingestListener != nil && len(queued) > 0 {
+		s.ingestListener(queued)
+	}
+	return limited, nil
+}
@@ -0,0 +1,45 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// WithReadOnly opens the database file without ever writing to it, to look through traces captured
+// elsewhere. The file has to exist already, at the schema version this binary uses, since it can't
+// be migrated. Adding, importing, annotating, or removing data returns ErrReadOnly instead, and
+// spilling is turned off.
+func WithReadOnly() Option {
+	return func(s *Store) {
+		s.readOnly = true
+	}
+}
+
+// writable returns ErrReadOnly for stores opened with WithReadOnly
+func (s *Store) writable() error {
+	if s.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// checkDatabaseFile fails unless there is a database file at dbPath to open read-only,
+// as DuckDB would otherwise report a missing file as an I/O error
+func checkDatabaseFile(dbPath string) error {
+	if dbPath == "" {
+		return errors.New("could not open the database read-only: an in-memory database has nothing to read")
+	}
+
+	info, err := os.Stat(dbPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrDatabaseNotFound, dbPath)
+	} else if err != nil {
+		return fmt.Errorf("could not open the database read-only: %s", err.Error())
+	}
+	if info.IsDir() {
+		return fmt.Errorf("could not open the database read-only: %s is a directory", dbPath)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+)
+
+// readSessionKey marks the context of a read session, whose reads already hold s.deleteMut
+type readSessionKey struct{}
+
+// ReadSession runs fn with no traces deleted or moved between tiers while it does, whether by
+// retention, the span cap, spilling, or clearing, so that the queries fn makes with the context
+// it is given agree with each other. Spans are still written in the meantime, so a trace can gain
+// spans between two queries, but none go missing. Deletes wait for fn to return, along with the
+// writes that have to make room first, so fn should only read, and be quick about it.
+func (s *Store) ReadSession(ctx context.Context, fn func(ctx context.Context) error) error {
+	if InReadSession(ctx) {
+		return fn(ctx)
+	}
+
+	s.deleteMut.RLock()
+	defer s.deleteMut.RUnlock()
+
+	return fn(context.WithValue(ctx, readSessionKey{}, true))
+}
+
+// InReadSession reports whether ctx comes from ReadSession, for callers to refuse anything that
+// would wait on the session to end
+func InReadSession(ctx context.Context) bool {
+	return ctx.Value(readSessionKey{}) != nil
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+)
+
+// memoryLimitPattern matches the sizes DuckDB takes as a memory limit, in decimal or binary units
+var memoryLimitPattern = regexp.MustCompile(`(?i)^\d+(\.\d+)?\s*(B|KB|MB|GB|TB|KiB|MiB|GiB|TiB)$`)
+
+// WithResourceLimits caps the memory DuckDB may use, such as "2GB", and the number of threads
+// it runs queries on. An empty memory limit and zero threads leave DuckDB's defaults in place,
+// which are 80% of the system's memory and a thread per core.
+func WithResourceLimits(memoryLimit string, threads int) Option {
+	return func(s *Store) {
+		s.memoryLimit = memoryLimit
+		s.threads = threads
+	}
+}
+
+// ValidateResourceLimits checks that a memory limit is empty or a size such as 512MB or 2GiB,
+// and that the thread count isn't negative
+func ValidateResourceLimits(memoryLimit string, threads int) error {
+	if memoryLimit != "" && !memoryLimitPattern.MatchString(memoryLimit) {
+		return fmt.Errorf("invalid memory limit %q: must be a size such as 512MB or 2GiB", memoryLimit)
+	}
+	if threads < 0 {
+		return fmt.Errorf("invalid thread count %d: must not be negative", threads)
+	}
+	return nil
+}
+
+// applyResourceLimits sets the store's resource limits on the database. Both settings are global
+// to the database, so they apply to every connection in the pool.
+func (s *Store) applyResourceLimits(ctx context.Context) error {
+	if err := ValidateResourceLimits(s.memoryLimit, s.threads); err != nil {
+		return err
+	}
+
+	if s.memoryLimit != "" {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(SET_MEMORY_LIMIT, s.memoryLimit)); err != nil {
+			return fmt.Errorf("could not set memory limit: %s", err.Error())
+		}
+	}
+	if s.threads > 0 {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(SET_THREADS, s.threads)); err != nil {
+			return fmt.Errorf("could not set thread count: %s", err.Error())
+		}
+	}
+	return nil
+}
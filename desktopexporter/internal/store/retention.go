@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+)
+
+// WithMaxSpans caps the number of spans the store holds. Before a batch is written, the least
+// recently active traces are evicted whole until the batch fits, so the store works like a ring
+// buffer. A batch larger than the cap on its own empties the store and is still written whole.
+func WithMaxSpans(maxSpans int) Option {
+	return func(s *Store) {
+		s.maxSpans = maxSpans
+	}
+}
+
+// RetentionPolicy bounds how much telemetry the store keeps.
+// The zero value keeps everything.
+type RetentionPolicy struct {
+	// MaxAge evicts traces whose most recent span ended longer ago than this
+	MaxAge time.Duration
+	// MaxTraces evicts the oldest traces once there are more than this many
+	MaxTraces int
+}
+
+// ParseRetentionPolicy reads a retention setting, which is either a duration
+// such as "30m" or a maximum number of traces such as "10000".
+// An empty setting keeps everything.
+func ParseRetentionPolicy(value string) (RetentionPolicy, error) {
+	if value == "" {
+		return RetentionPolicy{}, nil
+	}
+
+	if maxTraces, err := strconv.Atoi(value); err == nil {
+		if maxTraces <= 0 {
+			return RetentionPolicy{}, fmt.Errorf("invalid retention %q: trace count must be positive", value)
+		}
+		return RetentionPolicy{MaxTraces: maxTraces}, nil
+	}
+
+	maxAge, err := time.ParseDuration(value)
+	if err != nil || maxAge <= 0 {
+		return RetentionPolicy{}, fmt.Errorf("invalid retention %q: must be a positive duration (e.g. 30m) or trace count (e.g. 10000)", value)
+	}
+	return RetentionPolicy{MaxAge: maxAge}, nil
+}
+
+// Enabled reports whether the policy evicts anything at all
+func (policy RetentionPolicy) Enabled() bool {
+	return policy.MaxAge > 0 || policy.MaxTraces > 0
+}
+
+// ApplyRetention evicts whatever the policy no longer allows the store to keep as of now,
+// and returns the number of traces evicted.
+func (s *Store) ApplyRetention(ctx context.Context, policy RetentionPolicy, now time.Time) (int, error) {
+	evicted := 0
+
+	if policy.MaxAge > 0 {
+		n, err := s.EvictOlderThan(ctx, now.Add(-policy.MaxAge))
+		if err != nil {
+			return evicted, err
+		}
+		evicted += n
+	}
+
+	if policy.MaxTraces > 0 {
+		n, err := s.EvictBeyondCount(ctx, policy.MaxTraces)
+		if err != nil {
+			return evicted, err
+		}
+		evicted += n
+	}
+
+	return evicted, nil
+}
+
+// EvictOlderThan deletes every trace whose most recent span ended before the cutoff,
+// and returns the number of traces evicted. Traces are always deleted whole, and pinned
+// traces are never evicted.
+func (s *Store) EvictOlderThan(ctx context.Context, cutoff time.Time) (int, error) {
+	return s.evict(ctx, fmt.Sprintf(SELECT_TRACES_OLDER_THAN, UNPINNED_SPANS), cutoff)
+}
+
+// EvictBeyondCount deletes all but the n most recently active unpinned traces, and returns the
+// number of traces evicted. Traces are always deleted whole, and pinned traces are kept on top of
+// the n.
+func (s *Store) EvictBeyondCount(ctx context.Context, n int) (int, error) {
+	return s.evict(ctx, fmt.Sprintf(SELECT_TRACES_BEYOND_COUNT, UNPINNED_SPANS), n)
+}
+
+func (s *Store) evict(ctx context.Context, selection string, args ...any) (int, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	return s.evictLocked(ctx, selection, args...)
+}
+
+// makeRoom evicts the least recently active traces until n more spans fit under the span cap.
+// Pinned traces are never evicted, though their spans still count towards the cap, so with
+// enough of them pinned the store can stay over it. It must be called with s.mut held.
+func (s *Store) makeRoom(ctx context.Context, n int) error {
+	spanCount := 0
+	if err := s.db.QueryRowContext(ctx, COUNT_SPANS).Scan(&spanCount); err != nil {
+		return fmt.Errorf("could not count spans: %s", err.Error())
+	}
+
+	excess := spanCount + n - s.maxSpans
+	if excess <= 0 {
+		return nil
+	}
+
+	evicted, err := s.evictLocked(ctx, fmt.Sprintf(SELECT_OLDEST_TRACES_BY_SPANS, UNPINNED_SPANS), excess)
+	if err != nil {
+		return err
+	}
+	log.Printf("span cap of %d reached: evicted %d traces to make room for %d more spans", s.maxSpans, evicted, n)
+	return nil
+}
+
+// evictLocked deletes the traces a selection picks. It must be called with s.mut held.
+func (s *Store) evictLocked(ctx context.Context, selection string, args ...any) (int, error) {
+	evicted, err := s.deleteTracesLocked(ctx, selection, args...)
+	if err != nil {
+		return 0, fmt.Errorf("could not evict traces: %s", err.Error())
+	}
+
+	if s.observer != nil && evicted > 0 {
+		s.observer.TracesEvicted(evicted)
+	}
+	return evicted, nil
+}
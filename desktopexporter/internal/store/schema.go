@@ -0,0 +1,310 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+)
+
+// migrations upgrade the database one schema version at a time: migrations[i] takes
+// the schema from version i to version i+1. Append new migrations, never edit old ones,
+// and keep each one idempotent (IF NOT EXISTS and friends) in case it is interrupted.
+var migrations = []string{
+	// 1: the original spans table
+	CREATE_SPANS_TABLE,
+	// 2: logs, correlated with spans by trace and span ID
+	CREATE_LOGS_TABLE,
+	// 3: metrics, one row per data point
+	CREATE_METRICS_TABLE,
+	// 4: spans keyed by trace and span ID, so spans sent again replace rather than duplicate
+	KEY_SPANS_BY_ID,
+	// 5: annotations, keyed by trace ID
+	CREATE_ANNOTATIONS_TABLE,
+	// 6: exemplars on metric data points, linking them to the traces they were measured in
+	ADD_METRIC_EXEMPLARS,
+	// 7: pins, keyed by trace ID, keeping traces from being evicted
+	CREATE_PINS_TABLE,
+}
+
+// schemaVersion is the schema version this binary reads and writes
+var schemaVersion = len(migrations)
+
+type column struct {
+	name     string
+	dataType string
+}
+
+// spansColumns lists the columns created by CREATE_SPANS_TABLE, in order
+var spansColumns = []column{
+	{"traceID", "VARCHAR"},
+	{"traceState", "VARCHAR"},
+	{"spanID", "VARCHAR"},
+	{"parentSpanID", "VARCHAR"},
+	{"name", "VARCHAR"},
+	{"kind", "VARCHAR"},
+	{"startTime", "TIMESTAMP_NS"},
+	{"endTime", "TIMESTAMP_NS"},
+	{"attributes", "JSON"},
+	{"events", "JSON"},
+	{"links", "JSON"},
+	{"resourceAttributes", "JSON"},
+	{"resourceDroppedAttributesCount", "UINTEGER"},
+	{"scopeName", "VARCHAR"},
+	{"scopeVersion", "VARCHAR"},
+	{"scopeAttributes", "JSON"},
+	{"scopeDroppedAttributesCount", "UINTEGER"},
+	{"droppedAttributesCount", "UINTEGER"},
+	{"droppedEventsCount", "UINTEGER"},
+	{"droppedLinksCount", "UINTEGER"},
+	{"statusCode", "VARCHAR"},
+	{"statusMessage", "VARCHAR"},
+}
+
+// logsColumns lists the columns created by CREATE_LOGS_TABLE, in order
+var logsColumns = []column{
+	{"timestamp", "TIMESTAMP_NS"},
+	{"observedTimestamp", "TIMESTAMP_NS"},
+	{"traceID", "VARCHAR"},
+	{"spanID", "VARCHAR"},
+	{"severityText", "VARCHAR"},
+	{"severityNumber", "INTEGER"},
+	{"body", "VARCHAR"},
+	{"attributes", "JSON"},
+	{"resourceAttributes", "JSON"},
+	{"resourceDroppedAttributesCount", "UINTEGER"},
+	{"scopeName", "VARCHAR"},
+	{"scopeVersion", "VARCHAR"},
+	{"scopeAttributes", "JSON"},
+	{"scopeDroppedAttributesCount", "UINTEGER"},
+	{"droppedAttributesCount", "UINTEGER"},
+	{"flags", "UINTEGER"},
+}
+
+// metricsColumns lists the columns created by CREATE_METRICS_TABLE and ADD_METRIC_EXEMPLARS, in order
+var metricsColumns = []column{
+	{"name", "VARCHAR"},
+	{"description", "VARCHAR"},
+	{"unit", "VARCHAR"},
+	{"type", "VARCHAR"},
+	{"startTime", "TIMESTAMP_NS"},
+	{"timestamp", "TIMESTAMP_NS"},
+	{"attributes", "JSON"},
+	{"value", "DOUBLE"},
+	{"isMonotonic", "BOOLEAN"},
+	{"aggregationTemporality", "VARCHAR"},
+	{"histogram", "JSON"},
+	{"resourceAttributes", "JSON"},
+	{"resourceDroppedAttributesCount", "UINTEGER"},
+	{"scopeName", "VARCHAR"},
+	{"scopeVersion", "VARCHAR"},
+	{"scopeAttributes", "JSON"},
+	{"scopeDroppedAttributesCount", "UINTEGER"},
+	{"exemplars", "JSON"},
+}
+
+// annotationsColumns lists the columns created by CREATE_ANNOTATIONS_TABLE, in order
+var annotationsColumns = []column{
+	{"traceID", "VARCHAR"},
+	{"annotations", "JSON"},
+	{"updatedAt", "TIMESTAMP_NS"},
+}
+
+// pinsColumns lists the columns created by CREATE_PINS_TABLE, in order
+var pinsColumns = []column{
+	{"traceID", "VARCHAR"},
+	{"pinnedAt", "TIMESTAMP_NS"},
+}
+
+// tables lists every table this version reads and writes, along with its expected columns
+var tables = []struct {
+	name    string
+	columns []column
+}{
+	{"spans", spansColumns},
+	{"logs", logsColumns},
+	{"metrics", metricsColumns},
+	{"annotations", annotationsColumns},
+	{"pins", pinsColumns},
+}
+
+// openFiles tracks the database files opened by stores in this process
+var openFiles = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: map[string]bool{}}
+
+// claimDatabaseFile marks a database file as in use, failing if another store already has it open.
+// In-memory databases (an empty path) are independent of each other and are never claimed.
+func claimDatabaseFile(dbPath string) error {
+	if dbPath == "" {
+		return nil
+	}
+
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return fmt.Errorf("could not resolve database path: %s", err.Error())
+	}
+
+	openFiles.Lock()
+	defer openFiles.Unlock()
+
+	if openFiles.paths[absPath] {
+		return fmt.Errorf("%w: %s", ErrDatabaseInUse, absPath)
+	}
+	openFiles.paths[absPath] = true
+	return nil
+}
+
+func releaseDatabaseFile(dbPath string) {
+	if dbPath == "" {
+		return
+	}
+
+	absPath, err := filepath.Abs(dbPath)
+	if err != nil {
+		return
+	}
+
+	openFiles.Lock()
+	defer openFiles.Unlock()
+	delete(openFiles.paths, absPath)
+}
+
+// migrate brings the database up to the current schema version, applying each
+// outstanding migration in its own transaction along with its version record.
+func migrate(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, CREATE_SCHEMA_VERSION_TABLE); err != nil {
+		return fmt.Errorf("could not create table schema_version: %s", err.Error())
+	}
+
+	version, err := currentSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if version > schemaVersion {
+		return fmt.Errorf("%w: database is at schema version %d, but this version of otel-desktop-viewer only supports up to %d",
+			ErrSchemaTooNew, version, schemaVersion)
+	}
+
+	// Migrations rebuild the spans table by copying its rows, which only works with the columns
+	// they expect, so a spans table this version doesn't know about is refused before any of them run
+	if version > 0 && version < schemaVersion {
+		if err = validateTable(ctx, db, "spans", spansColumns); err != nil {
+			return err
+		}
+	}
+
+	for ; version < schemaVersion; version++ {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("could not begin migration to schema version %d: %s", version+1, err.Error())
+		}
+
+		if _, err = tx.ExecContext(ctx, migrations[version]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not migrate to schema version %d: %s", version+1, err.Error())
+		}
+
+		if _, err = tx.ExecContext(ctx, INSERT_SCHEMA_VERSION, version+1); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("could not record schema version %d: %s", version+1, err.Error())
+		}
+
+		if err = tx.Commit(); err != nil {
+			return fmt.Errorf("could not commit migration to schema version %d: %s", version+1, err.Error())
+		}
+	}
+	return nil
+}
+
+// currentSchemaVersion reads the schema version of the database. Files written before schema
+// versioning existed have a spans table but no version record, and are at version 1.
+func currentSchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	hasVersions := false
+	if err := db.QueryRowContext(ctx, SELECT_SCHEMA_VERSION_TABLE_EXISTS).Scan(&hasVersions); err != nil {
+		return 0, fmt.Errorf("could not check for table schema_version: %s", err.Error())
+	}
+
+	var version sql.NullInt64
+	if hasVersions {
+		if err := db.QueryRowContext(ctx, SELECT_SCHEMA_VERSION).Scan(&version); err != nil {
+			return 0, fmt.Errorf("could not read schema version: %s", err.Error())
+		}
+	}
+	if version.Valid {
+		return int(version.Int64), nil
+	}
+
+	hasSpans := false
+	if err := db.QueryRowContext(ctx, SELECT_SPANS_TABLE_EXISTS).Scan(&hasSpans); err != nil {
+		return 0, fmt.Errorf("could not check for table spans: %s", err.Error())
+	}
+	if hasSpans {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// checkSchemaVersion fails unless the database is at the schema version this binary reads and
+// writes, for databases opened read-only, which can't be migrated to it
+func checkSchemaVersion(ctx context.Context, db *sql.DB) error {
+	version, err := currentSchemaVersion(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	if version > schemaVersion {
+		return fmt.Errorf("%w: database is at schema version %d, but this version of otel-desktop-viewer only supports up to %d",
+			ErrSchemaTooNew, version, schemaVersion)
+	}
+	if version < schemaVersion {
+		return fmt.Errorf("%w: database is at schema version %d and can't be upgraded to version %d read-only: open it once without read-only to upgrade it",
+			ErrSchemaMismatch, version, schemaVersion)
+	}
+	return nil
+}
+
+// validateSchema checks that every table has exactly the columns this version expects,
+// so an incompatible database file is refused rather than read or written incorrectly.
+func validateSchema(ctx context.Context, db *sql.DB) error {
+	for _, table := range tables {
+		if err := validateTable(ctx, db, table.name, table.columns); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateTable(ctx context.Context, db *sql.DB, table string, expected []column) error {
+	rows, err := db.QueryContext(ctx, SELECT_TABLE_COLUMNS, table)
+	if err != nil {
+		return fmt.Errorf("could not read %s table schema: %s", table, err.Error())
+	}
+	defer rows.Close()
+
+	columns := []column{}
+	for rows.Next() {
+		c := column{}
+		if err = rows.Scan(&c.name, &c.dataType); err != nil {
+			return fmt.Errorf("could not scan %s table column: %s", table, err.Error())
+		}
+		columns = append(columns, c)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not read %s table schema: %s", table, err.Error())
+	}
+
+	if len(columns) != len(expected) {
+		return fmt.Errorf("%w: %s table has %d columns, expected %d", ErrSchemaMismatch, table, len(columns), len(expected))
+	}
+	for i, c := range columns {
+		if c != expected[i] {
+			return fmt.Errorf("%w: %s table column %d is %s %s, expected %s %s",
+				ErrSchemaMismatch, table, i+1, c.name, c.dataType, expected[i].name, expected[i].dataType)
+		}
+	}
+	return nil
+}
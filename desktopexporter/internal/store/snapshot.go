@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Snapshot writes everything in the store, spans still queued included, to a new database file
+// at path, which the viewer can open like any other. The tables are copied in one transaction,
+// so the snapshot is consistent even while spans keep arriving. A read-only store's file is
+// never written to, so it is copied as it is.
+func (s *Store) Snapshot(ctx context.Context, path string) error {
+	if s.readOnly {
+		return copyFile(s.dbPath, path)
+	}
+
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+
+	// Opening the file on its own first gives it the current schema
+	db, conn, err := connect(ctx, path, false)
+	if err != nil {
+		return err
+	}
+	conn.Close()
+	if err = db.Close(); err != nil {
+		return fmt.Errorf("could not close the snapshot file: %s", err.Error())
+	}
+
+	// Only one database can be attached as the snapshot at a time
+	s.snapshotMut.Lock()
+	defer s.snapshotMut.Unlock()
+
+	if _, err = s.db.ExecContext(ctx, fmt.Sprintf(ATTACH_SNAPSHOT_FILE, strings.ReplaceAll(path, "'", "''"))); err != nil {
+		return fmt.Errorf("could not attach the snapshot file: %s", err.Error())
+	}
+	// Detaching writes the snapshot out, so it has to succeed for the file to be complete
+	err = s.copyToSnapshot(ctx)
+	if _, detachErr := s.db.ExecContext(context.Background(), DETACH_SNAPSHOT_FILE); detachErr != nil && err == nil {
+		err = fmt.Errorf("could not detach the snapshot file: %s", detachErr.Error())
+	}
+	return err
+}
+
+func (s *Store) copyToSnapshot(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("could not begin snapshot: %s", err.Error())
+	}
+	defer tx.Rollback()
+
+	for _, table := range tables {
+		if _, err = tx.ExecContext(ctx, fmt.Sprintf(COPY_TO_SNAPSHOT, table.name, table.name)); err != nil {
+			return fmt.Errorf("could not copy %s to the snapshot: %s", table.name, err.Error())
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("could not commit snapshot: %s", err.Error())
+	}
+	return nil
+}
+
+func copyFile(source string, destination string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("could not open the database file: %s", err.Error())
+	}
+	defer in.Close()
+
+	out, err := os.Create(destination)
+	if err != nil {
+		return fmt.Errorf("could not create the snapshot file: %s", err.Error())
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("could not copy the database file: %s", err.Error())
+	}
+	return out.Close()
+}
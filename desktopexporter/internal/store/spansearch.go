@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// SearchTrace returns the IDs of the spans of a trace whose name or any attribute value contains
+// the query text (case-insensitive), so they can be picked out of a trace already on screen
+func (s *Store) SearchTrace(ctx context.Context, traceID string, query string) (telemetry.SpanMatches, error) {
+	matches := telemetry.SpanMatches{TraceID: traceID, Query: query, SpanIDs: []string{}}
+
+	exists := false
+	if err := s.db.QueryRowContext(ctx, TRACE_EXISTS, traceID).Scan(&exists); err != nil {
+		return matches, fmt.Errorf("could not look up trace %s: %s", traceID, err.Error())
+	}
+	if !exists {
+		return matches, telemetry.ErrTraceIDNotFound
+	}
+
+	pattern := likePattern(query)
+	rows, err := s.db.QueryContext(ctx, SELECT_TRACE_SPAN_MATCHES, traceID, pattern, pattern)
+	if err != nil {
+		return matches, fmt.Errorf("could not search trace %s: %s", traceID, err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var spanID string
+		if err = rows.Scan(&spanID); err != nil {
+			return matches, fmt.Errorf("could not scan span ID: %s", err.Error())
+		}
+		matches.SpanIDs = append(matches.SpanIDs, spanID)
+	}
+	matches.Count = len(matches.SpanIDs)
+	return matches, rows.Err()
+}
@@ -0,0 +1,213 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// spanNode is the part of a span needed to work out the shape of its trace
+type spanNode struct {
+	spanID       string
+	parentSpanID string
+	startTime    time.Time
+	endTime      time.Time
+}
+
+// GetTraceStats tallies the spans of a trace by kind and status in the database,
+// then works out its durations, depth, and detached spans from the parent-child links
+// of its spans, and adds up what its spans dropped
+func (s *Store) GetTraceStats(ctx context.Context, traceID string) (telemetry.TraceStats, error) {
+	stats := telemetry.TraceStats{
+		TraceID:          traceID,
+		SpanCountsByKind: map[string]int{},
+	}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_TRACE_KIND_COUNTS, traceID)
+	if err != nil {
+		return stats, fmt.Errorf("could not count spans by kind: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind string
+		var spanCount, errorCount int
+		if err = rows.Scan(&kind, &spanCount, &errorCount); err != nil {
+			return stats, fmt.Errorf("could not scan span counts: %s", err.Error())
+		}
+		stats.SpanCountsByKind[kind] = spanCount
+		stats.SpanCount += spanCount
+		stats.ErrorCount += errorCount
+	}
+	if err = rows.Err(); err != nil {
+		return stats, fmt.Errorf("could not count spans by kind: %s", err.Error())
+	}
+
+	if stats.SpanCount == 0 {
+		return stats, telemetry.ErrTraceIDNotFound
+	}
+
+	nodes, err := s.getSpanTree(ctx, traceID)
+	if err != nil {
+		return stats, err
+	}
+
+	stats.DurationNanos = traceDuration(nodes)
+	stats.SelfDurationNanos = rootSelfDuration(nodes)
+	stats.MaxDepth = maxDepth(nodes)
+
+	spans := make([]telemetry.SpanData, len(nodes))
+	for i, node := range nodes {
+		spans[i] = telemetry.SpanData{SpanID: node.spanID, ParentSpanID: node.parentSpanID, StartTime: node.startTime, EndTime: node.endTime}
+	}
+	roots := telemetry.BuildSpanTree(spans)
+	if err = s.LocateMissingParents(ctx, traceID, roots); err != nil {
+		return stats, err
+	}
+	stats.DetachedSpansByReason = telemetry.CountDetachedSpans(roots)
+	for _, count := range stats.DetachedSpansByReason {
+		stats.DetachedSpanCount += count
+	}
+
+	if err = s.addDroppedCounts(ctx, &stats); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+func (s *Store) addDroppedCounts(ctx context.Context, stats *telemetry.TraceStats) error {
+	rows, err := s.db.QueryContext(ctx, SELECT_TRACE_DROPPED_COUNTS, stats.TraceID)
+	if err != nil {
+		return fmt.Errorf("could not retrieve dropped counts: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dropped := telemetry.SpanDroppedCounts{}
+		err = rows.Scan(&dropped.SpanID, &dropped.Name, &dropped.DroppedAttributesCount, &dropped.DroppedEventsCount, &dropped.DroppedLinksCount)
+		if err != nil {
+			return fmt.Errorf("could not scan dropped counts: %s", err.Error())
+		}
+		stats.DroppedAttributesCount += int(dropped.DroppedAttributesCount)
+		stats.DroppedEventsCount += int(dropped.DroppedEventsCount)
+		stats.DroppedLinksCount += int(dropped.DroppedLinksCount)
+		stats.DroppedBySpan = append(stats.DroppedBySpan, dropped)
+	}
+	return rows.Err()
+}
+
+func (s *Store) getSpanTree(ctx context.Context, traceID string) ([]spanNode, error) {
+	nodes := []spanNode{}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_TRACE_SPAN_TREE, traceID)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve span tree: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		node := spanNode{}
+		if err = rows.Scan(&node.spanID, &node.parentSpanID, &node.startTime, &node.endTime); err != nil {
+			return nil, fmt.Errorf("could not scan span tree: %s", err.Error())
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, rows.Err()
+}
+
+func traceDuration(nodes []spanNode) int64 {
+	start, end := nodes[0].startTime, nodes[0].endTime
+	for _, node := range nodes[1:] {
+		if node.startTime.Before(start) {
+			start = node.startTime
+		}
+		if node.endTime.After(end) {
+			end = node.endTime
+		}
+	}
+	return telemetry.DurationNanos(start, end)
+}
+
+// rootSelfDuration subtracts the time covered by the root span's children from its duration.
+// Children may overlap each other or stick out past the root, so only the union of their time
+// within the root counts. Traces whose root span hasn't arrived have no self duration.
+func rootSelfDuration(nodes []spanNode) int64 {
+	var root *spanNode
+	for i, node := range nodes {
+		if node.parentSpanID == "" && (root == nil || node.startTime.Before(root.startTime)) {
+			root = &nodes[i]
+		}
+	}
+	if root == nil || !root.endTime.After(root.startTime) {
+		return 0
+	}
+
+	children := []spanNode{}
+	for _, node := range nodes {
+		if node.parentSpanID == root.spanID && node.spanID != root.spanID {
+			children = append(children, node)
+		}
+	}
+	sort.Slice(children, func(i, j int) bool {
+		return children[i].startTime.Before(children[j].startTime)
+	})
+
+	var covered int64
+	coveredUntil := root.startTime
+	for _, child := range children {
+		start, end := child.startTime, child.endTime
+		if start.Before(coveredUntil) {
+			start = coveredUntil
+		}
+		if end.After(root.endTime) {
+			end = root.endTime
+		}
+		if end.After(start) {
+			covered += end.Sub(start).Nanoseconds()
+			coveredUntil = end
+		}
+	}
+	return root.endTime.Sub(root.startTime).Nanoseconds() - covered
+}
+
+// maxDepth walks down from every span that has no parent in the trace. Spans that are
+// part of a parent cycle, and so never reached, don't count.
+func maxDepth(nodes []spanNode) int {
+	spanIDs := map[string]bool{}
+	children := map[string][]string{}
+	for _, node := range nodes {
+		spanIDs[node.spanID] = true
+	}
+
+	level := []string{}
+	for _, node := range nodes {
+		if node.parentSpanID == "" || !spanIDs[node.parentSpanID] {
+			level = append(level, node.spanID)
+		} else {
+			children[node.parentSpanID] = append(children[node.parentSpanID], node.spanID)
+		}
+	}
+
+	depth := 0
+	visited := map[string]bool{}
+	for len(level) > 0 {
+		reached := false
+		next := []string{}
+		for _, spanID := range level {
+			if visited[spanID] {
+				continue
+			}
+			visited[spanID] = true
+			reached = true
+			next = append(next, children[spanID]...)
+		}
+		if reached {
+			depth++
+		}
+		level = next
+	}
+	return depth
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// The modes GetStatus reports the store as opened in
+const (
+	ModeMemory = "memory"
+	ModeFile   = "file"
+)
+
+// GetStatus counts the traces and spans in the store, not counting spans still waiting to be
+// written, and finds the time range they cover, all in one pass over the spans
+func (s *Store) GetStatus(ctx context.Context) (telemetry.StoreStatus, error) {
+	status := telemetry.StoreStatus{Mode: ModeMemory, ReadOnly: s.readOnly}
+	if s.dbPath != "" {
+		status.Mode = ModeFile
+	}
+
+	var oldest, newest sql.NullTime
+	err := s.db.QueryRowContext(ctx, SELECT_STORE_STATUS).Scan(&status.TraceCount, &status.SpanCount, &oldest, &newest)
+	if err != nil {
+		return status, fmt.Errorf("could not retrieve store status: %s", err.Error())
+	}
+	if oldest.Valid {
+		t := oldest.Time.UTC()
+		status.OldestSpan = &t
+	}
+	if newest.Valid {
+		t := newest.Time.UTC()
+		status.NewestSpan = &t
+	}
+
+	if status.SchemaVersion, err = currentSchemaVersion(ctx, s.db); err != nil {
+		return status, err
+	}
+	return status, nil
+}
@@ -5,9 +5,14 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/marcboeker/go-duckdb"
@@ -16,50 +21,211 @@ import (
 )
 
 type Store struct {
-	mut  sync.Mutex
-	db   *sql.DB
-	conn driver.Conn
+	mut    sync.Mutex
+	db     *sql.DB
+	conn   driver.Conn
+	dbPath string
+
+	batchWindow    time.Duration
+	batchMaxSpans  int
+	batches        chan []telemetry.SpanData
+	flushes        chan chan error
+	stopBatcher    chan struct{}
+	batcherDone    chan struct{}
+	writeListener  func(traceIDs []string)
+	observer       Observer
+	maxSpans       int
+	spillPolicy    RetentionPolicy
+	ingestLimiter  *rateLimiter
+	ingestListener func(spans []telemetry.SpanData)
+	groupAttribute string
+	snapshotMut    sync.Mutex
+	deleteMut      sync.RWMutex
+	memoryLimit    string
+	threads        int
+	readOnly       bool
+
+	// opened and changes make up the data version, so a version from before a restart
+	// isn't mistaken for one after it
+	opened  int64
+	changes atomic.Int64
+
+	closeMut  sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	closeErr  error
 }
 
-func NewStore(ctx context.Context, dbPath string) *Store {
-	connector, err := duckdb.NewConnector(dbPath, nil)
+// NewStore opens the DuckDB database at dbPath, creating the schema if it is absent.
+// An empty dbPath opens DuckDB in in-memory mode, with nothing persisted to disk.
+func NewStore(ctx context.Context, dbPath string, opts ...Option) *Store {
+	store, err := openStore(ctx, dbPath, opts...)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return store
+}
+
+func openStore(ctx context.Context, dbPath string, opts ...Option) (*Store, error) {
+	store := &Store{
+		mut:           sync.Mutex{},
+		dbPath:        dbPath,
+		batchWindow:   defaultBatchWindow,
+		batchMaxSpans: defaultBatchMaxSpans,
+		batches:       make(chan []telemetry.SpanData, batchQueueSize),
+		flushes:       make(chan chan error),
+		stopBatcher:   make(chan struct{}),
+		batcherDone:   make(chan struct{}),
+		opened:        time.Now().UnixNano(),
+	}
+	for _, opt := range opts {
+		opt(store)
+	}
+
+	// DuckDB locks database files against other processes but not against
+	// a second database instance in this one, so we keep track of those ourselves
+	if err := claimDatabaseFile(dbPath); err != nil {
+		return nil, err
+	}
 
+	var err error
+	if store.tiered() {
+		store.db, store.conn, err = connectTiers(ctx, dbPath)
+	} else {
+		store.db, store.conn, err = connect(ctx, dbPath, store.readOnly)
+	}
 	if err != nil {
-		log.Fatalf("could not initialize new connector: %s", err.Error())
+		releaseDatabaseFile(dbPath)
+		return nil, err
+	}
+	if err = store.applyResourceLimits(ctx); err != nil {
+		store.conn.Close()
+		store.db.Close()
+		releaseDatabaseFile(dbPath)
+		return nil, err
+	}
+	go store.runBatcher()
+
+	return store, nil
+}
+
+func connect(ctx context.Context, dbPath string, readOnly bool) (*sql.DB, driver.Conn, error) {
+	dsn := dbPath
+	if readOnly {
+		if err := checkDatabaseFile(dbPath); err != nil {
+			return nil, nil, err
+		}
+		dsn += "?access_mode=read_only"
+	}
+
+	connector, err := duckdb.NewConnector(dsn, nil)
+	if err != nil {
+		if strings.Contains(err.Error(), "Could not set lock on file") {
+			return nil, nil, fmt.Errorf("%w: %s", ErrDatabaseInUse, err.Error())
+		}
+		return nil, nil, fmt.Errorf("could not initialize new connector: %s", err.Error())
 	}
 
 	conn, err := connector.Connect(ctx)
 	if err != nil {
-		log.Fatalf("could not connect to the database: %s", err.Error())
+		return nil, nil, fmt.Errorf("could not connect to the database: %s", err.Error())
 	}
 
 	db := sql.OpenDB(connector)
-	_, err = db.Exec(ENABLE_JSON)
-	if err != nil {
-		log.Fatalf("could not enable json: %s", err.Error())
+	closeAll := func() {
+		conn.Close()
+		db.Close()
+	}
+
+	if _, err = db.Exec(ENABLE_JSON); err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("could not enable json: %s", err.Error())
 	}
 
-	if _, err = db.Exec(CREATE_SPANS_TABLE); err != nil {
-		log.Fatalf("could not create table spans: %s", err.Error())
+	// A read-only database can't be migrated, so it has to be at the current version already
+	if readOnly {
+		err = checkSchemaVersion(ctx, db)
+	} else {
+		err = migrate(ctx, db)
+	}
+	if err != nil {
+		closeAll()
+		return nil, nil, err
 	}
 
-	return &Store{
-		mut:  sync.Mutex{},
-		db:   db,
-		conn: conn,
+	if err = validateSchema(ctx, db); err != nil {
+		closeAll()
+		return nil, nil, err
 	}
+
+	return db, conn, nil
 }
 
-func (s *Store) AddSpans(ctx context.Context, spans []telemetry.SpanData) error {
+// writeSpans appends a batch of spans through a single appender, which keeps
+// the nanosecond precision of their timestamps, unlike binding them to a statement.
+// The appender can only insert, so the spans are staged in a temporary table on the
+// appender's connection and upserted from there: a span sent again, as exporters do
+// when they retry, replaces the copy already stored rather than duplicating it.
+// If the store has a span cap, traces are evicted to make room for the batch first.
+func (s *Store) writeSpans(ctx context.Context, spans []telemetry.SpanData) error {
 	s.mut.Lock()
 	defer s.mut.Unlock()
+	defer s.changes.Add(1)
+
+	if s.maxSpans > 0 {
+		if err := s.makeRoom(ctx, len(spans)); err != nil {
+			return err
+		}
+	}
 
-	appender, err := duckdb.NewAppenderFromConn(s.conn, "", "spans")
+	execer, ok := s.conn.(driver.ExecerContext)
+	if !ok {
+		return fmt.Errorf("could not stage spans: connection can't execute statements")
+	}
+	if _, err := execer.ExecContext(ctx, fmt.Sprintf(CREATE_STAGED_SPANS, s.hotSpansTable()), nil); err != nil {
+		return fmt.Errorf("could not create table staged_spans: %s", err.Error())
+	}
+	defer execer.ExecContext(ctx, TRUNCATE_STAGED_SPANS, nil)
+
+	appender, err := duckdb.NewAppenderFromConn(s.conn, "", "staged_spans")
 	if err != nil {
 		return fmt.Errorf("could not create new appender for spans: %s", err.Error())
 	}
-	defer appender.Close()
+	err = appendSpans(appender, latestCopies(spans))
+	if closeErr := appender.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("could not flush appender for spans: %s", closeErr.Error())
+	}
+	if err != nil {
+		return err
+	}
 
+	if _, err = execer.ExecContext(ctx, fmt.Sprintf(UPSERT_STAGED_SPANS, s.hotSpansTable()), nil); err != nil {
+		return fmt.Errorf("could not upsert spans: %s", err.Error())
+	}
+	return nil
+}
+
+// latestCopies drops all but the last copy of any span repeated within a batch, as a single
+// upsert can't update the same row twice
+func latestCopies(spans []telemetry.SpanData) []telemetry.SpanData {
+	last := map[[2]string]int{}
+	for i, span := range spans {
+		last[[2]string{span.TraceID, span.SpanID}] = i
+	}
+	if len(last) == len(spans) {
+		return spans
+	}
+
+	unique := make([]telemetry.SpanData, 0, len(last))
+	for i, span := range spans {
+		if last[[2]string{span.TraceID, span.SpanID}] == i {
+			unique = append(unique, span)
+		}
+	}
+	return unique
+}
+
+func appendSpans(appender *duckdb.Appender, spans []telemetry.SpanData) error {
 	for _, span := range spans {
 		attributes, err := json.Marshal(span.Attributes)
 		if err != nil {
@@ -122,148 +288,732 @@ func (s *Store) GetTrace(ctx context.Context, traceID string) (telemetry.TraceDa
 		Spans:   []telemetry.SpanData{},
 	}
 
-	rows, err := s.db.QueryContext(ctx, SELECT_TRACE, traceID)
+	spans, err := s.querySpans(ctx, SELECT_TRACE, traceID)
 	if err != nil {
-		log.Fatalf("could not retrieve spans: %s", err.Error())
+		return trace, err
 	}
-	defer rows.Close()
+	trace.Spans = spans
 
-	for rows.Next() {
-		span := telemetry.SpanData{}
-		span.Resource = &telemetry.ResourceData{
-			Attributes:             map[string]interface{}{},
-			DroppedAttributesCount: 0,
+	// Fun thing: db.QueryContext does not return sql.ErrNoRows,
+	// but the first call to rows.Next() returns false,
+	// so we have to check for traceID not found here.
+	if len(trace.Spans) == 0 {
+		return trace, telemetry.ErrTraceIDNotFound
+	}
+
+	return trace, nil
+}
+
+// GetTraces returns the traces with the given IDs, keyed by trace ID, in a single query.
+// IDs with no spans in the store are left out rather than treated as an error.
+func (s *Store) GetTraces(ctx context.Context, traceIDs []string) (map[string]telemetry.TraceData, error) {
+	traces := map[string]telemetry.TraceData{}
+	if len(traceIDs) == 0 {
+		return traces, nil
+	}
+
+	args := make([]any, len(traceIDs))
+	for i, traceID := range traceIDs {
+		args[i] = traceID
+	}
+	spans, err := s.querySpans(ctx, fmt.Sprintf(SELECT_TRACES, placeholders(len(traceIDs))), args...)
+	if err != nil {
+		return traces, err
+	}
+
+	for _, span := range spans {
+		trace, ok := traces[span.TraceID]
+		if !ok {
+			trace = telemetry.TraceData{TraceID: span.TraceID, Spans: []telemetry.SpanData{}}
 		}
-		span.Scope = &telemetry.ScopeData{
-			Name:                   "",
-			Version:                "",
-			Attributes:             map[string]interface{}{},
-			DroppedAttributesCount: 0,
+		trace.Spans = append(trace.Spans, span)
+		traces[span.TraceID] = trace
+	}
+	return traces, nil
+}
+
+// GetSpan returns a single span of a trace, for when the rest of the trace isn't needed
+func (s *Store) GetSpan(ctx context.Context, traceID string, spanID string) (telemetry.SpanData, error) {
+	spans, err := s.querySpans(ctx, SELECT_SPAN, traceID, spanID)
+	if err != nil {
+		return telemetry.SpanData{}, err
+	}
+	if len(spans) == 0 {
+		return telemetry.SpanData{}, telemetry.ErrSpanIDNotFound
+	}
+	return spans[0], nil
+}
+
+// GetTraceOfKinds returns the spans of a trace whose kind is one of kinds, as ParseSpanKind
+// returns them, along with every ancestor of those spans, whatever its kind, so the spans can still
+// be nested under their parents. TotalSpans is the number of spans in the whole trace.
+func (s *Store) GetTraceOfKinds(ctx context.Context, traceID string, kinds []string) (telemetry.TraceData, error) {
+	trace := telemetry.TraceData{
+		TraceID: traceID,
+		Spans:   []telemetry.SpanData{},
+	}
+
+	if err := s.db.QueryRowContext(ctx, COUNT_TRACE_SPANS, traceID).Scan(&trace.TotalSpans); err != nil {
+		return trace, fmt.Errorf("could not count spans: %s", err.Error())
+	}
+	if trace.TotalSpans == 0 {
+		return trace, telemetry.ErrTraceIDNotFound
+	}
+
+	args := []any{traceID}
+	for _, kind := range kinds {
+		args = append(args, kind)
+	}
+	args = append(args, traceID, traceID)
+	spans, err := s.querySpans(ctx, fmt.Sprintf(SELECT_TRACE_OF_KINDS, placeholders(len(kinds))), args...)
+	if err != nil {
+		return trace, err
+	}
+	trace.Spans = spans
+	return trace, nil
+}
+
+// ParseSpanKind validates a span kind received from a client, in any case, and returns it as
+// spans store it, such as Server
+func ParseSpanKind(kind string) (string, error) {
+	for _, k := range []string{"Server", "Client", "Producer", "Consumer", "Internal"} {
+		if strings.EqualFold(kind, k) {
+			return k, nil
 		}
+	}
+	return "", fmt.Errorf("invalid kind %q: must be SERVER, CLIENT, PRODUCER, CONSUMER, or INTERNAL", kind)
+}
 
-		// Placeholders for JSON
-		attrBytes := []byte{}
-		evntBytes := []byte{}
-		linkBytes := []byte{}
-		rAttrBytes := []byte{}
-		sAttrBytes := []byte{}
-
-		if err = rows.Scan(
-			&span.TraceID,
-			&span.TraceState,
-			&span.SpanID,
-			&span.ParentSpanID,
-			&span.Name,
-			&span.Kind,
-			&span.StartTime,
-			&span.EndTime,
-			&attrBytes,
-			&evntBytes,
-			&linkBytes,
-			&rAttrBytes,
-			&span.Resource.DroppedAttributesCount,
-			&span.Scope.Name,
-			&span.Scope.Version,
-			&sAttrBytes,
-			&span.Scope.DroppedAttributesCount,
-			&span.DroppedAttributesCount,
-			&span.DroppedEventsCount,
-			&span.DroppedLinksCount,
-			&span.StatusCode,
-			&span.StatusMessage,
-		); err != nil {
-			return trace, fmt.Errorf("could not scan spans: %s", err.Error())
+// GetTracePage returns a page of a trace's spans along with the number of spans in the trace.
+// Root spans come first, so the first page always includes them, followed by the rest ordered
+// by start time. A limit of zero or less returns every span past the offset.
+func (s *Store) GetTracePage(ctx context.Context, traceID string, limit int, offset int) (telemetry.TraceData, error) {
+	trace := telemetry.TraceData{
+		TraceID: traceID,
+		Spans:   []telemetry.SpanData{},
+	}
+
+	if err := s.db.QueryRowContext(ctx, COUNT_TRACE_SPANS, traceID).Scan(&trace.TotalSpans); err != nil {
+		return trace, fmt.Errorf("could not count spans: %s", err.Error())
+	}
+	if trace.TotalSpans == 0 {
+		return trace, telemetry.ErrTraceIDNotFound
+	}
+
+	statement, pageArgs := paginate(SELECT_TRACE_PAGE, limit, offset)
+	spans, err := s.querySpans(ctx, statement, append([]any{traceID}, pageArgs...)...)
+	if err != nil {
+		return trace, err
+	}
+	trace.Spans = spans
+	return trace, nil
+}
+
+// querySpans reads the spans returned by a statement selecting every column of the spans table
+func (s *Store) querySpans(ctx context.Context, statement string, args ...any) ([]telemetry.SpanData, error) {
+	spans := []telemetry.SpanData{}
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve spans: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		span, err := scanSpan(rows)
+		if err != nil {
+			return spans, err
 		}
+		spans = append(spans, span)
+	}
+	if err = rows.Err(); err != nil {
+		return spans, fmt.Errorf("could not retrieve spans: %s", err.Error())
+	}
+
+	return spans, nil
+}
+
+// EachTrace calls fn with every trace in the store, one at a time and ordered by trace ID,
+// so they can be streamed out without holding them all in memory. The spans of each trace are
+// ordered by start time. EachTrace stops at the first error fn returns, and returns it.
+func (s *Store) EachTrace(ctx context.Context, fn func(trace telemetry.TraceData) error) error {
+	rows, err := s.db.QueryContext(ctx, SELECT_ALL_SPANS)
+	if err != nil {
+		return fmt.Errorf("could not retrieve spans: %s", err.Error())
+	}
+	defer rows.Close()
 
-		if err = json.Unmarshal(attrBytes, &span.Attributes); err != nil {
-			return trace, fmt.Errorf("could not unmarshal span attributes: %s", err.Error())
+	trace := telemetry.TraceData{Spans: []telemetry.SpanData{}}
+	for rows.Next() {
+		span, err := scanSpan(rows)
+		if err != nil {
+			return err
 		}
 
-		if err = json.Unmarshal(evntBytes, &span.Events); err != nil {
-			return trace, fmt.Errorf("could not unmarshal span events: %s", err.Error())
+		if span.TraceID != trace.TraceID && len(trace.Spans) > 0 {
+			if err = fn(trace); err != nil {
+				return err
+			}
+			trace = telemetry.TraceData{Spans: []telemetry.SpanData{}}
 		}
+		trace.TraceID = span.TraceID
+		trace.Spans = append(trace.Spans, span)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("could not retrieve spans: %s", err.Error())
+	}
+
+	if len(trace.Spans) > 0 {
+		return fn(trace)
+	}
+	return nil
+}
+
+// scanSpan reads a row holding every column of the spans table
+func scanSpan(rows *sql.Rows) (telemetry.SpanData, error) {
+	span := telemetry.SpanData{}
+	span.Resource = &telemetry.ResourceData{
+		Attributes:             map[string]interface{}{},
+		DroppedAttributesCount: 0,
+	}
+	span.Scope = &telemetry.ScopeData{
+		Name:                   "",
+		Version:                "",
+		Attributes:             map[string]interface{}{},
+		DroppedAttributesCount: 0,
+	}
+
+	// Placeholders for JSON
+	attrBytes := []byte{}
+	evntBytes := []byte{}
+	linkBytes := []byte{}
+	rAttrBytes := []byte{}
+	sAttrBytes := []byte{}
+
+	if err := rows.Scan(
+		&span.TraceID,
+		&span.TraceState,
+		&span.SpanID,
+		&span.ParentSpanID,
+		&span.Name,
+		&span.Kind,
+		&span.StartTime,
+		&span.EndTime,
+		&attrBytes,
+		&evntBytes,
+		&linkBytes,
+		&rAttrBytes,
+		&span.Resource.DroppedAttributesCount,
+		&span.Scope.Name,
+		&span.Scope.Version,
+		&sAttrBytes,
+		&span.Scope.DroppedAttributesCount,
+		&span.DroppedAttributesCount,
+		&span.DroppedEventsCount,
+		&span.DroppedLinksCount,
+		&span.StatusCode,
+		&span.StatusMessage,
+	); err != nil {
+		return span, fmt.Errorf("could not scan spans: %s", err.Error())
+	}
+	span.DurationNanos = telemetry.DurationNanos(span.StartTime, span.EndTime)
+
+	if err := json.Unmarshal(attrBytes, &span.Attributes); err != nil {
+		return span, fmt.Errorf("could not unmarshal span attributes: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(evntBytes, &span.Events); err != nil {
+		return span, fmt.Errorf("could not unmarshal span events: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(linkBytes, &span.Links); err != nil {
+		return span, fmt.Errorf("could not unmarshal span links: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(rAttrBytes, &span.Resource.Attributes); err != nil {
+		return span, fmt.Errorf("could not unmarshal resource attributes: %s", err.Error())
+	}
+
+	if err := json.Unmarshal(sAttrBytes, &span.Scope.Attributes); err != nil {
+		return span, fmt.Errorf("could not unmarshal scope attributes: %s", err.Error())
+	}
+
+	fillEmptyFields(&span)
+	inUTC(&span)
+	return span, nil
+}
 
-		if err = json.Unmarshal(linkBytes, &span.Links); err != nil {
-			return trace, fmt.Errorf("could not unmarshal span links: %s", err.Error())
+// inUTC puts the times of a span in UTC. DuckDB already returns its timestamp columns in UTC, but
+// event timestamps are kept in the events JSON with whatever offset they were written with.
+func inUTC(span *telemetry.SpanData) {
+	span.StartTime = span.StartTime.UTC()
+	span.EndTime = span.EndTime.UTC()
+	for i := range span.Events {
+		span.Events[i].Timestamp = span.Events[i].Timestamp.UTC()
+	}
+}
+
+// fillEmptyFields replaces the nil slices and attributes of spans written without them, such as
+// converted Jaeger or Zipkin spans, with empty ones, so they are never sent to clients as null
+func fillEmptyFields(span *telemetry.SpanData) {
+	if span.Attributes == nil {
+		span.Attributes = telemetry.Attributes{}
+	}
+	if span.Events == nil {
+		span.Events = []telemetry.EventData{}
+	}
+	for i := range span.Events {
+		if span.Events[i].Attributes == nil {
+			span.Events[i].Attributes = telemetry.Attributes{}
 		}
+	}
+	if span.Links == nil {
+		span.Links = []telemetry.LinkData{}
+	}
+	for i := range span.Links {
+		if span.Links[i].Attributes == nil {
+			span.Links[i].Attributes = telemetry.Attributes{}
+		}
+	}
+	if span.Resource.Attributes == nil {
+		span.Resource.Attributes = telemetry.Attributes{}
+	}
+	if span.Scope.Attributes == nil {
+		span.Scope.Attributes = telemetry.Attributes{}
+	}
+}
+
+// ImportSpans adds the spans that are not in the store yet, skipping any whose
+// trace and span ID are already there (or repeated among the spans themselves)
+func (s *Store) ImportSpans(ctx context.Context, spans []telemetry.SpanData) (telemetry.ImportedTraces, error) {
+	imported := telemetry.ImportedTraces{
+		TraceIDs:       []string{},
+		ImportedSpans:  0,
+		DuplicateSpans: 0,
+	}
+	if err := s.writable(); err != nil {
+		return imported, err
+	}
 
-		if err = json.Unmarshal(rAttrBytes, &span.Resource.Attributes); err != nil {
-			return trace, fmt.Errorf("could not unmarshal resource attributes: %s", err.Error())
+	// Spans still waiting to be written count as being in the store
+	if err := s.Flush(ctx); err != nil {
+		return imported, err
+	}
+
+	// Malformed spans would be dropped by AddSpans anyway, but shouldn't count as imported
+	spans = s.dropMalformedSpans(spans)
+
+	traceIDs := []string{}
+	args := []any{}
+	for _, span := range spans {
+		if !slices.Contains(traceIDs, span.TraceID) {
+			traceIDs = append(traceIDs, span.TraceID)
+			args = append(args, span.TraceID)
 		}
+	}
+	if len(traceIDs) == 0 {
+		return imported, nil
+	}
+	imported.TraceIDs = traceIDs
 
-		if err = json.Unmarshal(sAttrBytes, &span.Scope.Attributes); err != nil {
-			return trace, fmt.Errorf("could not unmarshal scope attributes: %s", err.Error())
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_SPAN_IDS, placeholders(len(args))), args...)
+	if err != nil {
+		return imported, fmt.Errorf("could not retrieve span IDs: %s", err.Error())
+	}
+	defer rows.Close()
+
+	seen := map[[2]string]bool{}
+	for rows.Next() {
+		var traceID, spanID string
+		if err = rows.Scan(&traceID, &spanID); err != nil {
+			return imported, fmt.Errorf("could not scan span ID: %s", err.Error())
 		}
+		seen[[2]string{traceID, spanID}] = true
+	}
+	if err = rows.Err(); err != nil {
+		return imported, fmt.Errorf("could not retrieve span IDs: %s", err.Error())
+	}
 
-		trace.Spans = append(trace.Spans, span)
+	newSpans := []telemetry.SpanData{}
+	for _, span := range spans {
+		id := [2]string{span.TraceID, span.SpanID}
+		if seen[id] {
+			imported.DuplicateSpans++
+			continue
+		}
+		seen[id] = true
+		newSpans = append(newSpans, span)
 	}
 
-	// Fun thing: db.QueryContext does not return sql.ErrNoRows,
-	// but the first call to rows.Next() returns false,
-	// so we have to check for traceID not found here.
-	if len(trace.Spans) == 0 {
-		return trace, telemetry.ErrTraceIDNotFound
+	if err = s.AddSpans(ctx, newSpans); err != nil {
+		return imported, err
+	}
+	if err = s.Flush(ctx); err != nil {
+		return imported, err
 	}
 
-	return trace, nil
+	imported.ImportedSpans = len(newSpans)
+	return imported, nil
 }
 
-func (s *Store) GetTraceSummaries(ctx context.Context) (*[]telemetry.TraceSummary, error) {
-	summaries := []telemetry.TraceSummary{}
+// CountTraces returns the number of traces in the store, not counting spans still waiting to be written
+func (s *Store) CountTraces(ctx context.Context) (int, error) {
+	count := 0
+	if err := s.db.QueryRowContext(ctx, COUNT_TRACES).Scan(&count); err != nil {
+		return 0, fmt.Errorf("could not count traces: %s", err.Error())
+	}
+	return count, nil
+}
 
-	rows, err := s.db.QueryContext(ctx, SELECT_ORDERED_TRACES)
-	if err == sql.ErrNoRows {
-		return &summaries, nil
-	} else if err != nil {
-		return nil, fmt.Errorf("could not retrieve trace summaries: %s", err.Error())
+// GetServiceNames returns the distinct service names of every span in the store, sorted alphabetically
+func (s *Store) GetServiceNames(ctx context.Context) ([]string, error) {
+	services := []string{}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_SERVICE_NAMES)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve service names: %s", err.Error())
 	}
 	defer rows.Close()
 
 	for rows.Next() {
-		summary := telemetry.TraceSummary{
-			HasRootSpan:     false,
-			RootServiceName: "",
-			RootName:        "",
-			RootStartTime:   time.Time{},
-			RootEndTime:     time.Time{},
-			SpanCount:       0,
-			TraceID:         "",
+		var service string
+		if err = rows.Scan(&service); err != nil {
+			return nil, fmt.Errorf("could not scan service name: %s", err.Error())
 		}
+		services = append(services, service)
+	}
+	return services, rows.Err()
+}
+
+// GetServiceSpanCounts returns the number of spans produced by each service, sorted alphabetically by service name
+func (s *Store) GetServiceSpanCounts(ctx context.Context) ([]telemetry.ServiceSpanCount, error) {
+	counts := []telemetry.ServiceSpanCount{}
+
+	rows, err := s.db.QueryContext(ctx, SELECT_SERVICE_SPAN_COUNTS)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve service span counts: %s", err.Error())
+	}
+	defer rows.Close()
 
-		if err = rows.Scan(&summary.TraceID); err != nil {
-			return nil, fmt.Errorf("could not scan summary traceID: %s", err.Error())
+	for rows.Next() {
+		count := telemetry.ServiceSpanCount{}
+		if err = rows.Scan(&count.ServiceName, &count.SpanCount); err != nil {
+			return nil, fmt.Errorf("could not scan service span count: %s", err.Error())
 		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+// GetServiceGroups returns the number of spans with each distinct combination of values of the
+// given resource attributes, sorted by those values in order. A span missing any of them is
+// counted with an empty value for it, so every span is in exactly one group.
+func (s *Store) GetServiceGroups(ctx context.Context, keys []string) ([]telemetry.ServiceGroup, error) {
+	groups := []telemetry.ServiceGroup{}
+	if len(keys) == 0 {
+		return nil, errors.New("could not retrieve service groups: no resource attributes to group by")
+	}
+
+	columns := make([]string, len(keys))
+	args := make([]any, len(keys))
+	for i, key := range keys {
+		columns[i] = SERVICE_GROUP_COLUMN
+		args[i] = key
+	}
+	statement := fmt.Sprintf(SELECT_SERVICE_GROUPS, strings.Join(columns, ", "))
+
+	rows, err := s.db.QueryContext(ctx, statement, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not retrieve service groups: %s", err.Error())
+	}
+	defer rows.Close()
 
-		spanCountRow := s.db.QueryRowContext(ctx, SELECT_SPAN_COUNT, summary.TraceID)
-		if err = spanCountRow.Scan(&summary.SpanCount); err != nil {
-			return nil, fmt.Errorf("could not scan summary spanCount: %s", err.Error())
+	values := make([]string, len(keys))
+	dest := make([]any, len(keys)+1)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	for rows.Next() {
+		group := telemetry.ServiceGroup{Attributes: map[string]string{}}
+		dest[len(keys)] = &group.SpanCount
+		if err = rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("could not scan service group: %s", err.Error())
+		}
+		for i, key := range keys {
+			group.Attributes[key] = values[i]
 		}
+		groups = append(groups, group)
+	}
+	return groups, rows.Err()
+}
+
+// DeleteTrace removes every span of a trace, from every tier, including any still waiting to be written
+func (s *Store) DeleteTrace(ctx context.Context, traceID string) error {
+	if err := s.writable(); err != nil {
+		return err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	deleted, err := s.deleteTracesLocked(ctx, SELECT_TRACE_ID, traceID)
+	if err != nil {
+		return fmt.Errorf("could not delete trace: %s", err.Error())
+	}
+	if deleted == 0 {
+		return telemetry.ErrTraceIDNotFound
+	}
+	return nil
+}
 
-		rootSpanRow := s.db.QueryRowContext(ctx, SELECT_ROOT_SPAN, summary.TraceID)
-		err = rootSpanRow.Scan(&summary.RootServiceName, &summary.RootName, &summary.RootStartTime, &summary.RootEndTime)
-		if err == nil {
-			summary.HasRootSpan = true
-			summaries = append(summaries, summary)
-		} else if err == sql.ErrNoRows {
-			summaries = append(summaries, summary)
-		} else {
-			return nil, fmt.Errorf("could not retrieve trace summaries: %s", err.Error())
+// ClearTraces removes every trace, including any spans still waiting to be written, along with all logs, metrics,
+// annotations, and pins.
+// It returns the number of traces removed.
+func (s *Store) ClearTraces(ctx context.Context) (int, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return 0, err
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.deleteMut.Lock()
+	defer s.deleteMut.Unlock()
+	defer s.changes.Add(1)
+
+	cleared := 0
+	if err := s.db.QueryRowContext(ctx, COUNT_TRACES).Scan(&cleared); err != nil {
+		return 0, fmt.Errorf("could not count traces: %s", err.Error())
+	}
+	for _, table := range s.spansTables() {
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf(TRUNCATE_SPANS, table)); err != nil {
+			return 0, fmt.Errorf("could not clear traces: %s", err.Error())
 		}
 	}
-	return &summaries, nil
+	if _, err := s.db.ExecContext(ctx, TRUNCATE_LOGS); err != nil {
+		return 0, fmt.Errorf("could not clear logs: %s", err.Error())
+	}
+	if _, err := s.db.ExecContext(ctx, TRUNCATE_METRICS); err != nil {
+		return 0, fmt.Errorf("could not clear metrics: %s", err.Error())
+	}
+	if _, err := s.db.ExecContext(ctx, TRUNCATE_ANNOTATIONS); err != nil {
+		return 0, fmt.Errorf("could not clear annotations: %s", err.Error())
+	}
+	if _, err := s.db.ExecContext(ctx, TRUNCATE_PINS); err != nil {
+		return 0, fmt.Errorf("could not clear pins: %s", err.Error())
+	}
+	return cleared, nil
+}
+
+// ClearQuery picks the traces ClearMatchingTraces removes. A trace matches if any of its spans came
+// from one of Services, and if its earliest span started before Before and after After. Zero values
+// don't filter anything.
+type ClearQuery struct {
+	Services []string
+	Before   time.Time
+	After    time.Time
 }
 
-func (s *Store) ClearTraces(ctx context.Context) error {
+// ClearMatchingTraces removes the traces matching the query, including any of their spans still waiting
+// to be written, and returns how many it removed. Unlike ClearTraces it leaves logs and metrics alone.
+func (s *Store) ClearMatchingTraces(ctx context.Context, query ClearQuery) (int, error) {
+	if err := s.writable(); err != nil {
+		return 0, err
+	}
+	if err := s.Flush(ctx); err != nil {
+		return 0, err
+	}
+
 	s.mut.Lock()
 	defer s.mut.Unlock()
 
-	if _, err := s.db.ExecContext(ctx, TRUNCATE_SPANS); err != nil {
-		return fmt.Errorf("could not clear traces: %s", err.Error())
+	conditions, args := query.conditions()
+	cleared, err := s.deleteTracesLocked(ctx, fmt.Sprintf(SELECT_MATCHING_TRACES, conditions), args...)
+	if err != nil {
+		return 0, fmt.Errorf("could not clear traces: %s", err.Error())
+	}
+	return cleared, nil
+}
+
+func (query ClearQuery) conditions() (string, []any) {
+	conditions := ""
+	args := []any{}
+
+	if len(query.Services) > 0 {
+		conditions += fmt.Sprintf(" AND bool_or(ifnull(resourceAttributes->>'service.name', '') IN (%s))", placeholders(len(query.Services)))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+	if !query.Before.IsZero() {
+		conditions += " AND MIN(startTime) < ?"
+		args = append(args, query.Before)
+	}
+	if !query.After.IsZero() {
+		conditions += " AND MIN(startTime) > ?"
+		args = append(args, query.After)
+	}
+	return conditions, args
+}
+
+// deleteTracesLocked deletes every span of the traces a selection picks, from every tier, along
+// with their annotations and pins, and counts the traces deleted. It all happens in one
+// transaction, but for the spans of the database file's tier: DuckDB only lets a transaction
+// write to one database, so those are deleted in a transaction of their own, committed first.
+// Should the rest fail, the traces keep the spans held in memory, and their annotations and
+// pins with them. It must be called with s.mut held.
+func (s *Store) deleteTracesLocked(ctx context.Context, selection string, args ...any) (int, error) {
+	deleted := map[string]bool{}
+	err := s.withSelectedTraces(ctx, selection, args, func(conn *sql.Conn) error {
+		tables := s.spansTables()
+		if s.tiered() {
+			coldDeleted := map[string]bool{}
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				return deleteSelectedTraces(ctx, tx, "cold.spans", coldDeleted)
+			})
+			if err != nil {
+				return err
+			}
+			maps.Copy(deleted, coldDeleted)
+			tables = []string{"hot_spans"}
+		}
+
+		hotDeleted := map[string]bool{}
+		err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+			for _, table := range tables {
+				if err := deleteSelectedTraces(ctx, tx, table, hotDeleted); err != nil {
+					return err
+				}
+			}
+			if _, err := tx.ExecContext(ctx, DELETE_SELECTED_ANNOTATIONS); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, DELETE_SELECTED_PINS)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		maps.Copy(deleted, hotDeleted)
+		return nil
+	})
+	if len(deleted) > 0 || err != nil {
+		s.changes.Add(1)
+	}
+	return len(deleted), err
+}
+
+// inTransaction runs fn in a transaction on conn, committing it if fn succeeds and rolling it
+// back otherwise
+func inTransaction(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// queryer is a connection or transaction to run a query on
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// deleteSelectedTraces deletes the traces in the selected_traces table from a spans table,
+// adding the IDs of those it deleted spans of to deleted
+func deleteSelectedTraces(ctx context.Context, conn queryer, table string, deleted map[string]bool) error {
+	rows, err := conn.QueryContext(ctx, fmt.Sprintf(DELETE_SELECTED_TRACES, table))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var traceID string
+		if err = rows.Scan(&traceID); err != nil {
+			return fmt.Errorf("could not scan deleted traceID: %s", err.Error())
+		}
+		deleted[traceID] = true
+	}
+	return rows.Err()
+}
+
+// withSelectedTraces holds on to the trace IDs a selection picks in the selected_traces table
+// while fn runs, so that deleting the traces from one tier doesn't change which are picked in
+// the next. The table only exists on the connection fn is given. As fn deletes or moves the
+// traces, it waits for read sessions to end, and keeps new ones from starting until it's done.
+func (s *Store) withSelectedTraces(ctx context.Context, selection string, args []any, fn func(conn *sql.Conn) error) error {
+	s.deleteMut.Lock()
+	defer s.deleteMut.Unlock()
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err = conn.ExecContext(ctx, fmt.Sprintf(CREATE_SELECTED_TRACES, selection), args...); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, DROP_SELECTED_TRACES)
+
+	return fn(conn)
+}
+
+// DataVersion changes whenever spans are written or deleted, for responses built from them to be
+// cached by. Moving traces between tiers doesn't change it.
+func (s *Store) DataVersion() string {
+	return fmt.Sprintf("%x-%x", s.opened, s.changes.Load())
+}
+
+// Ping checks that the database answers a trivial query, failing with ErrStoreClosed once the store is closed
+func (s *Store) Ping(ctx context.Context) error {
+	s.closeMut.RLock()
+	defer s.closeMut.RUnlock()
+
+	if s.closed {
+		return ErrStoreClosed
+	}
+
+	one := 0
+	if err := s.db.QueryRowContext(ctx, PING).Scan(&one); err != nil {
+		return fmt.Errorf("could not ping database: %s", err.Error())
 	}
 	return nil
 }
 
+// Close writes any spans still waiting to be written, along with everything still in memory
+// when spilling to a database file, then closes the database.
+// It is safe to call more than once; every call returns once the store is closed.
 func (s *Store) Close() error {
-	s.conn.Close()
-	return s.db.Close()
+	s.closeOnce.Do(func() {
+		s.closeMut.Lock()
+		s.closed = true
+		s.closeMut.Unlock()
+
+		close(s.stopBatcher)
+		<-s.batcherDone
+
+		var spillErr error
+		if s.tiered() {
+			spillErr = s.spillAll(context.Background())
+		}
+
+		s.conn.Close()
+		s.closeErr = s.db.Close()
+		if spillErr != nil {
+			s.closeErr = spillErr
+		}
+		releaseDatabaseFile(s.dbPath)
+	})
+	return s.closeErr
 }
@@ -1,12 +1,20 @@
 package store
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/plog"
 )
 
 func TestPersistence(t *testing.T) {
@@ -20,9 +28,11 @@ func TestPersistence(t *testing.T) {
 	// Add sample spans to the store
 	err = store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
 	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
 
 	// Get trace summaries and check length
-	summaries, err := store.GetTraceSummaries(ctx)
+	summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
 	if assert.NoErrorf(t, err, "could not get trace summaries: %v", err) {
 		assert.Len(t, *summaries, 2)
 	}
@@ -47,3 +57,3143 @@ func TestPersistence(t *testing.T) {
 	err = os.Remove("./quack.db")
 	assert.NoError(t, err, "could not remove database file: %v", err)
 }
+
+func TestDatabaseInUse(t *testing.T) {
+	ctx := context.Background()
+	store, err := openStore(ctx, "./in_use.db")
+	if !assert.NoErrorf(t, err, "could not open database: %v", err) {
+		return
+	}
+	defer os.Remove("./in_use.db")
+
+	// A second store on the same file must fail instead of racing the first one.
+	_, err = openStore(ctx, "./in_use.db")
+	assert.ErrorIs(t, err, ErrDatabaseInUse)
+
+	// Once the first store is closed the file can be opened again
+	err = store.Close()
+	assert.NoErrorf(t, err, "could not close database: %v", err)
+
+	store, err = openStore(ctx, "./in_use.db")
+	if assert.NoErrorf(t, err, "could not reopen database: %v", err) {
+		store.Close()
+	}
+}
+
+func TestResourceLimits(t *testing.T) {
+	ctx := context.Background()
+	setting := func(store *Store, name string) string {
+		var value string
+		err := store.db.QueryRowContext(ctx, "SELECT current_setting('"+name+"')::VARCHAR").Scan(&value)
+		assert.NoErrorf(t, err, "could not read setting %s: %v", name, err)
+		return value
+	}
+
+	t.Run("Defaults", func(t *testing.T) {
+		store, err := openStore(ctx, "", WithResourceLimits("", 0))
+		if !assert.NoErrorf(t, err, "could not open database: %v", err) {
+			return
+		}
+		defer store.Close()
+		assert.NotEqual(t, "256.0 MiB", setting(store, "memory_limit"))
+	})
+
+	t.Run("Limited", func(t *testing.T) {
+		store, err := openStore(ctx, "", WithResourceLimits("256MiB", 1))
+		if !assert.NoErrorf(t, err, "could not open database: %v", err) {
+			return
+		}
+		defer store.Close()
+		assert.Equal(t, "1", setting(store, "threads"))
+		assert.Equal(t, "256.0 MiB", setting(store, "memory_limit"))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, limits := range []struct {
+			memoryLimit string
+			threads     int
+		}{
+			{"lots", 0},
+			{"256MB'; DROP TABLE spans; --", 0},
+			{"", -1},
+		} {
+			_, err := openStore(ctx, "", WithResourceLimits(limits.memoryLimit, limits.threads))
+			assert.Error(t, err, limits)
+		}
+	})
+}
+
+func TestSchemaMismatch(t *testing.T) {
+	ctx := context.Background()
+
+	// Create a database file with a spans table this version doesn't know about
+	db, err := sql.Open("duckdb", "./mismatch.db")
+	if !assert.NoErrorf(t, err, "could not create database: %v", err) {
+		return
+	}
+	defer os.Remove("./mismatch.db")
+
+	_, err = db.Exec("CREATE TABLE spans (traceID VARCHAR, spanID VARCHAR, name VARCHAR)")
+	assert.NoErrorf(t, err, "could not create spans table: %v", err)
+	db.Close()
+
+	_, err = openStore(ctx, "./mismatch.db")
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	// The refused file is released so it can be fixed and opened again
+	absPath, err := filepath.Abs("./mismatch.db")
+	assert.NoError(t, err)
+	assert.NotContains(t, openFiles.paths, absPath)
+}
+
+// legacySpansTable is the spans table created by releases before schema versioning (schema version 1)
+const legacySpansTable = `
+	CREATE TABLE spans
+	(traceID VARCHAR, traceState VARCHAR, spanID VARCHAR, parentSpanID VARCHAR,
+	name VARCHAR, kind VARCHAR, startTime TIMESTAMP_NS, endTime TIMESTAMP_NS,
+	attributes JSON, events JSON, links JSON, resourceAttributes JSON,
+	resourceDroppedAttributesCount UINTEGER, scopeName VARCHAR, scopeVersion VARCHAR,
+	scopeAttributes JSON, scopeDroppedAttributesCount UINTEGER, droppedAttributesCount UINTEGER,
+	droppedEventsCount UINTEGER, droppedLinksCount UINTEGER, statusCode VARCHAR, statusMessage VARCHAR)
+`
+
+func createFixtureDatabase(t *testing.T, dbPath string, statements ...string) {
+	db, err := sql.Open("duckdb", dbPath)
+	if !assert.NoErrorf(t, err, "could not create database: %v", err) {
+		t.FailNow()
+	}
+	defer db.Close()
+
+	for _, statement := range append([]string{ENABLE_JSON}, statements...) {
+		_, err = db.Exec(statement)
+		if !assert.NoErrorf(t, err, "could not set up fixture database: %v", err) {
+			t.FailNow()
+		}
+	}
+}
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+	createFixtureDatabase(t, "./legacy.db", legacySpansTable, `
+		INSERT INTO spans VALUES
+		('42957c7c2fca940a0d32a0cdd38c06a4', '', '37fd1349bf83d330', '', 'SAMPLE HTTP POST', 'Client',
+		'2023-02-02 18:17:54.803511676', '2023-02-02 18:17:54.817351051',
+		'{"http.method":"POST"}', '[]', '[]', '{"service.name":"sample-loadgenerator"}',
+		0, 'sample.requests', '0.28b1', '{}', 0, 0, 0, 0, 'Unset', ''),
+		('42957c7c2fca940a0d32a0cdd38c06a4', '', '37fd1349bf83d330', '', 'SAMPLE HTTP POST', 'Client',
+		'2023-02-02 18:17:54.803511676', '2023-02-02 18:17:54.817351051',
+		'{"http.method":"POST"}', '[]', '[]', '{"service.name":"sample-loadgenerator"}',
+		0, 'sample.requests', '0.28b1', '{}', 0, 0, 0, 0, 'Unset', '')
+	`)
+	defer os.Remove("./legacy.db")
+
+	store, err := openStore(ctx, "./legacy.db")
+	if !assert.NoErrorf(t, err, "could not open legacy database: %v", err) {
+		return
+	}
+
+	// The existing rows survive the migration, less the duplicate a retrying exporter left behind
+	trace, err := store.GetTrace(ctx, "42957c7c2fca940a0d32a0cdd38c06a4")
+	if assert.NoErrorf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, "37fd1349bf83d330", trace.Spans[0].SpanID)
+		assert.Equal(t, "sample-loadgenerator", trace.Spans[0].Resource.Attributes["service.name"])
+	}
+
+	version, err := currentSchemaVersion(ctx, store.db)
+	if assert.NoErrorf(t, err, "could not read schema version: %v", err) {
+		assert.Equal(t, schemaVersion, version)
+	}
+
+	// Reopening an up to date database is a no-op
+	err = store.Close()
+	assert.NoErrorf(t, err, "could not close database: %v", err)
+
+	store, err = openStore(ctx, "./legacy.db")
+	if assert.NoErrorf(t, err, "could not reopen database: %v", err) {
+		store.Close()
+	}
+}
+
+func TestSchemaTooNew(t *testing.T) {
+	ctx := context.Background()
+	createFixtureDatabase(t, "./too_new.db", CREATE_SCHEMA_VERSION_TABLE, "INSERT INTO schema_version (version) VALUES (999)")
+	defer os.Remove("./too_new.db")
+
+	_, err := openStore(ctx, "./too_new.db")
+	assert.ErrorIs(t, err, ErrSchemaTooNew)
+}
+
+func TestReadOnly(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "captured.db")
+
+	spans := telemetry.NewSampleTelemetry().Spans
+	store := NewStore(ctx, dbPath)
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Close()
+	assert.NoErrorf(t, err, "could not close database: %v", err)
+	captured, err := os.ReadFile(dbPath)
+	assert.NoErrorf(t, err, "could not read database file: %v", err)
+
+	t.Run("Reads", func(t *testing.T) {
+		store, err := openStore(ctx, dbPath, WithReadOnly())
+		if !assert.NoErrorf(t, err, "could not open database read-only: %v", err) {
+			return
+		}
+
+		trace, err := store.GetTrace(ctx, spans[0].TraceID)
+		if assert.NoErrorf(t, err, "could not get trace: %v", err) {
+			assert.NotEmpty(t, trace.Spans)
+		}
+		_, totalCount, err := store.GetTraceSummaries(ctx, 0, 0)
+		if assert.NoErrorf(t, err, "could not get trace summaries: %v", err) {
+			assert.Equal(t, 2, totalCount)
+		}
+
+		assert.ErrorIs(t, store.AddSpans(ctx, spans), ErrReadOnly)
+		_, err = store.ImportSpans(ctx, spans)
+		assert.ErrorIs(t, err, ErrReadOnly)
+		assert.ErrorIs(t, store.AddLogs(ctx, []telemetry.LogData{{TraceID: spans[0].TraceID}}), ErrReadOnly)
+		assert.ErrorIs(t, store.AddMetrics(ctx, []telemetry.MetricData{{Name: "requests"}}), ErrReadOnly)
+		assert.ErrorIs(t, store.SetAnnotations(ctx, spans[0].TraceID, telemetry.Annotations{"note": "seen"}), ErrReadOnly)
+		assert.ErrorIs(t, store.DeleteTrace(ctx, spans[0].TraceID), ErrReadOnly)
+		_, err = store.ClearTraces(ctx)
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = store.ClearMatchingTraces(ctx, ClearQuery{Services: []string{"sample-frontend"}})
+		assert.ErrorIs(t, err, ErrReadOnly)
+		_, err = store.ApplyRetention(ctx, RetentionPolicy{MaxTraces: 1}, time.Now())
+		assert.ErrorIs(t, err, ErrReadOnly)
+
+		err = store.Close()
+		assert.NoErrorf(t, err, "could not close database: %v", err)
+
+		// Nothing was written to the file, not even on close
+		after, err := os.ReadFile(dbPath)
+		assert.NoErrorf(t, err, "could not read database file: %v", err)
+		assert.True(t, bytes.Equal(captured, after), "database file changed")
+	})
+
+	t.Run("Missing File", func(t *testing.T) {
+		missing := filepath.Join(t.TempDir(), "missing.db")
+		_, err := openStore(ctx, missing, WithReadOnly())
+		assert.ErrorIs(t, err, ErrDatabaseNotFound)
+
+		_, err = os.Stat(missing)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+	})
+
+	t.Run("In Memory", func(t *testing.T) {
+		_, err := openStore(ctx, "", WithReadOnly())
+		assert.Error(t, err)
+	})
+
+	t.Run("Out Of Date Schema", func(t *testing.T) {
+		legacyPath := filepath.Join(t.TempDir(), "legacy.db")
+		createFixtureDatabase(t, legacyPath, legacySpansTable)
+
+		_, err := openStore(ctx, legacyPath, WithReadOnly())
+		assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+		// The refused file is released, and can still be upgraded by opening it as usual
+		store, err := openStore(ctx, legacyPath)
+		if assert.NoErrorf(t, err, "could not open legacy database: %v", err) {
+			store.Close()
+		}
+	})
+}
+
+func TestTraceSummariesSort(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	// Add the sample spans and a trace whose root span never arrived
+	spans := telemetry.NewSampleTelemetry().Spans
+	orphan := spans[0]
+	orphan.TraceID = "00000000000000000000000000000001"
+	orphan.SpanID = "0000000000000001"
+	orphan.ParentSpanID = "0000000000000002"
+	spans = append(spans, orphan)
+
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	for _, ascending := range []bool{true, false} {
+		for _, sortBy := range []SortKey{SortByStart, SortByDuration, SortBySpanCount} {
+			summaries, totalCount, err := store.QueryTraceSummaries(ctx, SummaryQuery{SortBy: sortBy, Ascending: ascending})
+			if assert.NoErrorf(t, err, "could not get trace summaries: %v", err) {
+				assert.Equal(t, 3, totalCount)
+				assert.Len(t, *summaries, 3)
+				assert.Equal(t, orphan.TraceID, (*summaries)[2].TraceID, "trace without a root span should sort last")
+				assert.False(t, (*summaries)[2].HasRootSpan)
+			}
+		}
+	}
+}
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	// Three traces a minute or so apart; the middle one has a span that arrives a minute later
+	newSpan := func(traceID string, spanID string, startTime time.Time) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Second)
+		return span
+	}
+	spans := []telemetry.SpanData{
+		newSpan("00000000000000000000000000000001", "0000000000000001", start),
+		newSpan("00000000000000000000000000000002", "0000000000000002", start.Add(time.Minute)),
+		newSpan("00000000000000000000000000000002", "0000000000000003", start.Add(2*time.Minute)),
+		newSpan("00000000000000000000000000000003", "0000000000000004", start.Add(3*time.Minute)),
+	}
+
+	traceIDs := func(store *Store) []string {
+		summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		ids := []string{}
+		for _, summary := range *summaries {
+			ids = append(ids, summary.TraceID)
+		}
+		return ids
+	}
+
+	t.Run("Evict Older Than", func(t *testing.T) {
+		store := NewStore(ctx, "")
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		// Nothing has ended before the first trace did
+		evicted, err := store.EvictOlderThan(ctx, start)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, evicted)
+
+		// The second trace has a span ending after the cutoff, so it is kept whole
+		evicted, err = store.EvictOlderThan(ctx, start.Add(2*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, evicted)
+		assert.ElementsMatch(t, []string{
+			"00000000000000000000000000000002",
+			"00000000000000000000000000000003",
+		}, traceIDs(store))
+
+		trace, err := store.GetTrace(ctx, "00000000000000000000000000000002")
+		assert.NoError(t, err)
+		assert.Len(t, trace.Spans, 2)
+	})
+
+	t.Run("Evict Beyond Count", func(t *testing.T) {
+		store := NewStore(ctx, "")
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		evicted, err := store.EvictBeyondCount(ctx, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, evicted)
+
+		evicted, err = store.EvictBeyondCount(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, evicted)
+		assert.Equal(t, []string{"00000000000000000000000000000003"}, traceIDs(store))
+	})
+
+	t.Run("Apply Retention", func(t *testing.T) {
+		store := NewStore(ctx, "")
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		policy := RetentionPolicy{MaxAge: 10 * time.Minute}
+
+		// Advance the clock: nothing is old enough at first, then everything is
+		evicted, err := store.ApplyRetention(ctx, policy, start.Add(5*time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, 0, evicted)
+
+		evicted, err = store.ApplyRetention(ctx, policy, start.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 3, evicted)
+		assert.Empty(t, traceIDs(store))
+	})
+
+	t.Run("Max Spans", func(t *testing.T) {
+		observer := &testObserver{}
+		store := NewStore(ctx, "", WithMaxSpans(3), WithObserver(observer))
+		defer store.Close()
+
+		spanCount := func() int {
+			summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+			assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+			count := 0
+			for _, summary := range *summaries {
+				count += int(summary.SpanCount)
+			}
+			return count
+		}
+
+		// The first three spans fit, and the fourth evicts the first trace to make room
+		for _, span := range spans {
+			err := store.AddSpans(ctx, []telemetry.SpanData{span})
+			assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+			err = store.Flush(ctx)
+			assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		}
+		assert.Equal(t, 3, spanCount())
+		assert.ElementsMatch(t, []string{
+			"00000000000000000000000000000002",
+			"00000000000000000000000000000003",
+		}, traceIDs(store))
+		assert.Equal(t, 1, observer.evicted)
+
+		// Making room for two spans takes the whole of the second trace, keeping the count steady
+		for i := 0; i < 5; i++ {
+			traceID := fmt.Sprintf("0000000000000000000000000000010%d", i)
+			err := store.AddSpans(ctx, []telemetry.SpanData{
+				newSpan(traceID, fmt.Sprintf("000000000000010%d", i), start.Add(time.Duration(10+i)*time.Minute)),
+				newSpan(traceID, fmt.Sprintf("000000000000020%d", i), start.Add(time.Duration(10+i)*time.Minute)),
+			})
+			assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+			err = store.Flush(ctx)
+			assert.NoErrorf(t, err, "could not flush spans: %v", err)
+			assert.LessOrEqual(t, spanCount(), 3)
+			assert.Contains(t, traceIDs(store), traceID)
+		}
+		assert.Equal(t, []string{"00000000000000000000000000000104"}, traceIDs(store))
+		assert.Equal(t, 2, spanCount())
+	})
+}
+
+func TestParseRetentionPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected RetentionPolicy
+		wantErr  bool
+	}{
+		{name: "Empty", value: "", expected: RetentionPolicy{}},
+		{name: "Duration", value: "30m", expected: RetentionPolicy{MaxAge: 30 * time.Minute}},
+		{name: "Trace Count", value: "10000", expected: RetentionPolicy{MaxTraces: 10000}},
+		{name: "Zero Trace Count", value: "0", wantErr: true},
+		{name: "Negative Duration", value: "-5m", wantErr: true},
+		{name: "Garbage", value: "forever", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseRetentionPolicy(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, policy)
+		})
+	}
+}
+
+func TestSpillover(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "spill.db")
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	newSpan := func(traceID string, spanID string, startTime time.Time) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Second)
+		return span
+	}
+	first := "00000000000000000000000000000001"
+	second := "00000000000000000000000000000002"
+	third := "00000000000000000000000000000003"
+
+	countSpans := func(store *Store, table string) int {
+		count := 0
+		err := store.db.QueryRowContext(ctx, "SELECT count(*) FROM "+table).Scan(&count)
+		assert.NoError(t, err)
+		return count
+	}
+	traceIDs := func(store *Store) []string {
+		summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		ids := []string{}
+		for _, summary := range *summaries {
+			ids = append(ids, summary.TraceID)
+		}
+		return ids
+	}
+
+	store := NewStore(ctx, dbPath, WithSpillover(RetentionPolicy{MaxTraces: 1}))
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan(first, "0000000000000001", start),
+		newSpan(second, "0000000000000002", start.Add(time.Minute)),
+		newSpan(third, "0000000000000003", start.Add(2*time.Minute)),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	// Only the most recent trace stays in memory, but every trace can still be read
+	spilled, err := store.Spill(ctx, start)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, spilled)
+	assert.Equal(t, 1, countSpans(store, "hot_spans"))
+	assert.Equal(t, 2, countSpans(store, "cold.spans"))
+	assert.ElementsMatch(t, []string{first, second, third}, traceIDs(store))
+
+	// A span arriving late for a spilled trace is read along with the rest of it
+	err = store.AddSpans(ctx, []telemetry.SpanData{newSpan(first, "0000000000000004", start.Add(3*time.Minute))})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	trace, err := store.GetTrace(ctx, first)
+	if assert.NoErrorf(t, err, "could not get trace: %v", err) {
+		assert.Len(t, trace.Spans, 2)
+	}
+
+	// Deleting a trace takes it out of both tiers
+	err = store.DeleteTrace(ctx, first)
+	assert.NoErrorf(t, err, "could not delete trace: %v", err)
+	_, err = store.GetTrace(ctx, first)
+	assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+	assert.Equal(t, 1, countSpans(store, "hot_spans"))
+	assert.Equal(t, 1, countSpans(store, "cold.spans"))
+
+	// Logs are kept in memory until the store closes
+	err = store.AddLogs(ctx, []telemetry.LogData{{
+		Body:       "spilled",
+		TraceID:    second,
+		Timestamp:  start,
+		Attributes: map[string]interface{}{},
+		Resource:   &telemetry.ResourceData{Attributes: map[string]interface{}{}},
+		Scope:      &telemetry.ScopeData{Attributes: map[string]interface{}{}},
+	}})
+	assert.NoErrorf(t, err, "could not add logs to the database: %v", err)
+
+	err = store.Close()
+	assert.NoErrorf(t, err, "could not close database: %v", err)
+
+	// Everything is in the file once the store closes, so it opens without spilling just the same
+	store = NewStore(ctx, dbPath)
+	assert.ElementsMatch(t, []string{second, third}, traceIDs(store))
+	logs, err := store.GetLogsByTrace(ctx, second)
+	if assert.NoErrorf(t, err, "could not get logs: %v", err) && assert.Len(t, logs, 1) {
+		assert.Equal(t, "spilled", logs[0].Body)
+	}
+	err = store.Close()
+	assert.NoErrorf(t, err, "could not close database: %v", err)
+
+	// Reopened with spilling, the file's traces and logs are all there, and
+	// evicting picks traces from both tiers as one
+	store = NewStore(ctx, dbPath, WithSpillover(RetentionPolicy{MaxAge: time.Minute}))
+	defer store.Close()
+
+	logs, err = store.GetLogsByTrace(ctx, second)
+	assert.NoError(t, err)
+	assert.Len(t, logs, 1)
+
+	err = store.AddSpans(ctx, []telemetry.SpanData{newSpan(first, "0000000000000005", start.Add(4*time.Minute))})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	evicted, err := store.EvictBeyondCount(ctx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, evicted)
+	assert.ElementsMatch(t, []string{first}, traceIDs(store))
+
+	// The trace in memory ended more than a minute before now, so it spills
+	spilled, err = store.Spill(ctx, start.Add(6*time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spilled)
+	assert.Equal(t, 0, countSpans(store, "hot_spans"))
+	assert.ElementsMatch(t, []string{first}, traceIDs(store))
+}
+
+func TestBatching(t *testing.T) {
+	ctx := context.Background()
+	spans := telemetry.NewSampleTelemetry().Spans
+
+	countTraces := func(store *Store) int {
+		_, totalCount, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		return totalCount
+	}
+
+	t.Run("Window Elapses", func(t *testing.T) {
+		store := NewStore(ctx, "", WithBatching(20*time.Millisecond, 1000))
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		assert.Eventually(t, func() bool { return countTraces(store) == 2 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Batch Fills Up", func(t *testing.T) {
+		store := NewStore(ctx, "", WithBatching(time.Hour, len(spans)))
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans[:1])
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		assert.Never(t, func() bool { return countTraces(store) > 0 }, 50*time.Millisecond, 10*time.Millisecond)
+
+		err = store.AddSpans(ctx, spans[1:])
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		assert.Eventually(t, func() bool { return countTraces(store) == 2 }, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Flush", func(t *testing.T) {
+		store := NewStore(ctx, "", WithBatching(time.Hour, 1000))
+		defer store.Close()
+
+		for _, span := range spans {
+			err := store.AddSpans(ctx, []telemetry.SpanData{span})
+			assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		}
+		assert.Equal(t, 0, countTraces(store))
+
+		err := store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, 2, countTraces(store))
+	})
+
+	t.Run("Writes Flush", func(t *testing.T) {
+		store := NewStore(ctx, "", WithBatching(time.Hour, 1000))
+		defer store.Close()
+
+		// Deleting, pinning and annotating find traces whose spans are still in the batch
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.DeleteTrace(ctx, "42957c7c2fca940a0d32a0cdd38c06a4")
+		assert.NoErrorf(t, err, "could not delete trace: %v", err)
+		err = store.PinTrace(ctx, "7979cec4d1c04222fa9a3c7c97c0a99c")
+		assert.NoErrorf(t, err, "could not pin trace: %v", err)
+		err = store.SetAnnotations(ctx, "7979cec4d1c04222fa9a3c7c97c0a99c", telemetry.Annotations{"status": "investigated"})
+		assert.NoErrorf(t, err, "could not set annotations: %v", err)
+
+		// and nothing of the deleted trace is written back afterwards
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		_, err = store.GetTrace(ctx, "42957c7c2fca940a0d32a0cdd38c06a4")
+		assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+		assert.Equal(t, 1, countTraces(store))
+	})
+
+	t.Run("Close Flushes", func(t *testing.T) {
+		defer os.Remove("./batch.db")
+
+		store := NewStore(ctx, "./batch.db", WithBatching(time.Hour, 1000))
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+
+		err = store.Close()
+		assert.NoErrorf(t, err, "could not close the database: %v", err)
+
+		err = store.AddSpans(ctx, spans)
+		assert.ErrorIs(t, err, ErrStoreClosed)
+
+		store = NewStore(ctx, "./batch.db")
+		defer store.Close()
+		assert.Equal(t, 2, countTraces(store))
+	})
+
+	t.Run("Write Listener", func(t *testing.T) {
+		written := [][]string{}
+		store := NewStore(ctx, "", WithBatching(time.Hour, 1000), WithWriteListener(func(traceIDs []string) {
+			written = append(written, traceIDs)
+		}))
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		// Flushing an empty batch writes nothing, and tells the listener nothing
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		assert.Equal(t, [][]string{{spans[0].TraceID, spans[1].TraceID}}, written)
+	})
+
+	t.Run("Observer", func(t *testing.T) {
+		observer := &testObserver{}
+		store := NewStore(ctx, "", WithBatching(time.Hour, 1000), WithObserver(observer))
+		defer store.Close()
+
+		for _, span := range spans {
+			err := store.AddSpans(ctx, []telemetry.SpanData{span})
+			assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		}
+		assert.Equal(t, len(spans), observer.received)
+		assert.Equal(t, 0, observer.batches)
+
+		err := store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, 1, observer.batches)
+		assert.Equal(t, len(spans), observer.stored)
+		assert.Equal(t, 2, observer.traces)
+
+		count, err := store.CountTraces(ctx)
+		assert.NoErrorf(t, err, "could not count traces: %v", err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("Malformed IDs", func(t *testing.T) {
+		observer := &testObserver{}
+		store := NewStore(ctx, "", WithObserver(observer))
+		defer store.Close()
+
+		malformed := []telemetry.SpanData{}
+		for _, ids := range [][2]string{
+			{"", spans[0].SpanID},
+			{spans[0].TraceID, ""},
+			{"not-a-trace-id", spans[0].SpanID},
+			{spans[0].TraceID + "00", spans[0].SpanID},
+		} {
+			span := spans[0]
+			span.TraceID, span.SpanID = ids[0], ids[1]
+			malformed = append(malformed, span)
+		}
+
+		// The well-formed spans in the same batch are still stored
+		err := store.AddSpans(ctx, append(malformed, spans...))
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		assert.Equal(t, len(malformed), observer.dropped)
+		assert.Equal(t, len(spans), observer.received)
+		assert.Equal(t, len(spans), observer.stored)
+
+		// A batch of nothing but malformed spans isn't queued at all
+		err = store.AddSpans(ctx, malformed)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		assert.Equal(t, 2*len(malformed), observer.dropped)
+		assert.Equal(t, len(spans), observer.received)
+
+		count, err := store.CountTraces(ctx)
+		assert.NoErrorf(t, err, "could not count traces: %v", err)
+		assert.Equal(t, 2, count)
+	})
+}
+
+// testObserver adds up what the store reports. The batcher only reports once a flush
+// has been asked for, and the flush waits for it, so there is no need for a lock.
+type testObserver struct {
+	received int
+	stored   int
+	traces   int
+	batches  int
+	evicted  int
+	dropped  int
+	limited  int
+}
+
+func (o *testObserver) SpansReceived(count int) {
+	o.received += count
+}
+
+func (o *testObserver) BatchWritten(spans int, traces int, duration time.Duration) {
+	o.stored += spans
+	o.traces += traces
+	o.batches++
+}
+
+func (o *testObserver) SpansDropped(count int) {
+	o.dropped += count
+}
+
+func (o *testObserver) SpansRateLimited(count int) {
+	o.limited += count
+}
+
+func (o *testObserver) TracesEvicted(count int) {
+	o.evicted += count
+}
+
+func TestIngestRateLimit(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+
+	t.Run("Token Bucket", func(t *testing.T) {
+		limiter := newRateLimiter(10, clock)
+
+		// A full second's worth can be spent at once, and then nothing more
+		assert.Equal(t, 4, limiter.take(4))
+		assert.Equal(t, 6, limiter.take(20))
+		assert.Equal(t, 0, limiter.take(1))
+
+		// Tokens come back as time passes, but never more than a second's worth
+		now = now.Add(300 * time.Millisecond)
+		assert.Equal(t, 3, limiter.take(5))
+		now = now.Add(time.Hour)
+		assert.Equal(t, 10, limiter.take(50))
+	})
+
+	t.Run("Bursts", func(t *testing.T) {
+		observer := &testObserver{}
+		store := NewStore(ctx, "", WithObserver(observer))
+		defer store.Close()
+		store.ingestLimiter = newRateLimiter(5, clock)
+
+		newSpans := func(traceID int, count int) []telemetry.SpanData {
+			spans := []telemetry.SpanData{}
+			for i := 0; i < count; i++ {
+				span := telemetry.NewSampleTelemetry().Spans[0]
+				span.TraceID = fmt.Sprintf("%032x", traceID)
+				span.SpanID = fmt.Sprintf("%016x", i+1)
+				spans = append(spans, span)
+			}
+			return spans
+		}
+
+		limited, err := store.IngestSpans(ctx, newSpans(1, 8))
+		assert.NoErrorf(t, err, "could not ingest spans: %v", err)
+		assert.Equal(t, 3, limited)
+
+		// A burst right after is dropped whole, while imports go straight through
+		limited, err = store.IngestSpans(ctx, newSpans(2, 8))
+		assert.NoErrorf(t, err, "could not ingest spans: %v", err)
+		assert.Equal(t, 8, limited)
+		err = store.AddSpans(ctx, newSpans(3, 8))
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+
+		now = now.Add(time.Second)
+		limited, err = store.IngestSpans(ctx, newSpans(4, 2))
+		assert.NoErrorf(t, err, "could not ingest spans: %v", err)
+		assert.Equal(t, 0, limited)
+
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, 11, observer.limited)
+		assert.Equal(t, 0, observer.dropped)
+		assert.Equal(t, 15, observer.stored)
+
+		summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		ids := []string{}
+		for _, summary := range *summaries {
+			ids = append(ids, summary.TraceID)
+		}
+		assert.ElementsMatch(t, []string{fmt.Sprintf("%032x", 1), fmt.Sprintf("%032x", 3), fmt.Sprintf("%032x", 4)}, ids)
+	})
+
+	t.Run("Ingest Listener", func(t *testing.T) {
+		ingested := []string{}
+		store := NewStore(ctx, "", WithIngestListener(func(spans []telemetry.SpanData) {
+			for _, span := range spans {
+				ingested = append(ingested, span.SpanID)
+			}
+		}))
+		defer store.Close()
+		store.ingestLimiter = newRateLimiter(3, clock)
+
+		// Only the spans the store queues are passed on: not those over the limit, nor those
+		// it drops for their malformed IDs
+		spans := []telemetry.SpanData{}
+		for _, spanID := range []string{"0000000000000001", "not-a-span-id", "00000000000000AB", "0000000000000004"} {
+			span := telemetry.NewSampleTelemetry().Spans[0]
+			span.SpanID = spanID
+			spans = append(spans, span)
+		}
+		limited, err := store.IngestSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not ingest spans: %v", err)
+		assert.Equal(t, 1, limited)
+		assert.Equal(t, []string{"0000000000000001", "00000000000000ab"}, ingested)
+
+		// A call whose spans are all dropped passes nothing on
+		now = now.Add(time.Second)
+		limited, err = store.IngestSpans(ctx, spans[1:2])
+		assert.NoErrorf(t, err, "could not ingest spans: %v", err)
+		assert.Equal(t, 0, limited)
+		assert.Len(t, ingested, 2)
+	})
+}
+
+func TestDataVersion(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	initial := store.DataVersion()
+	assert.Equal(t, initial, store.DataVersion())
+
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	err := store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+	written := store.DataVersion()
+	assert.NotEqual(t, initial, written)
+
+	// Reading doesn't change it, and neither does evicting nothing
+	_, err = store.GetTrace(ctx, span.TraceID)
+	assert.NoErrorf(t, err, "could not get trace: %v", err)
+	_, err = store.EvictBeyondCount(ctx, 10)
+	assert.NoErrorf(t, err, "could not evict traces: %v", err)
+	assert.Equal(t, written, store.DataVersion())
+
+	err = store.DeleteTrace(ctx, span.TraceID)
+	assert.NoErrorf(t, err, "could not delete trace: %v", err)
+	deleted := store.DataVersion()
+	assert.NotEqual(t, written, deleted)
+
+	_, err = store.ClearTraces(ctx)
+	assert.NoErrorf(t, err, "could not clear traces: %v", err)
+	assert.NotEqual(t, deleted, store.DataVersion())
+
+	// A store opened later never hands out the versions of an earlier one
+	other := NewStore(ctx, "")
+	defer other.Close()
+	assert.NotEqual(t, initial, other.DataVersion())
+}
+
+func TestAnnotations(t *testing.T) {
+	ctx := context.Background()
+	traceID := "42957c7c2fca940a0d32a0cdd38c06a4"
+	otherTraceID := "7979cec4d1c04222fa9a3c7c97c0a99c"
+	annotations := telemetry.Annotations{"status": "investigated", "reviewers": []any{"ana", "bo"}, "priority": float64(2)}
+
+	summaryAnnotations := func(t *testing.T, store *Store) map[string]telemetry.Annotations {
+		summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		byTrace := map[string]telemetry.Annotations{}
+		for _, summary := range *summaries {
+			byTrace[summary.TraceID] = summary.Annotations
+		}
+		return byTrace
+	}
+	newStore := func(t *testing.T, dbPath string, opts ...Option) *Store {
+		store := NewStore(ctx, dbPath, opts...)
+		err := store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		return store
+	}
+
+	t.Run("Set And Get", func(t *testing.T) {
+		store := newStore(t, "")
+		defer store.Close()
+
+		got, err := store.GetAnnotations(ctx, traceID)
+		if assert.NoErrorf(t, err, "could not get annotations: %v", err) {
+			assert.Equal(t, telemetry.Annotations{}, got)
+		}
+
+		err = store.SetAnnotations(ctx, traceID, annotations)
+		assert.NoErrorf(t, err, "could not set annotations: %v", err)
+		got, err = store.GetAnnotations(ctx, traceID)
+		if assert.NoErrorf(t, err, "could not get annotations: %v", err) {
+			assert.Equal(t, annotations, got)
+		}
+		assert.Equal(t, map[string]telemetry.Annotations{traceID: annotations, otherTraceID: nil}, summaryAnnotations(t, store))
+
+		// Setting annotations again replaces them, and setting none removes them
+		err = store.SetAnnotations(ctx, traceID, telemetry.Annotations{"note": "flaky"})
+		assert.NoErrorf(t, err, "could not set annotations: %v", err)
+		got, _ = store.GetAnnotations(ctx, traceID)
+		assert.Equal(t, telemetry.Annotations{"note": "flaky"}, got)
+
+		err = store.SetAnnotations(ctx, traceID, telemetry.Annotations{})
+		assert.NoErrorf(t, err, "could not set annotations: %v", err)
+		assert.Nil(t, summaryAnnotations(t, store)[traceID])
+	})
+
+	t.Run("Unknown Trace", func(t *testing.T) {
+		store := newStore(t, "")
+		defer store.Close()
+
+		err := store.SetAnnotations(ctx, "0123", annotations)
+		assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+		_, err = store.GetAnnotations(ctx, "0123")
+		assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+	})
+
+	t.Run("Deleted With Their Trace", func(t *testing.T) {
+		store := newStore(t, "")
+		defer store.Close()
+
+		for _, id := range []string{traceID, otherTraceID} {
+			err := store.SetAnnotations(ctx, id, annotations)
+			assert.NoErrorf(t, err, "could not set annotations: %v", err)
+		}
+		err := store.DeleteTrace(ctx, traceID)
+		assert.NoErrorf(t, err, "could not delete trace: %v", err)
+
+		// Spans sent again for the deleted trace don't bring its annotations back
+		err = store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, map[string]telemetry.Annotations{traceID: nil, otherTraceID: annotations}, summaryAnnotations(t, store))
+
+		_, err = store.ClearTraces(ctx)
+		assert.NoErrorf(t, err, "could not clear traces: %v", err)
+		count := 0
+		err = store.db.QueryRowContext(ctx, "SELECT count(*) FROM annotations").Scan(&count)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, count)
+	})
+
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{name: "Survive Restarts"},
+		{name: "Survive Restarts With Spillover", opts: []Option{WithSpillover(RetentionPolicy{MaxTraces: 1})}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			dbPath := filepath.Join(t.TempDir(), "annotations.db")
+			store := newStore(t, dbPath, tt.opts...)
+			err := store.SetAnnotations(ctx, traceID, annotations)
+			assert.NoErrorf(t, err, "could not set annotations: %v", err)
+			err = store.Close()
+			assert.NoErrorf(t, err, "could not close database: %v", err)
+
+			store = NewStore(ctx, dbPath, tt.opts...)
+			defer store.Close()
+			got, err := store.GetAnnotations(ctx, traceID)
+			if assert.NoErrorf(t, err, "could not get annotations: %v", err) {
+				assert.Equal(t, annotations, got)
+			}
+		})
+	}
+}
+
+func TestPins(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+
+	newSpan := func(traceID string, spanID string, startTime time.Time) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Second)
+		return span
+	}
+	spans := []telemetry.SpanData{
+		newSpan("00000000000000000000000000000001", "0000000000000001", start),
+		newSpan("00000000000000000000000000000002", "0000000000000002", start.Add(time.Minute)),
+		newSpan("00000000000000000000000000000003", "0000000000000003", start.Add(2*time.Minute)),
+	}
+	oldest := "00000000000000000000000000000001"
+
+	setup := func(t *testing.T, opts ...Option) *Store {
+		store := NewStore(ctx, "", opts...)
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		return store
+	}
+	pinned := func(t *testing.T, store *Store) map[string]bool {
+		summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+		assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+		pins := map[string]bool{}
+		for _, summary := range *summaries {
+			pins[summary.TraceID] = summary.Pinned
+		}
+		return pins
+	}
+
+	t.Run("Pin And Unpin", func(t *testing.T) {
+		store := setup(t)
+		defer store.Close()
+
+		assert.NoError(t, store.PinTrace(ctx, oldest))
+		assert.NoError(t, store.PinTrace(ctx, oldest), "pinning twice should do nothing")
+		assert.Equal(t, map[string]bool{
+			"00000000000000000000000000000001": true,
+			"00000000000000000000000000000002": false,
+			"00000000000000000000000000000003": false,
+		}, pinned(t, store))
+
+		assert.NoError(t, store.UnpinTrace(ctx, oldest))
+		assert.NoError(t, store.UnpinTrace(ctx, oldest), "unpinning twice should do nothing")
+		assert.False(t, pinned(t, store)[oldest])
+	})
+
+	t.Run("Pin Not Found", func(t *testing.T) {
+		store := setup(t)
+		defer store.Close()
+
+		assert.ErrorIs(t, store.PinTrace(ctx, "987654321"), telemetry.ErrTraceIDNotFound)
+		assert.ErrorIs(t, store.UnpinTrace(ctx, "987654321"), telemetry.ErrTraceIDNotFound)
+	})
+
+	t.Run("Evict Older Than", func(t *testing.T) {
+		store := setup(t)
+		defer store.Close()
+
+		assert.NoError(t, store.PinTrace(ctx, oldest))
+		evicted, err := store.EvictOlderThan(ctx, start.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, evicted)
+		assert.Equal(t, map[string]bool{oldest: true}, pinned(t, store))
+
+		// Once unpinned, the trace is evicted like any other
+		assert.NoError(t, store.UnpinTrace(ctx, oldest))
+		evicted, err = store.EvictOlderThan(ctx, start.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, evicted)
+		assert.Empty(t, pinned(t, store))
+	})
+
+	t.Run("Evict Beyond Count", func(t *testing.T) {
+		store := setup(t)
+		defer store.Close()
+
+		// The pinned trace doesn't count toward the limit
+		assert.NoError(t, store.PinTrace(ctx, oldest))
+		evicted, err := store.EvictBeyondCount(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, evicted)
+		assert.Equal(t, map[string]bool{
+			"00000000000000000000000000000001": true,
+			"00000000000000000000000000000003": false,
+		}, pinned(t, store))
+	})
+
+	t.Run("Max Spans", func(t *testing.T) {
+		store := setup(t, WithMaxSpans(3))
+		defer store.Close()
+
+		// Making room for a fourth span passes over the pinned trace for the next least recent
+		assert.NoError(t, store.PinTrace(ctx, oldest))
+		err := store.AddSpans(ctx, []telemetry.SpanData{
+			newSpan("00000000000000000000000000000004", "0000000000000004", start.Add(3*time.Minute)),
+		})
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		assert.Equal(t, map[string]bool{
+			"00000000000000000000000000000001": true,
+			"00000000000000000000000000000003": false,
+			"00000000000000000000000000000004": false,
+		}, pinned(t, store))
+	})
+
+	t.Run("Delete Pinned Trace", func(t *testing.T) {
+		store := setup(t)
+		defer store.Close()
+
+		// Deleting a trace deletes its pin, so the trace comes back unpinned if it's sent again
+		assert.NoError(t, store.PinTrace(ctx, oldest))
+		assert.NoError(t, store.DeleteTrace(ctx, oldest))
+		err := store.AddSpans(ctx, spans[:1])
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		pins := pinned(t, store)
+		assert.Contains(t, pins, oldest)
+		assert.False(t, pins[oldest])
+	})
+}
+
+func TestDuplicateSpans(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	err := store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	// The span is sent again in a later batch, and twice more within a batch, the last time renamed
+	resent := span
+	resent.Name = "renamed"
+	err = store.AddSpans(ctx, []telemetry.SpanData{span, resent})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	trace, err := store.GetTrace(ctx, span.TraceID)
+	if assert.NoError(t, err) && assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, "renamed", trace.Spans[0].Name)
+	}
+
+	var spanCount int
+	err = store.db.QueryRowContext(ctx, COUNT_SPANS).Scan(&spanCount)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, spanCount)
+}
+
+func TestEmptyFields(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	// Spans converted from other formats may come without any events, links, or attributes
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.Attributes = nil
+	span.Events = nil
+	span.Links = []telemetry.LinkData{{TraceID: span.TraceID, SpanID: "0123456789abcdef"}}
+	span.Resource = &telemetry.ResourceData{}
+	span.Scope = &telemetry.ScopeData{}
+	err := store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	got, err := store.GetSpan(ctx, span.TraceID, span.SpanID)
+	if assert.NoError(t, err) {
+		assert.Equal(t, telemetry.Attributes{}, got.Attributes)
+		assert.Equal(t, []telemetry.EventData{}, got.Events)
+		if assert.Len(t, got.Links, 1) {
+			assert.Equal(t, telemetry.Attributes{}, got.Links[0].Attributes)
+		}
+		assert.Equal(t, telemetry.Attributes{}, got.Resource.Attributes)
+		assert.Equal(t, telemetry.Attributes{}, got.Scope.Attributes)
+	}
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	ctx := context.Background()
+	dbPath := filepath.Join(t.TempDir(), "timestamps.db")
+
+	// Imported spans can carry any offset, and times down to the nanosecond
+	zone := time.FixedZone("UTC+2", 2*60*60)
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.StartTime = time.Date(2024, 1, 1, 12, 0, 0, 123456789, zone)
+	span.EndTime = time.Date(2024, 1, 1, 12, 0, 1, 987654321, zone)
+	span.Events = []telemetry.EventData{{Name: "checkpoint", Timestamp: time.Date(2024, 1, 1, 12, 0, 0, 555555555, zone), Attributes: telemetry.Attributes{}}}
+
+	assertTimes := func(t *testing.T, got telemetry.SpanData) {
+		assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 123456789, time.UTC), got.StartTime)
+		assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 1, 987654321, time.UTC), got.EndTime)
+		assert.Equal(t, int64(1864197532), got.DurationNanos)
+		if assert.Len(t, got.Events, 1) {
+			assert.Equal(t, time.Date(2024, 1, 1, 10, 0, 0, 555555555, time.UTC), got.Events[0].Timestamp)
+		}
+	}
+
+	store := NewStore(ctx, dbPath)
+	err := store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	got, err := store.GetSpan(ctx, span.TraceID, span.SpanID)
+	if assert.NoError(t, err) {
+		assertTimes(t, got)
+	}
+	summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+	if assert.NoError(t, err) && assert.Len(t, *summaries, 1) {
+		assert.Equal(t, got.StartTime, (*summaries)[0].RootStartTime)
+		assert.Equal(t, got.EndTime, (*summaries)[0].RootEndTime)
+	}
+	store.Close()
+
+	t.Run("After Restart", func(t *testing.T) {
+		store := NewStore(ctx, dbPath)
+		defer store.Close()
+
+		got, err := store.GetSpan(ctx, span.TraceID, span.SpanID)
+		if assert.NoError(t, err) {
+			assertTimes(t, got)
+		}
+	})
+}
+
+func TestImportSpans(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	spans := telemetry.NewSampleTelemetry().Spans
+
+	// One of the sample traces is already there, and still waiting to be written
+	err := store.AddSpans(ctx, spans[:1])
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+
+	// The file repeats one of its own spans, too
+	imported, err := store.ImportSpans(ctx, append(spans, spans[len(spans)-1]))
+	if assert.NoErrorf(t, err, "could not import spans: %v", err) {
+		assert.ElementsMatch(t, []string{spans[0].TraceID, spans[1].TraceID}, imported.TraceIDs)
+		assert.Equal(t, len(spans)-1, imported.ImportedSpans)
+		assert.Equal(t, 2, imported.DuplicateSpans)
+	}
+
+	_, totalCount, err := store.GetTraceSummaries(ctx, 0, 0)
+	assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+	assert.Equal(t, 2, totalCount)
+
+	trace, err := store.GetTrace(ctx, "42957c7c2fca940a0d32a0cdd38c06a4")
+	assert.NoErrorf(t, err, "could not get trace: %v", err)
+	assert.Len(t, trace.Spans, 3)
+}
+
+func TestAttributeTypes(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	attributes := telemetry.Attributes{
+		"http.status_code": int64(200),
+		"http.duration":    1.5,
+		"http.ratio":       2.0,
+		"http.retried":     true,
+		"http.method":      "GET",
+		"http.hosts":       []interface{}{"pumpkin.pie", "apple.crumble"},
+		"http.nested":      map[string]interface{}{"attempt": int64(2)},
+	}
+
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.Attributes = attributes
+	span.Events[0].Attributes = attributes
+	span.Resource = &telemetry.ResourceData{Attributes: attributes}
+
+	err := store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	trace, err := store.GetTrace(ctx, span.TraceID)
+	if assert.NoErrorf(t, err, "could not get trace: %v", err) && assert.Len(t, trace.Spans, 1) {
+		assert.Equal(t, attributes, trace.Spans[0].Attributes)
+		assert.Equal(t, attributes, trace.Spans[0].Events[0].Attributes)
+		assert.Equal(t, attributes, trace.Spans[0].Resource.Attributes)
+
+		// Whole floats are still floats once they're served as JSON
+		ratio, err := json.Marshal(trace.Spans[0].Attributes)
+		assert.NoError(t, err)
+		assert.Contains(t, string(ratio), `"http.ratio":2.0`)
+		assert.Contains(t, string(ratio), `"http.status_code":200`)
+		assert.Contains(t, string(ratio), `"http.hosts":["pumpkin.pie","apple.crumble"]`)
+	}
+}
+
+func TestGetTracePage(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	// The root starts after its children, but still comes first
+	start := time.Now()
+	spans := []telemetry.SpanData{}
+	for i := 0; i < 5; i++ {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = "00000000000000000000000000000001"
+		span.SpanID = fmt.Sprintf("000000000000000%d", i)
+		span.ParentSpanID = "0000000000000000"
+		span.StartTime = start.Add(time.Duration(i) * time.Second)
+		span.EndTime = span.StartTime.Add(time.Second)
+		spans = append(spans, span)
+	}
+	spans[0].ParentSpanID = ""
+	spans[0].StartTime = start.Add(time.Minute)
+	spans[0].EndTime = start.Add(2 * time.Minute)
+
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	spanIDs := func(trace telemetry.TraceData) []string {
+		ids := []string{}
+		for _, span := range trace.Spans {
+			ids = append(ids, span.SpanID)
+		}
+		return ids
+	}
+
+	t.Run("First Page", func(t *testing.T) {
+		trace, err := store.GetTracePage(ctx, spans[0].TraceID, 2, 0)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 5, trace.TotalSpans)
+			assert.Equal(t, []string{"0000000000000000", "0000000000000001"}, spanIDs(trace))
+		}
+	})
+
+	t.Run("Last Page", func(t *testing.T) {
+		trace, err := store.GetTracePage(ctx, spans[0].TraceID, 2, 4)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 5, trace.TotalSpans)
+			assert.Equal(t, []string{"0000000000000004"}, spanIDs(trace))
+		}
+	})
+
+	t.Run("Past The End", func(t *testing.T) {
+		trace, err := store.GetTracePage(ctx, spans[0].TraceID, 2, 10)
+		if assert.NoError(t, err) {
+			assert.Equal(t, 5, trace.TotalSpans)
+			assert.Empty(t, trace.Spans)
+		}
+	})
+
+	t.Run("No Limit", func(t *testing.T) {
+		trace, err := store.GetTracePage(ctx, spans[0].TraceID, 0, 1)
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"0000000000000001", "0000000000000002", "0000000000000003", "0000000000000004"}, spanIDs(trace))
+		}
+	})
+
+	t.Run("Not Found", func(t *testing.T) {
+		_, err := store.GetTracePage(ctx, "00000000000000000000000000000002", 2, 0)
+		assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+	})
+}
+
+func TestGetTraces(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	err := store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	// Unknown trace IDs are left out
+	traces, err := store.GetTraces(ctx, []string{"42957c7c2fca940a0d32a0cdd38c06a4", "7979cec4d1c04222fa9a3c7c97c0a99c", "00000000000000000000000000000001"})
+	if assert.NoError(t, err) && assert.Len(t, traces, 2) {
+		for traceID, trace := range traces {
+			expected, err := store.GetTrace(ctx, traceID)
+			assert.NoError(t, err)
+			assert.Equal(t, traceID, trace.TraceID)
+			assert.ElementsMatch(t, expected.Spans, trace.Spans)
+		}
+		assert.Len(t, traces["42957c7c2fca940a0d32a0cdd38c06a4"].Spans, 3)
+	}
+
+	traces, err = store.GetTraces(ctx, []string{})
+	if assert.NoError(t, err) {
+		assert.Empty(t, traces)
+	}
+}
+
+func TestShortTraceIDs(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	// One span sent with a 64-bit trace ID, and its child with the same ID padded to 128 bits
+	root := telemetry.NewSampleTelemetry().Spans[0]
+	root.TraceID = "0D32A0CDD38C06A4"
+	root.SpanID = "1"
+	root.ParentSpanID = ""
+	child := root
+	child.TraceID = "00000000000000000d32a0cdd38c06a4"
+	child.SpanID = "0000000000000002"
+	child.ParentSpanID = "0000000000000001"
+
+	spans := []telemetry.SpanData{root, child}
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+	assert.Equal(t, "0D32A0CDD38C06A4", spans[0].TraceID)
+
+	count, err := store.CountTraces(ctx)
+	assert.NoErrorf(t, err, "could not count traces: %v", err)
+	assert.Equal(t, 1, count)
+
+	trace, err := store.GetTrace(ctx, telemetry.NormalizeTraceID("0d32a0cdd38c06a4"))
+	if assert.NoErrorf(t, err, "could not get trace: %v", err) {
+		assert.Equal(t, "00000000000000000d32a0cdd38c06a4", trace.TraceID)
+		spanIDs := []string{}
+		for _, span := range trace.Spans {
+			spanIDs = append(spanIDs, span.SpanID)
+		}
+		assert.ElementsMatch(t, []string{"0000000000000001", "0000000000000002"}, spanIDs)
+	}
+}
+
+func TestGetSpan(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	err := store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	span, err := store.GetSpan(ctx, "42957c7c2fca940a0d32a0cdd38c06a4", "37fd1349bf83d330")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "37fd1349bf83d330", span.SpanID)
+		assert.Equal(t, "SAMPLE HTTP POST", span.Name)
+		assert.Equal(t, "sample-loadgenerator", span.Resource.Attributes["service.name"])
+	}
+
+	// The span ID has to be in the trace asked for
+	_, err = store.GetSpan(ctx, "7979cec4d1c04222fa9a3c7c97c0a99c", "37fd1349bf83d330")
+	assert.ErrorIs(t, err, telemetry.ErrSpanIDNotFound)
+	_, err = store.GetSpan(ctx, "42957c7c2fca940a0d32a0cdd38c06a4", "0000000000000001")
+	assert.ErrorIs(t, err, telemetry.ErrSpanIDNotFound)
+
+	// A request that has gone away only fails itself
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = store.GetSpan(cancelled, "42957c7c2fca940a0d32a0cdd38c06a4", "37fd1349bf83d330")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+func TestGetTraceOfKinds(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	// A server span calls an internal span, which calls a client span, and another internal span
+	// hangs off the server span on its own
+	start := time.Now()
+	spans := []telemetry.SpanData{}
+	for i, kind := range []string{"Server", "Internal", "Client", "Internal"} {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = "00000000000000000000000000000001"
+		span.SpanID = fmt.Sprintf("000000000000000%d", i)
+		span.Kind = kind
+		span.StartTime = start.Add(time.Duration(i) * time.Second)
+		span.EndTime = span.StartTime.Add(time.Second)
+		spans = append(spans, span)
+	}
+	spans[0].ParentSpanID = ""
+	spans[1].ParentSpanID = spans[0].SpanID
+	spans[2].ParentSpanID = spans[1].SpanID
+	spans[3].ParentSpanID = spans[0].SpanID
+
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name            string
+		kinds           []string
+		expectedSpanIDs []string
+	}{
+		{
+			name:            "Ancestors Kept",
+			kinds:           []string{"Client"},
+			expectedSpanIDs: []string{"0000000000000000", "0000000000000001", "0000000000000002"},
+		},
+		{
+			name:            "Several Kinds",
+			kinds:           []string{"Server", "Internal"},
+			expectedSpanIDs: []string{"0000000000000000", "0000000000000001", "0000000000000003"},
+		},
+		{
+			name:            "No Matches",
+			kinds:           []string{"Consumer"},
+			expectedSpanIDs: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trace, err := store.GetTraceOfKinds(ctx, spans[0].TraceID, tt.kinds)
+			if !assert.NoError(t, err) {
+				return
+			}
+			assert.Equal(t, 4, trace.TotalSpans)
+
+			spanIDs := []string{}
+			for _, span := range trace.Spans {
+				spanIDs = append(spanIDs, span.SpanID)
+			}
+			assert.ElementsMatch(t, tt.expectedSpanIDs, spanIDs)
+		})
+	}
+
+	t.Run("Not Found", func(t *testing.T) {
+		_, err := store.GetTraceOfKinds(ctx, "00000000000000000000000000000002", []string{"Client"})
+		assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+	})
+
+	kind, err := ParseSpanKind("SERVER")
+	assert.NoError(t, err)
+	assert.Equal(t, "Server", kind)
+	_, err = ParseSpanKind("Unspecified")
+	assert.Error(t, err)
+}
+
+func TestEachTrace(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	err := store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	traces := []telemetry.TraceData{}
+	err = store.EachTrace(ctx, func(trace telemetry.TraceData) error {
+		traces = append(traces, trace)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, traces, 2) {
+		assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", traces[0].TraceID)
+		assert.Len(t, traces[0].Spans, 3)
+		assert.Equal(t, "7979cec4d1c04222fa9a3c7c97c0a99c", traces[1].TraceID)
+		assert.Len(t, traces[1].Spans, 1)
+
+		for i := 1; i < len(traces[0].Spans); i++ {
+			assert.False(t, traces[0].Spans[i].StartTime.Before(traces[0].Spans[i-1].StartTime))
+		}
+	}
+
+	// An error from fn stops the iteration
+	stop := errors.New("stop")
+	calls := 0
+	err = store.EachTrace(ctx, func(trace telemetry.TraceData) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestAttributeFilters(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	checkout := telemetry.NewSampleTelemetry().Spans[0]
+	checkout.TraceID = "00000000000000000000000000000001"
+	checkout.Attributes = telemetry.Attributes{
+		"http.target":  "/checkout",
+		"user.id":      int64(42),
+		"http.ratio":   2.0,
+		"http.retried": true,
+	}
+	cart := telemetry.NewSampleTelemetry().Spans[0]
+	cart.TraceID = "00000000000000000000000000000002"
+	cart.Attributes = telemetry.Attributes{
+		"http.target": "/cart",
+		"user.id":     "0043",
+		"query":       "a=b",
+	}
+
+	err := store.AddSpans(ctx, []telemetry.SpanData{checkout, cart})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		filters  []string
+		expected []string
+	}{
+		{name: "String Value", filters: []string{"http.target=/checkout"}, expected: []string{checkout.TraceID}},
+		{name: "Unknown Value", filters: []string{"http.target=/pay"}, expected: []string{}},
+		{name: "Key Exists", filters: []string{"http.target"}, expected: []string{checkout.TraceID, cart.TraceID}},
+		{name: "Unknown Key", filters: []string{"http.method"}, expected: []string{}},
+		{name: "Integer Value", filters: []string{"user.id=42"}, expected: []string{checkout.TraceID}},
+		{name: "Number Matches Float", filters: []string{"user.id=42.0"}, expected: []string{checkout.TraceID}},
+		{name: "Whole Float", filters: []string{"http.ratio=2"}, expected: []string{checkout.TraceID}},
+		{name: "Number Matches Numeric String", filters: []string{"user.id=43"}, expected: []string{cart.TraceID}},
+		{name: "Text Does Not Match Number", filters: []string{"http.target=42"}, expected: []string{}},
+		{name: "Boolean Value", filters: []string{"http.retried=true"}, expected: []string{checkout.TraceID}},
+		{name: "Value With Equals Sign", filters: []string{"query=a=b"}, expected: []string{cart.TraceID}},
+		{name: "Every Filter Must Match", filters: []string{"http.target=/cart", "user.id=42"}, expected: []string{}},
+		{name: "Several Filters", filters: []string{"http.target", "http.retried=true"}, expected: []string{checkout.TraceID}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := SummaryQuery{}
+			for _, filter := range tt.filters {
+				attributeFilter, err := ParseAttributeFilter(filter)
+				assert.NoError(t, err)
+				query.Attributes = append(query.Attributes, attributeFilter)
+			}
+
+			summaries, totalCount, err := store.QueryTraceSummaries(ctx, query)
+			if assert.NoErrorf(t, err, "could not get trace summaries: %v", err) {
+				traceIDs := []string{}
+				for _, summary := range *summaries {
+					traceIDs = append(traceIDs, summary.TraceID)
+				}
+				assert.ElementsMatch(t, tt.expected, traceIDs)
+				assert.Equal(t, len(tt.expected), totalCount)
+			}
+		})
+	}
+
+	_, err = ParseAttributeFilter("=42")
+	assert.Error(t, err)
+}
+
+func TestFindTracesByRootAttribute(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	newSpan := func(traceID string, spanID string, parentSpanID string, attributes telemetry.Attributes) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = parentSpanID
+		span.Attributes = attributes
+		return span
+	}
+
+	// A retried request shows up as two traces with the same request ID
+	first := "00000000000000000000000000000001"
+	retry := "00000000000000000000000000000002"
+	other := "00000000000000000000000000000003"
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan(first, "0000000000000001", "", telemetry.Attributes{"request.id": "abc123"}),
+		newSpan(retry, "0000000000000001", "", telemetry.Attributes{"request.id": "abc123", "request.attempt": int64(2)}),
+		newSpan(other, "0000000000000001", "", telemetry.Attributes{"request.id": "def456"}),
+		// Only root spans are looked at, so a downstream call carrying the ID doesn't count
+		newSpan(other, "0000000000000002", "0000000000000001", telemetry.Attributes{"request.id": "abc123"}),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		key      string
+		value    string
+		expected []string
+	}{
+		{name: "Several Traces", key: "request.id", value: "abc123", expected: []string{first, retry}},
+		{name: "One Trace", key: "request.id", value: "def456", expected: []string{other}},
+		{name: "Number", key: "request.attempt", value: "2", expected: []string{retry}},
+		{name: "Unknown Value", key: "request.id", value: "xyz", expected: []string{}},
+		{name: "Unknown Key", key: "session.id", value: "abc123", expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, err := store.FindTracesByRootAttribute(ctx, tt.key, tt.value)
+			if assert.NoErrorf(t, err, "could not find traces: %v", err) {
+				traceIDs := []string{}
+				for _, summary := range *summaries {
+					traceIDs = append(traceIDs, summary.TraceID)
+				}
+				assert.ElementsMatch(t, tt.expected, traceIDs)
+			}
+		})
+	}
+}
+
+func TestAttributePathFilters(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	nested := telemetry.NewSampleTelemetry().Spans[0]
+	nested.TraceID = "00000000000000000000000000000001"
+	nested.Attributes = telemetry.Attributes{
+		"http.response.header": map[string]any{"content_type": "text/html", "content-length": int64(512)},
+		"db.statement":         "SELECT 1",
+	}
+	flat := telemetry.NewSampleTelemetry().Spans[0]
+	flat.TraceID = "00000000000000000000000000000002"
+	flat.Attributes = telemetry.Attributes{
+		"http.response.header.content_type": "text/html",
+	}
+
+	err := store.AddSpans(ctx, []telemetry.SpanData{nested, flat})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	t.Run("Filter By Attribute Path", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			path     string
+			value    string
+			expected []string
+		}{
+			{name: "Nested Key", path: `$."http.response.header".content_type`, value: "text/html", expected: []string{nested.TraceID}},
+			{name: "Nested Number", path: `$."http.response.header"."content-length"`, value: "512", expected: []string{nested.TraceID}},
+			{name: "Dotted Key", path: `$."http.response.header.content_type"`, value: "text/html", expected: []string{flat.TraceID}},
+			{name: "Top Level Key", path: `$."db.statement"`, value: "SELECT 1", expected: []string{nested.TraceID}},
+			{name: "Unknown Value", path: `$."http.response.header".content_type`, value: "text/plain", expected: []string{}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				summaries, err := store.FilterByAttributePath(ctx, tt.path, tt.value)
+				if assert.NoErrorf(t, err, "could not filter trace summaries: %v", err) {
+					traceIDs := []string{}
+					for _, summary := range *summaries {
+						traceIDs = append(traceIDs, summary.TraceID)
+					}
+					assert.ElementsMatch(t, tt.expected, traceIDs)
+				}
+			})
+		}
+
+		_, err := store.FilterByAttributePath(ctx, "db.statement", "SELECT 1")
+		assert.Error(t, err)
+	})
+
+	t.Run("Parse Attribute Path Filter", func(t *testing.T) {
+		tests := []struct {
+			filter   string
+			expected AttributeFilter
+		}{
+			{filter: `$.db.statement`, expected: AttributeFilter{Key: `$."db"."statement"`}},
+			{filter: `$."db.statement"=SELECT 1`, expected: AttributeFilter{Key: `$."db.statement"`, Value: "SELECT 1", HasValue: true}},
+			{filter: `$."a=b"=c=d`, expected: AttributeFilter{Key: `$."a=b"`, Value: "c=d", HasValue: true}},
+			{filter: `$.tags[1]=b`, expected: AttributeFilter{Key: `$."tags"[1]`, Value: "b", HasValue: true}},
+			{filter: `$."http.request.header".accept[0]`, expected: AttributeFilter{Key: `$."http.request.header"."accept"[0]`}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.filter, func(t *testing.T) {
+				filter, err := ParseAttributeFilter(tt.filter)
+				if assert.NoError(t, err) {
+					assert.Equal(t, tt.expected, filter)
+				}
+			})
+		}
+
+		invalid := []string{`$`, `$=a`, `$.`, `$.""`, `$."db`, `$.tags[`, `$.tags[-1]`, `$.tags[*]`, `$."db"x`, `$.a"b`}
+		for _, filter := range invalid {
+			t.Run(filter, func(t *testing.T) {
+				_, err := ParseAttributeFilter(filter)
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestOperationFilter(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	newSpan := func(traceID string, spanID string, parentSpanID string, name string, service string) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = parentSpanID
+		span.Name = name
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+	checkout := "00000000000000000000000000000001"
+	confirm := "00000000000000000000000000000002"
+	cart := "00000000000000000000000000000003"
+	spans := []telemetry.SpanData{
+		newSpan(checkout, "0000000000000001", "", "GET /checkout", "frontend"),
+		newSpan(checkout, "0000000000000002", "0000000000000001", "SELECT orders", "orders"),
+		newSpan(confirm, "0000000000000003", "", "GET /checkout/confirm", "frontend"),
+		newSpan(cart, "0000000000000004", "", "POST /cart", "cart"),
+	}
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		query    SummaryQuery
+		expected []string
+	}{
+		{name: "Exact", query: SummaryQuery{Operation: "GET /checkout"}, expected: []string{checkout}},
+		{name: "Exact Is Case Sensitive", query: SummaryQuery{Operation: "get /checkout"}, expected: []string{}},
+		{name: "Span Other Than The Root", query: SummaryQuery{Operation: "SELECT orders"}, expected: []string{checkout}},
+		{name: "Prefix", query: SummaryQuery{Operation: "GET /checkout", OperationMatch: OperationPrefix}, expected: []string{checkout, confirm}},
+		{name: "Prefix Is Literal", query: SummaryQuery{Operation: "GET /%", OperationMatch: OperationPrefix}, expected: []string{}},
+		{name: "Glob", query: SummaryQuery{Operation: "* /c*t", OperationMatch: OperationGlob}, expected: []string{checkout, cart}},
+		{name: "Glob Single Character", query: SummaryQuery{Operation: "?ET /checkout", OperationMatch: OperationGlob}, expected: []string{checkout}},
+		{name: "With Service", query: SummaryQuery{Operation: "GET /*", OperationMatch: OperationGlob, Services: []string{"cart"}}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, totalCount, err := store.QueryTraceSummaries(ctx, tt.query)
+			if assert.NoErrorf(t, err, "could not get trace summaries: %v", err) {
+				traceIDs := []string{}
+				for _, summary := range *summaries {
+					traceIDs = append(traceIDs, summary.TraceID)
+				}
+				assert.ElementsMatch(t, tt.expected, traceIDs)
+				assert.Equal(t, len(tt.expected), totalCount)
+			}
+		})
+	}
+
+	_, err = ParseOperationMatch("regex")
+	assert.Error(t, err)
+}
+
+func TestEventSearch(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	checkout := telemetry.NewSampleTelemetry().Spans[0]
+	checkout.TraceID = "00000000000000000000000000000001"
+	checkout.SpanID = "0000000000000001"
+	checkout.Name = "checkout"
+	checkout.Events = []telemetry.EventData{
+		{Name: "retrying", Timestamp: start, Attributes: telemetry.Attributes{}},
+		{Name: "exception", Timestamp: start.Add(time.Millisecond), Attributes: telemetry.Attributes{"exception.message": "Card Declined"}},
+	}
+	cart := telemetry.NewSampleTelemetry().Spans[0]
+	cart.TraceID = "00000000000000000000000000000002"
+	cart.SpanID = "0000000000000002"
+	cart.Name = "cart"
+	cart.Events = []telemetry.EventData{
+		{Name: "cache miss", Timestamp: start, Attributes: telemetry.Attributes{"cache.key": "basket"}},
+	}
+	quiet := telemetry.NewSampleTelemetry().Spans[0]
+	quiet.TraceID = "00000000000000000000000000000003"
+	quiet.SpanID = "0000000000000003"
+	quiet.Name = "declined payments"
+	quiet.Events = []telemetry.EventData{}
+
+	err := store.AddSpans(ctx, []telemetry.SpanData{checkout, cart, quiet})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	exception := telemetry.EventMatch{SpanID: checkout.SpanID, SpanName: "checkout", EventIndex: 1, EventName: "exception", Timestamp: start.Add(time.Millisecond)}
+	cacheMiss := telemetry.EventMatch{SpanID: cart.SpanID, SpanName: "cart", EventIndex: 0, EventName: "cache miss", Timestamp: start}
+
+	tests := []struct {
+		name            string
+		query           SummaryQuery
+		expectedMatches map[string][]telemetry.EventMatch
+	}{
+		{
+			name:            "Event Name",
+			query:           SummaryQuery{EventNames: []string{"exception"}},
+			expectedMatches: map[string][]telemetry.EventMatch{checkout.TraceID: {exception}},
+		},
+		{
+			name:            "Unknown Event Name",
+			query:           SummaryQuery{EventNames: []string{"panic"}},
+			expectedMatches: map[string][]telemetry.EventMatch{},
+		},
+		{
+			name:            "Search Leaves Events Out",
+			query:           SummaryQuery{Search: "basket"},
+			expectedMatches: map[string][]telemetry.EventMatch{},
+		},
+		{
+			name:            "Search Event Attributes",
+			query:           SummaryQuery{Search: "basket", SearchEvents: true},
+			expectedMatches: map[string][]telemetry.EventMatch{cart.TraceID: {cacheMiss}},
+		},
+		{
+			name:            "Search Event Names",
+			query:           SummaryQuery{Search: "Cache", SearchEvents: true},
+			expectedMatches: map[string][]telemetry.EventMatch{cart.TraceID: {cacheMiss}},
+		},
+		{
+			// The quiet trace matches on its span name, so it has no events to point at
+			name:  "Search Spans And Events",
+			query: SummaryQuery{Search: "declined", SearchEvents: true},
+			expectedMatches: map[string][]telemetry.EventMatch{
+				checkout.TraceID: {exception},
+				quiet.TraceID:    nil,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, totalCount, err := store.QueryTraceSummaries(ctx, tt.query)
+			if !assert.NoErrorf(t, err, "could not query trace summaries: %v", err) {
+				return
+			}
+			assert.Equal(t, len(tt.expectedMatches), totalCount)
+
+			matches := map[string][]telemetry.EventMatch{}
+			for _, summary := range *summaries {
+				matches[summary.TraceID] = summary.MatchedEvents
+			}
+			assert.Equal(t, tt.expectedMatches, matches)
+		})
+	}
+}
+
+func TestTraceStats(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	traceID := "00000000000000000000000000000001"
+	newSpan := func(spanID string, parentSpanID string, kind string, statusCode string, from time.Duration, to time.Duration) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = parentSpanID
+		span.Kind = kind
+		span.StatusCode = statusCode
+		span.StartTime = start.Add(from)
+		span.EndTime = start.Add(to)
+		return span
+	}
+
+	// The root's children overlap, and one of them runs on past the root. The parent of one
+	// span never arrived, and that of another arrived in a different trace. Two of the spans
+	// dropped some of their data.
+	elsewhere := newSpan("000000000000000a", "", "Server", "Unset", 0, 10*time.Millisecond)
+	elsewhere.TraceID = "00000000000000000000000000000002"
+	spans := []telemetry.SpanData{
+		newSpan("0000000000000001", "", "Server", "Unset", 0, 100*time.Millisecond),
+		newSpan("0000000000000002", "0000000000000001", "Client", "Error", 10*time.Millisecond, 40*time.Millisecond),
+		newSpan("0000000000000003", "0000000000000001", "Internal", "Ok", 30*time.Millisecond, 60*time.Millisecond),
+		newSpan("0000000000000004", "0000000000000003", "Internal", "Unset", 35*time.Millisecond, 50*time.Millisecond),
+		newSpan("0000000000000005", "0000000000000001", "Producer", "Unset", 90*time.Millisecond, 120*time.Millisecond),
+		newSpan("0000000000000006", "0000000000000009", "Client", "Error", 200*time.Millisecond, 210*time.Millisecond),
+		newSpan("0000000000000007", "000000000000000a", "Internal", "Unset", 15*time.Millisecond, 20*time.Millisecond),
+		elsewhere,
+	}
+	spans[1].DroppedAttributesCount = 3
+	spans[1].DroppedLinksCount = 1
+	spans[3].DroppedAttributesCount = 2
+	spans[3].DroppedEventsCount = 7
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	stats, err := store.GetTraceStats(ctx, traceID)
+	if assert.NoErrorf(t, err, "could not get trace stats: %v", err) {
+		assert.Equal(t, telemetry.TraceStats{
+			TraceID:           traceID,
+			SpanCount:         7,
+			SpanCountsByKind:  map[string]int{"Server": 1, "Client": 2, "Internal": 3, "Producer": 1},
+			ErrorCount:        2,
+			DurationNanos:     (210 * time.Millisecond).Nanoseconds(),
+			SelfDurationNanos: (40 * time.Millisecond).Nanoseconds(),
+			MaxDepth:          3,
+
+			DetachedSpanCount: 2,
+			DetachedSpansByReason: map[string]int{
+				telemetry.DetachedMissingParent:      1,
+				telemetry.DetachedParentInOtherTrace: 1,
+			},
+
+			DroppedAttributesCount: 5,
+			DroppedEventsCount:     7,
+			DroppedLinksCount:      1,
+			DroppedBySpan: []telemetry.SpanDroppedCounts{
+				{SpanID: "0000000000000002", Name: "sample.CurrencyService/Convert", DroppedAttributesCount: 3, DroppedLinksCount: 1},
+				{SpanID: "0000000000000004", Name: "sample.CurrencyService/Convert", DroppedAttributesCount: 2, DroppedEventsCount: 7},
+			},
+		}, stats)
+	}
+
+	_, err = store.GetTraceStats(ctx, "notatrace")
+	assert.ErrorIs(t, err, telemetry.ErrTraceIDNotFound)
+}
+
+func TestServiceGroups(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	newSpan := func(spanID string, resource telemetry.Attributes) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.SpanID = spanID
+		span.Resource = &telemetry.ResourceData{Attributes: resource}
+		return span
+	}
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan("0000000000000001", telemetry.Attributes{"service.namespace": "shop", "service.name": "cart", "service.instance.id": "cart-1"}),
+		newSpan("0000000000000002", telemetry.Attributes{"service.namespace": "shop", "service.name": "cart", "service.instance.id": "cart-2"}),
+		newSpan("0000000000000003", telemetry.Attributes{"service.namespace": "shop", "service.name": "cart", "service.instance.id": "cart-2"}),
+		newSpan("0000000000000004", telemetry.Attributes{"service.name": "cart", "service.instance.id": int64(3)}),
+		newSpan("0000000000000005", telemetry.Attributes{}),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		keys     []string
+		expected []telemetry.ServiceGroup
+	}{
+		{
+			name: "By Namespace And Name",
+			keys: []string{"service.namespace", "service.name"},
+			expected: []telemetry.ServiceGroup{
+				{Attributes: map[string]string{"service.namespace": "", "service.name": ""}, SpanCount: 1},
+				{Attributes: map[string]string{"service.namespace": "", "service.name": "cart"}, SpanCount: 1},
+				{Attributes: map[string]string{"service.namespace": "shop", "service.name": "cart"}, SpanCount: 3},
+			},
+		},
+		{
+			// Values that aren't strings are grouped by their JSON form
+			name: "By Instance",
+			keys: []string{"service.instance.id"},
+			expected: []telemetry.ServiceGroup{
+				{Attributes: map[string]string{"service.instance.id": ""}, SpanCount: 1},
+				{Attributes: map[string]string{"service.instance.id": "3"}, SpanCount: 1},
+				{Attributes: map[string]string{"service.instance.id": "cart-1"}, SpanCount: 1},
+				{Attributes: map[string]string{"service.instance.id": "cart-2"}, SpanCount: 2},
+			},
+		},
+		{
+			name: "Unknown Attribute",
+			keys: []string{"deployment.environment"},
+			expected: []telemetry.ServiceGroup{
+				{Attributes: map[string]string{"deployment.environment": ""}, SpanCount: 5},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			groups, err := store.GetServiceGroups(ctx, tt.keys)
+			if assert.NoErrorf(t, err, "could not get service groups: %v", err) {
+				assert.Equal(t, tt.expected, groups)
+			}
+		})
+	}
+
+	t.Run("No Attributes", func(t *testing.T) {
+		_, err := store.GetServiceGroups(ctx, []string{})
+		assert.Error(t, err)
+	})
+}
+
+func TestTraceGroups(t *testing.T) {
+	ctx := context.Background()
+
+	newSpan := func(traceID string, spanID string, resource telemetry.Attributes) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = ""
+		span.Resource = &telemetry.ResourceData{Attributes: resource}
+		return span
+	}
+	acme := "00000000000000000000000000000001"
+	globex := "00000000000000000000000000000002"
+	shared := "00000000000000000000000000000003"
+	none := "00000000000000000000000000000004"
+	spans := []telemetry.SpanData{
+		newSpan(acme, "0000000000000001", telemetry.Attributes{"tenant.id": "acme"}),
+		newSpan(globex, "0000000000000001", telemetry.Attributes{"tenant.id": "globex"}),
+		// A trace crossing tenants is in both their groups, but not the default one
+		newSpan(shared, "0000000000000001", telemetry.Attributes{"tenant.id": "acme"}),
+		newSpan(shared, "0000000000000002", telemetry.Attributes{}),
+		newSpan(shared, "0000000000000003", telemetry.Attributes{"tenant.id": "globex"}),
+		newSpan(none, "0000000000000001", telemetry.Attributes{}),
+	}
+
+	store := NewStore(ctx, "", WithGroupAttribute("tenant.id"))
+	defer store.Close()
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	ungrouped := NewStore(ctx, "")
+	defer ungrouped.Close()
+	err = ungrouped.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = ungrouped.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	t.Run("Groups", func(t *testing.T) {
+		groups, err := store.GetGroups(ctx)
+		if assert.NoErrorf(t, err, "could not get trace groups: %v", err) {
+			assert.Equal(t, telemetry.TraceGroups{
+				Attribute: "tenant.id",
+				Groups: []telemetry.TraceGroup{
+					{Name: "acme", TraceCount: 2},
+					{Name: "globex", TraceCount: 2},
+					{Name: DefaultGroup, TraceCount: 1},
+				},
+			}, groups)
+		}
+	})
+
+	t.Run("Ungrouped", func(t *testing.T) {
+		groups, err := ungrouped.GetGroups(ctx)
+		if assert.NoErrorf(t, err, "could not get trace groups: %v", err) {
+			assert.Equal(t, telemetry.TraceGroups{
+				Groups: []telemetry.TraceGroup{{Name: DefaultGroup, TraceCount: 4}},
+			}, groups)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		store    *Store
+		groups   []string
+		expected []string
+	}{
+		{name: "One Group", store: store, groups: []string{"acme"}, expected: []string{acme, shared}},
+		{name: "Default Group", store: store, groups: []string{DefaultGroup}, expected: []string{none}},
+		{name: "Several Groups", store: store, groups: []string{"globex", DefaultGroup}, expected: []string{globex, shared, none}},
+		{name: "Unknown Group", store: store, groups: []string{"initech"}, expected: []string{}},
+		{name: "Ungrouped Default", store: ungrouped, groups: []string{DefaultGroup}, expected: []string{acme, globex, shared, none}},
+		{name: "Ungrouped Named", store: ungrouped, groups: []string{"acme"}, expected: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaries, totalCount, err := tt.store.QueryTraceSummaries(ctx, SummaryQuery{Groups: tt.groups})
+			if assert.NoErrorf(t, err, "could not query trace summaries: %v", err) {
+				traceIDs := []string{}
+				for _, summary := range *summaries {
+					traceIDs = append(traceIDs, summary.TraceID)
+				}
+				assert.ElementsMatch(t, tt.expected, traceIDs)
+				assert.Equal(t, len(tt.expected), totalCount)
+			}
+		})
+	}
+}
+
+func TestAttributeKeysAndValues(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	newSpan := func(spanID string, service string, attributes telemetry.Attributes) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.SpanID = spanID
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		span.Attributes = attributes
+		return span
+	}
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan("0000000000000001", "cart", telemetry.Attributes{"http.method": "GET", "http.status_code": int64(200)}),
+		newSpan("0000000000000002", "cart", telemetry.Attributes{"http.method": "POST", "http.status_code": int64(500)}),
+		newSpan("0000000000000003", "cart", telemetry.Attributes{"http.method": "GET"}),
+		newSpan("0000000000000004", "checkout", telemetry.Attributes{"http.method": "PUT", "db.system": "postgresql"}),
+		newSpan("0000000000000005", "checkout", telemetry.Attributes{}),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	t.Run("Keys", func(t *testing.T) {
+		keys, err := store.GetAttributeKeys(ctx, AttributeQuery{})
+		if assert.NoErrorf(t, err, "could not get attribute keys: %v", err) {
+			assert.Equal(t, []telemetry.AttributeKey{
+				{Key: "db.system", SpanCount: 1},
+				{Key: "http.method", SpanCount: 4},
+				{Key: "http.status_code", SpanCount: 2},
+			}, keys)
+		}
+	})
+
+	t.Run("Keys Of A Service", func(t *testing.T) {
+		keys, err := store.GetAttributeKeys(ctx, AttributeQuery{Services: []string{"checkout"}})
+		if assert.NoErrorf(t, err, "could not get attribute keys: %v", err) {
+			assert.Equal(t, []telemetry.AttributeKey{
+				{Key: "db.system", SpanCount: 1},
+				{Key: "http.method", SpanCount: 1},
+			}, keys)
+		}
+	})
+
+	t.Run("Values", func(t *testing.T) {
+		values, err := store.GetAttributeValues(ctx, "http.method", AttributeQuery{}, 10)
+		if assert.NoErrorf(t, err, "could not get attribute values: %v", err) {
+			assert.Equal(t, telemetry.AttributeValues{
+				Key: "http.method",
+				Values: []telemetry.AttributeValue{
+					{Value: "GET", SpanCount: 2},
+					{Value: "POST", SpanCount: 1},
+					{Value: "PUT", SpanCount: 1},
+				},
+			}, values)
+		}
+	})
+
+	t.Run("Values Truncated", func(t *testing.T) {
+		values, err := store.GetAttributeValues(ctx, "http.method", AttributeQuery{}, 2)
+		if assert.NoErrorf(t, err, "could not get attribute values: %v", err) {
+			assert.Equal(t, []telemetry.AttributeValue{{Value: "GET", SpanCount: 2}, {Value: "POST", SpanCount: 1}}, values.Values)
+			assert.True(t, values.Truncated)
+		}
+	})
+
+	t.Run("Values Of A Service", func(t *testing.T) {
+		// Values that aren't strings come back in their JSON form
+		values, err := store.GetAttributeValues(ctx, "http.status_code", AttributeQuery{Services: []string{"cart"}}, 2)
+		if assert.NoErrorf(t, err, "could not get attribute values: %v", err) {
+			assert.Equal(t, []telemetry.AttributeValue{{Value: "200", SpanCount: 1}, {Value: "500", SpanCount: 1}}, values.Values)
+			assert.False(t, values.Truncated)
+		}
+	})
+
+	t.Run("Values Of An Unknown Key", func(t *testing.T) {
+		values, err := store.GetAttributeValues(ctx, "rpc.method", AttributeQuery{}, 10)
+		if assert.NoErrorf(t, err, "could not get attribute values: %v", err) {
+			assert.Empty(t, values.Values)
+			assert.False(t, values.Truncated)
+		}
+	})
+
+	t.Run("No Limit", func(t *testing.T) {
+		_, err := store.GetAttributeValues(ctx, "http.method", AttributeQuery{}, 0)
+		assert.Error(t, err)
+	})
+
+	t.Run("Cardinality", func(t *testing.T) {
+		keys, err := store.GetAttributeCardinality(ctx, AttributeQuery{}, false, 0)
+		if assert.NoErrorf(t, err, "could not get attribute cardinality: %v", err) {
+			assert.Equal(t, []telemetry.AttributeCardinality{
+				{Key: "http.method", DistinctValues: 3, SpanCount: 4},
+				{Key: "http.status_code", DistinctValues: 2, SpanCount: 2},
+				{Key: "db.system", DistinctValues: 1, SpanCount: 1},
+			}, keys)
+		}
+	})
+
+	t.Run("Cardinality Ascending And Limited", func(t *testing.T) {
+		keys, err := store.GetAttributeCardinality(ctx, AttributeQuery{}, true, 2)
+		if assert.NoErrorf(t, err, "could not get attribute cardinality: %v", err) {
+			assert.Equal(t, []telemetry.AttributeCardinality{
+				{Key: "db.system", DistinctValues: 1, SpanCount: 1},
+				{Key: "http.status_code", DistinctValues: 2, SpanCount: 2},
+			}, keys)
+		}
+	})
+
+	t.Run("Cardinality Of A Service", func(t *testing.T) {
+		// Keys with as many values are ordered by key
+		keys, err := store.GetAttributeCardinality(ctx, AttributeQuery{Services: []string{"checkout"}}, false, 0)
+		if assert.NoErrorf(t, err, "could not get attribute cardinality: %v", err) {
+			assert.Equal(t, []telemetry.AttributeCardinality{
+				{Key: "db.system", DistinctValues: 1, SpanCount: 1},
+				{Key: "http.method", DistinctValues: 1, SpanCount: 1},
+			}, keys)
+		}
+	})
+}
+
+func TestServiceDependencies(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newSpan := func(traceID string, spanID string, parentSpanID string, service string, statusCode string, startTime time.Time) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = parentSpanID
+		span.StatusCode = statusCode
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Second)
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+
+	first := "00000000000000000000000000000001"
+	second := "00000000000000000000000000000002"
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan(first, "0000000000000001", "", "frontend", "Unset", start),
+		newSpan(first, "0000000000000002", "0000000000000001", "frontend", "Unset", start),
+		newSpan(first, "0000000000000003", "0000000000000002", "backend", "Error", start),
+		newSpan(first, "0000000000000004", "0000000000000003", "database", "Unset", start),
+		// The parent of this one never arrived
+		newSpan(first, "0000000000000005", "0000000000000009", "database", "Unset", start),
+		newSpan(second, "0000000000000001", "", "frontend", "Unset", start.Add(time.Hour)),
+		newSpan(second, "0000000000000002", "0000000000000001", "backend", "Unset", start.Add(time.Hour)),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		query    DependencyQuery
+		expected []telemetry.ServiceDependency
+	}{
+		{
+			name:  "All Traces",
+			query: DependencyQuery{},
+			expected: []telemetry.ServiceDependency{
+				{Parent: "backend", Child: "database", CallCount: 1, ErrorCount: 0},
+				{Parent: "frontend", Child: "backend", CallCount: 2, ErrorCount: 1},
+			},
+		},
+		{
+			name:  "Time Window",
+			query: DependencyQuery{Start: start.Add(time.Minute), End: start.Add(2 * time.Hour)},
+			expected: []telemetry.ServiceDependency{
+				{Parent: "frontend", Child: "backend", CallCount: 1, ErrorCount: 0},
+			},
+		},
+		{
+			name:     "Empty Window",
+			query:    DependencyQuery{End: start.Add(-time.Minute)},
+			expected: []telemetry.ServiceDependency{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dependencies, err := store.GetServiceDependencies(ctx, tt.query)
+			if assert.NoErrorf(t, err, "could not get service dependencies: %v", err) {
+				assert.Equal(t, tt.expected, dependencies)
+			}
+		})
+	}
+}
+
+func TestServiceNodes(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newSpan := func(spanID string, service string, statusCode string, startTime time.Time, duration time.Duration) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = "00000000000000000000000000000001"
+		span.SpanID = spanID
+		span.ParentSpanID = ""
+		span.StatusCode = statusCode
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(duration)
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan("0000000000000001", "frontend", "Unset", start, time.Second),
+		newSpan("0000000000000002", "frontend", "Unset", start.Add(time.Hour), time.Second),
+		newSpan("0000000000000003", "backend", "Error", start, time.Second),
+		newSpan("0000000000000004", "backend", "Unset", start, time.Second),
+		newSpan("0000000000000005", "backend", "Error", start, time.Second),
+		newSpan("0000000000000006", "backend", "Ok", start.Add(time.Hour), time.Second),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	tests := []struct {
+		name     string
+		query    DependencyQuery
+		expected []telemetry.ServiceNode
+	}{
+		{
+			name:  "All Traces",
+			query: DependencyQuery{},
+			expected: []telemetry.ServiceNode{
+				{ServiceName: "backend", SpanCount: 4, ErrorCount: 2, ErrorRate: 0.5, P95DurationNanos: int64(time.Second)},
+				{ServiceName: "frontend", SpanCount: 2, ErrorCount: 0, ErrorRate: 0, P95DurationNanos: int64(time.Second)},
+			},
+		},
+		{
+			name:  "Time Window",
+			query: DependencyQuery{Start: start.Add(time.Minute), End: start.Add(2 * time.Hour)},
+			expected: []telemetry.ServiceNode{
+				{ServiceName: "backend", SpanCount: 1, ErrorCount: 0, ErrorRate: 0, P95DurationNanos: int64(time.Second)},
+				{ServiceName: "frontend", SpanCount: 1, ErrorCount: 0, ErrorRate: 0, P95DurationNanos: int64(time.Second)},
+			},
+		},
+		{
+			name:     "Empty Window",
+			query:    DependencyQuery{End: start.Add(-time.Minute)},
+			expected: []telemetry.ServiceNode{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := store.GetServiceNodes(ctx, tt.query)
+			if assert.NoErrorf(t, err, "could not get service nodes: %v", err) {
+				assert.Equal(t, tt.expected, nodes)
+			}
+		})
+	}
+}
+
+func TestOperationStats(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newSpan := func(spanID int, name string, service string, startTime time.Time, duration time.Duration) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = fmt.Sprintf("%032x", spanID)
+		span.SpanID = fmt.Sprintf("%016x", spanID)
+		span.ParentSpanID = ""
+		span.Name = name
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(duration)
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+
+	// A hundred checkouts taking 1ms to 100ms, and a query in each of two services
+	spans := []telemetry.SpanData{}
+	for i := 1; i <= 100; i++ {
+		spans = append(spans, newSpan(i, "checkout", "frontend", start, time.Duration(i)*time.Millisecond))
+	}
+	spans = append(spans,
+		newSpan(101, "query", "backend", start, 5*time.Millisecond),
+		newSpan(102, "query", "database", start.Add(time.Hour), 3*time.Millisecond),
+	)
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	t.Run("All Operations", func(t *testing.T) {
+		operations, err := store.GetOperationStats(ctx, OperationQuery{})
+		if !assert.NoErrorf(t, err, "could not get operation stats: %v", err) || !assert.Len(t, operations, 2) {
+			return
+		}
+
+		// The slowest operation comes first
+		checkout := operations[0]
+		assert.Equal(t, "checkout", checkout.Name)
+		assert.Equal(t, "", checkout.ServiceName)
+		assert.Equal(t, uint32(100), checkout.SpanCount)
+		assert.InDelta(t, float64(50*time.Millisecond), float64(checkout.P50DurationNanos), float64(2*time.Millisecond))
+		assert.InDelta(t, float64(95*time.Millisecond), float64(checkout.P95DurationNanos), float64(2*time.Millisecond))
+		assert.InDelta(t, float64(99*time.Millisecond), float64(checkout.P99DurationNanos), float64(2*time.Millisecond))
+		assert.Equal(t, (100 * time.Millisecond).Nanoseconds(), checkout.MaxDurationNanos)
+
+		query := operations[1]
+		assert.Equal(t, "query", query.Name)
+		assert.Equal(t, uint32(2), query.SpanCount)
+		assert.Equal(t, (5 * time.Millisecond).Nanoseconds(), query.MaxDurationNanos)
+	})
+
+	t.Run("Grouped By Service", func(t *testing.T) {
+		operations, err := store.GetOperationStats(ctx, OperationQuery{GroupByService: true, Services: []string{"backend", "database"}})
+		if !assert.NoErrorf(t, err, "could not get operation stats: %v", err) || !assert.Len(t, operations, 2) {
+			return
+		}
+
+		assert.Equal(t, "backend", operations[0].ServiceName)
+		assert.Equal(t, uint32(1), operations[0].SpanCount)
+		assert.Equal(t, (5 * time.Millisecond).Nanoseconds(), operations[0].P50DurationNanos)
+		assert.Equal(t, "database", operations[1].ServiceName)
+		assert.Equal(t, (3 * time.Millisecond).Nanoseconds(), operations[1].P99DurationNanos)
+	})
+
+	t.Run("Time Window", func(t *testing.T) {
+		operations, err := store.GetOperationStats(ctx, OperationQuery{Start: start.Add(time.Minute)})
+		if assert.NoErrorf(t, err, "could not get operation stats: %v", err) && assert.Len(t, operations, 1) {
+			assert.Equal(t, "query", operations[0].Name)
+			assert.Equal(t, uint32(1), operations[0].SpanCount)
+			assert.Equal(t, (3 * time.Millisecond).Nanoseconds(), operations[0].MaxDurationNanos)
+		}
+	})
+
+	t.Run("Empty Window", func(t *testing.T) {
+		operations, err := store.GetOperationStats(ctx, OperationQuery{End: start.Add(-time.Minute)})
+		if assert.NoErrorf(t, err, "could not get operation stats: %v", err) {
+			assert.Equal(t, []telemetry.OperationStats{}, operations)
+		}
+	})
+}
+
+func TestTraceVolume(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	end := time.Date(2024, time.January, 1, 12, 0, 30, 0, time.UTC)
+	newSpan := func(traceID int, spanID int, service string, startTime time.Time, statusCode string) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = fmt.Sprintf("%032x", traceID)
+		span.SpanID = fmt.Sprintf("%016x", spanID)
+		span.ParentSpanID = ""
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Millisecond)
+		span.StatusCode = statusCode
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+
+	// Traces are bucketed by their first span, so the late failure of trace 2 counts at 11:57
+	err := store.AddSpans(ctx, []telemetry.SpanData{
+		newSpan(1, 1, "frontend", time.Date(2024, time.January, 1, 11, 57, 0, 0, time.UTC), "Ok"),
+		newSpan(2, 1, "frontend", time.Date(2024, time.January, 1, 11, 57, 59, 0, time.UTC), "Unset"),
+		newSpan(2, 2, "backend", time.Date(2024, time.January, 1, 11, 58, 30, 0, time.UTC), "Error"),
+		newSpan(3, 1, "backend", time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC), "Error"),
+		// Before the window, and after the end
+		newSpan(4, 1, "frontend", time.Date(2024, time.January, 1, 11, 56, 59, 0, time.UTC), "Ok"),
+		newSpan(5, 1, "frontend", time.Date(2024, time.January, 1, 12, 1, 0, 0, time.UTC), "Ok"),
+	})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	counts := func(volume telemetry.TraceVolume) ([]int, []int) {
+		traces, errors := []int{}, []int{}
+		for _, bucket := range volume.Buckets {
+			traces = append(traces, bucket.TraceCount)
+			errors = append(errors, bucket.ErrorCount)
+		}
+		return traces, errors
+	}
+
+	t.Run("Every Bucket", func(t *testing.T) {
+		volume, err := store.GetTraceVolume(ctx, TraceVolumeQuery{Bucket: time.Minute, Window: 4 * time.Minute, End: end})
+		if !assert.NoErrorf(t, err, "could not get trace volume: %v", err) {
+			return
+		}
+		assert.Equal(t, time.Date(2024, time.January, 1, 11, 57, 0, 0, time.UTC), volume.Start)
+		assert.Equal(t, time.Date(2024, time.January, 1, 12, 1, 0, 0, time.UTC), volume.End)
+		assert.Equal(t, time.Minute.Nanoseconds(), volume.BucketNanos)
+		if assert.Len(t, volume.Buckets, 4) {
+			assert.Equal(t, time.Date(2024, time.January, 1, 11, 58, 0, 0, time.UTC), volume.Buckets[1].Start)
+		}
+
+		traces, errors := counts(volume)
+		assert.Equal(t, []int{2, 0, 0, 1}, traces)
+		assert.Equal(t, []int{1, 0, 0, 1}, errors)
+	})
+
+	t.Run("Window Not A Multiple Of The Bucket", func(t *testing.T) {
+		volume, err := store.GetTraceVolume(ctx, TraceVolumeQuery{Bucket: 2 * time.Minute, Window: 3 * time.Minute, End: end})
+		if assert.NoErrorf(t, err, "could not get trace volume: %v", err) {
+			// Two buckets make up the window, the last of them running on past the end
+			assert.Equal(t, time.Date(2024, time.January, 1, 11, 58, 0, 0, time.UTC), volume.Start)
+			traces, _ := counts(volume)
+			assert.Equal(t, []int{0, 2}, traces)
+		}
+	})
+
+	t.Run("By Service", func(t *testing.T) {
+		volume, err := store.GetTraceVolume(ctx, TraceVolumeQuery{Bucket: time.Minute, Window: 4 * time.Minute, End: end, Services: []string{"backend"}})
+		if assert.NoErrorf(t, err, "could not get trace volume: %v", err) {
+			traces, errors := counts(volume)
+			assert.Equal(t, []int{1, 0, 0, 1}, traces)
+			assert.Equal(t, []int{1, 0, 0, 1}, errors)
+		}
+	})
+
+	t.Run("Empty Window", func(t *testing.T) {
+		volume, err := store.GetTraceVolume(ctx, TraceVolumeQuery{Bucket: time.Minute, Window: 2 * time.Minute, End: end.Add(-time.Hour)})
+		if assert.NoErrorf(t, err, "could not get trace volume: %v", err) {
+			traces, errors := counts(volume)
+			assert.Equal(t, []int{0, 0}, traces)
+			assert.Equal(t, []int{0, 0}, errors)
+		}
+	})
+}
+
+func TestTraceSizes(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newTrace := func(traceID int, spanCount int, service string, startTime time.Time) []telemetry.SpanData {
+		spans := []telemetry.SpanData{}
+		for i := 0; i < spanCount; i++ {
+			span := telemetry.NewSampleTelemetry().Spans[0]
+			span.TraceID = fmt.Sprintf("%032x", traceID)
+			span.SpanID = fmt.Sprintf("%016x", i+1)
+			span.ParentSpanID = ""
+			span.StartTime = startTime.Add(time.Duration(i) * time.Millisecond)
+			span.EndTime = span.StartTime.Add(time.Millisecond)
+			span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+			spans = append(spans, span)
+		}
+		return spans
+	}
+
+	// Traces right at the edges of the buckets, and one monster an hour later
+	spans := []telemetry.SpanData{}
+	for i, spanCount := range []int{1, 1, 2, 5, 6, 20, 100} {
+		spans = append(spans, newTrace(i+1, spanCount, "frontend", start)...)
+	}
+	spans = append(spans, newTrace(8, 250, "backend", start.Add(time.Hour))...)
+	err := store.AddSpans(ctx, spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	counts := func(histogram telemetry.TraceSizeHistogram) []int {
+		counts := []int{}
+		for _, bucket := range histogram.Buckets {
+			counts = append(counts, bucket.TraceCount)
+		}
+		return counts
+	}
+
+	t.Run("All Traces", func(t *testing.T) {
+		histogram, err := store.GetTraceSizes(ctx, TraceSizeQuery{})
+		if !assert.NoErrorf(t, err, "could not get trace sizes: %v", err) {
+			return
+		}
+		assert.Equal(t, []telemetry.TraceSizeBucket{
+			{Label: "1", MinSpans: 1, MaxSpans: 1, TraceCount: 2},
+			{Label: "2-5", MinSpans: 2, MaxSpans: 5, TraceCount: 2},
+			{Label: "6-20", MinSpans: 6, MaxSpans: 20, TraceCount: 2},
+			{Label: "21-100", MinSpans: 21, MaxSpans: 100, TraceCount: 1},
+			{Label: "101+", MinSpans: 101, TraceCount: 1},
+		}, histogram.Buckets)
+		assert.Equal(t, 8, histogram.TotalTraces)
+		assert.Equal(t, fmt.Sprintf("%032x", 8), histogram.LargestTraceID)
+		assert.Equal(t, 250, histogram.LargestTraceSpans)
+	})
+
+	t.Run("By Service", func(t *testing.T) {
+		histogram, err := store.GetTraceSizes(ctx, TraceSizeQuery{Services: []string{"frontend"}})
+		if assert.NoErrorf(t, err, "could not get trace sizes: %v", err) {
+			assert.Equal(t, []int{2, 2, 2, 1, 0}, counts(histogram))
+			assert.Equal(t, 100, histogram.LargestTraceSpans)
+		}
+	})
+
+	t.Run("Time Window", func(t *testing.T) {
+		histogram, err := store.GetTraceSizes(ctx, TraceSizeQuery{Start: start.Add(time.Minute)})
+		if assert.NoErrorf(t, err, "could not get trace sizes: %v", err) {
+			assert.Equal(t, []int{0, 0, 0, 0, 1}, counts(histogram))
+		}
+	})
+
+	t.Run("Empty Window", func(t *testing.T) {
+		histogram, err := store.GetTraceSizes(ctx, TraceSizeQuery{End: start.Add(-time.Minute)})
+		if assert.NoErrorf(t, err, "could not get trace sizes: %v", err) {
+			assert.Equal(t, []int{0, 0, 0, 0, 0}, counts(histogram))
+			assert.Equal(t, 0, histogram.TotalTraces)
+			assert.Empty(t, histogram.LargestTraceID)
+		}
+	})
+}
+
+func TestClearMatchingTraces(t *testing.T) {
+	ctx := context.Background()
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newSpan := func(traceID string, spanID string, service string, startTime time.Time) telemetry.SpanData {
+		span := telemetry.NewSampleTelemetry().Spans[0]
+		span.TraceID = traceID
+		span.SpanID = spanID
+		span.ParentSpanID = ""
+		span.StartTime = startTime
+		span.EndTime = startTime.Add(time.Second)
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": service}}
+		return span
+	}
+
+	first := "00000000000000000000000000000001"
+	second := "00000000000000000000000000000002"
+	third := "00000000000000000000000000000003"
+	spans := []telemetry.SpanData{
+		newSpan(first, "0000000000000001", "frontend", start),
+		newSpan(second, "0000000000000001", "backend", start.Add(time.Hour)),
+		newSpan(second, "0000000000000002", "frontend", start.Add(time.Hour)),
+		newSpan(third, "0000000000000001", "backend", start.Add(2*time.Hour)),
+	}
+
+	tests := []struct {
+		name        string
+		query       ClearQuery
+		expectedIDs []string
+	}{
+		{name: "By Service", query: ClearQuery{Services: []string{"frontend"}}, expectedIDs: []string{third}},
+		{name: "Unknown Service", query: ClearQuery{Services: []string{"database"}}, expectedIDs: []string{first, second, third}},
+		{name: "Before", query: ClearQuery{Before: start.Add(time.Hour)}, expectedIDs: []string{second, third}},
+		{name: "After", query: ClearQuery{After: start}, expectedIDs: []string{first}},
+		{name: "Between", query: ClearQuery{After: start, Before: start.Add(2 * time.Hour)}, expectedIDs: []string{first, third}},
+		{name: "Service And Time", query: ClearQuery{Services: []string{"backend"}, Before: start.Add(2 * time.Hour)}, expectedIDs: []string{first, third}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(ctx, "")
+			defer store.Close()
+
+			err := store.AddSpans(ctx, spans)
+			assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+
+			// Spans still waiting to be written are cleared too
+			cleared, err := store.ClearMatchingTraces(ctx, tt.query)
+			if !assert.NoErrorf(t, err, "could not clear traces: %v", err) {
+				return
+			}
+			assert.Equal(t, 3-len(tt.expectedIDs), cleared)
+
+			summaries, _, err := store.GetTraceSummaries(ctx, 0, 0)
+			assert.NoErrorf(t, err, "could not get trace summaries: %v", err)
+			traceIDs := []string{}
+			for _, summary := range *summaries {
+				traceIDs = append(traceIDs, summary.TraceID)
+			}
+			assert.ElementsMatch(t, tt.expectedIDs, traceIDs)
+		})
+	}
+}
+
+func TestLogs(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newLog := func(traceID string, body string, timestamp time.Time) telemetry.LogData {
+		return telemetry.LogData{
+			Body:           body,
+			TraceID:        traceID,
+			SpanID:         "0000000000000001",
+			Timestamp:      timestamp,
+			Attributes:     map[string]interface{}{"attempt": int64(1)},
+			SeverityText:   "WARN",
+			SeverityNumber: plog.SeverityNumberWarn,
+			Resource:       &telemetry.ResourceData{Attributes: map[string]interface{}{"service.name": "pumpkin.pie"}},
+			Scope:          &telemetry.ScopeData{Name: "pumpkin.logger", Attributes: map[string]interface{}{}},
+		}
+	}
+
+	err := store.AddLogs(ctx, []telemetry.LogData{
+		newLog("00000000000000000000000000000001", "second", start.Add(time.Nanosecond)),
+		newLog("00000000000000000000000000000001", "first", start),
+		newLog("00000000000000000000000000000002", "elsewhere", start),
+	})
+	assert.NoErrorf(t, err, "could not add logs to the database: %v", err)
+
+	logs, err := store.GetLogsByTrace(ctx, "00000000000000000000000000000001")
+	if assert.NoErrorf(t, err, "could not get logs: %v", err) && assert.Len(t, logs, 2) {
+		assert.Equal(t, "first", logs[0].Body)
+		assert.Equal(t, "second", logs[1].Body)
+		assert.Equal(t, start.Add(time.Nanosecond), logs[1].Timestamp.UTC())
+		assert.Equal(t, plog.SeverityNumberWarn, logs[1].SeverityNumber)
+		assert.Equal(t, "WARN", logs[1].SeverityText)
+		assert.Equal(t, "0000000000000001", logs[1].SpanID)
+		assert.Equal(t, int64(1), logs[1].Attributes["attempt"])
+		assert.Equal(t, "pumpkin.pie", logs[1].Resource.Attributes["service.name"])
+		assert.Equal(t, "pumpkin.logger", logs[1].Scope.Name)
+	}
+
+	logs, err = store.GetLogsByTrace(ctx, "notatrace")
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+
+	// Clearing traces takes their logs with them
+	_, err = store.ClearTraces(ctx)
+	assert.NoErrorf(t, err, "could not clear traces: %v", err)
+	logs, err = store.GetLogsByTrace(ctx, "00000000000000000000000000000002")
+	assert.NoError(t, err)
+	assert.Empty(t, logs)
+}
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	start := time.Date(2024, time.January, 1, 12, 0, 0, 0, time.UTC)
+	newMetric := func(name string, service string, value float64, timestamp time.Time) telemetry.MetricData {
+		return telemetry.MetricData{
+			Name:       name,
+			Type:       "Gauge",
+			Timestamp:  timestamp,
+			Attributes: map[string]interface{}{"queue": "pies"},
+			Value:      &value,
+			Resource:   &telemetry.ResourceData{Attributes: map[string]interface{}{"service.name": service}},
+			Scope:      &telemetry.ScopeData{Name: "pumpkin.meter", Attributes: map[string]interface{}{}},
+		}
+	}
+
+	sum := 7.5
+	histogram := newMetric("bake.duration", "pumpkin.pie", 0, start)
+	histogram.Type = "Histogram"
+	histogram.Value = nil
+	histogram.Histogram = &telemetry.HistogramData{Count: 2, Sum: &sum, BucketCounts: []uint64{1, 1}, ExplicitBounds: []float64{5}}
+	histogram.Exemplars = []telemetry.ExemplarData{
+		{Timestamp: start, Value: 6, TraceID: "00000000000000000000000000000001", SpanID: "0000000000000001", FilteredAttributes: telemetry.Attributes{}},
+		{Timestamp: start, Value: 1.5, TraceID: "00000000000000000000000000000002", SpanID: "0000000000000001", FilteredAttributes: telemetry.Attributes{}},
+		{Timestamp: start, Value: 0.5, FilteredAttributes: telemetry.Attributes{}},
+	}
+
+	exponential := newMetric("bake.temperature", "pumpkin.pie", 0, start)
+	exponential.Type = "ExponentialHistogram"
+	exponential.Value = nil
+	exponential.ExponentialHistogram = &telemetry.ExponentialHistogramData{
+		Count:         4,
+		Sum:           &sum,
+		Scale:         -1,
+		ZeroCount:     1,
+		ZeroThreshold: 0.5,
+		Positive:      telemetry.ExponentialHistogramBuckets{Offset: 2, BucketCounts: []uint64{1, 1}},
+		Negative:      telemetry.ExponentialHistogramBuckets{Offset: -1, BucketCounts: []uint64{1}},
+	}
+
+	err := store.AddMetrics(ctx, []telemetry.MetricData{
+		newMetric("queue.length", "pumpkin.pie", 2, start.Add(time.Minute)),
+		newMetric("queue.length", "pumpkin.pie", 1, start),
+		newMetric("queue.length", "apple.crumble", 5, start.Add(2*time.Minute)),
+		histogram,
+		exponential,
+	})
+	assert.NoErrorf(t, err, "could not add metrics to the database: %v", err)
+
+	tests := []struct {
+		name     string
+		query    MetricQuery
+		expected []float64
+	}{
+		{"by name, oldest first", MetricQuery{Name: "queue.length"}, []float64{1, 2, 5}},
+		{"by service", MetricQuery{Name: "queue.length", Service: "pumpkin.pie"}, []float64{1, 2}},
+		{"by time range", MetricQuery{Name: "queue.length", Start: start.Add(time.Minute), End: start.Add(2 * time.Minute)}, []float64{2, 5}},
+		{"no match", MetricQuery{Name: "notametric"}, []float64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metrics, err := store.QueryMetrics(ctx, tt.query)
+			if assert.NoErrorf(t, err, "could not query metrics: %v", err) {
+				values := []float64{}
+				for _, metric := range metrics {
+					values = append(values, *metric.Value)
+				}
+				assert.Equal(t, tt.expected, values)
+			}
+		})
+	}
+
+	metrics, err := store.QueryMetrics(ctx, MetricQuery{Name: "bake.duration"})
+	if assert.NoErrorf(t, err, "could not query metrics: %v", err) && assert.Len(t, metrics, 1) {
+		assert.Nil(t, metrics[0].Value)
+		assert.Equal(t, histogram.Histogram, metrics[0].Histogram)
+		assert.Nil(t, metrics[0].ExponentialHistogram)
+		assert.True(t, metrics[0].StartTime.IsZero())
+		assert.Equal(t, start, metrics[0].Timestamp.UTC())
+		assert.Equal(t, "pies", metrics[0].Attributes["queue"])
+		assert.Equal(t, "pumpkin.meter", metrics[0].Scope.Name)
+	}
+
+	// Only the first exemplar's trace is stored, and the last one has no trace to look for
+	span := telemetry.NewSampleTelemetry().Spans[0]
+	span.TraceID = "00000000000000000000000000000001"
+	err = store.AddSpans(ctx, []telemetry.SpanData{span})
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	metrics, err = store.QueryMetrics(ctx, MetricQuery{Name: "bake.duration"})
+	if assert.NoErrorf(t, err, "could not query metrics: %v", err) && assert.Len(t, metrics, 1) {
+		assert.Equal(t, histogram.Exemplars, metrics[0].Exemplars)
+		err = store.ResolveExemplars(ctx, metrics)
+		if assert.NoErrorf(t, err, "could not resolve exemplars: %v", err) {
+			resolved, unresolved := true, false
+			assert.Equal(t, &resolved, metrics[0].Exemplars[0].Resolved)
+			assert.Equal(t, &unresolved, metrics[0].Exemplars[1].Resolved)
+			assert.Nil(t, metrics[0].Exemplars[2].Resolved)
+		}
+	}
+
+	// Exponential histograms come back with every bucket, as there is no UI for them to lose any to
+	metrics, err = store.QueryMetrics(ctx, MetricQuery{Name: "bake.temperature"})
+	if assert.NoErrorf(t, err, "could not query metrics: %v", err) && assert.Len(t, metrics, 1) {
+		assert.Nil(t, metrics[0].Histogram)
+		assert.Equal(t, exponential.ExponentialHistogram, metrics[0].ExponentialHistogram)
+		assert.Equal(t, []telemetry.ExemplarData{}, metrics[0].Exemplars)
+	}
+
+	_, err = store.ClearTraces(ctx)
+	assert.NoErrorf(t, err, "could not clear data: %v", err)
+	metrics, err = store.QueryMetrics(ctx, MetricQuery{})
+	assert.NoError(t, err)
+	assert.Empty(t, metrics)
+}
+
+func TestGetStatus(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Empty", func(t *testing.T) {
+		store := NewStore(ctx, "")
+		defer store.Close()
+
+		status, err := store.GetStatus(ctx)
+		assert.NoErrorf(t, err, "could not get status: %v", err)
+		assert.Equal(t, telemetry.StoreStatus{Mode: ModeMemory, SchemaVersion: schemaVersion}, status)
+	})
+
+	t.Run("Sample Data", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "traces.db")
+		store := NewStore(ctx, dbPath)
+		defer store.Close()
+
+		spans := telemetry.NewSampleTelemetry().Spans
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+		oldest, newest := spans[0].StartTime, spans[0].EndTime
+		for _, span := range spans {
+			if span.StartTime.Before(oldest) {
+				oldest = span.StartTime
+			}
+			if span.EndTime.After(newest) {
+				newest = span.EndTime
+			}
+		}
+
+		status, err := store.GetStatus(ctx)
+		assert.NoErrorf(t, err, "could not get status: %v", err)
+		assert.Equal(t, 2, status.TraceCount)
+		assert.Equal(t, len(spans), status.SpanCount)
+		if assert.NotNil(t, status.OldestSpan) && assert.NotNil(t, status.NewestSpan) {
+			assert.True(t, oldest.Equal(*status.OldestSpan))
+			assert.True(t, newest.Equal(*status.NewestSpan))
+		}
+		assert.Equal(t, ModeFile, status.Mode)
+		assert.False(t, status.ReadOnly)
+		assert.Equal(t, schemaVersion, status.SchemaVersion)
+	})
+}
+
+func TestReadSession(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+	defer store.Close()
+
+	err := store.AddSpans(ctx, telemetry.NewSampleTelemetry().Spans)
+	assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+	err = store.Flush(ctx)
+	assert.NoErrorf(t, err, "could not flush spans: %v", err)
+
+	evicted := make(chan int)
+	err = store.ReadSession(ctx, func(ctx context.Context) error {
+		assert.True(t, InReadSession(ctx))
+
+		go func() {
+			n, err := store.EvictBeyondCount(context.Background(), 0)
+			assert.NoErrorf(t, err, "could not evict traces: %v", err)
+			evicted <- n
+		}()
+
+		// The eviction waits for the session to end, and the queries made in the meantime,
+		// which join the session rather than queue up behind the eviction, see every trace
+		select {
+		case <-evicted:
+			t.Fatal("traces were evicted during a read session")
+		case <-time.After(100 * time.Millisecond):
+		}
+		summaries, totalCount, err := store.QueryTraceSummaries(ctx, SummaryQuery{Limit: 1})
+		if assert.NoErrorf(t, err, "could not query trace summaries: %v", err) {
+			assert.Len(t, *summaries, 1)
+			assert.Equal(t, 2, totalCount)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, InReadSession(ctx))
+
+	select {
+	case n := <-evicted:
+		assert.Equal(t, 2, n)
+	case <-time.After(5 * time.Second):
+		t.Fatal("eviction didn't go ahead once the read session ended")
+	}
+	count, err := store.CountTraces(ctx)
+	assert.NoErrorf(t, err, "could not count traces: %v", err)
+	assert.Equal(t, 0, count)
+}
+
+func TestSnapshot(t *testing.T) {
+	ctx := context.Background()
+	spans := telemetry.NewSampleTelemetry().Spans
+
+	// The snapshot opens like any other database file, with every trace in it
+	checkSnapshot := func(t *testing.T, path string) {
+		snapshot, err := openStore(ctx, path, WithReadOnly())
+		if assert.NoErrorf(t, err, "could not open snapshot: %v", err) {
+			defer snapshot.Close()
+			count, err := snapshot.CountTraces(ctx)
+			assert.NoErrorf(t, err, "could not count traces: %v", err)
+			assert.Equal(t, 2, count)
+		}
+	}
+
+	t.Run("In Memory", func(t *testing.T) {
+		store := NewStore(ctx, "")
+		defer store.Close()
+
+		// Spans still queued make it into the snapshot
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+
+		path := filepath.Join(t.TempDir(), "snapshot.db")
+		err = store.Snapshot(ctx, path)
+		assert.NoErrorf(t, err, "could not snapshot the store: %v", err)
+		checkSnapshot(t, path)
+
+		// The store carries on as before, and can be snapshotted again
+		count, err := store.CountTraces(ctx)
+		assert.NoErrorf(t, err, "could not count traces: %v", err)
+		assert.Equal(t, 2, count)
+		err = store.Snapshot(ctx, filepath.Join(t.TempDir(), "snapshot.db"))
+		assert.NoErrorf(t, err, "could not snapshot the store again: %v", err)
+	})
+
+	t.Run("Spilled", func(t *testing.T) {
+		store := NewStore(ctx, filepath.Join(t.TempDir(), "traces.db"), WithSpillover(RetentionPolicy{MaxTraces: 1}))
+		defer store.Close()
+
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Flush(ctx)
+		assert.NoErrorf(t, err, "could not flush spans: %v", err)
+		spilled, err := store.Spill(ctx, time.Now())
+		assert.NoError(t, err)
+		assert.Equal(t, 1, spilled)
+
+		// Both tiers end up in the snapshot's one spans table
+		path := filepath.Join(t.TempDir(), "snapshot.db")
+		err = store.Snapshot(ctx, path)
+		assert.NoErrorf(t, err, "could not snapshot the store: %v", err)
+		checkSnapshot(t, path)
+	})
+
+	t.Run("Read Only", func(t *testing.T) {
+		dbPath := filepath.Join(t.TempDir(), "traces.db")
+		store := NewStore(ctx, dbPath)
+		err := store.AddSpans(ctx, spans)
+		assert.NoErrorf(t, err, "could not add spans to the database: %v", err)
+		err = store.Close()
+		assert.NoErrorf(t, err, "could not close the store: %v", err)
+
+		store, err = openStore(ctx, dbPath, WithReadOnly())
+		if assert.NoErrorf(t, err, "could not open the store read-only: %v", err) {
+			defer store.Close()
+			path := filepath.Join(t.TempDir(), "snapshot.db")
+			err = store.Snapshot(ctx, path)
+			assert.NoErrorf(t, err, "could not snapshot the store: %v", err)
+			checkSnapshot(t, path)
+		}
+	})
+}
+
+func TestPing(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(ctx, "")
+
+	err := store.Ping(ctx)
+	assert.NoErrorf(t, err, "could not ping the database: %v", err)
+
+	store.Close()
+	err = store.Ping(ctx)
+	assert.ErrorIs(t, err, ErrStoreClosed)
+}
@@ -0,0 +1,526 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// SortKey is the field trace summaries are ordered by
+type SortKey string
+
+const (
+	SortByStart     SortKey = "start"
+	SortByDuration  SortKey = "duration"
+	SortBySpanCount SortKey = "spanCount"
+)
+
+// rootDuration is the wall-clock duration of a trace's root span in nanoseconds
+const rootDuration = "epoch_ns(roots.rootEndTime) - epoch_ns(roots.rootStartTime)"
+
+// sortColumns maps each sort key to the SQL expression it orders by in SELECT_TRACE_SUMMARIES
+var sortColumns = map[SortKey]string{
+	SortByStart:     "roots.rootStartTime",
+	SortByDuration:  rootDuration,
+	SortBySpanCount: "traces.spanCount",
+}
+
+// ParseSortKey validates a sort key received from a client
+func ParseSortKey(key string) (SortKey, error) {
+	if _, ok := sortColumns[SortKey(key)]; !ok {
+		return "", fmt.Errorf("invalid sort key %q: must be one of %s, %s, %s", key, SortByStart, SortByDuration, SortBySpanCount)
+	}
+	return SortKey(key), nil
+}
+
+// StatusFilter restricts trace summaries by whether any of the trace's spans failed
+type StatusFilter string
+
+const (
+	// StatusError keeps traces with at least one span whose status code is Error
+	StatusError StatusFilter = "error"
+	// StatusOK keeps traces without any such span
+	StatusOK StatusFilter = "ok"
+)
+
+// ParseStatusFilter validates a status filter received from a client
+func ParseStatusFilter(status string) (StatusFilter, error) {
+	switch StatusFilter(status) {
+	case StatusError, StatusOK:
+		return StatusFilter(status), nil
+	default:
+		return "", fmt.Errorf("invalid status %q: must be %s or %s", status, StatusError, StatusOK)
+	}
+}
+
+// OperationMatch is how an operation filter compares span names
+type OperationMatch string
+
+const (
+	// OperationExact keeps traces with a span of exactly the operation's name
+	OperationExact OperationMatch = "exact"
+	// OperationPrefix keeps traces with a span whose name starts with the operation's
+	OperationPrefix OperationMatch = "prefix"
+	// OperationGlob keeps traces with a span whose name matches the operation as a glob,
+	// where * matches any run of characters and ? any one character
+	OperationGlob OperationMatch = "glob"
+)
+
+// ParseOperationMatch validates an operation match mode received from a client
+func ParseOperationMatch(match string) (OperationMatch, error) {
+	switch OperationMatch(match) {
+	case OperationExact, OperationPrefix, OperationGlob:
+		return OperationMatch(match), nil
+	default:
+		return "", fmt.Errorf("invalid operation match %q: must be %s, %s, or %s", match, OperationExact, OperationPrefix, OperationGlob)
+	}
+}
+
+// comparison returns the SQL comparison of a span name with an operation placeholder
+func (match OperationMatch) comparison() string {
+	switch match {
+	case OperationPrefix:
+		return "starts_with(operations.name, ?)"
+	case OperationGlob:
+		return "operations.name GLOB ?"
+	default:
+		return "operations.name = ?"
+	}
+}
+
+// AttributeFilter restricts trace summaries to traces with a span carrying an attribute.
+// Only span attributes are matched, not those of the span's resource or scope.
+type AttributeFilter struct {
+	// Key is either an attribute key, looked up whole, or a JSONPath starting with $
+	// into the lists and maps of attribute values, as parseAttributePath returns it
+	Key string
+	// Value is only compared when HasValue is set; otherwise carrying the key is enough.
+	// A value that parses as a finite number matches attributes that are equal as numbers,
+	// so 42 matches 42, 42.0 and "42". Any other value matches attributes whose text is
+	// exactly the same, which for booleans is true or false.
+	Value    string
+	HasValue bool
+	// RootOnly only matches the attributes of the trace's root spans
+	RootOnly bool
+}
+
+// ParseAttributeFilter parses an attribute filter received from a client, either
+// key=value or just key. Only the first = separates the two, so values may contain more.
+// A key starting with $ is a path into nested attribute values, in the syntax
+// parseAttributePath reads, and = may appear inside its quoted keys.
+func ParseAttributeFilter(filter string) (AttributeFilter, error) {
+	if strings.HasPrefix(filter, "$") {
+		path, rest, err := parseAttributePath(filter)
+		if err != nil {
+			return AttributeFilter{}, fmt.Errorf("invalid attribute path %q: %s", filter, err.Error())
+		}
+		value, hasValue := strings.CutPrefix(rest, "=")
+		if rest != "" && !hasValue {
+			return AttributeFilter{}, fmt.Errorf("invalid attribute path %q: unexpected %q", filter, rest)
+		}
+		return AttributeFilter{Key: path, Value: value, HasValue: hasValue}, nil
+	}
+
+	key, value, hasValue := strings.Cut(filter, "=")
+	if key == "" {
+		return AttributeFilter{}, fmt.Errorf("invalid attribute filter %q: must be key=value or key", filter)
+	}
+	return AttributeFilter{Key: key, Value: value, HasValue: hasValue}, nil
+}
+
+// condition returns the SQL condition for the filter, along with the arguments for its placeholders
+func (filter AttributeFilter) condition() (string, []any) {
+	spans := ""
+	if filter.RootOnly {
+		spans = ROOT_SPANS_ONLY
+	}
+
+	if !filter.HasValue {
+		return fmt.Sprintf(ATTRIBUTE_EXISTS_CONDITION, spans), []any{filter.Key}
+	}
+	if number, err := strconv.ParseFloat(filter.Value, 64); err == nil && !math.IsInf(number, 0) && !math.IsNaN(number) {
+		return fmt.Sprintf(ATTRIBUTE_EQUALS_NUMBER_CONDITION, spans), []any{filter.Key, number}
+	}
+	return fmt.Sprintf(ATTRIBUTE_EQUALS_CONDITION, spans), []any{filter.Key, filter.Value}
+}
+
+// SummaryQuery describes which page of trace summaries to return and how to order them.
+// The zero value returns every summary ordered by most recent activity, newest first.
+type SummaryQuery struct {
+	Limit  int
+	Offset int
+
+	// SortBy is empty for the default ordering
+	SortBy    SortKey
+	Ascending bool
+
+	// Services restricts the results to traces whose root span came from one of these services
+	Services []string
+
+	// MinDuration and MaxDuration bound the root span's duration (inclusive); zero leaves a bound open
+	MinDuration time.Duration
+	MaxDuration time.Duration
+
+	// Start and End bound the root span's start time (inclusive); the zero time leaves a bound open
+	Start time.Time
+	End   time.Time
+
+	// Search restricts the results to traces with a span whose name, status message,
+	// or any attribute value contains this text (case-insensitive)
+	Search string
+	// SearchEvents has Search match the names and attribute values of span events too,
+	// and lists the events that matched in each summary
+	SearchEvents bool
+
+	// EventNames restricts the results to traces with an event of every one of these names,
+	// and lists those events in each summary
+	EventNames []string
+
+	// Operation restricts the results to traces with a span of this name, compared as
+	// OperationMatch says, or exactly when it is empty
+	Operation      string
+	OperationMatch OperationMatch
+
+	// Status is empty to return traces whether or not they contain errors
+	Status StatusFilter
+
+	// TraceIDs restricts the results to these traces
+	TraceIDs []string
+
+	// Attributes restricts the results to traces matching every one of these filters,
+	// each of which may be matched by a different span
+	Attributes []AttributeFilter
+
+	// Groups restricts the results to traces in any of these groups, as WithGroupAttribute
+	// sorts them into
+	Groups []string
+
+	// groupAttribute is the store's, filled in by QueryTraceSummaries for where to use
+	groupAttribute string
+}
+
+// GetTraceSummaries returns a page of trace summaries, newest first, along with the total
+// number of traces in the store. A limit of zero or less returns every summary past the offset.
+func (s *Store) GetTraceSummaries(ctx context.Context, limit int, offset int) (*[]telemetry.TraceSummary, int, error) {
+	return s.QueryTraceSummaries(ctx, SummaryQuery{Limit: limit, Offset: offset})
+}
+
+// GetTraceSummariesByService returns a page of trace summaries for traces whose root span
+// came from any of the given services, along with the total number of matching traces.
+func (s *Store) GetTraceSummariesByService(ctx context.Context, services []string, limit int, offset int) (*[]telemetry.TraceSummary, int, error) {
+	return s.QueryTraceSummaries(ctx, SummaryQuery{Limit: limit, Offset: offset, Services: services})
+}
+
+// SearchSpans returns summaries of every trace containing a span whose name, status message,
+// or any attribute value contains the query text (case-insensitive). Each trace appears once.
+func (s *Store) SearchSpans(ctx context.Context, query string) (*[]telemetry.TraceSummary, error) {
+	summaries, _, err := s.QueryTraceSummaries(ctx, SummaryQuery{Search: query})
+	return summaries, err
+}
+
+// FilterByAttributePath returns summaries of every trace containing a span with a value at
+// the path into its attributes, such as $."http.response.header"."content_type", that is equal
+// to the given one. Values are compared as AttributeFilter compares them.
+func (s *Store) FilterByAttributePath(ctx context.Context, path string, value string) (*[]telemetry.TraceSummary, error) {
+	jsonPath, rest, err := parseAttributePath(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attribute path %q: %s", path, err.Error())
+	} else if rest != "" {
+		return nil, fmt.Errorf("invalid attribute path %q: unexpected %q", path, rest)
+	}
+
+	summaries, _, err := s.QueryTraceSummaries(ctx, SummaryQuery{
+		Attributes: []AttributeFilter{{Key: jsonPath, Value: value, HasValue: true}},
+	})
+	return summaries, err
+}
+
+// FindTracesByRootAttribute returns summaries of every trace whose root span has the attribute
+// key set to value, compared as AttributeFilter compares them. It is meant for IDs that services
+// stamp on the root span of each request, such as request.id, which needn't be unique.
+func (s *Store) FindTracesByRootAttribute(ctx context.Context, key string, value string) (*[]telemetry.TraceSummary, error) {
+	summaries, _, err := s.QueryTraceSummaries(ctx, SummaryQuery{
+		Attributes: []AttributeFilter{{Key: key, Value: value, HasValue: true, RootOnly: true}},
+	})
+	return summaries, err
+}
+
+// ResolveLinks marks every span link in the trace as resolved or not, depending on whether
+// the linked trace is in the store, and fills in the root of each linked trace that is.
+// The summaries of all the linked traces are looked up at once.
+func (s *Store) ResolveLinks(ctx context.Context, trace *telemetry.TraceData) error {
+	traceIDs := []string{}
+	for _, span := range trace.Spans {
+		for _, link := range span.Links {
+			if !slices.Contains(traceIDs, link.TraceID) {
+				traceIDs = append(traceIDs, link.TraceID)
+			}
+		}
+	}
+	if len(traceIDs) == 0 {
+		return nil
+	}
+
+	summaries, _, err := s.QueryTraceSummaries(ctx, SummaryQuery{TraceIDs: traceIDs})
+	if err != nil {
+		return fmt.Errorf("could not resolve span links: %s", err.Error())
+	}
+	summariesByID := map[string]telemetry.TraceSummary{}
+	for _, summary := range *summaries {
+		summariesByID[summary.TraceID] = summary
+	}
+
+	for i := range trace.Spans {
+		for j := range trace.Spans[i].Links {
+			link := &trace.Spans[i].Links[j]
+			summary, ok := summariesByID[link.TraceID]
+			link.Resolved = &ok
+			if ok {
+				link.RootServiceName = summary.RootServiceName
+				link.RootName = summary.RootName
+			}
+		}
+	}
+	return nil
+}
+
+// QueryTraceSummaries returns the page of trace summaries described by the query,
+// along with the total number of traces matching its filters. The page and the count are
+// read in one transaction, within a read session, so they always agree.
+func (s *Store) QueryTraceSummaries(ctx context.Context, query SummaryQuery) (*[]telemetry.TraceSummary, int, error) {
+	orderBy, err := query.orderBy()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query.groupAttribute = s.groupAttribute
+	summaries := []telemetry.TraceSummary{}
+	totalCount := 0
+	err = s.ReadSession(ctx, func(ctx context.Context) error {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("could not begin transaction: %s", err.Error())
+		}
+		defer tx.Rollback()
+
+		where, filterArgs := query.where()
+		countStatement := fmt.Sprintf(COUNT_TRACE_SUMMARIES, SELECT_TRACE_SUMMARIES+where)
+		if err := tx.QueryRowContext(ctx, countStatement, filterArgs...).Scan(&totalCount); err != nil {
+			return fmt.Errorf("could not count traces: %s", err.Error())
+		}
+
+		statement, pageArgs := paginate(SELECT_TRACE_SUMMARIES+where+orderBy, query.Limit, query.Offset)
+		rows, err := tx.QueryContext(ctx, statement, append(filterArgs, pageArgs...)...)
+		if err != nil {
+			return fmt.Errorf("could not retrieve trace summaries: %s", err.Error())
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			summary, err := scanTraceSummary(rows)
+			if err != nil {
+				return err
+			}
+			summaries = append(summaries, summary)
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("could not retrieve trace summaries: %s", err.Error())
+		}
+
+		if (query.Search != "" && query.SearchEvents) || len(query.EventNames) > 0 {
+			return s.addMatchedEvents(ctx, summaries, query)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return &summaries, totalCount, nil
+}
+
+// where builds the WHERE clause for the query's filters, along with the arguments for its placeholders
+func (query SummaryQuery) where() (string, []any) {
+	conditions := []string{}
+	args := []any{}
+
+	// Traces without a root span have a NULL service name and never match
+	if len(query.Services) > 0 {
+		conditions = append(conditions, fmt.Sprintf("roots.rootServiceName IN (%s)", placeholders(len(query.Services))))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+
+	// As are traces without a root span when filtering by duration or start time
+	if query.MinDuration > 0 {
+		conditions = append(conditions, rootDuration+" >= ?")
+		args = append(args, query.MinDuration.Nanoseconds())
+	}
+	if query.MaxDuration > 0 {
+		conditions = append(conditions, rootDuration+" <= ?")
+		args = append(args, query.MaxDuration.Nanoseconds())
+	}
+
+	if !query.Start.IsZero() {
+		conditions = append(conditions, "roots.rootStartTime >= ?")
+		args = append(args, query.Start)
+	}
+	if !query.End.IsZero() {
+		conditions = append(conditions, "roots.rootStartTime <= ?")
+		args = append(args, query.End)
+	}
+
+	if query.Search != "" && query.SearchEvents {
+		conditions = append(conditions, SEARCH_SPANS_AND_EVENTS_CONDITION)
+		pattern := likePattern(query.Search)
+		args = append(args, pattern, pattern, pattern, pattern, pattern)
+	} else if query.Search != "" {
+		conditions = append(conditions, SEARCH_SPANS_CONDITION)
+		pattern := likePattern(query.Search)
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	for _, name := range query.EventNames {
+		conditions = append(conditions, EVENT_NAME_CONDITION)
+		args = append(args, name)
+	}
+
+	if query.Operation != "" {
+		conditions = append(conditions, fmt.Sprintf(OPERATION_CONDITION, query.OperationMatch.comparison()))
+		args = append(args, query.Operation)
+	}
+
+	if len(query.TraceIDs) > 0 {
+		conditions = append(conditions, fmt.Sprintf("traces.traceID IN (%s)", placeholders(len(query.TraceIDs))))
+		for _, traceID := range query.TraceIDs {
+			args = append(args, traceID)
+		}
+	}
+
+	for _, filter := range query.Attributes {
+		condition, filterArgs := filter.condition()
+		conditions = append(conditions, condition)
+		args = append(args, filterArgs...)
+	}
+
+	if len(query.Groups) > 0 {
+		condition, groupArgs := groupsCondition(query.groupAttribute, query.Groups)
+		conditions = append(conditions, condition)
+		args = append(args, groupArgs...)
+	}
+
+	switch query.Status {
+	case StatusError:
+		conditions = append(conditions, TRACE_HAS_ERROR_CONDITION)
+	case StatusOK:
+		conditions = append(conditions, "NOT "+TRACE_HAS_ERROR_CONDITION)
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// orderBy builds the ORDER BY clause for the query. Traces without a root span have nothing
+// to sort on, so they always come last, and the trace ID breaks ties to keep pages stable.
+func (query SummaryQuery) orderBy() (string, error) {
+	if query.SortBy == "" {
+		return " ORDER BY traces.lastStartTime DESC, traces.traceID", nil
+	}
+
+	column, ok := sortColumns[query.SortBy]
+	if !ok {
+		return "", fmt.Errorf("invalid sort key %q", query.SortBy)
+	}
+
+	direction := "DESC"
+	if query.Ascending {
+		direction = "ASC"
+	}
+	return fmt.Sprintf(" ORDER BY roots.traceID IS NULL, %s %s, traces.traceID", column, direction), nil
+}
+
+// paginate appends LIMIT and OFFSET clauses to a query. DuckDB has no "no limit" value,
+// so the LIMIT clause is left off entirely when limit is zero or less.
+func paginate(query string, limit int, offset int) (string, []any) {
+	args := []any{}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+	if offset > 0 {
+		query += " OFFSET ?"
+		args = append(args, offset)
+	}
+	return query, args
+}
+
+// likePattern builds an ILIKE pattern that matches the text anywhere in a string,
+// escaping any LIKE wildcards so they are matched literally
+func likePattern(text string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(text)
+	return "%" + escaped + "%"
+}
+
+// placeholders returns a comma-separated list of n query placeholders for use in an IN clause
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+// scanTraceSummary reads a row produced by SELECT_TRACE_SUMMARIES.
+// The root span columns are NULL for traces whose root span has not arrived (yet).
+func scanTraceSummary(rows *sql.Rows) (telemetry.TraceSummary, error) {
+	summary := telemetry.TraceSummary{
+		HasRootSpan:       false,
+		RootServiceName:   "",
+		RootName:          "",
+		RootStartTime:     time.Time{},
+		RootEndTime:       time.Time{},
+		RootDurationNanos: 0,
+		SpanCount:         0,
+		TraceID:           "",
+	}
+
+	var rootServiceName, rootName sql.NullString
+	var rootStartTime, rootEndTime sql.NullTime
+	var annotations []byte
+
+	if err := rows.Scan(
+		&summary.TraceID,
+		&summary.SpanCount,
+		&rootServiceName,
+		&rootName,
+		&rootStartTime,
+		&rootEndTime,
+		&annotations,
+		&summary.Pinned,
+	); err != nil {
+		return summary, fmt.Errorf("could not scan trace summary: %s", err.Error())
+	}
+
+	if annotations != nil {
+		if err := json.Unmarshal(annotations, &summary.Annotations); err != nil {
+			return summary, fmt.Errorf("could not unmarshal trace annotations: %s", err.Error())
+		}
+	}
+
+	if rootName.Valid {
+		summary.HasRootSpan = true
+		summary.RootServiceName = rootServiceName.String
+		summary.RootName = rootName.String
+		summary.RootStartTime = rootStartTime.Time
+		summary.RootEndTime = rootEndTime.Time
+		summary.RootDurationNanos = telemetry.DurationNanos(summary.RootStartTime, summary.RootEndTime)
+	}
+	return summary, nil
+}
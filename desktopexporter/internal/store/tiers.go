@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// WithSpillover keeps recent traces in an in-memory database for speed, and moves those the
+// policy no longer allows keeping there to the database file whenever Spill is called. Queries
+// read both tiers as one. Logs, metrics, and annotations are kept in memory while the store is open, and
+// everything is written to the file when it closes, so it can be opened like any other.
+// Without a database file there is nowhere to spill to, and the option does nothing.
+func WithSpillover(policy RetentionPolicy) Option {
+	return func(s *Store) {
+		s.spillPolicy = policy
+	}
+}
+
+// tiered reports whether the store keeps its spans in two tiers
+func (s *Store) tiered() bool {
+	return s.spillPolicy.Enabled() && s.dbPath != "" && !s.readOnly
+}
+
+// hotSpansTable is the table new spans are written to
+func (s *Store) hotSpansTable() string {
+	if s.tiered() {
+		return "hot_spans"
+	}
+	return "spans"
+}
+
+// spansTables lists the tables spans are deleted from
+func (s *Store) spansTables() []string {
+	if s.tiered() {
+		return []string{"hot_spans", "cold.spans"}
+	}
+	return []string{"spans"}
+}
+
+// connectTiers opens an in-memory database with the database file attached to it as the cold tier
+func connectTiers(ctx context.Context, dbPath string) (*sql.DB, driver.Conn, error) {
+	// Opening the file on its own first migrates and validates it like any other
+	db, conn, err := connect(ctx, dbPath, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.Close()
+	if err = db.Close(); err != nil {
+		return nil, nil, fmt.Errorf("could not close the database file: %s", err.Error())
+	}
+
+	db, conn, err = connect(ctx, "", false)
+	if err != nil {
+		return nil, nil, err
+	}
+	closeAll := func() {
+		conn.Close()
+		db.Close()
+	}
+
+	if _, err = db.ExecContext(ctx, fmt.Sprintf(ATTACH_SPILL_FILE, strings.ReplaceAll(dbPath, "'", "''"))); err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("could not attach the database file: %s", err.Error())
+	}
+	if _, err = db.ExecContext(ctx, SPLIT_SPANS_INTO_TIERS); err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("could not split spans into tiers: %s", err.Error())
+	}
+	if _, err = db.ExecContext(ctx, LOAD_SPILLED_TABLES); err != nil {
+		closeAll()
+		return nil, nil, fmt.Errorf("could not load logs, metrics, and annotations: %s", err.Error())
+	}
+	return db, conn, nil
+}
+
+// Spill moves the traces the spillover policy no longer allows keeping in memory as of now
+// to the database file, and returns the number of traces moved. Spans that arrive for a trace
+// after it was moved are kept in memory until they are moved in turn.
+func (s *Store) Spill(ctx context.Context, now time.Time) (int, error) {
+	if !s.tiered() {
+		return 0, nil
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	spilled := 0
+	if s.spillPolicy.MaxAge > 0 {
+		n, err := s.spillLocked(ctx, fmt.Sprintf(SELECT_TRACES_OLDER_THAN, "hot_spans"), now.Add(-s.spillPolicy.MaxAge))
+		if err != nil {
+			return spilled, err
+		}
+		spilled += n
+	}
+
+	if s.spillPolicy.MaxTraces > 0 {
+		n, err := s.spillLocked(ctx, fmt.Sprintf(SELECT_TRACES_BEYOND_COUNT, "hot_spans"), s.spillPolicy.MaxTraces)
+		if err != nil {
+			return spilled, err
+		}
+		spilled += n
+	}
+
+	return spilled, nil
+}
+
+// spillLocked moves the traces a selection picks to the database file. DuckDB only lets
+// a transaction write to one database, so they are copied over before they are deleted.
+// It must be called with s.mut held.
+func (s *Store) spillLocked(ctx context.Context, selection string, args ...any) (int, error) {
+	spilled := map[string]bool{}
+	err := s.withSelectedTraces(ctx, selection, args, func(conn *sql.Conn) error {
+		if _, err := conn.ExecContext(ctx, SPILL_SELECTED_TRACES); err != nil {
+			return err
+		}
+		return deleteSelectedTraces(ctx, conn, "hot_spans", spilled)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("could not spill traces: %s", err.Error())
+	}
+	return len(spilled), nil
+}
+
+// spillAll writes everything held in memory to the database file, for when the store closes
+func (s *Store) spillAll(ctx context.Context) error {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if _, err := s.db.ExecContext(ctx, SPILL_EVERYTHING); err != nil {
+		return fmt.Errorf("could not spill to the database file: %s", err.Error())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// traceSizeBounds are the largest span counts of each trace size bucket but the last, which
+// takes every trace bigger than that
+var traceSizeBounds = []int{1, 5, 20, 100}
+
+// TraceSizeQuery scopes the trace size histogram to traces with a span from one of a set of
+// services, whose first span started within a time window. Zero values don't filter anything.
+type TraceSizeQuery struct {
+	Services []string
+	Start    time.Time
+	End      time.Time
+}
+
+// GetTraceSizes has DuckDB count the spans of every trace and tally the traces into buckets
+// by span count. Every bucket is returned, even those without any traces.
+func (s *Store) GetTraceSizes(ctx context.Context, query TraceSizeQuery) (telemetry.TraceSizeHistogram, error) {
+	histogram := telemetry.TraceSizeHistogram{Buckets: newTraceSizeBuckets()}
+
+	bucket := "CASE"
+	args := []any{}
+	for i, bound := range traceSizeBounds {
+		bucket += fmt.Sprintf(" WHEN spanCount <= ? THEN %d", i)
+		args = append(args, bound)
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(traceSizeBounds))
+
+	conditions, conditionArgs := query.conditions()
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_TRACE_SIZES, bucket, conditions), append(args, conditionArgs...)...)
+	if err != nil {
+		return histogram, fmt.Errorf("could not retrieve trace sizes: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var index, traceCount, largestSpans int
+		var largestTraceID string
+		if err = rows.Scan(&index, &traceCount, &largestSpans, &largestTraceID); err != nil {
+			return histogram, fmt.Errorf("could not scan trace sizes: %s", err.Error())
+		}
+		histogram.Buckets[index].TraceCount = traceCount
+		histogram.TotalTraces += traceCount
+		if largestSpans > histogram.LargestTraceSpans {
+			histogram.LargestTraceSpans = largestSpans
+			histogram.LargestTraceID = largestTraceID
+		}
+	}
+	return histogram, rows.Err()
+}
+
+func newTraceSizeBuckets() []telemetry.TraceSizeBucket {
+	buckets := []telemetry.TraceSizeBucket{}
+	from := 1
+	for _, bound := range traceSizeBounds {
+		label := fmt.Sprintf("%d-%d", from, bound)
+		if from == bound {
+			label = fmt.Sprint(from)
+		}
+		buckets = append(buckets, telemetry.TraceSizeBucket{Label: label, MinSpans: from, MaxSpans: bound})
+		from = bound + 1
+	}
+	return append(buckets, telemetry.TraceSizeBucket{Label: fmt.Sprintf("%d+", from), MinSpans: from})
+}
+
+// conditions are ANDed into the HAVING clause of the spans grouped by trace
+func (query TraceSizeQuery) conditions() (string, []any) {
+	conditions := ""
+	args := []any{}
+
+	if len(query.Services) > 0 {
+		conditions += fmt.Sprintf(" AND bool_or(serviceName IN (%s))", placeholders(len(query.Services)))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+	if !query.Start.IsZero() {
+		conditions += " AND min(startTime) >= ?"
+		args = append(args, query.Start)
+	}
+	if !query.End.IsZero() {
+		conditions += " AND min(startTime) <= ?"
+		args = append(args, query.End)
+	}
+	return conditions, args
+}
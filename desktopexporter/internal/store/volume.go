@@ -0,0 +1,68 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+)
+
+// TraceVolumeQuery picks the buckets GetTraceVolume counts traces in: as many buckets of Bucket
+// as it takes to cover Window, the last of them holding End. Services keeps to traces with a span
+// from one of them, and doesn't filter anything when empty.
+type TraceVolumeQuery struct {
+	Bucket   time.Duration
+	Window   time.Duration
+	End      time.Time
+	Services []string
+}
+
+// GetTraceVolume has DuckDB bucket traces by the time their first span started, counting them
+// along with those that have a failed span. Buckets are aligned to multiples of the bucket
+// size, and every bucket in the window is returned, even those without any traces.
+func (s *Store) GetTraceVolume(ctx context.Context, query TraceVolumeQuery) (telemetry.TraceVolume, error) {
+	bucketCount := int((query.Window + query.Bucket - 1) / query.Bucket)
+	end := query.End.Truncate(query.Bucket).Add(query.Bucket)
+	start := end.Add(-time.Duration(bucketCount) * query.Bucket)
+
+	volume := telemetry.TraceVolume{
+		Start:       start.UTC(),
+		End:         end.UTC(),
+		BucketNanos: query.Bucket.Nanoseconds(),
+		Buckets:     make([]telemetry.TraceVolumeBucket, bucketCount),
+	}
+	for i := range volume.Buckets {
+		volume.Buckets[i].Start = start.Add(time.Duration(i) * query.Bucket).UTC()
+	}
+
+	args := []any{query.Bucket.Microseconds(), start, start, end}
+	conditions := ""
+	if len(query.Services) > 0 {
+		conditions = fmt.Sprintf(" AND bool_or(serviceName IN (%s))", placeholders(len(query.Services)))
+		for _, service := range query.Services {
+			args = append(args, service)
+		}
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(SELECT_TRACE_VOLUME, conditions), args...)
+	if err != nil {
+		return volume, fmt.Errorf("could not retrieve trace volume: %s", err.Error())
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var bucketStart time.Time
+		var traceCount, errorCount int
+		if err = rows.Scan(&bucketStart, &traceCount, &errorCount); err != nil {
+			return volume, fmt.Errorf("could not scan trace volume: %s", err.Error())
+		}
+		i := int(bucketStart.Sub(start) / query.Bucket)
+		if i < 0 || i >= bucketCount {
+			continue
+		}
+		volume.Buckets[i].TraceCount = traceCount
+		volume.Buckets[i].ErrorCount = errorCount
+	}
+	return volume, rows.Err()
+}
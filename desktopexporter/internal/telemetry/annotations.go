@@ -0,0 +1,10 @@
+package telemetry
+
+// Annotations are free-form notes left on a trace by whoever is looking into it,
+// such as {"status": "investigated", "note": "retry storm after deploy"}
+type Annotations map[string]any
+
+type TraceAnnotations struct {
+	TraceID     string      `json:"traceID"`
+	Annotations Annotations `json:"annotations"`
+}
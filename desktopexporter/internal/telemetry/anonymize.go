@@ -0,0 +1,128 @@
+package telemetry
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Anonymizer replaces the names in spans with tokens, so that traces can be shared without giving
+// away what the services, operations, and values of its keys are called. Each name is hashed with
+// a salt of the Anonymizer's own, so the same name always gets the same token from one Anonymizer,
+// keeping the shape of a trace and the calls between services intact, while tokens can't be
+// matched against hashes of guessed names, nor across Anonymizers.
+type Anonymizer struct {
+	keys map[string]bool
+	salt []byte
+}
+
+// NewAnonymizer builds an Anonymizer that hashes service and span names, and the values of the
+// given attribute keys
+func NewAnonymizer(keys []string) *Anonymizer {
+	anonymizer := &Anonymizer{keys: map[string]bool{}, salt: make([]byte, 32)}
+	for _, key := range keys {
+		anonymizer.keys[key] = true
+	}
+	rand.Read(anonymizer.salt)
+	return anonymizer
+}
+
+// AnonymizeSpans hashes the name of each span, the service.name of its resource and any
+// peer.service attribute, and the values of the Anonymizer's keys wherever they appear in the
+// attributes of the span, its resource, scope, events and links, nested values included. The
+// values of its keys that aren't strings are hashed in their JSON form. Like a Redactor's, the
+// anonymized attributes are copies.
+func (a *Anonymizer) AnonymizeSpans(spans []SpanData) {
+	for i := range spans {
+		span := &spans[i]
+		span.Name = a.token("span", span.Name)
+		span.Attributes = a.anonymizeAttributes(span.Attributes)
+
+		if span.Resource != nil {
+			resource := *span.Resource
+			resource.Attributes = a.anonymizeAttributes(resource.Attributes)
+			span.Resource = &resource
+		}
+		if span.Scope != nil {
+			scope := *span.Scope
+			scope.Attributes = a.anonymizeAttributes(scope.Attributes)
+			span.Scope = &scope
+		}
+
+		if span.Events != nil {
+			events := make([]EventData, len(span.Events))
+			for j, event := range span.Events {
+				event.Attributes = a.anonymizeAttributes(event.Attributes)
+				events[j] = event
+			}
+			span.Events = events
+		}
+		if span.Links != nil {
+			links := make([]LinkData, len(span.Links))
+			for j, link := range span.Links {
+				link.Attributes = a.anonymizeAttributes(link.Attributes)
+				links[j] = link
+			}
+			span.Links = links
+		}
+	}
+}
+
+func (a *Anonymizer) anonymizeAttributes(attributes Attributes) Attributes {
+	if attributes == nil {
+		return nil
+	}
+	return Attributes(a.anonymizeMap(attributes))
+}
+
+func (a *Anonymizer) anonymizeMap(values map[string]interface{}) map[string]interface{} {
+	anonymized := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		switch {
+		// Both name services, so a call and the service it calls get the same token
+		case key == "service.name" || key == "peer.service":
+			anonymized[key] = a.token("service", a.text(value))
+		case a.keys[key]:
+			anonymized[key] = a.token("value", a.text(value))
+		default:
+			anonymized[key] = a.anonymizeValue(value)
+		}
+	}
+	return anonymized
+}
+
+func (a *Anonymizer) anonymizeValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		return a.anonymizeMap(value)
+	case []interface{}:
+		anonymized := make([]interface{}, len(value))
+		for i, element := range value {
+			anonymized[i] = a.anonymizeValue(element)
+		}
+		return anonymized
+	default:
+		return value
+	}
+}
+
+// text is a string value as it is, and any other value in its JSON form
+func (a *Anonymizer) text(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+	text, err := json.Marshal(value)
+	if err != nil {
+		return ""
+	}
+	return string(text)
+}
+
+// token hashes a name into a token that says what kind of name it was
+func (a *Anonymizer) token(kind string, name string) string {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(name))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil)[:6])
+}
@@ -0,0 +1,38 @@
+package telemetry
+
+// AttributeKey is a span attribute key, and the number of spans that have it
+type AttributeKey struct {
+	Key       string `json:"key"`
+	SpanCount uint32 `json:"spanCount"`
+}
+
+type AttributeKeyList struct {
+	Keys []AttributeKey `json:"keys"`
+}
+
+// AttributeCardinality is a span attribute key with the approximate number of distinct values
+// it takes, and the number of spans that have it
+type AttributeCardinality struct {
+	Key            string `json:"key"`
+	DistinctValues uint64 `json:"distinctValues"`
+	SpanCount      uint32 `json:"spanCount"`
+}
+
+type AttributeCardinalityList struct {
+	Keys []AttributeCardinality `json:"keys"`
+}
+
+// AttributeValue is one value of a span attribute, in its JSON form when it isn't a string,
+// and the number of spans with it
+type AttributeValue struct {
+	Value     string `json:"value"`
+	SpanCount uint32 `json:"spanCount"`
+}
+
+// AttributeValues lists the most common values of a span attribute first. Truncated is set
+// when the key has more distinct values than were asked for.
+type AttributeValues struct {
+	Key       string           `json:"key"`
+	Values    []AttributeValue `json:"values"`
+	Truncated bool             `json:"truncated"`
+}
@@ -0,0 +1,93 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Attributes holds attribute values as pcommon.Map.AsRaw returns them: string, bool, int64,
+// float64, []any, and map[string]any. Its JSON form writes whole floats with a trailing ".0",
+// and reading it back turns numbers into int64 or float64 accordingly, so attribute values keep
+// their types through the store. Bytes values are the exception, and come back as base64 strings.
+type Attributes map[string]interface{}
+
+func (attributes Attributes) MarshalJSON() ([]byte, error) {
+	if attributes == nil {
+		return []byte("null"), nil
+	}
+	return json.Marshal(markFloats(map[string]interface{}(attributes)))
+}
+
+func (attributes *Attributes) UnmarshalJSON(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	raw := map[string]interface{}{}
+	if err := decoder.Decode(&raw); err != nil {
+		return err
+	}
+	if raw == nil {
+		*attributes = nil
+		return nil
+	}
+	*attributes = restoreNumbers(raw).(map[string]interface{})
+	return nil
+}
+
+// markFloats replaces whole floats with json.Numbers that keep a fractional part,
+// as encoding/json would otherwise write 2.0 as 2, indistinguishable from an int
+func markFloats(value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e21 {
+			return json.Number(strconv.FormatFloat(v, 'f', -1, 64) + ".0")
+		}
+		return v
+	case []interface{}:
+		marked := make([]interface{}, len(v))
+		for i, item := range v {
+			marked[i] = markFloats(item)
+		}
+		return marked
+	case map[string]interface{}:
+		marked := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			marked[key] = markFloats(item)
+		}
+		return marked
+	default:
+		return v
+	}
+}
+
+// restoreNumbers turns json.Numbers into int64s, unless they have a fraction or an exponent
+// or don't fit, in which case they become float64s
+func restoreNumbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err == nil {
+				return i
+			}
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v.String()
+	case []interface{}:
+		for i, item := range v {
+			v[i] = restoreNumbers(item)
+		}
+		return v
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = restoreNumbers(item)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,171 @@
+package telemetry
+
+import (
+	"cmp"
+	"slices"
+)
+
+// TraceComparison lines up the spans of two traces, such as a fast and a slow run of the same
+// request, to show which operations took longer in B than in A
+type TraceComparison struct {
+	TraceIDA string `json:"traceIDA"`
+	TraceIDB string `json:"traceIDB"`
+	// DurationDeltaNanos is the duration of trace B minus that of trace A
+	DurationDeltaNanos int64 `json:"durationDeltaNanos"`
+	// Operations are ordered by the size of their delta, largest first
+	Operations []OperationDelta `json:"operations"`
+	Matches    []SpanMatch      `json:"matches"`
+	OnlyInA    []SpanData       `json:"onlyInA"`
+	OnlyInB    []SpanData       `json:"onlyInB"`
+}
+
+// OperationDelta sums up the durations of the spans sharing a name in each trace
+type OperationDelta struct {
+	Name                string `json:"name"`
+	SpanCountA          int    `json:"spanCountA"`
+	SpanCountB          int    `json:"spanCountB"`
+	TotalDurationNanosA int64  `json:"totalDurationNanosA"`
+	TotalDurationNanosB int64  `json:"totalDurationNanosB"`
+	DeltaNanos          int64  `json:"deltaNanos"`
+}
+
+// SpanMatch is a span of trace A and the span of trace B it was aligned with
+type SpanMatch struct {
+	Name           string `json:"name"`
+	SpanIDA        string `json:"spanIDA"`
+	SpanIDB        string `json:"spanIDB"`
+	DurationNanosA int64  `json:"durationNanosA"`
+	DurationNanosB int64  `json:"durationNanosB"`
+	DeltaNanos     int64  `json:"deltaNanos"`
+}
+
+// CompareTraces aligns the span trees of two traces by name. Starting from the roots, the
+// nth span with a given name under a parent in A is matched with the nth span with that name
+// under the matched parent in B, by start time, so repeated calls pair up in the order they
+// were made. Spans left without a partner, along with everything under them, are only in one
+// of the traces.
+func CompareTraces(a TraceData, b TraceData) TraceComparison {
+	comparison := TraceComparison{
+		TraceIDA:           a.TraceID,
+		TraceIDB:           b.TraceID,
+		DurationDeltaNanos: traceDuration(b.Spans) - traceDuration(a.Spans),
+		Operations:         []OperationDelta{},
+		Matches:            []SpanMatch{},
+		OnlyInA:            []SpanData{},
+		OnlyInB:            []SpanData{},
+	}
+	comparison.align(spanTreeRoots(a.Spans), spanTreeRoots(b.Spans))
+
+	operations := map[string]*OperationDelta{}
+	operation := func(name string) *OperationDelta {
+		if _, ok := operations[name]; !ok {
+			operations[name] = &OperationDelta{Name: name}
+		}
+		return operations[name]
+	}
+	for _, span := range a.Spans {
+		op := operation(span.Name)
+		op.SpanCountA++
+		op.TotalDurationNanosA += DurationNanos(span.StartTime, span.EndTime)
+	}
+	for _, span := range b.Spans {
+		op := operation(span.Name)
+		op.SpanCountB++
+		op.TotalDurationNanosB += DurationNanos(span.StartTime, span.EndTime)
+	}
+	for _, op := range operations {
+		op.DeltaNanos = op.TotalDurationNanosB - op.TotalDurationNanosA
+		comparison.Operations = append(comparison.Operations, *op)
+	}
+	slices.SortFunc(comparison.Operations, func(x, y OperationDelta) int {
+		return cmp.Or(
+			cmp.Compare(abs(y.DeltaNanos), abs(x.DeltaNanos)),
+			cmp.Compare(x.Name, y.Name),
+		)
+	})
+
+	return comparison
+}
+
+// align matches up two lists of sibling spans, both earliest first
+func (comparison *TraceComparison) align(a []*SpanNode, b []*SpanNode) {
+	unmatched := slices.Clone(b)
+	for _, nodeA := range a {
+		i := slices.IndexFunc(unmatched, func(nodeB *SpanNode) bool {
+			return nodeB != nil && nodeB.Span.Name == nodeA.Span.Name
+		})
+		if i < 0 {
+			comparison.OnlyInA = appendSubtree(comparison.OnlyInA, nodeA)
+			continue
+		}
+
+		nodeB := unmatched[i]
+		unmatched[i] = nil
+		durationA := DurationNanos(nodeA.Span.StartTime, nodeA.Span.EndTime)
+		durationB := DurationNanos(nodeB.Span.StartTime, nodeB.Span.EndTime)
+		comparison.Matches = append(comparison.Matches, SpanMatch{
+			Name:           nodeA.Span.Name,
+			SpanIDA:        nodeA.SpanID,
+			SpanIDB:        nodeB.SpanID,
+			DurationNanosA: durationA,
+			DurationNanosB: durationB,
+			DeltaNanos:     durationB - durationA,
+		})
+		comparison.align(nodeA.Children, nodeB.Children)
+	}
+
+	for _, nodeB := range unmatched {
+		if nodeB != nil {
+			comparison.OnlyInB = appendSubtree(comparison.OnlyInB, nodeB)
+		}
+	}
+}
+
+// spanTreeRoots returns the spans at the top of a trace's span tree, earliest first. The
+// orphans of a missing parent have nothing to line up by, so they count as roots.
+func spanTreeRoots(spans []SpanData) []*SpanNode {
+	roots := []*SpanNode{}
+	for _, node := range BuildSpanTree(spans) {
+		if node.Span == nil {
+			roots = append(roots, node.Children...)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	slices.SortStableFunc(roots, func(x, y *SpanNode) int {
+		return x.Span.StartTime.Compare(y.Span.StartTime)
+	})
+	return roots
+}
+
+func appendSubtree(spans []SpanData, node *SpanNode) []SpanData {
+	spans = append(spans, *node.Span)
+	for _, child := range node.Children {
+		spans = appendSubtree(spans, child)
+	}
+	return spans
+}
+
+// traceDuration is the time from the earliest span start to the latest span end
+func traceDuration(spans []SpanData) int64 {
+	if len(spans) == 0 {
+		return 0
+	}
+	start, end := spans[0].StartTime, spans[0].EndTime
+	for _, span := range spans[1:] {
+		if span.StartTime.Before(start) {
+			start = span.StartTime
+		}
+		if span.EndTime.After(end) {
+			end = span.EndTime
+		}
+	}
+	return DurationNanos(start, end)
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
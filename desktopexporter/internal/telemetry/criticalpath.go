@@ -0,0 +1,120 @@
+package telemetry
+
+import (
+	"slices"
+	"time"
+)
+
+// CriticalPath is the chain of spans that determined how long a trace took
+type CriticalPath struct {
+	TraceID string `json:"traceID"`
+	// DurationNanos is the duration of the span the path starts from, which the critical
+	// durations of the spans on the path add up to
+	DurationNanos int64              `json:"durationNanos"`
+	Spans         []CriticalPathSpan `json:"spans"`
+}
+
+// CriticalPathSpan is a span on the critical path of a trace
+type CriticalPathSpan struct {
+	SpanID        string    `json:"spanID"`
+	ParentSpanID  string    `json:"parentSpanID"`
+	Name          string    `json:"name"`
+	ServiceName   string    `json:"serviceName"`
+	StartTime     time.Time `json:"startTime"`
+	EndTime       time.Time `json:"endTime"`
+	DurationNanos int64     `json:"durationNanos"`
+	// CriticalDurationNanos is the part of the path during which this span, and none of its
+	// children, was what the trace was waiting on
+	CriticalDurationNanos int64 `json:"criticalDurationNanos"`
+}
+
+// FindCriticalPath walks back from the end of the root span, following at each step the child
+// that ended last before the point reached so far, and what that child was in turn waiting on,
+// then carries on from where the child started. Children that outlived their parent, as async
+// work does, only count until the parent ended, and children that started before it from when
+// it started. The path starts from the root span that ends last, or from the orphan that does
+// should the trace be missing its root. Spans come in the order the walk reaches them: the
+// root, then the child it was waiting on last and so on down, before earlier children.
+func FindCriticalPath(spans []SpanData) CriticalPath {
+	criticalPath := CriticalPath{Spans: []CriticalPathSpan{}}
+	if len(spans) == 0 {
+		return criticalPath
+	}
+	criticalPath.TraceID = spans[0].TraceID
+
+	var root, orphan *SpanNode
+	for _, node := range BuildSpanTree(spans) {
+		if node.Span != nil {
+			root = lastEnding(root, node)
+			continue
+		}
+		for _, child := range node.Children {
+			orphan = lastEnding(orphan, child)
+		}
+	}
+	if root == nil {
+		root = orphan
+	}
+
+	start, end := root.Span.StartTime, spanEnd(*root.Span)
+	criticalPath.DurationNanos = end.Sub(start).Nanoseconds()
+	walkCriticalPath(&criticalPath, root, start, end)
+	return criticalPath
+}
+
+// walkCriticalPath adds a span to the path, along with the children it was waiting on between
+// start and end, the part of the span that is on the path
+func walkCriticalPath(criticalPath *CriticalPath, node *SpanNode, start time.Time, end time.Time) {
+	criticalPath.Spans = append(criticalPath.Spans, CriticalPathSpan{
+		SpanID:        node.Span.SpanID,
+		ParentSpanID:  node.Span.ParentSpanID,
+		Name:          node.Span.Name,
+		ServiceName:   node.Span.GetServiceName(),
+		StartTime:     node.Span.StartTime,
+		EndTime:       node.Span.EndTime,
+		DurationNanos: node.Span.EndTime.Sub(node.Span.StartTime).Nanoseconds(),
+	})
+	index := len(criticalPath.Spans) - 1
+
+	// Taking children latest ending first, the first to have started before the cursor is the
+	// one that ended last before it, so a single pass finds each step of the path
+	children := slices.Clone(node.Children)
+	slices.SortStableFunc(children, func(a, b *SpanNode) int {
+		return spanEnd(*b.Span).Compare(spanEnd(*a.Span))
+	})
+
+	var critical int64
+	cursor := end
+	for _, child := range children {
+		if !cursor.After(start) {
+			break
+		}
+		childStart, childEnd := child.Span.StartTime, spanEnd(*child.Span)
+		if childStart.Before(start) {
+			childStart = start
+		}
+		if childEnd.After(cursor) {
+			childEnd = cursor
+		}
+		if !childEnd.After(childStart) {
+			continue
+		}
+
+		critical += cursor.Sub(childEnd).Nanoseconds()
+		walkCriticalPath(criticalPath, child, childStart, childEnd)
+		cursor = childStart
+	}
+	if cursor.After(start) {
+		critical += cursor.Sub(start).Nanoseconds()
+	}
+	criticalPath.Spans[index].CriticalDurationNanos = critical
+}
+
+// lastEnding returns whichever of two nodes has the span that ends later, keeping the first
+// on a tie. The first may be nil.
+func lastEnding(node *SpanNode, other *SpanNode) *SpanNode {
+	if node == nil || spanEnd(*other.Span).After(spanEnd(*node.Span)) {
+		return other
+	}
+	return node
+}
@@ -6,7 +6,11 @@ import (
 
 var ErrEmptySpansSlice = errors.New("slice of spans associated with this traceID must not be empty")
 var ErrTraceIDNotFound = errors.New("traceID not found")
+var ErrSpanIDNotFound = errors.New("spanID not found")
 var ErrTraceIDMismatch = errors.New("traceID mismatch between TraceStore.traceMap and TraceStore.traceQueue")
 
 var ErrMissingRootSpan = errors.New("warning: trace is incomplete - no root span found")
 var ErrInvalidServiceName = errors.New("warning: Resource.Attributes['service.name'] must be a string value that helps to distinguish a group of services")
+
+var ErrInvalidTraceID = errors.New("trace ID must be from 1 to 32 hex digits")
+var ErrInvalidSpanID = errors.New("span ID must be from 1 to 16 hex digits")
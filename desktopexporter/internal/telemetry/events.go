@@ -11,10 +11,20 @@ type EventPayload struct {
 }
 
 type EventData struct {
-	Name                   string                 `json:"name"`
-	Timestamp              time.Time              `json:"timestamp"`
-	Attributes             map[string]interface{} `json:"attributes"`
-	DroppedAttributesCount uint32                 `json:"droppedAttributesCount"`
+	Name                   string     `json:"name"`
+	Timestamp              time.Time  `json:"timestamp"`
+	Attributes             Attributes `json:"attributes"`
+	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+}
+
+// EventMatch points at a span event that matched a search, with EventIndex
+// giving its position among the span's events
+type EventMatch struct {
+	SpanID     string    `json:"spanID"`
+	SpanName   string    `json:"spanName"`
+	EventIndex int       `json:"eventIndex"`
+	EventName  string    `json:"eventName"`
+	Timestamp  time.Time `json:"timestamp"`
 }
 
 func (payload *EventPayload) extractEvents() []EventData {
@@ -0,0 +1,176 @@
+package telemetry
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// NewTracesFromSpans turns spans back into OTLP traces, grouping them under the resource and scope
+// they were received with, in the order those first appear.
+func NewTracesFromSpans(spans []SpanData) ptrace.Traces {
+	traces := ptrace.NewTraces()
+	resourceSpansByKey := map[string]ptrace.ResourceSpans{}
+	scopeSpansByKey := map[string]ptrace.ScopeSpans{}
+
+	for _, span := range spans {
+		resourceKey := groupingKey(span.Resource)
+		resourceSpans, ok := resourceSpansByKey[resourceKey]
+		if !ok {
+			resourceSpans = traces.ResourceSpans().AppendEmpty()
+			fillResource(resourceSpans.Resource(), span.Resource)
+			resourceSpansByKey[resourceKey] = resourceSpans
+		}
+
+		scopeKey := resourceKey + "\x00" + groupingKey(span.Scope)
+		scopeSpans, ok := scopeSpansByKey[scopeKey]
+		if !ok {
+			scopeSpans = resourceSpans.ScopeSpans().AppendEmpty()
+			fillScope(scopeSpans.Scope(), span.Scope)
+			scopeSpansByKey[scopeKey] = scopeSpans
+		}
+
+		fillSpan(scopeSpans.Spans().AppendEmpty(), span)
+	}
+	return traces
+}
+
+// groupingKey identifies a resource or scope by its contents; encoding/json sorts map keys,
+// so equal contents always give the same key
+func groupingKey(source any) string {
+	key, err := json.Marshal(source)
+	if err != nil {
+		return ""
+	}
+	return string(key)
+}
+
+func fillResource(resource pcommon.Resource, source *ResourceData) {
+	if source == nil {
+		return
+	}
+	fillAttributes(resource.Attributes(), source.Attributes)
+	resource.SetDroppedAttributesCount(source.DroppedAttributesCount)
+}
+
+func fillScope(scope pcommon.InstrumentationScope, source *ScopeData) {
+	if source == nil {
+		return
+	}
+	scope.SetName(source.Name)
+	scope.SetVersion(source.Version)
+	fillAttributes(scope.Attributes(), source.Attributes)
+	scope.SetDroppedAttributesCount(source.DroppedAttributesCount)
+}
+
+func fillSpan(span ptrace.Span, source SpanData) {
+	span.SetTraceID(parseTraceID(source.TraceID))
+	span.TraceState().FromRaw(source.TraceState)
+	span.SetSpanID(parseSpanID(source.SpanID))
+	span.SetParentSpanID(parseSpanID(source.ParentSpanID))
+	span.SetName(source.Name)
+	span.SetKind(parseSpanKind(source.Kind))
+	span.SetStartTimestamp(newTimestamp(source.StartTime))
+	span.SetEndTimestamp(newTimestamp(source.EndTime))
+	fillAttributes(span.Attributes(), source.Attributes)
+
+	for _, event := range source.Events {
+		fillEvent(span.Events().AppendEmpty(), event)
+	}
+	for _, link := range source.Links {
+		fillLink(span.Links().AppendEmpty(), link)
+	}
+
+	span.SetDroppedAttributesCount(source.DroppedAttributesCount)
+	span.SetDroppedEventsCount(source.DroppedEventsCount)
+	span.SetDroppedLinksCount(source.DroppedLinksCount)
+
+	span.Status().SetCode(parseStatusCode(source.StatusCode))
+	span.Status().SetMessage(source.StatusMessage)
+}
+
+func fillEvent(event ptrace.SpanEvent, source EventData) {
+	event.SetName(source.Name)
+	event.SetTimestamp(newTimestamp(source.Timestamp))
+	fillAttributes(event.Attributes(), source.Attributes)
+	event.SetDroppedAttributesCount(source.DroppedAttributesCount)
+}
+
+func fillLink(link ptrace.SpanLink, source LinkData) {
+	link.SetTraceID(parseTraceID(source.TraceID))
+	link.SetSpanID(parseSpanID(source.SpanID))
+	link.TraceState().FromRaw(source.TraceState)
+	fillAttributes(link.Attributes(), source.Attributes)
+	link.SetDroppedAttributesCount(source.DroppedAttributesCount)
+}
+
+// fillAttributes keeps the type of each attribute value
+func fillAttributes(attributes pcommon.Map, source map[string]interface{}) {
+	for key, value := range source {
+		fillValue(attributes.PutEmpty(key), value)
+	}
+}
+
+func fillValue(dest pcommon.Value, value any) {
+	switch v := value.(type) {
+	case []any:
+		slice := dest.SetEmptySlice()
+		for _, item := range v {
+			fillValue(slice.AppendEmpty(), item)
+		}
+	case map[string]any:
+		fillAttributes(dest.SetEmptyMap(), v)
+	default:
+		if err := dest.FromRaw(v); err != nil {
+			dest.SetStr(groupingKey(v))
+		}
+	}
+}
+
+func newTimestamp(t time.Time) pcommon.Timestamp {
+	if t.IsZero() {
+		return 0
+	}
+	return pcommon.NewTimestampFromTime(t)
+}
+
+// parseTraceID leaves the ID empty rather than failing on IDs that are missing or malformed,
+// which the spans we stored might well have
+func parseTraceID(traceID string) pcommon.TraceID {
+	id := pcommon.NewTraceIDEmpty()
+	hex.Decode(id[:], []byte(traceID))
+	return id
+}
+
+func parseSpanID(spanID string) pcommon.SpanID {
+	id := pcommon.NewSpanIDEmpty()
+	hex.Decode(id[:], []byte(spanID))
+	return id
+}
+
+func parseSpanKind(kind string) ptrace.SpanKind {
+	for _, k := range []ptrace.SpanKind{
+		ptrace.SpanKindInternal,
+		ptrace.SpanKindServer,
+		ptrace.SpanKindClient,
+		ptrace.SpanKindProducer,
+		ptrace.SpanKindConsumer,
+	} {
+		if k.String() == kind {
+			return k
+		}
+	}
+	return ptrace.SpanKindUnspecified
+}
+
+func parseStatusCode(code string) ptrace.StatusCode {
+	for _, c := range []ptrace.StatusCode{ptrace.StatusCodeOk, ptrace.StatusCodeError} {
+		if c.String() == code {
+			return c
+		}
+	}
+	return ptrace.StatusCodeUnset
+}
@@ -0,0 +1,71 @@
+package telemetry
+
+// FlameGraph holds the spans of a trace merged by name into frames, ready for a flamegraph renderer
+type FlameGraph struct {
+	TraceID string        `json:"traceID"`
+	Frames  []*FlameFrame `json:"frames"`
+}
+
+// FlameFrame is every span with the same name at the same place in the call stack. A span
+// whose parent has the same name is folded into its parent's frame, so recursive calls
+// don't stack up. Children are ordered by the earliest of their spans.
+type FlameFrame struct {
+	Name      string `json:"name"`
+	SpanCount int    `json:"spanCount"`
+	// SelfDurationNanos sums the time of each span in the frame that none of its children cover
+	SelfDurationNanos int64 `json:"selfDurationNanos"`
+	// TotalDurationNanos is the frame's self duration plus the total durations of its children
+	TotalDurationNanos int64         `json:"totalDurationNanos"`
+	Children           []*FlameFrame `json:"children"`
+}
+
+// BuildFlameGraph merges the span tree of a trace into frames by span name. Spans whose
+// parent is missing from the trace start frames of their own at the top.
+func BuildFlameGraph(spans []SpanData) []*FlameFrame {
+	top := &FlameFrame{Children: []*FlameFrame{}}
+	for _, node := range BuildSpanTree(spans) {
+		if node.Span == nil {
+			for _, orphan := range node.Children {
+				top.child(orphan.Span.Name).addSpan(orphan)
+			}
+			continue
+		}
+		top.child(node.Span.Name).addSpan(node)
+	}
+
+	for _, frame := range top.Children {
+		frame.sumTotals()
+	}
+	return top.Children
+}
+
+func (frame *FlameFrame) child(name string) *FlameFrame {
+	for _, child := range frame.Children {
+		if child.Name == name {
+			return child
+		}
+	}
+	child := &FlameFrame{Name: name, Children: []*FlameFrame{}}
+	frame.Children = append(frame.Children, child)
+	return child
+}
+
+func (frame *FlameFrame) addSpan(node *SpanNode) {
+	frame.SpanCount++
+	frame.SelfDurationNanos += selfDuration(node)
+	for _, child := range node.Children {
+		if child.Span.Name == frame.Name {
+			frame.addSpan(child)
+		} else {
+			frame.child(child.Span.Name).addSpan(child)
+		}
+	}
+}
+
+func (frame *FlameFrame) sumTotals() int64 {
+	frame.TotalDurationNanos = frame.SelfDurationNanos
+	for _, child := range frame.Children {
+		frame.TotalDurationNanos += child.sumTotals()
+	}
+	return frame.TotalDurationNanos
+}
@@ -0,0 +1,78 @@
+package telemetry
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// TraceGaps lists the stretches of a trace during which none of its spans were running
+type TraceGaps struct {
+	TraceID string `json:"traceID"`
+	// DurationNanos runs from the earliest span start to the latest span end
+	DurationNanos int64 `json:"durationNanos"`
+	// IdleNanos sums the durations of every gap, even those too short to be listed
+	IdleNanos int64      `json:"idleNanos"`
+	Gaps      []TraceGap `json:"gaps"`
+}
+
+// TraceGap is a stretch of time between two spans with nothing else running
+type TraceGap struct {
+	Start         time.Time `json:"start"`
+	End           time.Time `json:"end"`
+	DurationNanos int64     `json:"durationNanos"`
+	// AfterSpanID is the span whose end opens the gap, and BeforeSpanID the one whose start closes it
+	AfterSpanID  string `json:"afterSpanID"`
+	BeforeSpanID string `json:"beforeSpanID"`
+}
+
+// FindGaps merges the time the spans of a trace ran into one timeline and returns the gaps in
+// it, longest first. Spans running at the same time cover the timeline together, so a gap is
+// only idle time for the whole trace. A span that ended before it started, or took no time at
+// all, still marks an instant when something happened, which splits a gap around it.
+func FindGaps(spans []SpanData) TraceGaps {
+	traceGaps := TraceGaps{Gaps: []TraceGap{}}
+	if len(spans) == 0 {
+		return traceGaps
+	}
+	traceGaps.TraceID = spans[0].TraceID
+
+	sorted := slices.Clone(spans)
+	slices.SortStableFunc(sorted, func(a, b SpanData) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	start := sorted[0].StartTime
+	coveredUntil, lastSpanID := spanEnd(sorted[0]), sorted[0].SpanID
+	for _, span := range sorted[1:] {
+		if span.StartTime.After(coveredUntil) {
+			traceGaps.Gaps = append(traceGaps.Gaps, TraceGap{
+				Start:         coveredUntil,
+				End:           span.StartTime,
+				DurationNanos: span.StartTime.Sub(coveredUntil).Nanoseconds(),
+				AfterSpanID:   lastSpanID,
+				BeforeSpanID:  span.SpanID,
+			})
+		}
+		if end := spanEnd(span); end.After(coveredUntil) {
+			coveredUntil, lastSpanID = end, span.SpanID
+		}
+	}
+
+	traceGaps.DurationNanos = coveredUntil.Sub(start).Nanoseconds()
+	for _, gap := range traceGaps.Gaps {
+		traceGaps.IdleNanos += gap.DurationNanos
+	}
+	slices.SortStableFunc(traceGaps.Gaps, func(a, b TraceGap) int {
+		return cmp.Compare(b.DurationNanos, a.DurationNanos)
+	})
+	return traceGaps
+}
+
+// spanEnd is when a span stopped running, which is never before it started
+func spanEnd(span SpanData) time.Time {
+	if span.EndTime.Before(span.StartTime) {
+		return span.StartTime
+	}
+	return span.EndTime
+}
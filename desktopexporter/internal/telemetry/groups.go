@@ -0,0 +1,15 @@
+package telemetry
+
+// TraceGroup counts the traces in a group, those with a span whose resource has the group's name
+// as the value of the grouping attribute
+type TraceGroup struct {
+	Name       string `json:"name"`
+	TraceCount int    `json:"traceCount"`
+}
+
+// TraceGroups lists the groups traces fall into by Attribute, a resource attribute, in order
+// of name, with the default group for traces without it last
+type TraceGroups struct {
+	Attribute string       `json:"attribute"`
+	Groups    []TraceGroup `json:"groups"`
+}
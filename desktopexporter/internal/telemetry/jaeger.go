@@ -0,0 +1,300 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// jaegerTraces is the JSON the Jaeger UI downloads and its query API returns
+type jaegerTraces struct {
+	Data []jaegerTrace `json:"data"`
+}
+
+type jaegerTrace struct {
+	TraceID   string                   `json:"traceID"`
+	Spans     []jaegerSpan             `json:"spans"`
+	Processes map[string]jaegerProcess `json:"processes"`
+}
+
+type jaegerSpan struct {
+	TraceID       string            `json:"traceID"`
+	SpanID        string            `json:"spanID"`
+	OperationName string            `json:"operationName"`
+	References    []jaegerReference `json:"references"`
+	// StartTime and Duration are in microseconds
+	StartTime int64       `json:"startTime"`
+	Duration  int64       `json:"duration"`
+	Tags      []jaegerTag `json:"tags"`
+	Logs      []jaegerLog `json:"logs"`
+	ProcessID string      `json:"processID"`
+}
+
+type jaegerReference struct {
+	RefType string `json:"refType"`
+	TraceID string `json:"traceID"`
+	SpanID  string `json:"spanID"`
+}
+
+type jaegerProcess struct {
+	ServiceName string      `json:"serviceName"`
+	Tags        []jaegerTag `json:"tags"`
+}
+
+type jaegerLog struct {
+	// Timestamp is in microseconds
+	Timestamp int64       `json:"timestamp"`
+	Fields    []jaegerTag `json:"fields"`
+}
+
+type jaegerTag struct {
+	Key   string
+	Type  string
+	Value any
+}
+
+// UnmarshalJSON keeps numeric values as json.Number, so large int64 tags aren't rounded through a float
+func (tag *jaegerTag) UnmarshalJSON(data []byte) error {
+	var fields struct {
+		Key   string          `json:"key"`
+		Type  string          `json:"type"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	tag.Key = fields.Key
+	tag.Type = strings.ToLower(fields.Type)
+
+	decoder := json.NewDecoder(bytes.NewReader(fields.Value))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tag.Value); err != nil {
+		return fmt.Errorf("tag %q has no value: %s", tag.Key, err.Error())
+	}
+	return nil
+}
+
+// attributeValue converts the tag to an attribute value of its type. Some exporters quote numbers
+// and booleans, so those are read from strings too. Binary tags are kept as their base64 strings.
+func (tag *jaegerTag) attributeValue() (any, error) {
+	text := fmt.Sprint(tag.Value)
+
+	switch tag.Type {
+	case "int64":
+		return strconv.ParseInt(text, 10, 64)
+	case "float64":
+		return strconv.ParseFloat(text, 64)
+	case "bool":
+		return strconv.ParseBool(text)
+	default:
+		return text, nil
+	}
+}
+
+func jaegerAttributes(tags []jaegerTag) (Attributes, error) {
+	attributes := Attributes{}
+	for i := range tags {
+		value, err := tags[i].attributeValue()
+		if err != nil {
+			return nil, fmt.Errorf("tag %q is not a valid %s: %s", tags[i].Key, tags[i].Type, err.Error())
+		}
+		attributes[tags[i].Key] = value
+	}
+	return attributes, nil
+}
+
+// spanKinds maps the lowercased span kinds of Jaeger and Zipkin to the kinds we store
+var spanKinds = map[string]ptrace.SpanKind{
+	"client":   ptrace.SpanKindClient,
+	"server":   ptrace.SpanKindServer,
+	"producer": ptrace.SpanKindProducer,
+	"consumer": ptrace.SpanKindConsumer,
+	"internal": ptrace.SpanKindInternal,
+}
+
+// ParseJaegerTraces reads the spans of Jaeger JSON, such as a trace downloaded from the Jaeger UI.
+// Each process becomes the span's resource, with its service name as service.name. A span's first
+// CHILD_OF reference becomes its parent, and its other references become links. The tags that
+// OpenTelemetry's Jaeger exporter uses for the span kind, status, and scope are mapped back to
+// them, rather than being kept as attributes.
+func ParseJaegerTraces(payload []byte) ([]SpanData, error) {
+	traces := jaegerTraces{}
+	if err := json.Unmarshal(payload, &traces); err != nil {
+		return nil, fmt.Errorf("invalid Jaeger JSON: %s", err.Error())
+	}
+	if traces.Data == nil {
+		return nil, fmt.Errorf("invalid Jaeger JSON: expected traces under \"data\"")
+	}
+
+	spans := []SpanData{}
+	for _, trace := range traces.Data {
+		for _, span := range trace.Spans {
+			spanData, err := jaegerSpanData(trace, span)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Jaeger JSON: span %q: %s", span.OperationName, err.Error())
+			}
+			spans = append(spans, spanData)
+		}
+	}
+	if len(spans) == 0 {
+		return nil, fmt.Errorf("invalid Jaeger JSON: no spans found under \"data\"")
+	}
+	return spans, nil
+}
+
+func jaegerSpanData(trace jaegerTrace, span jaegerSpan) (SpanData, error) {
+	spanData := SpanData{
+		Name:      span.OperationName,
+		Kind:      ptrace.SpanKindUnspecified.String(),
+		StartTime: time.UnixMicro(span.StartTime).UTC(),
+		EndTime:   time.UnixMicro(span.StartTime + span.Duration).UTC(),
+		Events:    []EventData{},
+		Links:     []LinkData{},
+		Scope: &ScopeData{
+			Name:       "",
+			Version:    "",
+			Attributes: Attributes{},
+		},
+		StatusCode: ptrace.StatusCodeUnset.String(),
+	}
+	spanData.DurationNanos = DurationNanos(spanData.StartTime, spanData.EndTime)
+
+	var err error
+	if span.TraceID == "" {
+		span.TraceID = trace.TraceID
+	}
+	if spanData.TraceID, err = paddedID(span.TraceID, 16); err != nil {
+		return spanData, fmt.Errorf("invalid traceID: %s", err.Error())
+	}
+	if spanData.SpanID, err = paddedID(span.SpanID, 8); err != nil {
+		return spanData, fmt.Errorf("invalid spanID: %s", err.Error())
+	}
+
+	process, ok := trace.Processes[span.ProcessID]
+	if !ok {
+		return spanData, fmt.Errorf("unknown processID %q", span.ProcessID)
+	}
+	resourceAttributes, err := jaegerAttributes(process.Tags)
+	if err != nil {
+		return spanData, err
+	}
+	resourceAttributes["service.name"] = process.ServiceName
+	spanData.Resource = &ResourceData{Attributes: resourceAttributes}
+
+	if spanData.Attributes, err = jaegerAttributes(span.Tags); err != nil {
+		return spanData, err
+	}
+	mapJaegerTags(&spanData)
+
+	for _, reference := range span.References {
+		traceID, err := paddedID(reference.TraceID, 16)
+		if err != nil {
+			return spanData, fmt.Errorf("invalid reference traceID: %s", err.Error())
+		}
+		spanID, err := paddedID(reference.SpanID, 8)
+		if err != nil {
+			return spanData, fmt.Errorf("invalid reference spanID: %s", err.Error())
+		}
+
+		if reference.RefType == "CHILD_OF" && spanData.ParentSpanID == "" && traceID == spanData.TraceID {
+			spanData.ParentSpanID = spanID
+			continue
+		}
+		spanData.Links = append(spanData.Links, LinkData{
+			TraceID:    traceID,
+			SpanID:     spanID,
+			Attributes: Attributes{},
+		})
+	}
+
+	for _, log := range span.Logs {
+		attributes, err := jaegerAttributes(log.Fields)
+		if err != nil {
+			return spanData, err
+		}
+		// OpenTelemetry's Jaeger exporter names events with an event field
+		name, ok := attributes["event"].(string)
+		if ok {
+			delete(attributes, "event")
+		}
+
+		spanData.Events = append(spanData.Events, EventData{
+			Name:       name,
+			Timestamp:  time.UnixMicro(log.Timestamp).UTC(),
+			Attributes: attributes,
+		})
+	}
+
+	return spanData, nil
+}
+
+// mapJaegerTags moves the tags standing in for fields Jaeger doesn't have into those fields
+func mapJaegerTags(spanData *SpanData) {
+	attributes := spanData.Attributes
+
+	if kind, ok := attributes["span.kind"].(string); ok {
+		if spanKind, ok := spanKinds[strings.ToLower(kind)]; ok {
+			spanData.Kind = spanKind.String()
+			delete(attributes, "span.kind")
+		}
+	}
+
+	if isError, ok := attributes["error"].(bool); ok && isError {
+		spanData.StatusCode = ptrace.StatusCodeError.String()
+		delete(attributes, "error")
+	}
+	mapOTelTags(spanData)
+}
+
+// mapOTelTags moves the tags OpenTelemetry's Jaeger and Zipkin exporters add for a span's
+// status and scope into those fields
+func mapOTelTags(spanData *SpanData) {
+	attributes := spanData.Attributes
+
+	if code, ok := attributes["otel.status_code"].(string); ok {
+		switch strings.ToUpper(code) {
+		case "ERROR":
+			spanData.StatusCode = ptrace.StatusCodeError.String()
+		case "OK":
+			spanData.StatusCode = ptrace.StatusCodeOk.String()
+		}
+		delete(attributes, "otel.status_code")
+	}
+	if message, ok := attributes["otel.status_description"].(string); ok {
+		spanData.StatusMessage = message
+		delete(attributes, "otel.status_description")
+	}
+
+	for _, key := range []string{"otel.scope.name", "otel.library.name"} {
+		if name, ok := attributes[key].(string); ok {
+			spanData.Scope.Name = name
+			delete(attributes, key)
+		}
+	}
+	for _, key := range []string{"otel.scope.version", "otel.library.version"} {
+		if version, ok := attributes[key].(string); ok {
+			spanData.Scope.Version = version
+			delete(attributes, key)
+		}
+	}
+}
+
+// paddedID normalizes a hex ID to the length OTLP uses. Jaeger drops leading zeros, and both
+// Jaeger and Zipkin trace IDs may be only 64 bits long, so shorter IDs are padded with zeros.
+func paddedID(id string, size int) (string, error) {
+	id = strings.ToLower(id)
+	if id == "" || len(id) > size*2 {
+		return "", fmt.Errorf("%q must be between 1 and %d hex digits", id, size*2)
+	}
+	id = strings.Repeat("0", size*2-len(id)) + id
+	if _, err := hex.DecodeString(id); err != nil {
+		return "", fmt.Errorf("%q is not hex", id)
+	}
+	return id, nil
+}
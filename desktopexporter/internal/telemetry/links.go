@@ -7,11 +7,17 @@ type LinkPayload struct {
 }
 
 type LinkData struct {
-	TraceID                string                 `json:"traceID"`
-	SpanID                 string                 `json:"spanID"`
-	TraceState             string                 `json:"traceState"`
-	Attributes             map[string]interface{} `json:"attributes"`
-	DroppedAttributesCount uint32                 `json:"droppedAttributesCount"`
+	TraceID                string     `json:"traceID"`
+	SpanID                 string     `json:"spanID"`
+	TraceState             string     `json:"traceState"`
+	Attributes             Attributes `json:"attributes"`
+	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
+
+	// Resolved is only set once the link has been looked up in the store, and
+	// the root of the linked trace is only filled in if that trace was found
+	Resolved        *bool  `json:"resolved,omitempty"`
+	RootServiceName string `json:"rootServiceName,omitempty"`
+	RootName        string `json:"rootName,omitempty"`
 }
 
 func (payload *LinkPayload) ExtractLinks() []LinkData {
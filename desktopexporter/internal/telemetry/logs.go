@@ -11,18 +11,24 @@ type LogsPayload struct {
 }
 
 type LogData struct {
-	Body                   string                 `json:"body,omitempty"`
-	TraceID                string                 `json:"traceID,omitempty"`
-	SpanID                 string                 `json:"spanID,omitempty"`
-	Timestamp              time.Time              `json:"timestamp,omitempty"`
-	ObservedTimestamp      time.Time              `json:"observedTimestamp,omitempty"`
-	Attributes             map[string]interface{} `json:"attributes,omitempty"`
-	SeverityText           string                 `json:"severityText,omitempty"`
-	SeverityNumber         plog.SeverityNumber    `json:"severityNumber,omitempty"`
-	DroppedAttributesCount uint32                 `json:"droppedAttributeCount,omitempty"`
-	Flags                  plog.LogRecordFlags    `json:"flags,omitempty"`
-	Resource               *ResourceData          `json:"resource"`
-	Scope                  *ScopeData             `json:"scope"`
+	Body                   string              `json:"body,omitempty"`
+	TraceID                string              `json:"traceID,omitempty"`
+	SpanID                 string              `json:"spanID,omitempty"`
+	Timestamp              time.Time           `json:"timestamp,omitempty"`
+	ObservedTimestamp      time.Time           `json:"observedTimestamp,omitempty"`
+	Attributes             Attributes          `json:"attributes,omitempty"`
+	SeverityText           string              `json:"severityText,omitempty"`
+	SeverityNumber         plog.SeverityNumber `json:"severityNumber,omitempty"`
+	DroppedAttributesCount uint32              `json:"droppedAttributeCount,omitempty"`
+	Flags                  plog.LogRecordFlags `json:"flags,omitempty"`
+	Resource               *ResourceData       `json:"resource"`
+	Scope                  *ScopeData          `json:"scope"`
+}
+
+// TraceLogs holds the logs recorded as part of a trace
+type TraceLogs struct {
+	TraceID string    `json:"traceID"`
+	Logs    []LogData `json:"logs"`
 }
 
 func NewLogsPayload(l plog.Logs) *LogsPayload {
@@ -32,7 +38,7 @@ func NewLogsPayload(l plog.Logs) *LogsPayload {
 func (payload *LogsPayload) ExtractLogs() []LogData {
 	logData := []LogData{}
 
-	for rli := 0; rli < payload.logs.LogRecordCount(); rli++ {
+	for rli := 0; rli < payload.logs.ResourceLogs().Len(); rli++ {
 		resourceLogs := payload.logs.ResourceLogs().At(rli)
 		resourceData := AggregateResourceData(resourceLogs.Resource())
 
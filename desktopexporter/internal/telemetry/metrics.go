@@ -3,6 +3,7 @@ package telemetry
 import (
 	"time"
 
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/pmetric"
 )
 
@@ -10,23 +11,98 @@ type MetricsPayload struct {
 	metrics pmetric.Metrics
 }
 
-type MetricsData struct {
-	Name        string             `json:"name,omitempty"`
-	Description string             `json:"description,omitempty"`
-	Unit        string             `json:"unit,omitempty"`
-	Type        pmetric.MetricType `json:"type,omitempty"`
-	// add datapoints
+// MetricData is a single data point of a gauge, sum, histogram, or exponential histogram metric
+type MetricData struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	Type        string `json:"type"`
+
+	StartTime  time.Time  `json:"startTime"`
+	Timestamp  time.Time  `json:"timestamp"`
+	Attributes Attributes `json:"attributes"`
+
+	// Value is the value of a gauge or sum data point, and nil for histograms
+	Value *float64 `json:"value"`
+	// IsMonotonic and AggregationTemporality only apply to sums and histograms
+	IsMonotonic            bool   `json:"isMonotonic"`
+	AggregationTemporality string `json:"aggregationTemporality"`
+	// Histogram holds the buckets of a histogram data point, and is nil otherwise
+	Histogram *HistogramData `json:"histogram"`
+	// ExponentialHistogram holds the buckets of an exponential histogram data point, and is nil otherwise
+	ExponentialHistogram *ExponentialHistogramData `json:"exponentialHistogram"`
+	// Exemplars are the measurements the data point was sampled from, which may carry the
+	// trace and span they were taken in
+	Exemplars []ExemplarData `json:"exemplars"`
+
 	Resource *ResourceData `json:"resource"`
 	Scope    *ScopeData    `json:"scope"`
-	Received time.Time     `json:"-"`
+}
+
+type HistogramData struct {
+	Count          uint64    `json:"count"`
+	Sum            *float64  `json:"sum"`
+	Min            *float64  `json:"min"`
+	Max            *float64  `json:"max"`
+	BucketCounts   []uint64  `json:"bucketCounts"`
+	ExplicitBounds []float64 `json:"explicitBounds"`
+}
+
+// ExponentialHistogramData keeps everything needed to rebuild the bucket boundaries: bucket
+// index i covers (base^i, base^(i+1)], where base is 2^(2^-scale), and the counts of each
+// range start at index offset
+type ExponentialHistogramData struct {
+	Count         uint64                      `json:"count"`
+	Sum           *float64                    `json:"sum"`
+	Min           *float64                    `json:"min"`
+	Max           *float64                    `json:"max"`
+	Scale         int32                       `json:"scale"`
+	ZeroCount     uint64                      `json:"zeroCount"`
+	ZeroThreshold float64                     `json:"zeroThreshold"`
+	Positive      ExponentialHistogramBuckets `json:"positive"`
+	Negative      ExponentialHistogramBuckets `json:"negative"`
+}
+
+type ExponentialHistogramBuckets struct {
+	Offset       int32    `json:"offset"`
+	BucketCounts []uint64 `json:"bucketCounts"`
+}
+
+// ExemplarData is a single measurement recorded into a data point. TraceID and SpanID are
+// empty unless it was taken within a sampled span.
+type ExemplarData struct {
+	Timestamp          time.Time  `json:"timestamp"`
+	Value              float64    `json:"value"`
+	TraceID            string     `json:"traceID"`
+	SpanID             string     `json:"spanID"`
+	FilteredAttributes Attributes `json:"filteredAttributes"`
+
+	// Resolved is only set once the exemplar's trace has been looked up in the store, and
+	// only for exemplars with a trace ID
+	Resolved *bool `json:"resolved,omitempty"`
+}
+
+// MetricSeries holds the data points of a metric, oldest first
+type MetricSeries struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Unit        string       `json:"unit"`
+	Type        string       `json:"type"`
+	DataPoints  []MetricData `json:"dataPoints"`
+}
+
+type MetricSeriesList struct {
+	Series []MetricSeries `json:"series"`
 }
 
 func NewMetricsPayload(m pmetric.Metrics) *MetricsPayload {
 	return &MetricsPayload{metrics: m}
 }
 
-func (payload *MetricsPayload) ExtractMetrics() []MetricsData {
-	metricsDataSlice := []MetricsData{}
+// ExtractMetrics returns one MetricData per data point. Summaries are not supported yet, and
+// are skipped.
+func (payload *MetricsPayload) ExtractMetrics() []MetricData {
+	metricsDataSlice := []MetricData{}
 
 	for rmi := 0; rmi < payload.metrics.ResourceMetrics().Len(); rmi++ {
 		resourceMetrics := payload.metrics.ResourceMetrics().At(rmi)
@@ -38,29 +114,185 @@ func (payload *MetricsPayload) ExtractMetrics() []MetricsData {
 
 			for si := 0; si < scopeMetrics.Metrics().Len(); si++ {
 				metric := scopeMetrics.Metrics().At(si)
-				metricsDataSlice = append(metricsDataSlice, aggregateMetricsData(metric, scopeData, resourceData))
+				metricsDataSlice = append(metricsDataSlice, aggregateMetricData(metric, scopeData, resourceData)...)
 			}
 		}
 	}
 	return metricsDataSlice
 }
 
-func aggregateMetricsData(source pmetric.Metric, scopeData *ScopeData, resourceData *ResourceData) MetricsData {
-	return MetricsData{
-		Name:        source.Name(),
-		Description: source.Description(),
-		Unit:        source.Unit(),
-		Type:        source.Type(),
-		Received:    time.Now(),
-		// TODO: add other fields
-		Resource: resourceData,
-		Scope:    scopeData,
+func aggregateMetricData(source pmetric.Metric, scopeData *ScopeData, resourceData *ResourceData) []MetricData {
+	metricDataSlice := []MetricData{}
+	newMetricData := func() MetricData {
+		return MetricData{
+			Name:        source.Name(),
+			Description: source.Description(),
+			Unit:        source.Unit(),
+			Type:        source.Type().String(),
+			Resource:    resourceData,
+			Scope:       scopeData,
+		}
+	}
+
+	switch source.Type() {
+	case pmetric.MetricTypeGauge:
+		dataPoints := source.Gauge().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			metricDataSlice = append(metricDataSlice, aggregateNumberDataPoint(newMetricData(), dataPoints.At(i)))
+		}
+
+	case pmetric.MetricTypeSum:
+		dataPoints := source.Sum().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			metricData := aggregateNumberDataPoint(newMetricData(), dataPoints.At(i))
+			metricData.IsMonotonic = source.Sum().IsMonotonic()
+			metricData.AggregationTemporality = source.Sum().AggregationTemporality().String()
+			metricDataSlice = append(metricDataSlice, metricData)
+		}
+
+	case pmetric.MetricTypeHistogram:
+		dataPoints := source.Histogram().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			metricData := aggregateHistogramDataPoint(newMetricData(), dataPoints.At(i))
+			metricData.AggregationTemporality = source.Histogram().AggregationTemporality().String()
+			metricDataSlice = append(metricDataSlice, metricData)
+		}
+
+	case pmetric.MetricTypeExponentialHistogram:
+		dataPoints := source.ExponentialHistogram().DataPoints()
+		for i := 0; i < dataPoints.Len(); i++ {
+			metricData := aggregateExponentialHistogramDataPoint(newMetricData(), dataPoints.At(i))
+			metricData.AggregationTemporality = source.ExponentialHistogram().AggregationTemporality().String()
+			metricDataSlice = append(metricDataSlice, metricData)
+		}
+	}
+	return metricDataSlice
+}
+
+func aggregateNumberDataPoint(metricData MetricData, source pmetric.NumberDataPoint) MetricData {
+	metricData.StartTime = timestampAsTime(source.StartTimestamp())
+	metricData.Timestamp = timestampAsTime(source.Timestamp())
+	metricData.Attributes = source.Attributes().AsRaw()
+
+	value := source.DoubleValue()
+	if source.ValueType() == pmetric.NumberDataPointValueTypeInt {
+		value = float64(source.IntValue())
+	}
+	metricData.Value = &value
+	metricData.Exemplars = aggregateExemplars(source.Exemplars())
+	return metricData
+}
+
+func aggregateHistogramDataPoint(metricData MetricData, source pmetric.HistogramDataPoint) MetricData {
+	metricData.StartTime = timestampAsTime(source.StartTimestamp())
+	metricData.Timestamp = timestampAsTime(source.Timestamp())
+	metricData.Attributes = source.Attributes().AsRaw()
+
+	histogram := HistogramData{
+		Count:          source.Count(),
+		BucketCounts:   source.BucketCounts().AsRaw(),
+		ExplicitBounds: source.ExplicitBounds().AsRaw(),
 	}
+	if source.HasSum() {
+		sum := source.Sum()
+		histogram.Sum = &sum
+	}
+	if source.HasMin() {
+		min := source.Min()
+		histogram.Min = &min
+	}
+	if source.HasMax() {
+		max := source.Max()
+		histogram.Max = &max
+	}
+	metricData.Histogram = &histogram
+	metricData.Exemplars = aggregateExemplars(source.Exemplars())
+	return metricData
 }
 
-func (metricData MetricsData) ID() string {
-	// may need to consider additional fields to uniquely identify
-	// a metric, for example different resources could potentially
-	// send the same data at the same time and create collisions
-	return metricData.Name + metricData.Received.String()
+func aggregateExponentialHistogramDataPoint(metricData MetricData, source pmetric.ExponentialHistogramDataPoint) MetricData {
+	metricData.StartTime = timestampAsTime(source.StartTimestamp())
+	metricData.Timestamp = timestampAsTime(source.Timestamp())
+	metricData.Attributes = source.Attributes().AsRaw()
+
+	histogram := ExponentialHistogramData{
+		Count:         source.Count(),
+		Scale:         source.Scale(),
+		ZeroCount:     source.ZeroCount(),
+		ZeroThreshold: source.ZeroThreshold(),
+		Positive: ExponentialHistogramBuckets{
+			Offset:       source.Positive().Offset(),
+			BucketCounts: source.Positive().BucketCounts().AsRaw(),
+		},
+		Negative: ExponentialHistogramBuckets{
+			Offset:       source.Negative().Offset(),
+			BucketCounts: source.Negative().BucketCounts().AsRaw(),
+		},
+	}
+	if source.HasSum() {
+		sum := source.Sum()
+		histogram.Sum = &sum
+	}
+	if source.HasMin() {
+		min := source.Min()
+		histogram.Min = &min
+	}
+	if source.HasMax() {
+		max := source.Max()
+		histogram.Max = &max
+	}
+	metricData.ExponentialHistogram = &histogram
+	metricData.Exemplars = aggregateExemplars(source.Exemplars())
+	return metricData
+}
+
+func aggregateExemplars(source pmetric.ExemplarSlice) []ExemplarData {
+	exemplars := []ExemplarData{}
+	for i := 0; i < source.Len(); i++ {
+		exemplar := source.At(i)
+		value := exemplar.DoubleValue()
+		if exemplar.ValueType() == pmetric.ExemplarValueTypeInt {
+			value = float64(exemplar.IntValue())
+		}
+		exemplars = append(exemplars, ExemplarData{
+			Timestamp:          timestampAsTime(exemplar.Timestamp()),
+			Value:              value,
+			TraceID:            exemplar.TraceID().String(),
+			SpanID:             exemplar.SpanID().String(),
+			FilteredAttributes: exemplar.FilteredAttributes().AsRaw(),
+		})
+	}
+	return exemplars
+}
+
+// timestampAsTime leaves unset timestamps as the zero time rather than the Unix epoch
+func timestampAsTime(timestamp pcommon.Timestamp) time.Time {
+	if timestamp == 0 {
+		return time.Time{}
+	}
+	return timestamp.AsTime()
+}
+
+// NewMetricSeriesList groups data points into a series per metric name, keeping the order
+// the data points come in, so data points sorted by time make series sorted by time
+func NewMetricSeriesList(metrics []MetricData) MetricSeriesList {
+	seriesList := MetricSeriesList{Series: []MetricSeries{}}
+	seriesIndex := map[string]int{}
+
+	for _, metricData := range metrics {
+		i, ok := seriesIndex[metricData.Name]
+		if !ok {
+			i = len(seriesList.Series)
+			seriesIndex[metricData.Name] = i
+			seriesList.Series = append(seriesList.Series, MetricSeries{
+				Name:        metricData.Name,
+				Description: metricData.Description,
+				Unit:        metricData.Unit,
+				Type:        metricData.Type,
+				DataPoints:  []MetricData{},
+			})
+		}
+		seriesList.Series[i].DataPoints = append(seriesList.Series[i].DataPoints, metricData)
+	}
+	return seriesList
 }
@@ -0,0 +1,19 @@
+package telemetry
+
+// OperationStats sums up the durations of the spans sharing a name, across every trace.
+// The percentiles are approximate.
+type OperationStats struct {
+	// ServiceName is only set when the stats are grouped by service
+	ServiceName string `json:"serviceName,omitempty"`
+	Name        string `json:"name"`
+	SpanCount   uint32 `json:"spanCount"`
+
+	P50DurationNanos int64 `json:"p50DurationNanos"`
+	P95DurationNanos int64 `json:"p95DurationNanos"`
+	P99DurationNanos int64 `json:"p99DurationNanos"`
+	MaxDurationNanos int64 `json:"maxDurationNanos"`
+}
+
+type OperationStatsList struct {
+	Operations []OperationStats `json:"operations"`
+}
@@ -0,0 +1,112 @@
+package telemetry
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// RedactedValue is what a Redactor replaces hidden attribute values with
+const RedactedValue = "***"
+
+// Redactor hides attribute values before spans are stored, so that neither the database nor the
+// API ever sees them. The whole value of an attribute with one of its keys is replaced, while its
+// patterns only replace the parts of string values that match them. Nested values are redacted
+// the same way, keys of maps included.
+type Redactor struct {
+	keys     map[string]bool
+	patterns []*regexp.Regexp
+}
+
+// NewRedactor builds a Redactor hiding the values of the given keys, and whatever in string
+// values matches one of the given regular expressions
+func NewRedactor(keys []string, patterns []string) (*Redactor, error) {
+	redactor := &Redactor{keys: map[string]bool{}}
+	for _, key := range keys {
+		redactor.keys[key] = true
+	}
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %s", pattern, err.Error())
+		}
+		redactor.patterns = append(redactor.patterns, compiled)
+	}
+	return redactor, nil
+}
+
+// RedactSpans redacts the attributes of each span, along with those of its resource, scope,
+// events and links. Redacted attributes are copies, so the spans' original maps are left as
+// they were for anyone else holding them.
+func (r *Redactor) RedactSpans(spans []SpanData) {
+	for i := range spans {
+		span := &spans[i]
+		span.Attributes = r.redactAttributes(span.Attributes)
+
+		if span.Resource != nil {
+			resource := *span.Resource
+			resource.Attributes = r.redactAttributes(resource.Attributes)
+			span.Resource = &resource
+		}
+		if span.Scope != nil {
+			scope := *span.Scope
+			scope.Attributes = r.redactAttributes(scope.Attributes)
+			span.Scope = &scope
+		}
+
+		if span.Events != nil {
+			events := make([]EventData, len(span.Events))
+			for j, event := range span.Events {
+				event.Attributes = r.redactAttributes(event.Attributes)
+				events[j] = event
+			}
+			span.Events = events
+		}
+		if span.Links != nil {
+			links := make([]LinkData, len(span.Links))
+			for j, link := range span.Links {
+				link.Attributes = r.redactAttributes(link.Attributes)
+				links[j] = link
+			}
+			span.Links = links
+		}
+	}
+}
+
+func (r *Redactor) redactAttributes(attributes Attributes) Attributes {
+	if attributes == nil {
+		return nil
+	}
+	return Attributes(r.redactMap(attributes))
+}
+
+func (r *Redactor) redactMap(values map[string]interface{}) map[string]interface{} {
+	redacted := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		if r.keys[key] {
+			redacted[key] = RedactedValue
+		} else {
+			redacted[key] = r.redactValue(value)
+		}
+	}
+	return redacted
+}
+
+func (r *Redactor) redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case string:
+		for _, pattern := range r.patterns {
+			value = pattern.ReplaceAllLiteralString(value, RedactedValue)
+		}
+		return value
+	case map[string]interface{}:
+		return r.redactMap(value)
+	case []interface{}:
+		redacted := make([]interface{}, len(value))
+		for i, element := range value {
+			redacted[i] = r.redactValue(element)
+		}
+		return redacted
+	default:
+		return value
+	}
+}
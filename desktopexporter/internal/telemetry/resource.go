@@ -3,8 +3,8 @@ package telemetry
 import "go.opentelemetry.io/collector/pdata/pcommon"
 
 type ResourceData struct {
-	Attributes             map[string]interface{} `json:"attributes"`
-	DroppedAttributesCount uint32                 `json:"droppedAttributesCount"`
+	Attributes             Attributes `json:"attributes"`
+	DroppedAttributesCount uint32     `json:"droppedAttributesCount"`
 }
 
 func AggregateResourceData(source pcommon.Resource) *ResourceData {
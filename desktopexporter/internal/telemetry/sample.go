@@ -14,7 +14,7 @@ import (
 type SampleTelemetry struct {
 	Spans   []SpanData
 	Logs    []LogData
-	Metrics []MetricsData
+	Metrics []MetricData
 }
 
 func NewSampleTelemetry() SampleTelemetry {
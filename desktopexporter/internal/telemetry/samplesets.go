@@ -0,0 +1,77 @@
+package telemetry
+
+import (
+	"embed"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// DefaultSampleSet is the sample data loaded when no set is asked for
+const DefaultSampleSet = "default"
+
+//go:embed samples/*.json
+var sampleSetFiles embed.FS
+
+// SampleSet describes a set of sample traces that can be loaded into the viewer
+type SampleSet struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type SampleSetList struct {
+	Sets []SampleSet `json:"sets"`
+}
+
+// Every set other than the default one is read from samples/<name>.json, in OTLP JSON
+var sampleSets = []SampleSet{
+	{
+		Name:        DefaultSampleSet,
+		Description: "A currency conversion and a chain of HTTP calls, with sample logs and metrics",
+	},
+	{
+		Name:        "microservices",
+		Description: "A checkout fanning out across six services, handing off to a queue consumer linked from another trace",
+	},
+	{
+		Name:        "errors",
+		Description: "A payment failing with an exception event, and a request succeeding after two failed retries",
+	},
+	{
+		Name:        "deep-trace",
+		Description: "A recursive directory walk nested twelve levels deep",
+	},
+}
+
+// SampleSets lists the sample data sets, the default one first
+func SampleSets() SampleSetList {
+	return SampleSetList{Sets: append([]SampleSet{}, sampleSets...)}
+}
+
+// LoadSampleSet returns the spans of the sample set with the given name
+func LoadSampleSet(name string) ([]SpanData, error) {
+	if name == DefaultSampleSet {
+		return NewSampleTelemetry().Spans, nil
+	}
+
+	names := []string{}
+	for _, set := range sampleSets {
+		names = append(names, set.Name)
+	}
+	if !slices.Contains(names, name) {
+		return nil, fmt.Errorf("unknown sample set %q: must be one of %s", name, strings.Join(names, ", "))
+	}
+
+	payload, err := sampleSetFiles.ReadFile("samples/" + name + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("could not read sample set %s: %s", name, err.Error())
+	}
+	unmarshaler := ptrace.JSONUnmarshaler{}
+	traces, err := unmarshaler.UnmarshalTraces(payload)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse sample set %s: %s", name, err.Error())
+	}
+	return NewSpanPayload(traces).ExtractSpans(), nil
+}
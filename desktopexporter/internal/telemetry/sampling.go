@@ -0,0 +1,71 @@
+package telemetry
+
+import (
+	"cmp"
+	"slices"
+)
+
+// SampleSpans cuts a trace with more than maxSpans spans down to maxSpans of them, and returns
+// them along with the number of spans left out. So that the shape of the trace stays recognizable,
+// the root spans are always kept, even should there be more of them than maxSpans, followed by
+// as many of the spans directly under them as there is room for, and then the longest of the rest.
+// Spans whose parent is missing from the trace count as roots. Either way the longest spans go
+// first, and the spans kept are returned earliest first.
+func SampleSpans(spans []SpanData, maxSpans int) ([]SpanData, int) {
+	if maxSpans <= 0 || len(spans) <= maxSpans {
+		return spans, 0
+	}
+
+	roots := []*SpanNode{}
+	for _, node := range BuildSpanTree(spans) {
+		if node.Span == nil {
+			roots = append(roots, node.Children...)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+
+	kept := []SpanData{}
+	keep := func(nodes []*SpanNode) {
+		slices.SortStableFunc(nodes, func(a, b *SpanNode) int {
+			return cmp.Compare(spanDuration(b), spanDuration(a))
+		})
+		for _, node := range nodes {
+			if len(kept) >= maxSpans {
+				return
+			}
+			kept = append(kept, *node.Span)
+		}
+	}
+
+	for _, root := range roots {
+		kept = append(kept, *root.Span)
+	}
+	children := []*SpanNode{}
+	for _, root := range roots {
+		children = append(children, root.Children...)
+	}
+	keep(children)
+
+	rest := []*SpanNode{}
+	for _, child := range children {
+		rest = appendDescendants(rest, child)
+	}
+	keep(rest)
+
+	slices.SortStableFunc(kept, func(a, b SpanData) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+	return kept, len(spans) - len(kept)
+}
+
+func appendDescendants(nodes []*SpanNode, node *SpanNode) []*SpanNode {
+	for _, child := range node.Children {
+		nodes = appendDescendants(append(nodes, child), child)
+	}
+	return nodes
+}
+
+func spanDuration(node *SpanNode) int64 {
+	return DurationNanos(node.Span.StartTime, node.Span.EndTime)
+}
@@ -0,0 +1,63 @@
+package telemetry
+
+import (
+	"time"
+)
+
+// SetSelfDurations sets the SelfDurationNanos of every span to the time it spent outside of its
+// children. Only children among spans are subtracted, so spans should be a whole trace.
+func SetSelfDurations(spans []SpanData) {
+	// Spans are told apart by more than their ID, since a repeated ID only nests children under
+	// the earliest span with it
+	type spanKey struct {
+		spanID     string
+		start, end time.Time
+	}
+	selfDurations := map[spanKey]int64{}
+
+	var walk func(nodes []*SpanNode)
+	walk = func(nodes []*SpanNode) {
+		for _, node := range nodes {
+			if node.Span != nil {
+				key := spanKey{node.Span.SpanID, node.Span.StartTime, node.Span.EndTime}
+				if _, ok := selfDurations[key]; !ok {
+					selfDurations[key] = selfDuration(node)
+				}
+			}
+			walk(node.Children)
+		}
+	}
+	walk(BuildSpanTree(spans))
+
+	for i := range spans {
+		self := selfDurations[spanKey{spans[i].SpanID, spans[i].StartTime, spans[i].EndTime}]
+		spans[i].SelfDurationNanos = &self
+	}
+}
+
+// selfDuration subtracts the time covered by a span's children from its duration. Children
+// may overlap each other or stick out past the span, so only the union of their time within
+// the span counts. Children come earliest first from BuildSpanTree.
+func selfDuration(node *SpanNode) int64 {
+	span := node.Span
+	if !span.EndTime.After(span.StartTime) {
+		return 0
+	}
+
+	var covered int64
+	coveredUntil := span.StartTime
+	for _, child := range node.Children {
+		start, end := child.Span.StartTime, child.Span.EndTime
+		if start.Before(coveredUntil) {
+			start = coveredUntil
+		}
+		if end.After(span.EndTime) {
+			end = span.EndTime
+		}
+		if end.After(start) {
+			covered += end.Sub(start).Nanoseconds()
+			coveredUntil = end
+		}
+	}
+	return span.EndTime.Sub(span.StartTime).Nanoseconds() - covered
+}
@@ -0,0 +1,42 @@
+package telemetry
+
+type ServiceSpanCount struct {
+	ServiceName string `json:"serviceName"`
+	SpanCount   uint32 `json:"spanCount"`
+}
+
+// ServiceGroup is a distinct combination of values of the resource attributes spans were grouped
+// by, and the number of spans with it. Attributes a span doesn't have group as empty values.
+type ServiceGroup struct {
+	Attributes map[string]string `json:"attributes"`
+	SpanCount  uint32            `json:"spanCount"`
+}
+
+type ServiceGroups struct {
+	GroupBy []string       `json:"groupBy"`
+	Groups  []ServiceGroup `json:"groups"`
+}
+
+// ServiceDependency is an edge of the service graph: Parent called Child CallCount times,
+// and ErrorCount of those calls ended with an Error status on the Child's side
+type ServiceDependency struct {
+	Parent     string `json:"parent"`
+	Child      string `json:"child"`
+	CallCount  uint32 `json:"callCount"`
+	ErrorCount uint32 `json:"errorCount"`
+}
+
+// ServiceNode is a node of the service graph, summing up the spans of one service.
+// ErrorRate is the fraction of them with an Error status, and the p95 is approximate.
+type ServiceNode struct {
+	ServiceName      string  `json:"serviceName"`
+	SpanCount        uint32  `json:"spanCount"`
+	ErrorCount       uint32  `json:"errorCount"`
+	ErrorRate        float64 `json:"errorRate"`
+	P95DurationNanos int64   `json:"p95DurationNanos"`
+}
+
+type ServiceDependencies struct {
+	Services     []ServiceNode       `json:"services"`
+	Dependencies []ServiceDependency `json:"dependencies"`
+}
@@ -0,0 +1,130 @@
+package telemetry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// DefaultServiceName is the service.name of spans sent without a resource, as OpenTelemetry SDKs
+// name services that don't say what they are
+const DefaultServiceName = "unknown_service"
+
+// simpleSpan is a span as posted to /api/spans, with only the fields a script needs to send
+type simpleSpan struct {
+	TraceID       string        `json:"traceId"`
+	SpanID        string        `json:"spanId"`
+	ParentSpanID  string        `json:"parentSpanId"`
+	Name          string        `json:"name"`
+	Kind          string        `json:"kind"`
+	StartUnixNano *unixNano     `json:"startUnixNano"`
+	EndUnixNano   *unixNano     `json:"endUnixNano"`
+	Attributes    Attributes    `json:"attributes"`
+	Resource      *simpleEntity `json:"resource"`
+}
+
+type simpleEntity struct {
+	Attributes Attributes `json:"attributes"`
+}
+
+// unixNano reads nanoseconds since the Unix epoch written either as a number, or as a string
+// the way OTLP/JSON writes them
+type unixNano int64
+
+func (n *unixNano) UnmarshalJSON(data []byte) error {
+	text := strings.Trim(string(bytes.TrimSpace(data)), `"`)
+	nanos, err := strconv.ParseInt(text, 10, 64)
+	if err != nil || nanos <= 0 {
+		return fmt.Errorf("%s must be a positive whole number of nanoseconds since the Unix epoch", data)
+	}
+	*n = unixNano(nanos)
+	return nil
+}
+
+// ParseSimpleSpans reads a JSON array of spans in the minimal shape /api/spans takes. Trace and
+// span IDs are hex, and left-padded with zeros if short, so that scripts can number spans 1, 2,
+// 3. Spans without a resource get one with DefaultServiceName as their service.name.
+func ParseSimpleSpans(payload []byte) ([]SpanData, error) {
+	spans := []simpleSpan{}
+	if err := json.Unmarshal(payload, &spans); err != nil {
+		return nil, fmt.Errorf("invalid span JSON: %s", err.Error())
+	}
+
+	spanData := make([]SpanData, 0, len(spans))
+	for i, span := range spans {
+		data, err := simpleSpanData(span)
+		if err != nil {
+			return nil, fmt.Errorf("invalid span JSON: span %d: %s", i, err.Error())
+		}
+		spanData = append(spanData, data)
+	}
+	return spanData, nil
+}
+
+func simpleSpanData(span simpleSpan) (SpanData, error) {
+	switch {
+	case span.TraceID == "":
+		return SpanData{}, fmt.Errorf("missing traceId")
+	case span.SpanID == "":
+		return SpanData{}, fmt.Errorf("missing spanId")
+	case span.Name == "":
+		return SpanData{}, fmt.Errorf("missing name")
+	case span.StartUnixNano == nil:
+		return SpanData{}, fmt.Errorf("missing startUnixNano")
+	case span.EndUnixNano == nil:
+		return SpanData{}, fmt.Errorf("missing endUnixNano")
+	case *span.EndUnixNano < *span.StartUnixNano:
+		return SpanData{}, fmt.Errorf("endUnixNano must not be before startUnixNano")
+	}
+
+	spanData := SpanData{
+		Name:       span.Name,
+		Kind:       ptrace.SpanKindUnspecified.String(),
+		StartTime:  time.Unix(0, int64(*span.StartUnixNano)).UTC(),
+		EndTime:    time.Unix(0, int64(*span.EndUnixNano)).UTC(),
+		Attributes: span.Attributes,
+		Events:     []EventData{},
+		Links:      []LinkData{},
+		Resource:   &ResourceData{Attributes: Attributes{"service.name": DefaultServiceName}},
+		Scope: &ScopeData{
+			Name:       "",
+			Version:    "",
+			Attributes: Attributes{},
+		},
+		StatusCode: ptrace.StatusCodeUnset.String(),
+	}
+	spanData.DurationNanos = DurationNanos(spanData.StartTime, spanData.EndTime)
+	if spanData.Attributes == nil {
+		spanData.Attributes = Attributes{}
+	}
+	if span.Resource != nil && span.Resource.Attributes != nil {
+		spanData.Resource.Attributes = span.Resource.Attributes
+	}
+
+	var err error
+	if spanData.TraceID, err = paddedID(span.TraceID, 16); err != nil {
+		return spanData, fmt.Errorf("invalid traceId: %s", err.Error())
+	}
+	if spanData.SpanID, err = paddedID(span.SpanID, 8); err != nil {
+		return spanData, fmt.Errorf("invalid spanId: %s", err.Error())
+	}
+	if span.ParentSpanID != "" {
+		if spanData.ParentSpanID, err = paddedID(span.ParentSpanID, 8); err != nil {
+			return spanData, fmt.Errorf("invalid parentSpanId: %s", err.Error())
+		}
+	}
+	if span.Kind != "" {
+		spanKind, ok := spanKinds[strings.ToLower(span.Kind)]
+		if !ok {
+			return spanData, fmt.Errorf("invalid kind %q: must be one of server, client, producer, consumer, internal", span.Kind)
+		}
+		spanData.Kind = spanKind.String()
+	}
+
+	return spanData, nil
+}
@@ -2,6 +2,7 @@ package telemetry
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/collector/pdata/ptrace"
@@ -21,12 +22,17 @@ type SpanData struct {
 	Kind      string    `json:"kind"`
 	StartTime time.Time `json:"startTime"`
 	EndTime   time.Time `json:"endTime"`
-
-	Attributes map[string]interface{} `json:"attributes"`
-	Events     []EventData            `json:"events"`
-	Links      []LinkData             `json:"links"`
-	Resource   *ResourceData          `json:"resource"`
-	Scope      *ScopeData             `json:"scope"`
+	// DurationNanos is derived from StartTime and EndTime, and isn't stored
+	DurationNanos int64 `json:"durationNanos"`
+	// SelfDurationNanos is the part of DurationNanos not covered by the span's children. It is only
+	// set on the spans of a whole trace, by SetSelfDurations.
+	SelfDurationNanos *int64 `json:"selfDurationNanos,omitempty"`
+
+	Attributes Attributes    `json:"attributes"`
+	Events     []EventData   `json:"events"`
+	Links      []LinkData    `json:"links"`
+	Resource   *ResourceData `json:"resource"`
+	Scope      *ScopeData    `json:"scope"`
 
 	DroppedAttributesCount uint32 `json:"droppedAttributesCount"`
 	DroppedEventsCount     uint32 `json:"droppedEventsCount"`
@@ -72,13 +78,14 @@ func aggregateSpanData(source ptrace.Span, eventData []EventData, LinkData []Lin
 		TraceID:    source.TraceID().String(),
 		TraceState: source.TraceState().AsRaw(),
 
-		SpanID:       source.SpanID().String(),
-		ParentSpanID: source.ParentSpanID().String(),
-		Name:         source.Name(),
-		Kind:         source.Kind().String(),
-		StartTime:    source.StartTimestamp().AsTime(),
-		EndTime:      source.EndTimestamp().AsTime(),
-		Attributes:   source.Attributes().AsRaw(),
+		SpanID:        source.SpanID().String(),
+		ParentSpanID:  source.ParentSpanID().String(),
+		Name:          source.Name(),
+		Kind:          source.Kind().String(),
+		StartTime:     source.StartTimestamp().AsTime(),
+		EndTime:       source.EndTimestamp().AsTime(),
+		DurationNanos: DurationNanos(source.StartTimestamp().AsTime(), source.EndTimestamp().AsTime()),
+		Attributes:    source.Attributes().AsRaw(),
 
 		Events:   eventData,
 		Links:    LinkData,
@@ -94,6 +101,67 @@ func aggregateSpanData(source ptrace.Span, eventData []EventData, LinkData []Lin
 	}
 }
 
+// DurationNanos returns the time between start and end in nanoseconds. An end before the start,
+// which clock skew between hosts can cause, counts as no time at all rather than negative time.
+func DurationNanos(start time.Time, end time.Time) int64 {
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Nanoseconds()
+}
+
+// ValidateIDs checks that the span's trace and span IDs are hex, as OTLP sends them, and no
+// longer than OTLP's 16 and 8 bytes. Spans without them can't be grouped into traces.
+func (spanData *SpanData) ValidateIDs() error {
+	if !validID(spanData.TraceID, 32) {
+		return fmt.Errorf("%w, got %q", ErrInvalidTraceID, spanData.TraceID)
+	}
+	if !validID(spanData.SpanID, 16) {
+		return fmt.Errorf("%w, got %q", ErrInvalidSpanID, spanData.SpanID)
+	}
+	return nil
+}
+
+func validID(id string, maxDigits int) bool {
+	return id != "" && len(id) <= maxDigits && strings.Trim(id, "0123456789abcdefABCDEF") == ""
+}
+
+// NormalizeTraceID lower-cases a trace ID and pads it with leading zeros to 32 hex digits, so
+// that a 64-bit trace ID, as older tracing stacks send them, and the same ID padded to 128 bits
+// are one and the same. IDs that aren't valid are returned as they are.
+func NormalizeTraceID(id string) string {
+	return normalizeID(id, 32)
+}
+
+// NormalizeSpanID lower-cases a span ID and pads it with leading zeros to 16 hex digits
+func NormalizeSpanID(id string) string {
+	return normalizeID(id, 16)
+}
+
+func normalizeID(id string, digits int) string {
+	if !validID(id, digits) {
+		return id
+	}
+	return strings.Repeat("0", digits-len(id)) + strings.ToLower(id)
+}
+
+// NormalizeIDs normalizes the IDs of the span, its parent, and the spans it links to, as the
+// store keeps them. The links are copied rather than changed in place, as they may be shared.
+func (spanData *SpanData) NormalizeIDs() {
+	spanData.TraceID = NormalizeTraceID(spanData.TraceID)
+	spanData.SpanID = NormalizeSpanID(spanData.SpanID)
+	spanData.ParentSpanID = NormalizeSpanID(spanData.ParentSpanID)
+	if len(spanData.Links) > 0 {
+		links := make([]LinkData, len(spanData.Links))
+		for i, link := range spanData.Links {
+			link.TraceID = NormalizeTraceID(link.TraceID)
+			link.SpanID = NormalizeSpanID(link.SpanID)
+			links[i] = link
+		}
+		spanData.Links = links
+	}
+}
+
 // Get the service name of a span with respect to OTEL semanic conventions:
 // service.name must be a string value having a meaning that helps to distinguish a group of services.
 // Read more here: (https://opentelemetry.io/docs/reference/specification/resource/semantic_conventions/#service)
@@ -0,0 +1,9 @@
+package telemetry
+
+// SpanMatches lists the spans of a trace matching a search, in the order they started
+type SpanMatches struct {
+	TraceID string   `json:"traceID"`
+	Query   string   `json:"query"`
+	SpanIDs []string `json:"spanIDs"`
+	Count   int      `json:"count"`
+}
@@ -0,0 +1,33 @@
+package telemetry
+
+import "time"
+
+// StoreStatus sums up what the store holds and how it was opened. OldestSpan is when the
+// earliest span started and NewestSpan when the latest one ended, both left out while the
+// store is empty.
+type StoreStatus struct {
+	TraceCount    int        `json:"traceCount"`
+	SpanCount     int        `json:"spanCount"`
+	OldestSpan    *time.Time `json:"oldestSpan,omitempty"`
+	NewestSpan    *time.Time `json:"newestSpan,omitempty"`
+	Mode          string     `json:"mode"`
+	ReadOnly      bool       `json:"readOnly"`
+	SchemaVersion int        `json:"schemaVersion"`
+}
+
+// ForwardingStatus counts the spans forwarded to the upstream endpoint, and those dropped for
+// not fitting in the queue or failing to export
+type ForwardingStatus struct {
+	Endpoint       string `json:"endpoint"`
+	ForwardedSpans int64  `json:"forwardedSpans"`
+	DroppedSpans   int64  `json:"droppedSpans"`
+}
+
+// Status is the store's status along with how long the viewer has been running.
+// Forwarding is left out unless spans are forwarded upstream.
+type Status struct {
+	StoreStatus
+	StartedAt   time.Time         `json:"startedAt"`
+	UptimeNanos int64             `json:"uptimeNanos"`
+	Forwarding  *ForwardingStatus `json:"forwarding,omitempty"`
+}
@@ -0,0 +1,82 @@
+package telemetry_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnonymizeSpans(t *testing.T) {
+	newSpans := func() []telemetry.SpanData {
+		return []telemetry.SpanData{
+			{
+				SpanID:     "a",
+				Name:       "GET /checkout",
+				Attributes: telemetry.Attributes{"peer.service": "payments", "http.url": "https://shop.example.com/checkout", "http.status_code": int64(200)},
+				Resource:   &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": "checkout", "host.name": "laptop"}},
+				Events:     []telemetry.EventData{{Name: "retry", Attributes: telemetry.Attributes{"http.url": "https://shop.example.com/checkout"}}},
+			},
+			{
+				SpanID:       "b",
+				ParentSpanID: "a",
+				Name:         "charge",
+				Attributes:   telemetry.Attributes{"card.last4": int64(4242), "request": map[string]interface{}{"http.url": "https://pay.example.com"}},
+				Resource:     &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": "payments"}},
+				Scope:        &telemetry.ScopeData{Name: "payments", Attributes: telemetry.Attributes{"http.url": "https://pay.example.com"}},
+				Links:        []telemetry.LinkData{{TraceID: "1234", Attributes: telemetry.Attributes{"card.last4": int64(4242)}}},
+			},
+		}
+	}
+
+	anonymizer := telemetry.NewAnonymizer([]string{"http.url", "card.last4"})
+	original := newSpans()
+	spans := newSpans()
+	anonymizer.AnonymizeSpans(spans)
+
+	checkout, payments := spans[0], spans[1]
+	assert.True(t, strings.HasPrefix(checkout.Name, "span-"))
+	assert.True(t, strings.HasPrefix(checkout.GetServiceName(), "service-"))
+	assert.NotEqual(t, checkout.GetServiceName(), payments.GetServiceName())
+
+	// The service a span calls gets the token the service itself does
+	assert.Equal(t, payments.GetServiceName(), checkout.Attributes["peer.service"])
+
+	// A value gets the same token wherever it appears
+	url := checkout.Attributes["http.url"]
+	assert.True(t, strings.HasPrefix(url.(string), "value-"))
+	assert.Equal(t, url, checkout.Events[0].Attributes["http.url"])
+	assert.Equal(t, payments.Scope.Attributes["http.url"], payments.Attributes["request"].(map[string]interface{})["http.url"])
+	assert.Equal(t, payments.Attributes["card.last4"], payments.Links[0].Attributes["card.last4"])
+	assert.True(t, strings.HasPrefix(payments.Attributes["card.last4"].(string), "value-"))
+
+	// Everything else is kept as it was
+	assert.Equal(t, "a", payments.ParentSpanID)
+	assert.Equal(t, int64(200), checkout.Attributes["http.status_code"])
+	assert.Equal(t, "laptop", checkout.Resource.Attributes["host.name"])
+	assert.Equal(t, "retry", checkout.Events[0].Name)
+	assert.Equal(t, "1234", payments.Links[0].TraceID)
+
+	t.Run("Same Anonymizer", func(t *testing.T) {
+		again := newSpans()
+		anonymizer.AnonymizeSpans(again)
+		assert.Equal(t, spans, again)
+	})
+
+	t.Run("Another Anonymizer", func(t *testing.T) {
+		other := newSpans()
+		telemetry.NewAnonymizer([]string{"http.url", "card.last4"}).AnonymizeSpans(other)
+		assert.NotEqual(t, checkout.Name, other[0].Name)
+		assert.NotEqual(t, checkout.GetServiceName(), other[0].GetServiceName())
+		assert.NotEqual(t, url, other[0].Attributes["http.url"])
+	})
+
+	t.Run("Original Maps", func(t *testing.T) {
+		shared := newSpans()
+		resource, attributes := shared[0].Resource, shared[0].Attributes
+		anonymizer.AnonymizeSpans(shared)
+		assert.Equal(t, original[0].Resource, resource)
+		assert.Equal(t, original[0].Attributes, attributes)
+	})
+}
@@ -0,0 +1,50 @@
+package telemetry_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttributesJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+	}{
+		{"Int", int64(200), `200`},
+		{"Large Int", int64(9007199254740993), `9007199254740993`},
+		{"Float", 1.5, `1.5`},
+		{"Whole Float", 2.0, `2.0`},
+		{"Large Float", 1e21, `1e+21`},
+		{"Bool", true, `true`},
+		{"String", "200", `"200"`},
+		{"String Slice", []interface{}{"a", "b"}, `["a","b"]`},
+		{"Mixed Slice", []interface{}{int64(1), 1.0}, `[1,1.0]`},
+		{"Map", map[string]interface{}{"ratio": 3.0}, `{"ratio":3.0}`},
+		{
+			"Nested",
+			map[string]interface{}{"levels": []interface{}{map[string]interface{}{"depth": int64(3), "tags": []interface{}{"a", false}}}},
+			`{"levels":[{"depth":3,"tags":["a",false]}]}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(telemetry.Attributes{"key": tt.value})
+			assert.NoErrorf(t, err, "could not marshal attributes: %v", err)
+			assert.Equal(t, `{"key":`+tt.expected+`}`, string(data))
+
+			attributes := telemetry.Attributes{}
+			err = json.Unmarshal(data, &attributes)
+			assert.NoErrorf(t, err, "could not unmarshal attributes: %v", err)
+			assert.Equal(t, tt.value, attributes["key"])
+		})
+	}
+
+	data, err := json.Marshal(telemetry.Attributes(nil))
+	assert.NoError(t, err)
+	assert.Equal(t, "null", string(data))
+}
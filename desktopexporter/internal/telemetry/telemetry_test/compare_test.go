@@ -0,0 +1,69 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompareTraces(t *testing.T) {
+	span := func(traceID string, spanID string, parentSpanID string, name string, from int, to int) telemetry.SpanData {
+		span := namedFixtureSpan(spanID, parentSpanID, name, from, to)
+		span.TraceID = traceID
+		return span
+	}
+	ms := func(n int64) int64 {
+		return (time.Duration(n) * time.Millisecond).Nanoseconds()
+	}
+
+	// The slow trace makes its second query take longer, and calls render instead of cache
+	fast := telemetry.TraceData{
+		TraceID: "fast",
+		Spans: []telemetry.SpanData{
+			span("fast", "a1", "", "handler", 0, 100),
+			span("fast", "a2", "a1", "query", 10, 20),
+			span("fast", "a3", "a1", "query", 30, 40),
+			span("fast", "a4", "a1", "cache", 50, 60),
+			span("fast", "a5", "a4", "lookup", 52, 58),
+		},
+	}
+	slow := telemetry.TraceData{
+		TraceID: "slow",
+		Spans: []telemetry.SpanData{
+			span("slow", "b1", "", "handler", 0, 300),
+			span("slow", "b3", "b1", "query", 30, 230),
+			span("slow", "b2", "b1", "query", 10, 20),
+			span("slow", "b4", "b1", "render", 240, 250),
+		},
+	}
+
+	comparison := telemetry.CompareTraces(fast, slow)
+	assert.Equal(t, "fast", comparison.TraceIDA)
+	assert.Equal(t, "slow", comparison.TraceIDB)
+	assert.Equal(t, ms(200), comparison.DurationDeltaNanos)
+
+	assert.Equal(t, []telemetry.SpanMatch{
+		{Name: "handler", SpanIDA: "a1", SpanIDB: "b1", DurationNanosA: ms(100), DurationNanosB: ms(300), DeltaNanos: ms(200)},
+		{Name: "query", SpanIDA: "a2", SpanIDB: "b2", DurationNanosA: ms(10), DurationNanosB: ms(10), DeltaNanos: 0},
+		{Name: "query", SpanIDA: "a3", SpanIDB: "b3", DurationNanosA: ms(10), DurationNanosB: ms(200), DeltaNanos: ms(190)},
+	}, comparison.Matches)
+
+	onlyInA := []string{}
+	for _, span := range comparison.OnlyInA {
+		onlyInA = append(onlyInA, span.SpanID)
+	}
+	assert.Equal(t, []string{"a4", "a5"}, onlyInA)
+	if assert.Len(t, comparison.OnlyInB, 1) {
+		assert.Equal(t, "b4", comparison.OnlyInB[0].SpanID)
+	}
+
+	assert.Equal(t, []telemetry.OperationDelta{
+		{Name: "handler", SpanCountA: 1, SpanCountB: 1, TotalDurationNanosA: ms(100), TotalDurationNanosB: ms(300), DeltaNanos: ms(200)},
+		{Name: "query", SpanCountA: 2, SpanCountB: 2, TotalDurationNanosA: ms(20), TotalDurationNanosB: ms(210), DeltaNanos: ms(190)},
+		{Name: "cache", SpanCountA: 1, SpanCountB: 0, TotalDurationNanosA: ms(10), TotalDurationNanosB: 0, DeltaNanos: -ms(10)},
+		{Name: "render", SpanCountA: 0, SpanCountB: 1, TotalDurationNanosA: 0, TotalDurationNanosB: ms(10), DeltaNanos: ms(10)},
+		{Name: "lookup", SpanCountA: 1, SpanCountB: 0, TotalDurationNanosA: ms(6), TotalDurationNanosB: 0, DeltaNanos: -ms(6)},
+	}, comparison.Operations)
+}
@@ -0,0 +1,106 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindCriticalPath(t *testing.T) {
+	// The path names the service of each span, so every span needs one
+	span := func(spanID string, parentSpanID string, from int, to int) telemetry.SpanData {
+		span := fixtureSpan(spanID, parentSpanID, from, to)
+		span.Resource = &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": "frontend"}}
+		return span
+	}
+	type step struct {
+		spanID     string
+		criticalMs int
+	}
+
+	tests := []struct {
+		name             string
+		spans            []telemetry.SpanData
+		expectedDuration int
+		expectedPath     []step
+	}{
+		{
+			name:         "Empty",
+			spans:        []telemetry.SpanData{},
+			expectedPath: []step{},
+		},
+		{
+			// The root waits on the later call first, then on the earlier one
+			name: "Sequential",
+			spans: []telemetry.SpanData{
+				span("1", "", 0, 100),
+				span("2", "1", 10, 40),
+				span("3", "1", 50, 90),
+			},
+			expectedDuration: 100,
+			expectedPath:     []step{{"1", 30}, {"3", 40}, {"2", 30}},
+		},
+		{
+			// A call that finishes while a longer one is still running never holds the trace up
+			name: "Concurrent",
+			spans: []telemetry.SpanData{
+				span("1", "", 0, 100),
+				span("2", "1", 10, 90),
+				span("3", "1", 20, 50),
+				span("4", "2", 20, 60),
+			},
+			expectedDuration: 100,
+			expectedPath:     []step{{"1", 20}, {"2", 40}, {"4", 40}},
+		},
+		{
+			// Once the later call started, the earlier one only held the trace up until then
+			name: "Overlapping",
+			spans: []telemetry.SpanData{
+				span("1", "", 0, 100),
+				span("2", "1", 10, 60),
+				span("3", "1", 50, 90),
+			},
+			expectedDuration: 100,
+			expectedPath:     []step{{"1", 20}, {"3", 40}, {"2", 40}},
+		},
+		{
+			// Work the root didn't wait for only counts until the root ended
+			name: "Async",
+			spans: []telemetry.SpanData{
+				span("1", "", 0, 50),
+				span("2", "1", 10, 200),
+				span("3", "1", 60, 70),
+			},
+			expectedDuration: 50,
+			expectedPath:     []step{{"1", 10}, {"2", 40}},
+		},
+		{
+			name: "Missing Root",
+			spans: []telemetry.SpanData{
+				span("2", "1", 0, 30),
+				span("3", "1", 10, 80),
+			},
+			expectedDuration: 70,
+			expectedPath:     []step{{"3", 70}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			criticalPath := telemetry.FindCriticalPath(tt.spans)
+			assert.Equal(t, (time.Duration(tt.expectedDuration) * time.Millisecond).Nanoseconds(), criticalPath.DurationNanos)
+
+			path := []step{}
+			var total int64
+			for _, span := range criticalPath.Spans {
+				path = append(path, step{span.SpanID, int(time.Duration(span.CriticalDurationNanos) / time.Millisecond)})
+				total += span.CriticalDurationNanos
+				assert.Equal(t, "frontend", span.ServiceName)
+			}
+			assert.Equal(t, tt.expectedPath, path)
+			assert.Equal(t, criticalPath.DurationNanos, total, "critical durations should add up to the path's duration")
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildFlameGraph(t *testing.T) {
+	ms := func(n int64) int64 {
+		return (time.Duration(n) * time.Millisecond).Nanoseconds()
+	}
+
+	tests := []struct {
+		name           string
+		spans          []telemetry.SpanData
+		expectedFrames []*telemetry.FlameFrame
+	}{
+		{
+			name:           "Empty",
+			spans:          []telemetry.SpanData{},
+			expectedFrames: []*telemetry.FlameFrame{},
+		},
+		{
+			// The queries overlap each other, and the last one runs on past the handler. Each frame
+			// is as wide as the self time under it, so the handler is wider than its own duration.
+			name: "Overlapping Children",
+			spans: []telemetry.SpanData{
+				namedFixtureSpan("1", "", "handler", 0, 100),
+				namedFixtureSpan("2", "1", "query", 10, 40),
+				namedFixtureSpan("3", "1", "query", 30, 60),
+				namedFixtureSpan("4", "1", "render", 50, 70),
+				namedFixtureSpan("5", "1", "query", 90, 120),
+			},
+			expectedFrames: []*telemetry.FlameFrame{
+				{Name: "handler", SpanCount: 1, SelfDurationNanos: ms(30), TotalDurationNanos: ms(140), Children: []*telemetry.FlameFrame{
+					{Name: "query", SpanCount: 3, SelfDurationNanos: ms(90), TotalDurationNanos: ms(90), Children: []*telemetry.FlameFrame{}},
+					{Name: "render", SpanCount: 1, SelfDurationNanos: ms(20), TotalDurationNanos: ms(20), Children: []*telemetry.FlameFrame{}},
+				}},
+			},
+		},
+		{
+			name: "Recursive Calls",
+			spans: []telemetry.SpanData{
+				namedFixtureSpan("1", "", "walk", 0, 100),
+				namedFixtureSpan("2", "1", "walk", 10, 90),
+				namedFixtureSpan("3", "2", "walk", 20, 80),
+				namedFixtureSpan("4", "3", "visit", 30, 40),
+				namedFixtureSpan("5", "2", "visit", 85, 90),
+			},
+			expectedFrames: []*telemetry.FlameFrame{
+				{Name: "walk", SpanCount: 3, SelfDurationNanos: ms(85), TotalDurationNanos: ms(100), Children: []*telemetry.FlameFrame{
+					{Name: "visit", SpanCount: 2, SelfDurationNanos: ms(15), TotalDurationNanos: ms(15), Children: []*telemetry.FlameFrame{}},
+				}},
+			},
+		},
+		{
+			name: "Missing Parent",
+			spans: []telemetry.SpanData{
+				namedFixtureSpan("1", "", "handler", 0, 10),
+				namedFixtureSpan("2", "9", "query", 20, 30),
+				namedFixtureSpan("3", "8", "query", 40, 45),
+			},
+			expectedFrames: []*telemetry.FlameFrame{
+				{Name: "handler", SpanCount: 1, SelfDurationNanos: ms(10), TotalDurationNanos: ms(10), Children: []*telemetry.FlameFrame{}},
+				{Name: "query", SpanCount: 2, SelfDurationNanos: ms(15), TotalDurationNanos: ms(15), Children: []*telemetry.FlameFrame{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedFrames, telemetry.BuildFlameGraph(tt.spans))
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindGaps(t *testing.T) {
+	gap := func(afterSpanID string, from int, to int, beforeSpanID string) telemetry.TraceGap {
+		return telemetry.TraceGap{
+			Start:         fixtureTime(from),
+			End:           fixtureTime(to),
+			DurationNanos: (time.Duration(to-from) * time.Millisecond).Nanoseconds(),
+			AfterSpanID:   afterSpanID,
+			BeforeSpanID:  beforeSpanID,
+		}
+	}
+
+	tests := []struct {
+		name             string
+		spans            []telemetry.SpanData
+		expectedDuration int
+		expectedIdle     int
+		expectedGaps     []telemetry.TraceGap
+	}{
+		{
+			name:         "Empty",
+			spans:        []telemetry.SpanData{},
+			expectedGaps: []telemetry.TraceGap{},
+		},
+		{
+			name: "Nested",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 100),
+				fixtureSpan("2", "", 10, 30),
+				fixtureSpan("3", "", 50, 80),
+			},
+			expectedDuration: 100,
+			expectedGaps:     []telemetry.TraceGap{},
+		},
+		{
+			// Spans come in any order, and the longest gap comes first
+			name: "Sequential",
+			spans: []telemetry.SpanData{
+				fixtureSpan("3", "", 70, 100),
+				fixtureSpan("1", "", 0, 10),
+				fixtureSpan("2", "", 30, 40),
+			},
+			expectedDuration: 100,
+			expectedIdle:     50,
+			expectedGaps:     []telemetry.TraceGap{gap("2", 40, 70, "3"), gap("1", 10, 30, "2")},
+		},
+		{
+			// A gap is only idle if no span is running, however many overlap on either side
+			name: "Concurrent",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 50),
+				fixtureSpan("2", "", 10, 30),
+				fixtureSpan("3", "", 20, 60),
+				fixtureSpan("4", "", 90, 100),
+				fixtureSpan("5", "", 95, 120),
+			},
+			expectedDuration: 120,
+			expectedIdle:     30,
+			expectedGaps:     []telemetry.TraceGap{gap("3", 60, 90, "4")},
+		},
+		{
+			name: "Touching",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 10),
+				fixtureSpan("2", "", 10, 20),
+			},
+			expectedDuration: 20,
+			expectedGaps:     []telemetry.TraceGap{},
+		},
+		{
+			// A span that took no time, or ended before it started, splits the gap it falls in
+			name: "Instants",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 10),
+				fixtureSpan("2", "", 40, 40),
+				fixtureSpan("3", "", 70, 50),
+				fixtureSpan("4", "", 100, 110),
+			},
+			expectedDuration: 110,
+			expectedIdle:     90,
+			expectedGaps:     []telemetry.TraceGap{gap("1", 10, 40, "2"), gap("2", 40, 70, "3"), gap("3", 70, 100, "4")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gaps := telemetry.FindGaps(tt.spans)
+			assert.Equal(t, (time.Duration(tt.expectedDuration) * time.Millisecond).Nanoseconds(), gaps.DurationNanos)
+			assert.Equal(t, (time.Duration(tt.expectedIdle) * time.Millisecond).Nanoseconds(), gaps.IdleNanos)
+			assert.Equal(t, tt.expectedGaps, gaps.Gaps)
+		})
+	}
+}
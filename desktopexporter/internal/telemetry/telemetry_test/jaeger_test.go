@@ -0,0 +1,128 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+// jaegerTrace is a trace as the Jaeger UI downloads it, with a 64-bit trace ID
+const jaegerTrace = `{
+	"data": [{
+		"traceID": "a1b2c3d4e5f60718",
+		"spans": [
+			{
+				"traceID": "a1b2c3d4e5f60718",
+				"spanID": "1",
+				"operationName": "GET /checkout",
+				"references": [],
+				"startTime": 1700000000000123,
+				"duration": 2500,
+				"tags": [
+					{"key": "span.kind", "type": "string", "value": "server"},
+					{"key": "http.status_code", "type": "int64", "value": 500},
+					{"key": "http.retried", "type": "bool", "value": false},
+					{"key": "error", "type": "bool", "value": true},
+					{"key": "otel.library.name", "type": "string", "value": "checkout.http"},
+					{"key": "otel.library.version", "type": "string", "value": "1.2.0"}
+				],
+				"logs": [
+					{"timestamp": 1700000000001000, "fields": [
+						{"key": "event", "type": "string", "value": "cache miss"},
+						{"key": "cache.ratio", "type": "float64", "value": 0.25}
+					]}
+				],
+				"processID": "p1"
+			},
+			{
+				"traceID": "a1b2c3d4e5f60718",
+				"spanID": "00000000000000b2",
+				"operationName": "charge",
+				"references": [
+					{"refType": "CHILD_OF", "traceID": "a1b2c3d4e5f60718", "spanID": "1"},
+					{"refType": "FOLLOWS_FROM", "traceID": "ffff", "spanID": "c3"}
+				],
+				"startTime": 1700000000000500,
+				"duration": 1000,
+				"tags": [{"key": "payment.amount", "type": "float64", "value": "12.5"}],
+				"logs": [],
+				"processID": "p2"
+			}
+		],
+		"processes": {
+			"p1": {"serviceName": "checkout", "tags": [{"key": "host.name", "type": "string", "value": "pumpkin"}]},
+			"p2": {"serviceName": "payments", "tags": []}
+		}
+	}],
+	"total": 0, "limit": 0, "offset": 0, "errors": null
+}`
+
+func TestParseJaegerTraces(t *testing.T) {
+	spans, err := telemetry.ParseJaegerTraces([]byte(jaegerTrace))
+	if !assert.NoError(t, err) || !assert.Len(t, spans, 2) {
+		return
+	}
+
+	t.Run("Root Span", func(t *testing.T) {
+		span := spans[0]
+		assert.Equal(t, "0000000000000000a1b2c3d4e5f60718", span.TraceID)
+		assert.Equal(t, "0000000000000001", span.SpanID)
+		assert.Equal(t, "", span.ParentSpanID)
+		assert.Equal(t, "GET /checkout", span.Name)
+		assert.Equal(t, "Server", span.Kind)
+		assert.Equal(t, "Error", span.StatusCode)
+		assert.Equal(t, time.UnixMicro(1700000000000123).UTC(), span.StartTime)
+		assert.Equal(t, 2500*time.Microsecond, span.EndTime.Sub(span.StartTime))
+		assert.Equal(t, (2500 * time.Microsecond).Nanoseconds(), span.DurationNanos)
+
+		assert.Equal(t, telemetry.Attributes{"http.status_code": int64(500), "http.retried": false}, span.Attributes)
+		assert.Equal(t, "checkout", span.GetServiceName())
+		assert.Equal(t, "pumpkin", span.Resource.Attributes["host.name"])
+		assert.Equal(t, "checkout.http", span.Scope.Name)
+		assert.Equal(t, "1.2.0", span.Scope.Version)
+
+		if assert.Len(t, span.Events, 1) {
+			assert.Equal(t, "cache miss", span.Events[0].Name)
+			assert.Equal(t, time.UnixMicro(1700000000001000).UTC(), span.Events[0].Timestamp)
+			assert.Equal(t, telemetry.Attributes{"cache.ratio": 0.25}, span.Events[0].Attributes)
+		}
+		assert.Empty(t, span.Links)
+	})
+
+	t.Run("Child Span", func(t *testing.T) {
+		span := spans[1]
+		assert.Equal(t, "00000000000000b2", span.SpanID)
+		assert.Equal(t, "0000000000000001", span.ParentSpanID)
+		assert.Equal(t, "Unspecified", span.Kind)
+		assert.Equal(t, "Unset", span.StatusCode)
+		assert.Equal(t, "payments", span.GetServiceName())
+		assert.Equal(t, telemetry.Attributes{"payment.amount": 12.5}, span.Attributes)
+
+		if assert.Len(t, span.Links, 1) {
+			assert.Equal(t, "0000000000000000000000000000ffff", span.Links[0].TraceID)
+			assert.Equal(t, "00000000000000c3", span.Links[0].SpanID)
+		}
+	})
+
+	invalid := []struct {
+		name    string
+		payload string
+	}{
+		{name: "Not JSON", payload: "pumpkin pie"},
+		{name: "OTLP JSON", payload: `{"resourceSpans": []}`},
+		{name: "No Spans", payload: `{"data": [{"traceID": "1", "spans": [], "processes": {}}]}`},
+		{name: "Unknown Process", payload: `{"data": [{"spans": [{"traceID": "1", "spanID": "2", "processID": "p9"}], "processes": {}}]}`},
+		{name: "Bad Span ID", payload: `{"data": [{"spans": [{"traceID": "1", "spanID": "pie", "processID": "p1"}], "processes": {"p1": {"serviceName": "a"}}}]}`},
+		{name: "Bad Tag", payload: `{"data": [{"spans": [{"traceID": "1", "spanID": "2", "processID": "p1", "tags": [{"key": "n", "type": "int64", "value": "many"}]}], "processes": {"p1": {"serviceName": "a"}}}]}`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := telemetry.ParseJaegerTraces([]byte(tt.payload))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "invalid Jaeger JSON")
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+)
+
+func TestExtractLogs(t *testing.T) {
+	// More log records than resources, all under a single resource and scope
+	logs := plog.NewLogs()
+	resourceLogs := logs.ResourceLogs().AppendEmpty()
+	resourceLogs.Resource().Attributes().PutStr("service.name", "sample.currencyservice")
+	scopeLogs := resourceLogs.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().SetName("sample.logger")
+
+	timestamp := time.Date(2023, 02, 01, 20, 25, 36, 179472007, time.UTC)
+	for _, body := range []string{"converting", "converted"} {
+		record := scopeLogs.LogRecords().AppendEmpty()
+		record.Body().SetStr(body)
+		record.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		record.SetSeverityNumber(plog.SeverityNumberInfo)
+		record.SetSeverityText("INFO")
+		record.SetTraceID(pcommon.TraceID([16]byte{1}))
+		record.Attributes().PutInt("attempt", 1)
+	}
+
+	logData := telemetry.NewLogsPayload(logs).ExtractLogs()
+	if assert.Len(t, logData, 2) {
+		assert.Equal(t, "converted", logData[1].Body)
+		assert.Equal(t, timestamp, logData[1].Timestamp)
+		assert.Equal(t, plog.SeverityNumberInfo, logData[1].SeverityNumber)
+		assert.Equal(t, "INFO", logData[1].SeverityText)
+		assert.Equal(t, pcommon.TraceID([16]byte{1}).String(), logData[1].TraceID)
+		assert.Equal(t, "", logData[1].SpanID)
+		assert.Equal(t, int64(1), logData[1].Attributes["attempt"])
+		assert.Equal(t, "sample.currencyservice", logData[1].Resource.Attributes["service.name"])
+		assert.Equal(t, "sample.logger", logData[1].Scope.Name)
+	}
+}
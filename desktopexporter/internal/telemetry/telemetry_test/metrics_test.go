@@ -0,0 +1,140 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestExtractMetrics(t *testing.T) {
+	timestamp := time.Date(2023, 02, 01, 20, 25, 36, 179472007, time.UTC)
+
+	metrics := pmetric.NewMetrics()
+	resourceMetrics := metrics.ResourceMetrics().AppendEmpty()
+	resourceMetrics.Resource().Attributes().PutStr("service.name", "sample.currencyservice")
+	scopeMetrics := resourceMetrics.ScopeMetrics().AppendEmpty()
+	scopeMetrics.Scope().SetName("sample.meter")
+
+	gauge := scopeMetrics.Metrics().AppendEmpty()
+	gauge.SetName("queue.length")
+	gauge.SetEmptyGauge()
+	for i := 0; i < 2; i++ {
+		pt := gauge.Gauge().DataPoints().AppendEmpty()
+		pt.SetIntValue(int64(i + 3))
+		pt.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		pt.Attributes().PutStr("queue", "conversions")
+	}
+
+	histogram := scopeMetrics.Metrics().AppendEmpty()
+	histogram.SetName("conversion.duration")
+	histogram.SetUnit("ms")
+	histogram.SetEmptyHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	hpt := histogram.Histogram().DataPoints().AppendEmpty()
+	hpt.SetCount(3)
+	hpt.SetSum(12.5)
+	hpt.BucketCounts().FromRaw([]uint64{1, 2})
+	hpt.ExplicitBounds().FromRaw([]float64{5})
+	hpt.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	exemplar := hpt.Exemplars().AppendEmpty()
+	exemplar.SetIntValue(7)
+	exemplar.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+	exemplar.SetTraceID(pcommon.TraceID([16]byte{1}))
+	exemplar.SetSpanID(pcommon.SpanID([8]byte{2}))
+	exemplar.FilteredAttributes().PutStr("currency", "EUR")
+	hpt.Exemplars().AppendEmpty().SetDoubleValue(2.5)
+
+	exponential := scopeMetrics.Metrics().AppendEmpty()
+	exponential.SetName("conversion.size")
+	exponential.SetEmptyExponentialHistogram().SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	ept := exponential.ExponentialHistogram().DataPoints().AppendEmpty()
+	ept.SetCount(6)
+	ept.SetSum(40)
+	ept.SetMax(16)
+	ept.SetScale(1)
+	ept.SetZeroCount(1)
+	ept.SetZeroThreshold(0.001)
+	ept.Positive().SetOffset(3)
+	ept.Positive().BucketCounts().FromRaw([]uint64{2, 0, 3})
+	ept.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+
+	metricData := telemetry.NewMetricsPayload(metrics).ExtractMetrics()
+	if assert.Len(t, metricData, 4) {
+		assert.Equal(t, "queue.length", metricData[0].Name)
+		assert.Equal(t, "Gauge", metricData[0].Type)
+		assert.Equal(t, 3.0, *metricData[0].Value)
+		assert.Equal(t, timestamp, metricData[0].Timestamp)
+		assert.True(t, metricData[0].StartTime.IsZero())
+		assert.Equal(t, "conversions", metricData[0].Attributes["queue"])
+		assert.Nil(t, metricData[0].Histogram)
+		assert.Empty(t, metricData[0].Exemplars)
+		assert.Equal(t, "sample.currencyservice", metricData[0].Resource.Attributes["service.name"])
+		assert.Equal(t, "sample.meter", metricData[0].Scope.Name)
+
+		assert.Equal(t, 4.0, *metricData[1].Value)
+
+		assert.Equal(t, "Histogram", metricData[2].Type)
+		assert.Equal(t, "Cumulative", metricData[2].AggregationTemporality)
+		assert.Nil(t, metricData[2].Value)
+		if assert.NotNil(t, metricData[2].Histogram) {
+			assert.Equal(t, uint64(3), metricData[2].Histogram.Count)
+			assert.Equal(t, 12.5, *metricData[2].Histogram.Sum)
+			assert.Nil(t, metricData[2].Histogram.Min)
+			assert.Equal(t, []uint64{1, 2}, metricData[2].Histogram.BucketCounts)
+			assert.Equal(t, []float64{5}, metricData[2].Histogram.ExplicitBounds)
+		}
+		assert.Nil(t, metricData[2].ExponentialHistogram)
+		// Exemplars taken outside of a span have no trace or span ID
+		assert.Equal(t, []telemetry.ExemplarData{
+			{
+				Timestamp:          timestamp,
+				Value:              7,
+				TraceID:            "01000000000000000000000000000000",
+				SpanID:             "0200000000000000",
+				FilteredAttributes: telemetry.Attributes{"currency": "EUR"},
+			},
+			{Value: 2.5, FilteredAttributes: telemetry.Attributes{}},
+		}, metricData[2].Exemplars)
+
+		assert.Equal(t, "ExponentialHistogram", metricData[3].Type)
+		assert.Equal(t, "Delta", metricData[3].AggregationTemporality)
+		assert.Nil(t, metricData[3].Histogram)
+		sum, max := 40.0, 16.0
+		assert.Equal(t, &telemetry.ExponentialHistogramData{
+			Count:         6,
+			Sum:           &sum,
+			Max:           &max,
+			Scale:         1,
+			ZeroCount:     1,
+			ZeroThreshold: 0.001,
+			Positive:      telemetry.ExponentialHistogramBuckets{Offset: 3, BucketCounts: []uint64{2, 0, 3}},
+		}, metricData[3].ExponentialHistogram)
+	}
+
+	// Sums keep their monotonicity and temporality, as in the sample metric
+	sample := telemetry.NewSampleTelemetry().Metrics
+	if assert.Len(t, sample, 1) {
+		assert.Equal(t, "Sum", sample[0].Type)
+		assert.Equal(t, 1.9, *sample[0].Value)
+		assert.True(t, sample[0].IsMonotonic)
+		assert.Equal(t, "Delta", sample[0].AggregationTemporality)
+	}
+}
+
+func TestNewMetricSeriesList(t *testing.T) {
+	one, two := 1.0, 2.0
+	seriesList := telemetry.NewMetricSeriesList([]telemetry.MetricData{
+		{Name: "b", Value: &one},
+		{Name: "a", Value: &one},
+		{Name: "b", Value: &two},
+	})
+	if assert.Len(t, seriesList.Series, 2) {
+		assert.Equal(t, "b", seriesList.Series[0].Name)
+		assert.Len(t, seriesList.Series[0].DataPoints, 2)
+		assert.Equal(t, 2.0, *seriesList.Series[0].DataPoints[1].Value)
+		assert.Equal(t, "a", seriesList.Series[1].Name)
+	}
+}
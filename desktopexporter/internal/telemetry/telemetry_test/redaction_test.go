@@ -0,0 +1,55 @@
+package telemetry_test
+
+import (
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedactSpans(t *testing.T) {
+	redactor, err := telemetry.NewRedactor([]string{"user.email", "auth.token"}, []string{`\b\d{13,16}\b`})
+	assert.Nilf(t, err, "could not build redactor: %v", err)
+
+	resource := &telemetry.ResourceData{Attributes: telemetry.Attributes{"service.name": "checkout", "auth.token": "secret"}}
+	original := telemetry.Attributes{
+		"user.email":  "someone@example.com",
+		"user.id":     int64(42),
+		"card":        "paid with 4111111111111111 today",
+		"order.lines": []interface{}{"4111111111111111", int64(3)},
+		"customer":    map[string]interface{}{"user.email": "someone@example.com", "name": "Someone"},
+	}
+	spans := []telemetry.SpanData{{
+		Attributes: original,
+		Resource:   resource,
+		Scope:      &telemetry.ScopeData{Name: "checkout", Attributes: telemetry.Attributes{"auth.token": "secret"}},
+		Events:     []telemetry.EventData{{Name: "login", Attributes: telemetry.Attributes{"user.email": "someone@example.com"}}},
+		Links:      []telemetry.LinkData{{TraceID: "1234", Attributes: telemetry.Attributes{"card": "4111111111111111"}}},
+	}}
+	redactor.RedactSpans(spans)
+
+	span := spans[0]
+	assert.Equal(t, telemetry.Attributes{
+		"user.email":  telemetry.RedactedValue,
+		"user.id":     int64(42),
+		"card":        "paid with *** today",
+		"order.lines": []interface{}{"***", int64(3)},
+		"customer":    map[string]interface{}{"user.email": "***", "name": "Someone"},
+	}, span.Attributes)
+	assert.Equal(t, telemetry.Attributes{"service.name": "checkout", "auth.token": "***"}, span.Resource.Attributes)
+	assert.Equal(t, telemetry.Attributes{"auth.token": "***"}, span.Scope.Attributes)
+	assert.Equal(t, "checkout", span.Scope.Name)
+	assert.Equal(t, telemetry.Attributes{"user.email": "***"}, span.Events[0].Attributes)
+	assert.Equal(t, "login", span.Events[0].Name)
+	assert.Equal(t, telemetry.Attributes{"card": "***"}, span.Links[0].Attributes)
+	assert.Equal(t, "1234", span.Links[0].TraceID)
+
+	// The maps the spans came with, which other spans may share, are left alone
+	assert.Equal(t, "someone@example.com", original["user.email"])
+	assert.Equal(t, "secret", resource.Attributes["auth.token"])
+
+	t.Run("Invalid Pattern", func(t *testing.T) {
+		_, err := telemetry.NewRedactor(nil, []string{"("})
+		assert.ErrorContains(t, err, `invalid redaction pattern "("`)
+	})
+}
@@ -0,0 +1,83 @@
+package telemetry_test
+
+import (
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadSampleSet(t *testing.T) {
+	for _, set := range telemetry.SampleSets().Sets {
+		t.Run(set.Name, func(t *testing.T) {
+			spans, err := telemetry.LoadSampleSet(set.Name)
+			assert.Nilf(t, err, "could not load sample set: %v", err)
+			assert.NotEmpty(t, spans)
+
+			for _, span := range spans {
+				assert.Nilf(t, span.ValidateIDs(), "span %s has malformed IDs", span.Name)
+				assert.NotEmpty(t, span.Resource.Attributes["service.name"])
+				assert.False(t, span.EndTime.Before(span.StartTime))
+			}
+		})
+	}
+
+	t.Run("Default Set", func(t *testing.T) {
+		spans, err := telemetry.LoadSampleSet(telemetry.DefaultSampleSet)
+		assert.Nilf(t, err, "could not load sample set: %v", err)
+		assert.Equal(t, telemetry.NewSampleTelemetry().Spans, spans)
+	})
+
+	t.Run("Microservices Set", func(t *testing.T) {
+		spans, err := telemetry.LoadSampleSet("microservices")
+		assert.Nilf(t, err, "could not load sample set: %v", err)
+
+		services := map[string]bool{}
+		links := 0
+		for _, span := range spans {
+			services[span.Resource.Attributes["service.name"].(string)] = true
+			links += len(span.Links)
+		}
+		assert.Len(t, services, 6)
+		assert.Equal(t, 1, links)
+	})
+
+	t.Run("Errors Set", func(t *testing.T) {
+		spans, err := telemetry.LoadSampleSet("errors")
+		assert.Nilf(t, err, "could not load sample set: %v", err)
+
+		failed, exceptions := 0, 0
+		for _, span := range spans {
+			if span.StatusCode == "Error" {
+				failed++
+			}
+			for _, event := range span.Events {
+				if event.Name == "exception" {
+					exceptions++
+				}
+			}
+		}
+		assert.Equal(t, 5, failed)
+		assert.Equal(t, 3, exceptions)
+	})
+
+	t.Run("Deep Trace Set", func(t *testing.T) {
+		spans, err := telemetry.LoadSampleSet("deep-trace")
+		assert.Nilf(t, err, "could not load sample set: %v", err)
+
+		var depth func(nodes []*telemetry.SpanNode) int
+		depth = func(nodes []*telemetry.SpanNode) int {
+			deepest := 0
+			for _, node := range nodes {
+				deepest = max(deepest, 1+depth(node.Children))
+			}
+			return deepest
+		}
+		assert.Equal(t, 13, depth(telemetry.BuildSpanTree(spans)))
+	})
+
+	t.Run("Unknown Set", func(t *testing.T) {
+		_, err := telemetry.LoadSampleSet("nonsense")
+		assert.ErrorContains(t, err, "must be one of default, microservices, errors, deep-trace")
+	})
+}
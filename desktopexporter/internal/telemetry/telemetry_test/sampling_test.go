@@ -0,0 +1,71 @@
+package telemetry_test
+
+import (
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleSpans(t *testing.T) {
+	// The root has two short children, one of which has long children of its own
+	spans := []telemetry.SpanData{
+		fixtureSpan("1", "", 0, 100),
+		fixtureSpan("2", "1", 0, 10),
+		fixtureSpan("3", "1", 10, 15),
+		fixtureSpan("4", "2", 20, 90),
+		fixtureSpan("5", "2", 20, 60),
+		fixtureSpan("6", "2", 20, 30),
+		fixtureSpan("7", "9", 95, 96),
+	}
+
+	tests := []struct {
+		name            string
+		maxSpans        int
+		expectedSpanIDs []string
+		expectedOmitted int
+	}{
+		{
+			name:            "No Limit",
+			maxSpans:        0,
+			expectedSpanIDs: []string{"1", "2", "3", "4", "5", "6", "7"},
+			expectedOmitted: 0,
+		},
+		{
+			name:            "Under The Limit",
+			maxSpans:        7,
+			expectedSpanIDs: []string{"1", "2", "3", "4", "5", "6", "7"},
+			expectedOmitted: 0,
+		},
+		{
+			name:            "Children Before Longer Spans",
+			maxSpans:        5,
+			expectedSpanIDs: []string{"1", "2", "3", "4", "7"},
+			expectedOmitted: 2,
+		},
+		{
+			name:            "Room For Some Children",
+			maxSpans:        3,
+			expectedSpanIDs: []string{"1", "2", "7"},
+			expectedOmitted: 4,
+		},
+		{
+			name:            "Roots Always Kept",
+			maxSpans:        1,
+			expectedSpanIDs: []string{"1", "7"},
+			expectedOmitted: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sampled, omitted := telemetry.SampleSpans(spans, tt.maxSpans)
+			spanIDs := []string{}
+			for _, span := range sampled {
+				spanIDs = append(spanIDs, span.SpanID)
+			}
+			assert.ElementsMatch(t, tt.expectedSpanIDs, spanIDs)
+			assert.Equal(t, tt.expectedOmitted, omitted)
+		})
+	}
+}
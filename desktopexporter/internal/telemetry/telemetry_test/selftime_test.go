@@ -0,0 +1,85 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetSelfDurations(t *testing.T) {
+	tests := []struct {
+		name     string
+		spans    []telemetry.SpanData
+		expected map[string]int
+	}{
+		{
+			name:     "Empty",
+			spans:    []telemetry.SpanData{},
+			expected: map[string]int{},
+		},
+		{
+			name: "Sequential Children",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 100),
+				fixtureSpan("2", "1", 10, 30),
+				fixtureSpan("3", "1", 50, 80),
+				fixtureSpan("4", "3", 60, 70),
+			},
+			expected: map[string]int{"1": 50, "2": 20, "3": 20, "4": 10},
+		},
+		{
+			// Overlapping children only count once, and the part of a child running past its
+			// parent's end doesn't count at all
+			name: "Overlapping Children",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 100),
+				fixtureSpan("2", "1", 10, 40),
+				fixtureSpan("3", "1", 30, 60),
+				fixtureSpan("4", "1", 35, 45),
+				fixtureSpan("5", "1", 90, 120),
+			},
+			expected: map[string]int{"1": 40, "2": 30, "3": 30, "4": 10, "5": 30},
+		},
+		{
+			name: "Fully Covered",
+			spans: []telemetry.SpanData{
+				fixtureSpan("1", "", 0, 100),
+				fixtureSpan("2", "1", 0, 100),
+			},
+			expected: map[string]int{"1": 0, "2": 100},
+		},
+		{
+			name: "Missing Parent",
+			spans: []telemetry.SpanData{
+				fixtureSpan("2", "9", 20, 30),
+				fixtureSpan("3", "2", 22, 25),
+			},
+			expected: map[string]int{"2": 7, "3": 3},
+		},
+		{
+			name: "Children Out Of Order",
+			spans: []telemetry.SpanData{
+				fixtureSpan("3", "1", 60, 90),
+				fixtureSpan("1", "", 0, 100),
+				fixtureSpan("2", "1", 0, 20),
+			},
+			expected: map[string]int{"1": 50, "2": 20, "3": 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			telemetry.SetSelfDurations(tt.spans)
+
+			selfDurations := map[string]int{}
+			for _, span := range tt.spans {
+				if assert.NotNil(t, span.SelfDurationNanos, span.SpanID) {
+					selfDurations[span.SpanID] = int(time.Duration(*span.SelfDurationNanos) / time.Millisecond)
+				}
+			}
+			assert.Equal(t, tt.expected, selfDurations)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSimpleSpans(t *testing.T) {
+	t.Run("Spans", func(t *testing.T) {
+		spans, err := telemetry.ParseSimpleSpans([]byte(`[
+			{"traceId": "a1", "spanId": "1", "name": "checkout", "kind": "SERVER",
+			 "startUnixNano": 1700000000000000123, "endUnixNano": "1700000000002500123",
+			 "attributes": {"http.status_code": 200, "cart.total": 12.5},
+			 "resource": {"attributes": {"service.name": "shop"}}},
+			{"traceId": "a1", "spanId": "2", "parentSpanId": "1", "name": "charge",
+			 "startUnixNano": 1700000000000500000, "endUnixNano": 1700000000000500000}
+		]`))
+		if !assert.NoError(t, err) || !assert.Len(t, spans, 2) {
+			return
+		}
+
+		root := spans[0]
+		assert.Equal(t, "000000000000000000000000000000a1", root.TraceID)
+		assert.Equal(t, "0000000000000001", root.SpanID)
+		assert.Equal(t, "", root.ParentSpanID)
+		assert.Equal(t, "Server", root.Kind)
+		assert.Equal(t, time.Unix(0, 1700000000000000123).UTC(), root.StartTime)
+		assert.Equal(t, (2500 * time.Microsecond).Nanoseconds(), root.DurationNanos)
+		assert.Equal(t, telemetry.Attributes{"http.status_code": int64(200), "cart.total": 12.5}, root.Attributes)
+		assert.Equal(t, "shop", root.GetServiceName())
+		assert.Equal(t, "Unset", root.StatusCode)
+		assert.Nil(t, root.ValidateIDs())
+
+		// Spans without a resource or attributes get empty ones, named like an unconfigured SDK
+		child := spans[1]
+		assert.Equal(t, "0000000000000001", child.ParentSpanID)
+		assert.Equal(t, "Unspecified", child.Kind)
+		assert.Equal(t, int64(0), child.DurationNanos)
+		assert.Equal(t, telemetry.Attributes{}, child.Attributes)
+		assert.Equal(t, telemetry.DefaultServiceName, child.GetServiceName())
+		assert.NotNil(t, child.Scope)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		tests := []struct {
+			payload  string
+			expected string
+		}{
+			{`{"traceId": "a1"}`, "invalid span JSON"},
+			{`[{"spanId": "1", "name": "a", "startUnixNano": 1, "endUnixNano": 2}]`, "span 0: missing traceId"},
+			{`[{"traceId": "a1", "name": "a", "startUnixNano": 1, "endUnixNano": 2}]`, "span 0: missing spanId"},
+			{`[{"traceId": "a1", "spanId": "1", "startUnixNano": 1, "endUnixNano": 2}]`, "span 0: missing name"},
+			{`[{"traceId": "a1", "spanId": "1", "name": "a", "endUnixNano": 2}]`, "span 0: missing startUnixNano"},
+			{`[{"traceId": "a1", "spanId": "1", "name": "a", "startUnixNano": 1}]`, "span 0: missing endUnixNano"},
+			{`[{"traceId": "a1", "spanId": "1", "name": "a", "startUnixNano": 2, "endUnixNano": 1}]`, "endUnixNano must not be before startUnixNano"},
+			{`[{"traceId": "a1", "spanId": "1", "name": "a", "startUnixNano": "soon", "endUnixNano": 1}]`, "must be a positive whole number of nanoseconds"},
+			{`[{"traceId": "xyz", "spanId": "1", "name": "a", "startUnixNano": 1, "endUnixNano": 2}]`, "invalid traceId"},
+			{`[{"traceId": "a1", "spanId": "12345678901234567", "name": "a", "startUnixNano": 1, "endUnixNano": 2}]`, "invalid spanId"},
+			{`[{"traceId": "a1", "spanId": "1", "name": "a", "kind": "sideways", "startUnixNano": 1, "endUnixNano": 2}]`, "invalid kind"},
+		}
+		for _, tt := range tests {
+			_, err := telemetry.ParseSimpleSpans([]byte(tt.payload))
+			assert.ErrorContains(t, err, tt.expected, tt.payload)
+		}
+	})
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
 	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 )
 
 // Validate resource data
@@ -55,6 +56,33 @@ func init() {
 	spans = telemetry.NewSampleTelemetry().Spans
 }
 
+// fixtureStart is what the times of the spans built by fixtureSpan are counted from
+var fixtureStart = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// fixtureTime is the time ms milliseconds after fixtureStart
+func fixtureTime(ms int) time.Time {
+	return fixtureStart.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// fixtureSpan builds a span of trace 1234 that starts and ends the given number of milliseconds
+// after fixtureStart
+func fixtureSpan(spanID string, parentSpanID string, from int, to int) telemetry.SpanData {
+	return telemetry.SpanData{
+		TraceID:      "1234",
+		SpanID:       spanID,
+		ParentSpanID: parentSpanID,
+		StartTime:    fixtureTime(from),
+		EndTime:      fixtureTime(to),
+	}
+}
+
+// namedFixtureSpan is a fixtureSpan with a name
+func namedFixtureSpan(spanID string, parentSpanID string, name string, from int, to int) telemetry.SpanData {
+	span := fixtureSpan(spanID, parentSpanID, from, to)
+	span.Name = name
+	return span
+}
+
 func TestExtractSpans(t *testing.T) {
 	// Validate number of spans extraced from the sample telemetry
 	assert.Len(t, spans, 4)
@@ -149,6 +177,9 @@ func TestSpan(t *testing.T) {
 	// End time
 	assert.Equal(t, time.Date(2023, 02, 02, 18, 17, 54, 816274688, time.UTC), span.EndTime)
 
+	// Duration
+	assert.Equal(t, int64(11234816), span.DurationNanos)
+
 	// Span kind
 	assert.Equal(t, "Unset", span.StatusCode)
 
@@ -166,3 +197,99 @@ func TestSpan(t *testing.T) {
 		assert.Equal(t, attr.expectedValue, span.Attributes[attr.key])
 	}
 }
+
+func TestDurationNanos(t *testing.T) {
+	start := time.Date(2023, 02, 02, 18, 17, 54, 805039872, time.UTC)
+	tests := []struct {
+		name     string
+		end      time.Time
+		expected int64
+	}{
+		{"End After Start", start.Add(1500 * time.Microsecond), 1500000},
+		{"End At Start", start, 0},
+		{"End Before Start", start.Add(-time.Second), 0},
+		{"Other Time Zone", start.In(time.FixedZone("UTC+2", 2*60*60)).Add(time.Second), 1000000000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, telemetry.DurationNanos(start, tt.end))
+		})
+	}
+}
+
+func TestValidateIDs(t *testing.T) {
+	tests := []struct {
+		name     string
+		traceID  string
+		spanID   string
+		expected error
+	}{
+		{"Well Formed", "42957c7c2fca940a0d32a0cdd38c06a4", "37fd1349bf83d330", nil},
+		{"Short", "1234567890", "12345", nil},
+		{"Empty Trace ID", "", "37fd1349bf83d330", telemetry.ErrInvalidTraceID},
+		{"Empty Span ID", "42957c7c2fca940a0d32a0cdd38c06a4", "", telemetry.ErrInvalidSpanID},
+		{"Not Hex", "42957c7c2fca940a0d32a0cdd38c06zz", "37fd1349bf83d330", telemetry.ErrInvalidTraceID},
+		{"Too Long", "42957c7c2fca940a0d32a0cdd38c06a4", "37fd1349bf83d33000", telemetry.ErrInvalidSpanID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			span := telemetry.SpanData{TraceID: tt.traceID, SpanID: tt.spanID}
+			err := span.ValidateIDs()
+			if tt.expected == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.expected)
+			}
+		})
+	}
+}
+
+func TestNormalizeIDs(t *testing.T) {
+	assert.Equal(t, "00000000000000000d32a0cdd38c06a4", telemetry.NormalizeTraceID("0D32A0CDD38C06A4"))
+	assert.Equal(t, "42957c7c2fca940a0d32a0cdd38c06a4", telemetry.NormalizeTraceID("42957c7c2fca940a0d32a0cdd38c06a4"))
+	assert.Equal(t, "0000000000012345", telemetry.NormalizeSpanID("12345"))
+	assert.Equal(t, "", telemetry.NormalizeSpanID(""))
+	assert.Equal(t, "sample-trace", telemetry.NormalizeTraceID("sample-trace"))
+
+	links := []telemetry.LinkData{{TraceID: "0d32a0cdd38c06a4", SpanID: "abc"}}
+	span := telemetry.SpanData{TraceID: "0d32a0cdd38c06a4", SpanID: "37FD1349BF83D330", ParentSpanID: "1", Links: links}
+	span.NormalizeIDs()
+	assert.Equal(t, "00000000000000000d32a0cdd38c06a4", span.TraceID)
+	assert.Equal(t, "37fd1349bf83d330", span.SpanID)
+	assert.Equal(t, "0000000000000001", span.ParentSpanID)
+	assert.Equal(t, "00000000000000000d32a0cdd38c06a4", span.Links[0].TraceID)
+	assert.Equal(t, "0000000000000abc", span.Links[0].SpanID)
+
+	// The links the span was given are left alone
+	assert.Equal(t, "0d32a0cdd38c06a4", links[0].TraceID)
+}
+
+func TestNewTracesFromSpans(t *testing.T) {
+	// Spans converted back into OTLP traces extract to the same spans
+	traces := telemetry.NewTracesFromSpans(spans)
+	assert.Equal(t, 3, traces.ResourceSpans().Len())
+	assert.Equal(t, 4, traces.SpanCount())
+	assert.Equal(t, spans, telemetry.NewSpanPayload(traces).ExtractSpans())
+
+	// Numbers keep their type
+	span := spans[0]
+	span.Attributes = telemetry.Attributes{
+		"http.status_code": int64(200),
+		"http.duration":    1.5,
+		"http.retries":     []any{int64(1), int64(2)},
+	}
+	attributes := telemetry.NewTracesFromSpans([]telemetry.SpanData{span}).ResourceSpans().At(0).ScopeSpans().At(0).Spans().At(0).Attributes()
+
+	statusCode, _ := attributes.Get("http.status_code")
+	assert.Equal(t, pcommon.ValueTypeInt, statusCode.Type())
+	assert.Equal(t, int64(200), statusCode.Int())
+
+	duration, _ := attributes.Get("http.duration")
+	assert.Equal(t, pcommon.ValueTypeDouble, duration.Type())
+	assert.Equal(t, 1.5, duration.Double())
+
+	retries, _ := attributes.Get("http.retries")
+	assert.Equal(t, []any{int64(1), int64(2)}, retries.Slice().AsRaw())
+}
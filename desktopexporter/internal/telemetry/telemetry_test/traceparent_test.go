@@ -0,0 +1,42 @@
+package telemetry_test
+
+import (
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceID(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		expected string
+		valid    bool
+	}{
+		{"Bare Trace ID", "42957c7c2fca940a0d32a0cdd38c06a4", "42957c7c2fca940a0d32a0cdd38c06a4", true},
+		{"Upper Case", "42957C7C2FCA940A0D32A0CDD38C06A4", "42957c7c2fca940a0d32a0cdd38c06a4", true},
+		{"64-Bit Trace ID", "0d32a0cdd38c06a4", "00000000000000000d32a0cdd38c06a4", true},
+		{"Traceparent", "00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01", "42957c7c2fca940a0d32a0cdd38c06a4", true},
+		{"Future Version With More Fields", "01-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01-extra", "42957c7c2fca940a0d32a0cdd38c06a4", true},
+		{"Empty", "", "", false},
+		{"Not Hex", "sample-trace", "", false},
+		{"Version 00 With More Fields", "00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01-extra", "", false},
+		{"Invalid Version", "ff-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01", "", false},
+		{"Short Trace ID", "00-42957c7c2fca940a-37fd1349bf83d330-01", "", false},
+		{"Zero Trace ID", "00-00000000000000000000000000000000-37fd1349bf83d330-01", "", false},
+		{"Missing Flags", "00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			traceID, err := telemetry.ParseTraceID(tt.text)
+			if tt.valid {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, traceID)
+			} else {
+				assert.ErrorContains(t, err, "W3C traceparent")
+			}
+		})
+	}
+}
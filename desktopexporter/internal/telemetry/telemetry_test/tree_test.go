@@ -0,0 +1,146 @@
+package telemetry_test
+
+import (
+	"testing"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+// treeShape describes a tree by span ID, with an asterisk marking placeholders
+type treeShape map[string][]string
+
+func shapeOf(roots []*telemetry.SpanNode) ([]string, treeShape) {
+	rootIDs := []string{}
+	shape := treeShape{}
+
+	var walk func(node *telemetry.SpanNode) string
+	walk = func(node *telemetry.SpanNode) string {
+		id := node.SpanID
+		if node.Span == nil {
+			id += "*"
+		}
+		for _, child := range node.Children {
+			shape[id] = append(shape[id], walk(child))
+		}
+		return id
+	}
+	for _, root := range roots {
+		rootIDs = append(rootIDs, walk(root))
+	}
+	return rootIDs, shape
+}
+
+func TestBuildSpanTree(t *testing.T) {
+	tests := []struct {
+		name          string
+		spans         []telemetry.SpanData
+		expectedRoots []string
+		expectedShape treeShape
+	}{
+		{
+			name:          "Empty",
+			spans:         []telemetry.SpanData{},
+			expectedRoots: []string{},
+			expectedShape: treeShape{},
+		},
+		{
+			name: "Out Of Order",
+			spans: []telemetry.SpanData{
+				fixtureSpan("c", "b", 2, 2),
+				fixtureSpan("d", "a", 3, 3),
+				fixtureSpan("b", "a", 1, 1),
+				fixtureSpan("a", "", 0, 0),
+			},
+			expectedRoots: []string{"a"},
+			expectedShape: treeShape{"a": {"b", "d"}, "b": {"c"}},
+		},
+		{
+			name: "Orphans",
+			spans: []telemetry.SpanData{
+				fixtureSpan("a", "", 0, 0),
+				fixtureSpan("c", "missing2", 3, 3),
+				fixtureSpan("b", "missing1", 1, 1),
+				fixtureSpan("d", "missing1", 4, 4),
+			},
+			expectedRoots: []string{"a", "missing1*", "missing2*"},
+			expectedShape: treeShape{"missing1*": {"b", "d"}, "missing2*": {"c"}},
+		},
+		{
+			name: "Cycle",
+			spans: []telemetry.SpanData{
+				fixtureSpan("a", "", 0, 0),
+				fixtureSpan("c", "b", 2, 2),
+				fixtureSpan("b", "c", 1, 1),
+				fixtureSpan("d", "c", 3, 3),
+			},
+			expectedRoots: []string{"a", "b"},
+			expectedShape: treeShape{"b": {"c"}, "c": {"d"}},
+		},
+		{
+			name: "Child Of A Cycle",
+			spans: []telemetry.SpanData{
+				fixtureSpan("c", "a", 0, 0),
+				fixtureSpan("a", "b", 1, 1),
+				fixtureSpan("b", "a", 2, 2),
+			},
+			expectedRoots: []string{"a"},
+			expectedShape: treeShape{"a": {"c", "b"}},
+		},
+		{
+			name: "Own Parent",
+			spans: []telemetry.SpanData{
+				fixtureSpan("a", "a", 0, 0),
+				fixtureSpan("b", "a", 1, 1),
+			},
+			expectedRoots: []string{"a"},
+			expectedShape: treeShape{"a": {"b"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roots, shape := shapeOf(telemetry.BuildSpanTree(tt.spans))
+			assert.Equal(t, tt.expectedRoots, roots)
+			assert.Equal(t, tt.expectedShape, shape)
+		})
+	}
+}
+
+func TestDetachedSpans(t *testing.T) {
+	// Orphans are detached under their placeholder, and a cycle at the span it was cut at,
+	// while the spans nested under either are attached as usual
+	roots := telemetry.BuildSpanTree([]telemetry.SpanData{
+		fixtureSpan("a", "", 0, 0),
+		fixtureSpan("b", "a", 1, 1),
+		fixtureSpan("c", "missing", 2, 2),
+		fixtureSpan("d", "c", 3, 3),
+		fixtureSpan("e", "missing", 4, 4),
+		fixtureSpan("f", "g", 5, 5),
+		fixtureSpan("g", "f", 6, 6),
+	})
+
+	detached := map[string]string{}
+	nodes := roots
+	for len(nodes) > 0 {
+		node := nodes[0]
+		nodes = append(nodes[1:], node.Children...)
+		detached[node.SpanID] = node.Detached
+	}
+	assert.Equal(t, map[string]string{
+		"a":       "",
+		"b":       "",
+		"c":       telemetry.DetachedMissingParent,
+		"d":       "",
+		"e":       telemetry.DetachedMissingParent,
+		"f":       telemetry.DetachedParentCycle,
+		"g":       "",
+		"missing": "",
+	}, detached)
+
+	assert.Equal(t, map[string]int{
+		telemetry.DetachedMissingParent: 2,
+		telemetry.DetachedParentCycle:   1,
+	}, telemetry.CountDetachedSpans(roots))
+	assert.Empty(t, telemetry.CountDetachedSpans(telemetry.BuildSpanTree([]telemetry.SpanData{fixtureSpan("a", "", 0, 0)})))
+}
@@ -0,0 +1,103 @@
+package telemetry_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CtrlSpice/otel-desktop-viewer/desktopexporter/internal/telemetry"
+	"github.com/stretchr/testify/assert"
+)
+
+// zipkinSpans is a span list as a Zipkin reporter sends it, with a 64-bit trace ID
+const zipkinSpans = `[
+	{
+		"traceId": "a1b2c3d4e5f60718",
+		"id": "1",
+		"name": "get /checkout",
+		"kind": "SERVER",
+		"timestamp": 1700000000000123,
+		"duration": 2500,
+		"localEndpoint": {"serviceName": "checkout", "ipv4": "10.0.0.1"},
+		"annotations": [{"timestamp": 1700000000001000, "value": "cache miss"}],
+		"tags": {"http.method": "GET", "error": "card declined", "otel.library.name": "checkout.http"}
+	},
+	{
+		"traceId": "a1b2c3d4e5f60718",
+		"id": "00000000000000b2",
+		"parentId": "0000000000000001",
+		"name": "charge",
+		"kind": "CLIENT",
+		"timestamp": 1700000000000500,
+		"duration": 1000,
+		"localEndpoint": {"serviceName": "checkout"},
+		"remoteEndpoint": {"serviceName": "payments", "ipv6": "::1", "port": 8080}
+	}
+]`
+
+func TestParseZipkinSpans(t *testing.T) {
+	spans, err := telemetry.ParseZipkinSpans([]byte(zipkinSpans))
+	if !assert.NoError(t, err) || !assert.Len(t, spans, 2) {
+		return
+	}
+
+	t.Run("Root Span", func(t *testing.T) {
+		span := spans[0]
+		assert.Equal(t, "0000000000000000a1b2c3d4e5f60718", span.TraceID)
+		assert.Equal(t, "0000000000000001", span.SpanID)
+		assert.Equal(t, "", span.ParentSpanID)
+		assert.Equal(t, "get /checkout", span.Name)
+		assert.Equal(t, "Server", span.Kind)
+		assert.Equal(t, "Error", span.StatusCode)
+		assert.Equal(t, "card declined", span.StatusMessage)
+		assert.Equal(t, time.UnixMicro(1700000000000123).UTC(), span.StartTime)
+		assert.Equal(t, 2500*time.Microsecond, span.EndTime.Sub(span.StartTime))
+		assert.Equal(t, (2500 * time.Microsecond).Nanoseconds(), span.DurationNanos)
+
+		assert.Equal(t, telemetry.Attributes{"http.method": "GET"}, span.Attributes)
+		assert.Equal(t, "checkout", span.GetServiceName())
+		assert.Equal(t, "checkout.http", span.Scope.Name)
+
+		if assert.Len(t, span.Events, 1) {
+			assert.Equal(t, "cache miss", span.Events[0].Name)
+			assert.Equal(t, time.UnixMicro(1700000000001000).UTC(), span.Events[0].Timestamp)
+		}
+	})
+
+	t.Run("Child Span", func(t *testing.T) {
+		span := spans[1]
+		assert.Equal(t, "00000000000000b2", span.SpanID)
+		assert.Equal(t, "0000000000000001", span.ParentSpanID)
+		assert.Equal(t, "Client", span.Kind)
+		assert.Equal(t, "Unset", span.StatusCode)
+		assert.Equal(t, telemetry.Attributes{
+			"peer.service":  "payments",
+			"net.peer.ip":   "::1",
+			"net.peer.port": int64(8080),
+		}, span.Attributes)
+	})
+
+	t.Run("Empty List", func(t *testing.T) {
+		spans, err := telemetry.ParseZipkinSpans([]byte("[]"))
+		assert.NoError(t, err)
+		assert.Empty(t, spans)
+	})
+
+	invalid := []struct {
+		name    string
+		payload string
+	}{
+		{name: "Not JSON", payload: "pumpkin pie"},
+		{name: "Not A List", payload: `{"resourceSpans": []}`},
+		{name: "Missing Timestamp", payload: `[{"traceId": "1", "id": "2", "duration": 10}]`},
+		{name: "Bad Trace ID", payload: `[{"traceId": "pie", "id": "2", "timestamp": 1700000000000000}]`},
+		{name: "Bad Parent ID", payload: `[{"traceId": "1", "id": "2", "parentId": "pie", "timestamp": 1700000000000000}]`},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := telemetry.ParseZipkinSpans([]byte(tt.payload))
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), "invalid Zipkin JSON")
+			}
+		})
+	}
+}
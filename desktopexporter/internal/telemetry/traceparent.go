@@ -0,0 +1,38 @@
+package telemetry
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseTraceID reads the trace ID out of text that is either a bare trace ID, or a W3C
+// traceparent header value such as 00-42957c7c2fca940a0d32a0cdd38c06a4-37fd1349bf83d330-01,
+// as logs often print them. Trace IDs are returned normalized, as spans store them, so a 64-bit
+// trace ID finds the same trace as its zero-padded form.
+func ParseTraceID(text string) (string, error) {
+	if !strings.Contains(text, "-") {
+		if !validID(text, 32) {
+			return "", invalidTraceIDError(text)
+		}
+		return NormalizeTraceID(text), nil
+	}
+
+	// Versions after 00 may add fields, but always start with these four
+	fields := strings.Split(text, "-")
+	if len(fields) < 4 || (fields[0] == "00" && len(fields) != 4) {
+		return "", invalidTraceIDError(text)
+	}
+	version, traceID, parentID, flags := fields[0], fields[1], fields[2], fields[3]
+	if len(version) != 2 || !validID(version, 2) || strings.EqualFold(version, "ff") ||
+		len(traceID) != 32 || !validID(traceID, 32) || strings.Trim(traceID, "0") == "" ||
+		len(parentID) != 16 || !validID(parentID, 16) ||
+		len(flags) != 2 || !validID(flags, 2) {
+		return "", invalidTraceIDError(text)
+	}
+	return strings.ToLower(traceID), nil
+}
+
+func invalidTraceIDError(text string) error {
+	return fmt.Errorf("invalid trace ID %q: must be a trace ID of up to 32 hex digits, "+
+		"or a W3C traceparent such as 00-<32 hex digit trace ID>-<16 hex digit span ID>-01", text)
+}
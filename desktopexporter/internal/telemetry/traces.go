@@ -7,10 +7,86 @@ import (
 type TraceData struct {
 	TraceID string     `json:"traceID"`
 	Spans   []SpanData `json:"spans"`
+	// TotalSpans is only set when Spans is a page of the trace's spans, or only those of some kinds
+	TotalSpans int `json:"totalSpans,omitempty"`
+	// Sampled is set when only some of the trace's spans were kept to cut it down to size,
+	// and OmittedSpans is the number left out
+	Sampled      bool `json:"sampled,omitempty"`
+	OmittedSpans int  `json:"omittedSpans,omitempty"`
+}
+
+// TraceBatch holds the traces fetched together by ID, keyed by trace ID. IDs that weren't found are left out.
+type TraceBatch struct {
+	Traces map[string]TraceData `json:"traces"`
 }
 
 type TraceSummaries struct {
 	TraceSummaries []TraceSummary `json:"traceSummaries"`
+	TotalCount     int            `json:"totalCount"`
+	NextOffset     *int           `json:"nextOffset"`
+}
+
+// FollowedTraces holds the summaries of the traces written since a long poll's cursor,
+// and the cursor to poll from next
+type FollowedTraces struct {
+	TraceSummaries []TraceSummary `json:"traceSummaries"`
+	Cursor         time.Time      `json:"cursor"`
+}
+
+// ImportedTraces reports which traces an imported file contained,
+// and how many of its spans were already in the store
+type ImportedTraces struct {
+	TraceIDs       []string `json:"traceIDs"`
+	ImportedSpans  int      `json:"importedSpans"`
+	DuplicateSpans int      `json:"duplicateSpans"`
+}
+
+// ClearedTraces reports how many traces a clear removed
+type ClearedTraces struct {
+	ClearedTraces int `json:"clearedTraces"`
+}
+
+// TracePin reports whether a trace is pinned, keeping it from being evicted
+type TracePin struct {
+	TraceID string `json:"traceID"`
+	Pinned  bool   `json:"pinned"`
+}
+
+// TraceStats sums up a trace, so it can be triaged without going through every span
+type TraceStats struct {
+	TraceID          string         `json:"traceID"`
+	SpanCount        int            `json:"spanCount"`
+	SpanCountsByKind map[string]int `json:"spanCountsByKind"`
+	// ErrorCount counts the spans with an Error status code
+	ErrorCount int `json:"errorCount"`
+
+	// DurationNanos runs from the earliest span start to the latest span end
+	DurationNanos int64 `json:"durationNanos"`
+	// SelfDurationNanos is the part of the root span's duration that none of its children cover
+	SelfDurationNanos int64 `json:"selfDurationNanos"`
+	// MaxDepth counts the levels of nesting, with the root span as the first level.
+	// Spans whose parent hasn't arrived count from their own level.
+	MaxDepth int `json:"maxDepth"`
+	// DetachedSpanCount counts the spans that couldn't be nested under their parent, which
+	// DetachedSpansByReason breaks down by why, as the span tree marks them
+	DetachedSpanCount     int            `json:"detachedSpanCount"`
+	DetachedSpansByReason map[string]int `json:"detachedSpansByReason"`
+
+	// The dropped counts add up what the spans' SDKs had to throw away to stay within their limits
+	DroppedAttributesCount int `json:"droppedAttributesCount"`
+	DroppedEventsCount     int `json:"droppedEventsCount"`
+	DroppedLinksCount      int `json:"droppedLinksCount"`
+	// DroppedBySpan lists the spans that dropped anything, in the order they started
+	DroppedBySpan []SpanDroppedCounts `json:"droppedBySpan,omitempty"`
+}
+
+// SpanDroppedCounts is what a single span dropped
+type SpanDroppedCounts struct {
+	SpanID                 string `json:"spanID"`
+	Name                   string `json:"name"`
+	DroppedAttributesCount uint32 `json:"droppedAttributesCount"`
+	DroppedEventsCount     uint32 `json:"droppedEventsCount"`
+	DroppedLinksCount      uint32 `json:"droppedLinksCount"`
 }
 
 type TraceSummary struct {
@@ -20,7 +96,19 @@ type TraceSummary struct {
 	RootName        string    `json:"rootName"`
 	RootStartTime   time.Time `json:"rootStartTime"`
 	RootEndTime     time.Time `json:"rootEndTime"`
+	// RootDurationNanos is derived from RootStartTime and RootEndTime
+	RootDurationNanos int64 `json:"rootDurationNanos"`
 
 	SpanCount uint32 `json:"spanCount"`
 	TraceID   string `json:"traceID"`
+
+	// MatchedEvents lists the span events that matched an event search or filter, in the order
+	// their spans started. It is left out of summaries that weren't searched for events.
+	MatchedEvents []EventMatch `json:"matchedEvents,omitempty"`
+
+	// Annotations are the notes left on the trace, left out of summaries of traces without any
+	Annotations Annotations `json:"annotations,omitempty"`
+
+	// Pinned traces are never evicted by the retention policy or the span cap
+	Pinned bool `json:"pinned"`
 }
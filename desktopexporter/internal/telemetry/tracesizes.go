@@ -0,0 +1,19 @@
+package telemetry
+
+// TraceSizeBucket counts the traces with between MinSpans and MaxSpans spans, inclusive.
+// The last bucket has no MaxSpans.
+type TraceSizeBucket struct {
+	Label      string `json:"label"`
+	MinSpans   int    `json:"minSpans"`
+	MaxSpans   int    `json:"maxSpans,omitempty"`
+	TraceCount int    `json:"traceCount"`
+}
+
+// TraceSizeHistogram sorts traces into buckets by their span count, smallest first, and points
+// out the largest of them
+type TraceSizeHistogram struct {
+	Buckets           []TraceSizeBucket `json:"buckets"`
+	TotalTraces       int               `json:"totalTraces"`
+	LargestTraceID    string            `json:"largestTraceID,omitempty"`
+	LargestTraceSpans int               `json:"largestTraceSpans,omitempty"`
+}
@@ -0,0 +1,140 @@
+package telemetry
+
+import (
+	"slices"
+)
+
+// SpanTree holds the spans of a trace nested under their parents
+type SpanTree struct {
+	TraceID string      `json:"traceID"`
+	Roots   []*SpanNode `json:"roots"`
+}
+
+// The reasons a span can be detached from its parent, rather than nested under it
+const (
+	// DetachedMissingParent marks a span whose parent was never received
+	DetachedMissingParent = "missingParent"
+	// DetachedParentInOtherTrace marks a span whose parent was received as part of another trace
+	DetachedParentInOtherTrace = "parentInOtherTrace"
+	// DetachedParentCycle marks the span a cycle of parent IDs was cut at
+	DetachedParentCycle = "parentCycle"
+)
+
+// SpanNode is a span and the spans whose parent it is, earliest first. A node whose Span is nil
+// is a placeholder for a parent span that isn't in the trace, holding the orphans that name it.
+type SpanNode struct {
+	SpanID string    `json:"spanID"`
+	Span   *SpanData `json:"span"`
+	// Detached is why the span isn't nested under its parent, and empty when it is or has none
+	Detached string `json:"detached,omitempty"`
+	// TraceID is only set on a placeholder whose span is in another trace, to that trace's ID
+	TraceID  string      `json:"traceID,omitempty"`
+	Children []*SpanNode `json:"children"`
+}
+
+// BuildSpanTree nests spans under their parents by ParentSpanID. The roots it returns are the
+// spans without a parent, earliest first, followed by a placeholder for every parent that is
+// missing, ordered by the earliest of its children. Spans whose parent IDs form a cycle would
+// never be reached from a root, so the cycle is cut at its earliest span, which becomes a root.
+// Orphans and the spans cycles are cut at are marked detached, with the reason why.
+func BuildSpanTree(spans []SpanData) []*SpanNode {
+	sorted := slices.Clone(spans)
+	slices.SortStableFunc(sorted, func(a, b SpanData) int {
+		return a.StartTime.Compare(b.StartTime)
+	})
+
+	nodes := make([]*SpanNode, len(sorted))
+	nodesByID := map[string]*SpanNode{}
+	for i := range sorted {
+		nodes[i] = &SpanNode{SpanID: sorted[i].SpanID, Span: &sorted[i], Children: []*SpanNode{}}
+		// Should a span ID be repeated, children go to the earliest span with it
+		if _, ok := nodesByID[sorted[i].SpanID]; !ok {
+			nodesByID[sorted[i].SpanID] = nodes[i]
+		}
+	}
+
+	roots := []*SpanNode{}
+	placeholders := []*SpanNode{}
+	placeholdersByID := map[string]*SpanNode{}
+	parents := map[*SpanNode]*SpanNode{}
+	for _, node := range nodes {
+		parentID := node.Span.ParentSpanID
+		if parentID == "" {
+			roots = append(roots, node)
+			continue
+		}
+
+		parent, ok := nodesByID[parentID]
+		if !ok {
+			node.Detached = DetachedMissingParent
+			parent, ok = placeholdersByID[parentID]
+			if !ok {
+				parent = &SpanNode{SpanID: parentID, Children: []*SpanNode{}}
+				placeholdersByID[parentID] = parent
+				placeholders = append(placeholders, parent)
+			}
+		}
+		parent.Children = append(parent.Children, node)
+		parents[node] = parent
+	}
+
+	reached := map[*SpanNode]bool{}
+	reach := func(from *SpanNode) {
+		stack := []*SpanNode{from}
+		for len(stack) > 0 {
+			node := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if reached[node] {
+				continue
+			}
+			reached[node] = true
+			stack = append(stack, node.Children...)
+		}
+	}
+	for _, root := range roots {
+		reach(root)
+	}
+	for _, placeholder := range placeholders {
+		reach(placeholder)
+	}
+
+	// Every span left unreached is on a cycle or hangs off one, and walking up from it comes
+	// back to where it started only if it is on the cycle itself
+	onCycle := func(node *SpanNode) bool {
+		seen := map[*SpanNode]bool{}
+		for ancestor := parents[node]; !seen[ancestor]; ancestor = parents[ancestor] {
+			if ancestor == node {
+				return true
+			}
+			seen[ancestor] = true
+		}
+		return false
+	}
+	for _, node := range nodes {
+		if reached[node] || !onCycle(node) {
+			continue
+		}
+		parents[node].Children = slices.DeleteFunc(parents[node].Children, func(child *SpanNode) bool {
+			return child == node
+		})
+		node.Detached = DetachedParentCycle
+		roots = append(roots, node)
+		reach(node)
+	}
+
+	return append(roots, placeholders...)
+}
+
+// CountDetachedSpans counts the detached spans in a span tree by the reason they are detached
+func CountDetachedSpans(roots []*SpanNode) map[string]int {
+	counts := map[string]int{}
+	nodes := slices.Clone(roots)
+	for len(nodes) > 0 {
+		node := nodes[len(nodes)-1]
+		nodes = append(nodes[:len(nodes)-1], node.Children...)
+		if node.Detached != "" {
+			counts[node.Detached]++
+		}
+	}
+	return counts
+}
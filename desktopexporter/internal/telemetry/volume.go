@@ -0,0 +1,20 @@
+package telemetry
+
+import "time"
+
+// TraceVolumeBucket counts the traces whose first span started within BucketNanos of Start,
+// and the traces among them with a span that failed
+type TraceVolumeBucket struct {
+	Start      time.Time `json:"start"`
+	TraceCount int       `json:"traceCount"`
+	ErrorCount int       `json:"errorCount"`
+}
+
+// TraceVolume counts traces over a window of time, a bucket at a time, oldest first. Every
+// bucket in the window is listed, even those without any traces.
+type TraceVolume struct {
+	Start       time.Time           `json:"start"`
+	End         time.Time           `json:"end"`
+	BucketNanos int64               `json:"bucketNanos"`
+	Buckets     []TraceVolumeBucket `json:"buckets"`
+}
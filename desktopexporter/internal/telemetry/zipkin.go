@@ -0,0 +1,141 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/collector/pdata/ptrace"
+)
+
+// zipkinSpan is a span as Zipkin reporters send it to /api/v2/spans
+type zipkinSpan struct {
+	TraceID  string `json:"traceId"`
+	ID       string `json:"id"`
+	ParentID string `json:"parentId"`
+	Name     string `json:"name"`
+	Kind     string `json:"kind"`
+	// Timestamp and Duration are in microseconds
+	Timestamp      int64              `json:"timestamp"`
+	Duration       int64              `json:"duration"`
+	LocalEndpoint  *zipkinEndpoint    `json:"localEndpoint"`
+	RemoteEndpoint *zipkinEndpoint    `json:"remoteEndpoint"`
+	Annotations    []zipkinAnnotation `json:"annotations"`
+	Tags           map[string]string  `json:"tags"`
+}
+
+type zipkinEndpoint struct {
+	ServiceName string `json:"serviceName"`
+	IPv4        string `json:"ipv4"`
+	IPv6        string `json:"ipv6"`
+	Port        int64  `json:"port"`
+}
+
+type zipkinAnnotation struct {
+	// Timestamp is in microseconds
+	Timestamp int64  `json:"timestamp"`
+	Value     string `json:"value"`
+}
+
+// ParseZipkinSpans reads a Zipkin v2 JSON span list. The service name of each span's local
+// endpoint becomes its resource's service.name, and its remote endpoint becomes peer.service,
+// net.peer.ip, and net.peer.port attributes. Tags become string attributes, apart from those
+// Zipkin and OpenTelemetry's Zipkin exporter use for the status and scope, and annotations
+// become events.
+func ParseZipkinSpans(payload []byte) ([]SpanData, error) {
+	spans := []zipkinSpan{}
+	if err := json.Unmarshal(payload, &spans); err != nil {
+		return nil, fmt.Errorf("invalid Zipkin JSON: %s", err.Error())
+	}
+
+	spanData := make([]SpanData, 0, len(spans))
+	for _, span := range spans {
+		data, err := zipkinSpanData(span)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Zipkin JSON: span %q: %s", span.Name, err.Error())
+		}
+		spanData = append(spanData, data)
+	}
+	return spanData, nil
+}
+
+func zipkinSpanData(span zipkinSpan) (SpanData, error) {
+	if span.Timestamp <= 0 {
+		return SpanData{}, fmt.Errorf("missing timestamp")
+	}
+
+	spanData := SpanData{
+		Name:       span.Name,
+		Kind:       ptrace.SpanKindUnspecified.String(),
+		StartTime:  time.UnixMicro(span.Timestamp).UTC(),
+		EndTime:    time.UnixMicro(span.Timestamp + span.Duration).UTC(),
+		Attributes: Attributes{},
+		Events:     []EventData{},
+		Links:      []LinkData{},
+		Resource:   &ResourceData{Attributes: Attributes{}},
+		Scope: &ScopeData{
+			Name:       "",
+			Version:    "",
+			Attributes: Attributes{},
+		},
+		StatusCode: ptrace.StatusCodeUnset.String(),
+	}
+	spanData.DurationNanos = DurationNanos(spanData.StartTime, spanData.EndTime)
+
+	var err error
+	if spanData.TraceID, err = paddedID(span.TraceID, 16); err != nil {
+		return spanData, fmt.Errorf("invalid traceId: %s", err.Error())
+	}
+	if spanData.SpanID, err = paddedID(span.ID, 8); err != nil {
+		return spanData, fmt.Errorf("invalid id: %s", err.Error())
+	}
+	if span.ParentID != "" {
+		if spanData.ParentSpanID, err = paddedID(span.ParentID, 8); err != nil {
+			return spanData, fmt.Errorf("invalid parentId: %s", err.Error())
+		}
+	}
+	if spanKind, ok := spanKinds[strings.ToLower(span.Kind)]; ok {
+		spanData.Kind = spanKind.String()
+	}
+
+	if span.LocalEndpoint != nil && span.LocalEndpoint.ServiceName != "" {
+		spanData.Resource.Attributes["service.name"] = span.LocalEndpoint.ServiceName
+	}
+	if remote := span.RemoteEndpoint; remote != nil {
+		if remote.ServiceName != "" {
+			spanData.Attributes["peer.service"] = remote.ServiceName
+		}
+		if remote.IPv4 != "" {
+			spanData.Attributes["net.peer.ip"] = remote.IPv4
+		} else if remote.IPv6 != "" {
+			spanData.Attributes["net.peer.ip"] = remote.IPv6
+		}
+		if remote.Port != 0 {
+			spanData.Attributes["net.peer.port"] = remote.Port
+		}
+	}
+
+	for key, value := range span.Tags {
+		spanData.Attributes[key] = value
+	}
+	// Zipkin marks failed spans with an error tag, whose value is the error message if there is one
+	if message, ok := spanData.Attributes["error"].(string); ok {
+		spanData.StatusCode = ptrace.StatusCodeError.String()
+		if message != "" && message != "true" {
+			spanData.StatusMessage = message
+		}
+		delete(spanData.Attributes, "error")
+	}
+	mapOTelTags(&spanData)
+
+	for _, annotation := range span.Annotations {
+		spanData.Events = append(spanData.Events, EventData{
+			Name:       annotation.Value,
+			Timestamp:  time.UnixMicro(annotation.Timestamp).UTC(),
+			Attributes: Attributes{},
+		})
+	}
+
+	return spanData, nil
+}
@@ -0,0 +1,36 @@
+package desktopexporter
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+)
+
+var logLevels = map[string]slog.Level{
+	"debug": slog.LevelDebug,
+	"info":  slog.LevelInfo,
+	"warn":  slog.LevelWarn,
+	"error": slog.LevelError,
+}
+
+// newLogger builds the logger requests are logged with. Empty settings fall back to
+// the defaults, so a config written before they existed still works.
+func newLogger(writer io.Writer, level string, format string) (*slog.Logger, error) {
+	if level == "" {
+		level = defaultLogLevel
+	}
+	minLevel, ok := logLevels[level]
+	if !ok {
+		return nil, fmt.Errorf("log_level %q must be debug, info, warn, or error", level)
+	}
+
+	options := &slog.HandlerOptions{Level: minLevel}
+	switch format {
+	case "", "text":
+		return slog.New(slog.NewTextHandler(writer, options)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(writer, options)), nil
+	default:
+		return nil, fmt.Errorf("log_format %q must be text or json", format)
+	}
+}